@@ -59,7 +59,6 @@ var catalogTables = []struct {
 		{Name: "name", Type: "VARCHAR(50)", Comment: "团队成员真实姓名"},
 		{Name: "avatar", Type: "VARCHAR(255)", Comment: "头像URL"},
 		{Name: "role", Type: "VARCHAR(50)", Comment: "职位角色"},
-		{Name: "team", Type: "VARCHAR(50)", Comment: "所属团队名称"},
 		{Name: "team_id", Type: "INT", Comment: "所属团队ID,关联teams.id"},
 		{Name: "status", Type: "VARCHAR(20)", Comment: "状态:active/deleted"},
 		{Name: "is_admin", Type: "BOOL", Comment: "是否管理员"},
@@ -67,6 +66,8 @@ var catalogTables = []struct {
 	{"teams", []sdk.Column{
 		{Name: "id", Type: "INT", IsPk: true, Comment: "主键"},
 		{Name: "name", Type: "VARCHAR(50)", Comment: "团队名称"},
+		{Name: "parent_id", Type: "INT", Comment: "上级团队ID,关联teams.id,0为顶级部门"},
+		{Name: "lead_id", Type: "INT", Comment: "组长,关联members.id,0为未指定"},
 	}},
 	{"daily_entries", []sdk.Column{
 		{Name: "id", Type: "INT", IsPk: true, Comment: "主键"},
@@ -82,9 +83,9 @@ var catalogTables = []struct {
 		{Name: "member_id", Type: "INT", Comment: "关联members.id"},
 		{Name: "daily_date", Type: "DATE", Comment: "日报所属日期"},
 		{Name: "summary", Type: "TEXT", Comment: "AI生成的工作摘要"},
-		{Name: "status", Type: "TEXT", Comment: "工作状态"},
+		{Name: "status", Type: "TEXT", Comment: "工作状态:on-track(正常)/at-risk(有风险)/blocked(被阻塞)/off(休假)"},
 		{Name: "risk", Type: "TEXT", Comment: "AI检测到的风险项"},
-		{Name: "blocker", Type: "TEXT", Comment: "阻塞问题"},
+		{Name: "blocker", Type: "TEXT", Comment: "阻塞问题,多条以'; '分隔,无阻塞为空"},
 	}},
 	{"topics", []sdk.Column{
 		{Name: "id", Type: "INT", IsPk: true, Comment: "主键"},
@@ -103,6 +104,23 @@ var catalogTables = []struct {
 		{Name: "content", Type: "TEXT", Comment: "工作内容"},
 		{Name: "entry_id", Type: "INT", Comment: "关联daily_entries.id"},
 	}},
+	{"risks", []sdk.Column{
+		{Name: "id", Type: "INT", IsPk: true, Comment: "主键"},
+		{Name: "member_id", Type: "INT", Comment: "上报人ID,关联members.id"},
+		{Name: "member_name", Type: "VARCHAR(50)", Comment: "上报人姓名"},
+		{Name: "daily_date", Type: "DATE", Comment: "上报日期"},
+		{Name: "entry_id", Type: "INT", Comment: "来源日报,关联daily_entries.id"},
+		{Name: "topic_id", Type: "INT", Comment: "关联topics.id"},
+		{Name: "description", Type: "TEXT", Comment: "风险描述"},
+		{Name: "severity", Type: "VARCHAR(10)", Comment: "严重程度:high/medium/low"},
+		{Name: "category", Type: "VARCHAR(20)", Comment: "分类:blocked/delay/incident/dependency"},
+		{Name: "owner_id", Type: "INT", Comment: "负责人ID,关联members.id"},
+		{Name: "status", Type: "VARCHAR(20)", Comment: "状态:open/mitigated/closed"},
+		{Name: "resolution", Type: "TEXT", Comment: "处理说明"},
+		{Name: "created_at", Type: "DATETIME", Comment: "创建时间"},
+		{Name: "updated_at", Type: "DATETIME", Comment: "更新时间"},
+		{Name: "closed_at", Type: "DATETIME", Comment: "关闭时间"},
+	}},
 }
 
 func discoverDatabaseID(ctx context.Context, client *sdk.RawClient, catalogID sdk.CatalogID, dbName string) (sdk.DatabaseID, error) {
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+	"os"
+	"time"
+
+	"smart-daily/internal/config"
+	"smart-daily/internal/fakellm"
+	"smart-daily/internal/logger"
+)
+
+// fakellm serves scripted chat completions for offline runs and CI.
+// Point the server at it with LLM_PROVIDER=openai LLM_BASE_URL=http://localhost:9880/v1
+// (or moi.base_url, since /llm-proxy/v1/chat/completions is served too).
+func main() {
+	addr := flag.String("addr", ":9880", "listen address")
+	scriptFile := flag.String("script", "", "JSON script file (default: built-in script)")
+	delay := flag.Duration("delay", 0, "delay between streamed chunks, e.g. 20ms")
+	flag.Parse()
+
+	logger.Init(config.LogConfig{Level: "info", Console: true})
+
+	script := fakellm.DefaultScript()
+	if *scriptFile != "" {
+		s, err := fakellm.LoadScript(*scriptFile)
+		if err != nil {
+			logger.Error("load script failed", "file", *scriptFile, "err", err)
+			os.Exit(1)
+		}
+		script = s
+	}
+
+	srv := fakellm.NewServer(script)
+	srv.Delay = *delay
+	srv.OnMiss = func(fp, firstLine string) {
+		logger.Warn("fakellm.miss", "fingerprint", fp, "prompt", firstLine)
+	}
+
+	logger.Info("fakellm starting", "addr", *addr, "rules", len(script.Rules))
+	if err := (&http.Server{Addr: *addr, Handler: srv, ReadHeaderTimeout: 10 * time.Second}).ListenAndServe(); err != nil {
+		logger.Error("fakellm failed", "err", err)
+		os.Exit(1)
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"io/fs"
 	"net/http"
 	"os"
+	"smart-daily/internal/audit"
+	"smart-daily/internal/authz"
 	"smart-daily/internal/config"
 	"strconv"
 	"smart-daily/internal/handler"
@@ -17,6 +19,7 @@ import (
 	"smart-daily/internal/middleware"
 	"smart-daily/internal/model"
 	"smart-daily/internal/repository"
+	"smart-daily/internal/scheduler"
 	"smart-daily/internal/service"
 	"sync"
 	"sync/atomic"
@@ -40,14 +43,33 @@ func main() {
 		logger.Error("db connect failed", "err", err)
 		os.Exit(1)
 	}
-	// Auto-create teams table if not exists
-	db.Exec("CREATE TABLE IF NOT EXISTS teams (id INT AUTO_INCREMENT PRIMARY KEY, name VARCHAR(50) NOT NULL UNIQUE)")
-	// Add team_id column to members (ignore error if already exists)
-	db.Exec("ALTER TABLE members ADD COLUMN team_id INT DEFAULT 0")
-	// Auto-create topic_activities table if not exists
-	db.Exec("CREATE TABLE IF NOT EXISTS topic_activities (id INT AUTO_INCREMENT PRIMARY KEY, topic VARCHAR(100) NOT NULL, member_id INT NOT NULL, member_name VARCHAR(50) NOT NULL, daily_date DATE NOT NULL, content TEXT, entry_id INT DEFAULT 0, INDEX idx_topic (topic), INDEX idx_daily_date (daily_date))")
-	db.Exec("CREATE TABLE IF NOT EXISTS topics (id INT AUTO_INCREMENT PRIMARY KEY, name VARCHAR(100) NOT NULL UNIQUE, description TEXT DEFAULT '', status VARCHAR(20) DEFAULT 'active', created_at DATETIME DEFAULT NOW(), resolved_at DATETIME DEFAULT NULL)")
-	db.Exec("CREATE TABLE IF NOT EXISTS feedback (id INT AUTO_INCREMENT PRIMARY KEY, member_id INT NOT NULL, member_name VARCHAR(50) NOT NULL, content TEXT NOT NULL, status VARCHAR(20) DEFAULT 'open', created_at DATETIME DEFAULT NOW())")
+	sqlDB, err := db.DB()
+	if err != nil {
+		logger.Error("db connect failed", "err", err)
+		os.Exit(1)
+	}
+	if args := flag.Args(); len(args) > 0 && args[0] == "migrate" {
+		os.Exit(runMigrate(sqlDB, args[1:]))
+	}
+	jwtKeys, err := cfg.Auth.LoadKeys()
+	if err == nil {
+		var ring *middleware.KeyRing
+		if ring, err = middleware.NewKeyRing(jwtKeys, cfg.Auth.ActiveKID); err == nil {
+			middleware.SetKeyRing(ring)
+			if ring.Ephemeral() {
+				logger.Warn("no auth.keys configured, using a random JWT key: logins will not survive a restart")
+			}
+		}
+	}
+	if err != nil {
+		logger.Error("jwt keys init failed", "err", err)
+		os.Exit(1)
+	}
+	// Refuse to serve on an outdated or half-migrated schema
+	if err := checkSchema(sqlDB); err != nil {
+		logger.Error("schema not up to date, run `smart-daily migrate up`", "err", err)
+		os.Exit(1)
+	}
 
 	raw, err := cfg.NewRawClient()
 	if err != nil {
@@ -59,7 +81,13 @@ func main() {
 		catalogSync = service.NewCatalogSync(raw, cfg.MOI.CatalogID, cfg.Database.Name, cfg.MOI.BaseURL, cfg.MOI.APIKey)
 	}
 
-	aiSvc := service.NewAIService(cfg.MOI.BaseURL, cfg.MOI.APIKey, cfg.MOI.Model, cfg.MOI.FastModel, cfg.Database.Name, raw)
+	llm, err := service.NewLLMProvider(cfg.MOI)
+	if err != nil {
+		logger.Error("llm provider init failed", "err", err)
+		os.Exit(1)
+	}
+	logger.Info("llm provider", "name", llm.Name(), "model", llm.Model(), "fast_model", llm.FastModel())
+	aiSvc := service.NewAIService(llm, cfg.Database.Name, raw)
 	if catalogSync != nil && catalogSync.Ready() {
 		aiSvc.SetCatalogDBID(catalogSync.DatabaseID())
 	}
@@ -67,10 +95,43 @@ func main() {
 	memberRepo := repository.NewMemberRepo(db)
 	dailyRepo := repository.NewDailyRepo(db)
 	topicRepo := repository.NewTopicRepo(db)
+	draftRepo := repository.NewDraftRepo(db)
+	riskRepo := repository.NewRiskRepo(db)
+	webhookRepo := repository.NewWebhookRepo(db)
+	tokenRepo := repository.NewTokenRepo(db)
+	middleware.SetStores(tokenRepo, memberRepo)
+	auditRepo := repository.NewAuditRepo(db)
+	audit.SetStore(auditRepo)
 
 	// Services
-	dailySvc := service.NewDailyService(dailyRepo)
+	webhookSvc := service.NewWebhookService(webhookRepo)
+	webhookSvc.Start(context.Background())
+	topicRepo.SetPublisher(webhookSvc)
+	dailySvc := service.NewDailyService(dailyRepo, topicRepo, riskRepo, aiSvc)
+	dailySvc.SetPublisher(webhookSvc)
+	if catalogSync != nil {
+		catalogSync.SetDB(db)
+		dailySvc.SetCatalogSync(catalogSync)
+	}
 	authSvc := service.NewAuthService(memberRepo)
+	authenticators, err := service.NewAuthenticators(cfg.Auth, memberRepo)
+	if err != nil {
+		logger.Error("auth provider init failed", "err", err)
+		os.Exit(1)
+	}
+	authSvc.SetAuthenticators(authenticators, cfg.Auth.Provision)
+	if cfg.Auth.OIDC.Issuer != "" {
+		oidcProvider, err := service.NewOIDCProvider(cfg.Auth.OIDC)
+		if err != nil {
+			logger.Error("auth provider init failed", "err", err)
+			os.Exit(1)
+		}
+		authSvc.SetOIDC(oidcProvider)
+	}
+	logger.Info("auth providers", "password", authSvc.Providers(), "oidc", cfg.Auth.OIDC.Issuer)
+
+	// File legacy risk text as risk records (one-time, before the Catalog sync below)
+	dailySvc.BackfillRisks(context.Background())
 
 	// Sync all data to Catalog at startup (idempotent, ConflictPolicyReplace)
 	if catalogSync != nil && catalogSync.Ready() {
@@ -110,6 +171,12 @@ func main() {
 			if len(activities) > 0 {
 				catalogSync.SyncAllTopicActivities(activities)
 			}
+
+			var risks []model.Risk
+			db.Find(&risks)
+			if len(risks) > 0 {
+				catalogSync.SyncAllRisks(risks)
+			}
 		}()
 	}
 	// Seed NL2SQL knowledge for Data Asking
@@ -226,68 +293,168 @@ func main() {
 		}
 	}()
 
-	importSvc := service.NewImportService(aiSvc, memberRepo, dailyRepo, topicRepo, catalogSync)
-	chatH := handler.NewChatHandler(aiSvc, dailySvc, catalogSync, topicRepo, memberRepo)
-	authH := handler.NewAuthHandler(authSvc)
-	importH := handler.NewImportHandler(importSvc)
+	importSvc := service.NewImportService(aiSvc, memberRepo, dailyRepo, topicRepo, riskRepo, catalogSync, repository.NewImportJobRepo(db), repository.NewImportBatchRepo(db))
+	importSvc.RecoverJobs(context.Background())
+	chatH := handler.NewChatHandler(aiSvc, dailySvc, catalogSync, memberRepo, draftRepo)
+	draftH := handler.NewDraftHandler(draftRepo)
+	reportSvc := service.NewReportService(repository.NewReportRepo(db), dailySvc, aiSvc)
+	reportH := handler.NewReportHandler(reportSvc, memberRepo)
+	dailyH := handler.NewDailyHandler(dailySvc, memberRepo)
+	authH := handler.NewAuthHandler(authSvc, tokenRepo)
+	importH := handler.NewImportHandler(importSvc, memberRepo)
 	sessionSvc := service.NewSessionService(cfg.MOI.BaseURL, cfg.MOI.APIKey)
 	sessionH := handler.NewSessionHandler(sessionSvc)
-	memberH := handler.NewMemberHandler(memberRepo)
+	memberH := handler.NewMemberHandler(memberRepo, service.NewMemberService(memberRepo, catalogSync), service.NewTeamService(memberRepo, catalogSync), tokenRepo)
 	exportH := handler.NewExportHandler(dailyRepo)
-	feedH := handler.NewFeedHandler(topicRepo)
+	feedH := handler.NewFeedHandler(topicRepo, riskRepo, memberRepo)
+	riskH := handler.NewRiskHandler(riskRepo, memberRepo, topicRepo, catalogSync)
 	holidaySvc := service.NewHolidayService()
 	calendarH := handler.NewCalendarHandler(dailyRepo, holidaySvc)
+	complianceH := handler.NewComplianceHandler(service.NewComplianceService(dailyRepo, memberRepo, holidaySvc))
+	notificationRepo := repository.NewNotificationRepo(db)
+	notificationH := handler.NewNotificationHandler(notificationRepo)
+
+	// Scheduled jobs
+	notifiers, err := service.NewNotifiers(cfg.Scheduler.Reminder.Notifiers, cfg.Notify, notificationRepo)
+	if err != nil {
+		logger.Error("notifier init failed", "err", err)
+		os.Exit(1)
+	}
+	reminderSvc := service.NewReminderService(memberRepo, dailyRepo, holidaySvc, notifiers, cfg.Scheduler.Reminder.Message)
+	sched := scheduler.New()
+	if spec := cfg.Scheduler.Reminder.Cron; spec != "" {
+		if err := sched.Add("reminder", spec, reminderSvc.Run); err != nil {
+			logger.Error("scheduler: invalid reminder cron", "err", err)
+			os.Exit(1)
+		}
+	}
+	sched.Add("purge-revoked-tokens", "0 4 * * *", func(ctx context.Context) {
+		if n, err := tokenRepo.PurgeExpired(ctx); err != nil {
+			logger.Error("purge revoked tokens failed", "err", err)
+		} else if n > 0 {
+			logger.Info("purged revoked tokens", "count", n)
+		}
+	})
+	sched.Start(context.Background())
 
 	chatH.SetSessionService(sessionSvc)
+	chatH.SetDigestService(service.NewDigestService(dailyRepo, memberRepo, topicRepo, riskRepo, holidaySvc))
+	chatH.SetReportService(reportSvc)
+	chatH.SetReviewService(service.NewReviewService(repository.NewPeriodSummaryRepo(db), dailySvc, topicRepo, memberRepo, aiSvc))
+	chatH.SetPublisher(webhookSvc)
+	importSvc.SetPublisher(webhookSvc)
+	webhookH := handler.NewWebhookHandler(webhookRepo, webhookSvc)
+	auditH := handler.NewAuditHandler(auditRepo)
 
 	r := gin.Default()
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", middleware.RequestIDHeader},
+		ExposeHeaders:    []string{middleware.RequestIDHeader},
 		AllowCredentials: true,
 	}))
+	r.Use(middleware.RequestID())
 
 	r.POST("/api/login", authH.Login)
+	r.GET("/api/auth/providers", authH.Providers)
+	r.GET("/api/auth/oidc/login", authH.OIDCLogin)
+	r.GET("/api/auth/oidc/callback", authH.OIDCCallback)
 	api := r.Group("/api", middleware.JWTAuth())
+	api.POST("/logout", authH.Logout)
+	api.GET("/me", authH.Me)
+	api.PUT("/me/password", authH.ChangePassword)
 	api.POST("/chat", chatH.Chat)
 	api.POST("/chat/stream", chatH.ChatStream)
 	api.GET("/files/:name", chatH.DownloadFile)
+	api.GET("/digest", chatH.Digest)
+	api.GET("/review", chatH.Review)
+	api.GET("/drafts", draftH.List)
+	api.PUT("/drafts/:id", draftH.Update)
+	api.DELETE("/drafts/:id", draftH.Delete)
+	api.GET("/reports", reportH.List)
+	api.GET("/reports/:id", reportH.Get)
+	api.PUT("/reports/:id", reportH.Update)
+	api.GET("/reports/:id/download", reportH.Download)
+	api.GET("/daily/entries", dailyH.ListEntries)
+	api.PUT("/daily/entries/:id", dailyH.UpdateEntry)
+	api.DELETE("/daily/entries/:id", dailyH.DeleteEntry)
 	api.POST("/sessions", sessionH.Create)
 	api.GET("/sessions", sessionH.List)
 	api.DELETE("/sessions/:id", sessionH.Delete)
 	api.GET("/sessions/:id/messages", sessionH.Messages)
 	api.POST("/import/preview", importH.Preview)
 	api.POST("/import/confirm", importH.Confirm)
+	api.POST("/import/jobs", importH.CreateJob)
+	api.GET("/import/jobs", importH.ListJobs)
+	api.GET("/import/jobs/:id", importH.GetJob)
+	api.GET("/import/jobs/:id/events", importH.JobEvents)
+	api.POST("/import/jobs/:id/confirm", importH.ConfirmJob)
+	api.POST("/import/jobs/:id/resume", importH.ResumeJob)
+	api.GET("/import/batches", importH.ListBatches)
+	api.DELETE("/import/batches/:id", importH.RevertBatch)
 	api.GET("/members", memberH.List)
 	api.GET("/teams", memberH.ListTeams)
-	// Admin-only: member/team management
-	admin := api.Group("", middleware.AdminOnly())
-	admin.PUT("/members/:id", memberH.Update)
-	admin.DELETE("/members/:id", memberH.Delete)
-	admin.POST("/teams", memberH.CreateTeam)
-	// Logs (admin-only)
-	admin.GET("/logs", logsHandler(cfg.Log.File))
-	admin.GET("/logs/stream", logsStreamHandler(cfg.Log.File))
+	// Member/team management (see internal/authz for the role → permission sets)
+	can := middleware.Require
+	api.POST("/members", can(authz.MemberManage), memberH.Create)
+	api.PUT("/members/:id", can(authz.MemberManage), memberH.Update)
+	api.DELETE("/members/:id", can(authz.MemberManage), memberH.Delete)
+	api.POST("/members/:id/restore", can(authz.MemberManage), memberH.Restore)
+	api.DELETE("/members/:id/purge", can(authz.MemberManage), memberH.Purge)
+	api.POST("/members/:id/logout", can(authz.MemberManage), authH.ForceLogout)
+	api.POST("/members/:id/password/reset", can(authz.MemberManage), authH.ResetPassword)
+	api.POST("/teams", can(authz.TeamManage), memberH.CreateTeam)
+	api.POST("/teams/merge", can(authz.TeamManage), memberH.MergeTeam)
+	api.PUT("/teams/:id", can(authz.TeamManage), memberH.UpdateTeam)
+	api.DELETE("/teams/:id", can(authz.TeamManage), memberH.DeleteTeam)
+	// Logs
+	api.GET("/logs", can(authz.SystemAdmin), logsHandler(cfg.Log.File))
+	api.GET("/logs/stream", can(authz.SystemAdmin), logsStreamHandler(cfg.Log.File))
 	// Feed & Insights
 	api.GET("/feed/by-member", feedH.FeedByMember)
 	api.GET("/feed/by-topic", feedH.FeedByTopic)
 	api.GET("/insights", feedH.Insights)
 	// Topic management
 	api.GET("/topics/all", feedH.ListTopics)
-	api.PUT("/topics/:id", feedH.UpdateTopic)
-	api.PUT("/topics/:id/resolve", feedH.ResolveTopic)
-	api.PUT("/topics/:id/reopen", feedH.ReopenTopic)
-	api.POST("/topics/merge", feedH.MergeTopic)
+	api.PUT("/topics/:id", can(authz.TopicManage), feedH.UpdateTopic)
+	api.PUT("/topics/:id/resolve", can(authz.TopicManage), feedH.ResolveTopic)
+	api.PUT("/topics/:id/reopen", can(authz.TopicManage), feedH.ReopenTopic)
+	api.POST("/topics/merge", can(authz.TopicManage), feedH.MergeTopic)
+	// Risks
+	api.GET("/risks", riskH.List)
+	api.GET("/risks/:id", riskH.Get)
+	api.PUT("/risks/:id", riskH.Update)
+	api.PUT("/risks/:id/close", riskH.Close)
 	api.GET("/export/daily", exportH.ExportDaily)
 	api.GET("/calendar", calendarH.Calendar)
 	api.GET("/calendar/day", calendarH.DaySummary)
+	api.GET("/compliance", complianceH.Get)
+	api.GET("/compliance/export", complianceH.Export)
+	api.GET("/notifications", notificationH.List)
+	api.PUT("/notifications/read-all", notificationH.MarkAllRead)
+	api.PUT("/notifications/:id/read", notificationH.MarkRead)
+	api.POST("/reminders/run", can(authz.SystemAdmin), handler.Audited(audit.ReminderRun, audit.TargetReminder, func(c *gin.Context) {
+		reminderSvc.Run(c.Request.Context())
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	}))
+	// Outbound webhooks
+	hooks := api.Group("/webhooks", can(authz.WebhookManage))
+	hooks.GET("", webhookH.List)
+	hooks.POST("", webhookH.Create)
+	hooks.PUT("/:id", webhookH.Update)
+	hooks.DELETE("/:id", webhookH.Delete)
+	hooks.GET("/:id/deliveries", webhookH.Deliveries)
+	hooks.POST("/:id/ping", webhookH.Ping)
 	// Feedback
 	fbH := handler.NewFeedbackHandler(db)
 	api.POST("/feedback", fbH.Submit)
 	api.GET("/feedback", fbH.List)
-	admin.PUT("/feedback/:id/close", fbH.Close)
-	admin.DELETE("/feedback/:id", fbH.Delete)
+	api.PUT("/feedback/:id/close", can(authz.SystemAdmin), fbH.Close)
+	api.DELETE("/feedback/:id", can(authz.SystemAdmin), fbH.Delete)
+	// Audit log
+	api.GET("/admin/audit", can(authz.AuditView), auditH.List)
+	api.GET("/admin/audit/export", can(authz.AuditView), auditH.Export)
 
 	distFS, _ := fs.Sub(staticFS, "dist")
 	r.NoRoute(gin.WrapH(http.FileServer(http.FS(distFS))))
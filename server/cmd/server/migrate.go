@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"smart-daily/internal/migrate"
+	"smart-daily/migration"
+	"strconv"
+)
+
+const migrateUsage = `usage: smart-daily [--config file] migrate <command>
+
+  status       list migrations and whether they are applied
+  up [N]       apply all (or the next N) pending migrations
+  down [N]     revert the last (or last N) applied migrations
+  force V      mark the schema as exactly version V without running SQL
+               (after repairing a dirty migration by hand)`
+
+func checkSchema(db *sql.DB) error {
+	m, err := migrate.New(db, migration.FS)
+	if err != nil {
+		return err
+	}
+	return m.Check(context.Background())
+}
+
+// runMigrate implements the migrate subcommand and returns the exit code.
+func runMigrate(db *sql.DB, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	m, err := migrate.New(db, migration.FS)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "load migrations:", err)
+		return 1
+	}
+	ctx := context.Background()
+	n := 0
+	if len(args) > 1 {
+		if n, err = strconv.Atoi(args[1]); err != nil || n < 0 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+	}
+
+	switch args[0] {
+	case "status":
+		st, err := m.Status(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		for _, s := range st {
+			state := "pending"
+			switch {
+			case s.Dirty:
+				state = "DIRTY"
+			case s.Applied:
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-32s %s\n", s.Version, s.Name, state)
+		}
+		return 0
+	case "up", "down":
+		run := m.Up
+		if args[0] == "down" {
+			run = m.Down
+		}
+		done, err := run(ctx, n)
+		for _, mg := range done {
+			fmt.Printf("%s %04d_%s\n", args[0], mg.Version, mg.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if len(done) == 0 {
+			fmt.Println("nothing to do")
+		}
+		return 0
+	case "force":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+		if err := m.Force(ctx, int64(n)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Printf("schema forced to version %d\n", n)
+		return 0
+	}
+	fmt.Fprintln(os.Stderr, migrateUsage)
+	return 2
+}
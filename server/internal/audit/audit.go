@@ -0,0 +1,127 @@
+// Package audit records who changed what: administrative actions and edits to
+// report data, with JSON snapshots of the target before and after, in the
+// audit_events table.
+package audit
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"smart-daily/internal/logger"
+	"smart-daily/internal/model"
+	"strconv"
+	"strings"
+)
+
+// Actions.
+const (
+	MemberCreate        = "member.create"
+	MemberUpdate        = "member.update"
+	MemberDelete        = "member.delete"
+	MemberRestore       = "member.restore"
+	MemberPurge         = "member.purge"
+	MemberForceLogout   = "member.force_logout"
+	MemberPasswordReset = "member.password_reset"
+	PasswordChange      = "member.password_change" // a member changed their own password
+	TeamCreate          = "team.create"
+	TeamUpdate          = "team.update" // rename, move or change of lead
+	TeamDelete          = "team.delete"
+	TeamMerge           = "team.merge"
+	TopicUpdate         = "topic.update"
+	TopicResolve        = "topic.resolve"
+	TopicReopen         = "topic.reopen"
+	TopicMerge          = "topic.merge"
+	ReportConfirm       = "report.confirm" // a draft confirmed in chat
+	ReportUpdate        = "report.update"
+	ReportDelete        = "report.delete"
+	WeeklyReportUpdate  = "weekly_report.update" // a saved weekly report edited by hand
+	ImportConfirm       = "import.confirm"
+	ImportRevert        = "import.revert" // an import batch undone
+	RiskUpdate          = "risk.update"
+	RiskClose           = "risk.close"
+	FeedbackClose       = "feedback.close"
+	FeedbackDelete      = "feedback.delete"
+	WebhookCreate       = "webhook.create"
+	WebhookUpdate       = "webhook.update"
+	WebhookDelete       = "webhook.delete"
+	ReminderRun         = "reminder.run"
+)
+
+// Target types.
+const (
+	TargetMember   = "member"
+	TargetTeam     = "team"
+	TargetTopic    = "topic"
+	TargetReport   = "daily_entry"
+	TargetWeekly   = "weekly_report"
+	TargetImport   = "import"
+	TargetBatch    = "import_batch"
+	TargetRisk     = "risk"
+	TargetFeedback = "feedback"
+	TargetWebhook  = "webhook"
+	TargetReminder = "reminder"
+)
+
+// Store persists audit events.
+type Store interface {
+	Create(ctx context.Context, e *model.AuditEvent) error
+}
+
+var store Store
+
+// SetStore enables recording; without a store Record only logs.
+func SetStore(s Store) { store = s }
+
+// Record writes e. A failure is logged but never fails the action itself.
+func Record(ctx context.Context, e *model.AuditEvent) {
+	logger.Info("audit", "action", e.Action, "actor_id", e.ActorID, "target_type", e.TargetType, "target_id", e.TargetID, "request_id", e.RequestID)
+	if store == nil {
+		return
+	}
+	if err := store.Create(ctx, e); err != nil {
+		logger.Error("audit.write.failed", "action", e.Action, "actor_id", e.ActorID, "err", err)
+	}
+}
+
+// Snapshot encodes v for AuditEvent.Before / After; nil gives an empty snapshot.
+func Snapshot(v any) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil || string(b) == "null" {
+		return nil
+	}
+	return b
+}
+
+// CSVHeader is the column order written by WriteCSV.
+var CSVHeader = []string{"id", "time", "actor_id", "actor_name", "action", "target_type", "target_id", "before", "after", "request_id", "ip"}
+
+// WriteCSV writes events as CSV with a header row. Cells that a spreadsheet
+// would run as a formula are prefixed with a quote.
+func WriteCSV(w io.Writer, events []model.AuditEvent) error {
+	cw := csv.NewWriter(w)
+	cw.Write(CSVHeader)
+	for _, e := range events {
+		row := []string{
+			strconv.FormatInt(e.ID, 10), e.CreatedAt.Format("2006-01-02 15:04:05"),
+			strconv.Itoa(e.ActorID), e.ActorName, e.Action, e.TargetType, e.TargetID,
+			string(e.Before), string(e.After), e.RequestID, e.IP,
+		}
+		for i, cell := range row {
+			row[i] = escapeFormula(cell)
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func escapeFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
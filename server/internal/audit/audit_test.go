@@ -0,0 +1,70 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"smart-daily/internal/model"
+	"testing"
+	"time"
+)
+
+type memStore struct {
+	events []model.AuditEvent
+	err    error
+}
+
+func (m *memStore) Create(_ context.Context, e *model.AuditEvent) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.events = append(m.events, *e)
+	return nil
+}
+
+func TestRecord(t *testing.T) {
+	defer SetStore(nil)
+	Record(context.Background(), &model.AuditEvent{Action: TeamCreate}) // no store: must not panic
+
+	s := &memStore{}
+	SetStore(s)
+	Record(context.Background(), &model.AuditEvent{Action: MemberDelete, TargetID: "7"})
+	if len(s.events) != 1 || s.events[0].Action != MemberDelete {
+		t.Fatalf("events = %+v", s.events)
+	}
+	s.err = errors.New("db down")
+	Record(context.Background(), &model.AuditEvent{Action: MemberDelete}) // logged, not returned
+}
+
+func TestSnapshot(t *testing.T) {
+	var m *model.Member
+	if Snapshot(nil) != nil || Snapshot(m) != nil {
+		t.Error("nil values should give an empty snapshot")
+	}
+	got := string(Snapshot(&model.Member{ID: 3, Name: "张三", Password: "hash"}))
+	if !bytes.Contains([]byte(got), []byte(`"name":"张三"`)) || bytes.Contains([]byte(got), []byte("hash")) {
+		t.Errorf("Snapshot = %s; want name and no password", got)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteCSV(&buf, []model.AuditEvent{{
+		ID: 1, ActorID: 2, ActorName: "=HYPERLINK(\"x\")", Action: TopicMerge, TargetType: TargetTopic, TargetID: "9",
+		Before: Snapshot(map[string]string{"name": "a,b"}), CreatedAt: time.Date(2026, 3, 2, 9, 30, 0, 0, time.Local),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || len(rows[1]) != len(CSVHeader) {
+		t.Fatalf("rows = %v", rows)
+	}
+	if r := rows[1]; r[1] != "2026-03-02 09:30:00" || r[3] != `'=HYPERLINK("x")` || r[7] != `{"name":"a,b"}` || r[8] != "" {
+		t.Errorf("row = %q", r)
+	}
+}
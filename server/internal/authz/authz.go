@@ -0,0 +1,142 @@
+// Package authz defines access roles, their permission sets and how a team
+// lead's permissions are scoped to their own team and the teams nested below it.
+package authz
+
+import (
+	"errors"
+	"slices"
+	"sort"
+)
+
+type Role string
+
+// ErrNoSubject is returned by subject loaders for a member that does not exist
+// or is no longer active; tokens of such members are rejected.
+var ErrNoSubject = errors.New("member not found or not active")
+
+const (
+	RoleMember   Role = "member"
+	RoleTeamLead Role = "team_lead"
+	RoleAdmin    Role = "admin"
+)
+
+// Valid reports whether r is a known role.
+func (r Role) Valid() bool { return r == RoleMember || r == RoleTeamLead || r == RoleAdmin }
+
+// RoleOf reads a member's stored role; the legacy is_admin flag still grants
+// admin, and unknown values fall back to member.
+func RoleOf(accessRole string, isAdmin bool) Role {
+	if isAdmin {
+		return RoleAdmin
+	}
+	if r := Role(accessRole); r.Valid() {
+		return r
+	}
+	return RoleMember
+}
+
+type Permission string
+
+// Report permissions apply to other members' data; everyone may always act on
+// their own reports. Team leads hold them for their own team only.
+const (
+	ReportRead     Permission = "report.read"     // others' entries, weekly reports, export
+	ReportEdit     Permission = "report.edit"     // edit or withdraw others' submitted entries
+	ReportImport   Permission = "report.import"   // import reports on others' behalf
+	TopicManage    Permission = "topic.manage"    // rename, resolve, reopen, merge topics
+	RiskManage     Permission = "risk.manage"     // update or close others' risks
+	ComplianceView Permission = "compliance.view" // submission stats
+	DataQueryAll   Permission = "data.query_all"  // Data Asking beyond the own team
+	MemberManage   Permission = "member.manage"   // edit/delete members, reset passwords, force logout
+	TeamManage     Permission = "team.manage"
+	WebhookManage  Permission = "webhook.manage"
+	SystemAdmin    Permission = "system.admin" // logs, reminders, feedback triage
+	AuditView      Permission = "audit.view"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleMember:   {},
+	RoleTeamLead: {ReportRead, ReportImport, TopicManage, RiskManage, ComplianceView},
+	RoleAdmin: {ReportRead, ReportEdit, ReportImport, TopicManage, RiskManage, ComplianceView,
+		DataQueryAll, MemberManage, TeamManage, WebhookManage, SystemAdmin, AuditView},
+}
+
+// Permissions returns the role's permissions, sorted.
+func Permissions(r Role) []string {
+	out := make([]string, 0, len(rolePermissions[r]))
+	for _, p := range rolePermissions[r] {
+		out = append(out, string(p))
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Subject is the caller an authorization decision is made for.
+type Subject struct {
+	MemberID int
+	TeamID   int
+	SubTeams []int // teams nested below TeamID, covered by a team lead's scope too
+	Role     Role
+}
+
+func (s Subject) IsAdmin() bool { return s.Role == RoleAdmin }
+
+// Can reports whether the role holds p at all, regardless of scope.
+func (s Subject) Can(p Permission) bool {
+	for _, q := range rolePermissions[s.Role] {
+		if q == p {
+			return true
+		}
+	}
+	return false
+}
+
+// CanAccess reports whether s may use p on data of the member memberID in team
+// teamID: always for their own data, anywhere for admins, and within the own
+// team and its sub-teams for team leads.
+func (s Subject) CanAccess(p Permission, memberID, teamID int) bool {
+	if memberID != 0 && memberID == s.MemberID {
+		return true
+	}
+	return s.CanAccessTeam(p, teamID)
+}
+
+// CanAccessTeam is CanAccess for team-level data (topics, compliance).
+func (s Subject) CanAccessTeam(p Permission, teamID int) bool {
+	if !s.Can(p) {
+		return false
+	}
+	return s.IsAdmin() || (teamID != 0 && slices.Contains(s.Teams(), teamID))
+}
+
+// Teams returns the own team followed by its sub-teams; nil without a team.
+func (s Subject) Teams() []int {
+	if s.TeamID == 0 {
+		return nil
+	}
+	return append([]int{s.TeamID}, s.SubTeams...)
+}
+
+// Scope is the set of teams a permission may be used on.
+type Scope struct {
+	All     bool  // every team
+	TeamIDs []int // otherwise these teams; none means only the own data
+}
+
+// Includes reports whether teamID is in the scope.
+func (sc Scope) Includes(teamID int) bool {
+	return sc.All || (teamID != 0 && slices.Contains(sc.TeamIDs, teamID))
+}
+
+// TeamScope returns the teams s may use p on: every team for admins, the own
+// team and its sub-teams for team leads, and none otherwise.
+func (s Subject) TeamScope(p Permission) Scope {
+	switch {
+	case s.IsAdmin() && s.Can(p):
+		return Scope{All: true}
+	case s.Can(p):
+		return Scope{TeamIDs: s.Teams()}
+	default:
+		return Scope{}
+	}
+}
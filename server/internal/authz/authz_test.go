@@ -0,0 +1,95 @@
+package authz
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRoleOf(t *testing.T) {
+	for _, tc := range []struct {
+		stored  string
+		isAdmin bool
+		want    Role
+	}{
+		{"", false, RoleMember},
+		{"team_lead", false, RoleTeamLead},
+		{"admin", false, RoleAdmin},
+		{"member", true, RoleAdmin},
+		{"owner", false, RoleMember},
+	} {
+		if got := RoleOf(tc.stored, tc.isAdmin); got != tc.want {
+			t.Errorf("RoleOf(%q, %v) = %s, want %s", tc.stored, tc.isAdmin, got, tc.want)
+		}
+	}
+}
+
+func TestSubjectScopes(t *testing.T) {
+	admin := Subject{MemberID: 1, TeamID: 1, Role: RoleAdmin}
+	lead := Subject{MemberID: 2, TeamID: 1, Role: RoleTeamLead}
+	member := Subject{MemberID: 3, TeamID: 1, Role: RoleMember}
+	loneLead := Subject{MemberID: 4, Role: RoleTeamLead}
+	deptLead := Subject{MemberID: 5, TeamID: 10, SubTeams: []int{1, 2}, Role: RoleTeamLead}
+
+	for _, tc := range []struct {
+		name     string
+		s        Subject
+		p        Permission
+		memberID int
+		teamID   int
+		want     bool
+	}{
+		{"admin any team", admin, ReportRead, 9, 2, true},
+		{"admin member without team", admin, ReportEdit, 9, 0, true},
+		{"lead own team", lead, ReportRead, 3, 1, true},
+		{"lead other team", lead, ReportRead, 9, 2, false},
+		{"lead member without team", lead, ReportRead, 9, 0, false},
+		{"lead lacks permission", lead, ReportEdit, 3, 1, false},
+		{"member own data", member, ReportEdit, 3, 1, true},
+		{"member teammate", member, ReportRead, 2, 1, false},
+		{"lead without team", loneLead, TopicManage, 9, 0, false},
+		{"department lead sub-team", deptLead, ReportRead, 9, 2, true},
+		{"department lead other team", deptLead, ReportRead, 9, 3, false},
+	} {
+		if got := tc.s.CanAccess(tc.p, tc.memberID, tc.teamID); got != tc.want {
+			t.Errorf("%s: CanAccess = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	for _, tc := range []struct {
+		name string
+		s    Subject
+		want Scope
+	}{
+		{"admin", admin, Scope{All: true}},
+		{"lead", lead, Scope{TeamIDs: []int{1}}},
+		{"department lead", deptLead, Scope{TeamIDs: []int{10, 1, 2}}},
+		{"member", member, Scope{}},
+		{"lead without team", loneLead, Scope{}},
+	} {
+		got := tc.s.TeamScope(ReportRead)
+		if got.All != tc.want.All || !slices.Equal(got.TeamIDs, tc.want.TeamIDs) {
+			t.Errorf("%s: TeamScope = %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+	if sc := deptLead.TeamScope(ReportRead); !sc.Includes(2) || sc.Includes(0) || sc.Includes(3) {
+		t.Errorf("department lead scope %+v", sc)
+	}
+}
+
+func TestPermissions(t *testing.T) {
+	if len(Permissions(RoleMember)) != 0 {
+		t.Errorf("member permissions = %v, want none", Permissions(RoleMember))
+	}
+	lead := Permissions(RoleTeamLead)
+	if !slices.Contains(lead, string(TopicManage)) || slices.Contains(lead, string(MemberManage)) {
+		t.Errorf("team lead permissions = %v", lead)
+	}
+	for _, p := range lead {
+		if !slices.Contains(Permissions(RoleAdmin), p) {
+			t.Errorf("admin lacks team lead permission %s", p)
+		}
+	}
+	if !slices.IsSorted(Permissions(RoleAdmin)) {
+		t.Error("Permissions not sorted")
+	}
+}
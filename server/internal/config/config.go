@@ -15,10 +15,13 @@ import (
 )
 
 type Config struct {
-	Server   ServerConfig   `yaml:"server"`
-	Log      LogConfig      `yaml:"log"`
-	MOI      MOIConfig      `yaml:"moi"`
-	Database DatabaseConfig `yaml:"database"`
+	Server    ServerConfig    `yaml:"server"`
+	Log       LogConfig       `yaml:"log"`
+	MOI       MOIConfig       `yaml:"moi"`
+	Database  DatabaseConfig  `yaml:"database"`
+	Scheduler SchedulerConfig `yaml:"scheduler"`
+	Notify    NotifyConfig    `yaml:"notify"`
+	Auth      AuthConfig      `yaml:"auth"`
 }
 
 type LogConfig struct {
@@ -40,6 +43,112 @@ type MOIConfig struct {
 	CatalogID int64  `yaml:"catalog_id"`
 	Model     string `yaml:"model"`
 	FastModel string `yaml:"fast_model"`
+
+	// LLM backend: moi (default, MOI LLM proxy) / openai (any OpenAI-compatible API) / fake (in-process echo)
+	LLMProvider string `yaml:"llm_provider"`
+	LLMBaseURL  string `yaml:"llm_base_url"` // openai only, e.g. http://localhost:11434/v1
+	LLMAPIKey   string `yaml:"llm_api_key"`  // openai only, sent as Bearer token
+}
+
+// SchedulerConfig holds cron-like schedules (minute hour day month weekday) of background jobs.
+type SchedulerConfig struct {
+	Reminder ReminderConfig `yaml:"reminder"`
+}
+
+// ReminderConfig configures the workday reminder for members who have not submitted today.
+type ReminderConfig struct {
+	Cron      string   `yaml:"cron"`      // e.g. "30 17 * * 1-5"; empty disables reminders
+	Notifiers []string `yaml:"notifiers"` // inapp / webhook / smtp; defaults to inapp
+	Message   string   `yaml:"message"`
+}
+
+// NotifyConfig configures notification delivery channels.
+type NotifyConfig struct {
+	WebhookURL string     `yaml:"webhook_url"` // receives a JSON POST per notification batch
+	SMTP       SMTPConfig `yaml:"smtp"`
+}
+
+type SMTPConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+}
+
+// AuthConfig holds the JWT signing keys. The active key signs new tokens;
+// the others only verify, until their not_after, so rotation has a grace period.
+// With no keys configured a random key is generated per start.
+type AuthConfig struct {
+	ActiveKID string   `yaml:"active_kid"` // defaults to the first key
+	Keys      []JWTKey `yaml:"keys"`
+	KeyFile   string   `yaml:"key_file"` // YAML file with a "keys" list, merged after Keys
+
+	// Providers is the password login chain, tried in order: local / ldap.
+	// Defaults to local only. OIDC is a redirect flow and is enabled by OIDC.Issuer.
+	Providers []string        `yaml:"providers"`
+	Provision ProvisionConfig `yaml:"provision"`
+	LDAP      LDAPConfig      `yaml:"ldap"`
+	OIDC      OIDCConfig      `yaml:"oidc"`
+}
+
+// ProvisionConfig controls creating members on their first external (LDAP/OIDC) login.
+type ProvisionConfig struct {
+	Enabled bool   `yaml:"enabled"` // false: external users must already exist as members
+	TeamID  int    `yaml:"team_id"`
+	Role    string `yaml:"role"`
+}
+
+// LDAPConfig configures LDAP bind authentication: the service account searches
+// for the user's DN, then the user's own password is checked with a bind.
+type LDAPConfig struct {
+	URL                string `yaml:"url"` // ldap://host:389 or ldaps://host:636
+	StartTLS           bool   `yaml:"start_tls"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+	BindDN             string `yaml:"bind_dn"`
+	BindPassword       string `yaml:"bind_password"`
+	BaseDN             string `yaml:"base_dn"`
+	UserFilter         string `yaml:"user_filter"` // %s is replaced by the escaped username; default (uid=%s)
+	UsernameAttr       string `yaml:"username_attr"`
+	EmailAttr          string `yaml:"email_attr"`
+	NameAttr           string `yaml:"name_attr"`
+}
+
+// OIDCConfig configures the OpenID Connect authorization-code login.
+type OIDCConfig struct {
+	Issuer        string   `yaml:"issuer"` // empty disables OIDC
+	ClientID      string   `yaml:"client_id"`
+	ClientSecret  string   `yaml:"client_secret"`
+	RedirectURL   string   `yaml:"redirect_url"` // https://<host>/api/auth/oidc/callback
+	Scopes        []string `yaml:"scopes"`       // default openid profile email
+	UsernameClaim string   `yaml:"username_claim"`
+	DisplayName   string   `yaml:"display_name"` // login button label
+	FrontendURL   string   `yaml:"frontend_url"` // where the callback lands with the token; default "/"
+}
+
+type JWTKey struct {
+	ID       string `yaml:"id"`
+	Secret   string `yaml:"secret"`    // at least 32 bytes
+	NotAfter string `yaml:"not_after"` // RFC3339 or YYYY-MM-DD; empty = no expiry
+}
+
+// LoadKeys returns Keys plus those from KeyFile.
+func (a AuthConfig) LoadKeys() ([]JWTKey, error) {
+	keys := append([]JWTKey(nil), a.Keys...)
+	if a.KeyFile == "" {
+		return keys, nil
+	}
+	data, err := os.ReadFile(a.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("read key file: %w", err)
+	}
+	var f struct {
+		Keys []JWTKey `yaml:"keys"`
+	}
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse key file %s: %w", a.KeyFile, err)
+	}
+	return append(keys, f.Keys...), nil
 }
 
 type DatabaseConfig struct {
@@ -56,6 +165,15 @@ func Load(configFile string) *Config {
 		MOI:      MOIConfig{BaseURL: "https://freetier-01.cn-hangzhou.cluster.cn-dev.matrixone.tech", CatalogID: 1, Model: "qwen-plus", FastModel: "qwen-turbo"},
 		Log:      LogConfig{Level: "info", Console: true, MaxSizeMB: 100, MaxBackups: 3, MaxAgeDays: 30},
 		Database: DatabaseConfig{Port: 6001, Name: "smart_daily"},
+		Scheduler: SchedulerConfig{Reminder: ReminderConfig{
+			Notifiers: []string{"inapp"}, Message: "今天的日报还没有提交，记得在下班前填写哦。",
+		}},
+		Notify: NotifyConfig{SMTP: SMTPConfig{Port: 587}},
+		Auth: AuthConfig{
+			LDAP: LDAPConfig{UserFilter: "(uid=%s)", UsernameAttr: "uid", EmailAttr: "mail", NameAttr: "cn"},
+			OIDC: OIDCConfig{Scopes: []string{"openid", "profile", "email"}, UsernameClaim: "preferred_username",
+				DisplayName: "SSO", FrontendURL: "/"},
+		},
 	}
 
 	paths := []string{"etc/config-dev.yaml", "/etc/smart-daily/config.yaml"}
@@ -71,12 +189,25 @@ func Load(configFile string) *Config {
 
 	envOverride(&c.MOI.BaseURL, "MOI_BASE_URL")
 	envOverride(&c.MOI.APIKey, "MOI_API_KEY")
+	envOverride(&c.MOI.LLMProvider, "LLM_PROVIDER")
+	envOverride(&c.MOI.LLMBaseURL, "LLM_BASE_URL")
+	envOverride(&c.MOI.LLMAPIKey, "LLM_API_KEY")
 	envOverride(&c.Database.Host, "MO_HOST")
 	envOverride(&c.Database.User, "MO_USER")
 	envOverride(&c.Database.Password, "MO_PASS")
 	envOverride(&c.Database.Name, "MO_DB")
 	envOverride(&c.Log.Level, "LOG_LEVEL")
 	envOverride(&c.Log.File, "LOG_FILE")
+	envOverride(&c.Scheduler.Reminder.Cron, "REMINDER_CRON")
+	envOverride(&c.Notify.WebhookURL, "NOTIFY_WEBHOOK_URL")
+	envOverride(&c.Notify.SMTP.Password, "SMTP_PASSWORD")
+	envOverride(&c.Auth.KeyFile, "JWT_KEY_FILE")
+	envOverride(&c.Auth.LDAP.BindPassword, "LDAP_BIND_PASSWORD")
+	envOverride(&c.Auth.OIDC.ClientSecret, "OIDC_CLIENT_SECRET")
+	if v := os.Getenv("JWT_SECRET"); v != "" {
+		c.Auth.Keys = append([]JWTKey{{ID: "env", Secret: v}}, c.Auth.Keys...)
+		c.Auth.ActiveKID = "env"
+	}
 	envOverrideInt(&c.Server.Port, "PORT")
 	envOverrideInt(&c.Database.Port, "MO_PORT")
 	envOverrideInt64(&c.MOI.CatalogID, "MOI_CATALOG_ID")
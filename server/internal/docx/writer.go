@@ -0,0 +1,139 @@
+// Package docx writes the Markdown reports the assistant generates as Word
+// documents. It covers the subset the report prompts produce: headings,
+// bullet and numbered lists, paragraphs and **bold** runs.
+package docx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const contentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>
+<Override PartName="/word/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"/>
+</Types>`
+
+const rootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>
+</Relationships>`
+
+const docRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+</Relationships>`
+
+// styles defines the heading styles referenced by document.xml, with an East
+// Asian font so Chinese text renders the same in Word and WPS.
+const styles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+<w:docDefaults><w:rPrDefault><w:rPr><w:rFonts w:ascii="Calibri" w:hAnsi="Calibri" w:eastAsia="微软雅黑"/><w:sz w:val="22"/></w:rPr></w:rPrDefault>
+<w:pPrDefault><w:pPr><w:spacing w:after="80" w:line="300" w:lineRule="auto"/></w:pPr></w:pPrDefault></w:docDefaults>
+<w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:name w:val="Normal"/></w:style>
+<w:style w:type="paragraph" w:styleId="Heading1"><w:name w:val="heading 1"/><w:basedOn w:val="Normal"/><w:pPr><w:spacing w:before="240" w:after="120"/><w:outlineLvl w:val="0"/></w:pPr><w:rPr><w:b/><w:sz w:val="36"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Heading2"><w:name w:val="heading 2"/><w:basedOn w:val="Normal"/><w:pPr><w:spacing w:before="200" w:after="100"/><w:outlineLvl w:val="1"/></w:pPr><w:rPr><w:b/><w:sz w:val="30"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Heading3"><w:name w:val="heading 3"/><w:basedOn w:val="Normal"/><w:pPr><w:spacing w:before="160" w:after="80"/><w:outlineLvl w:val="2"/></w:pPr><w:rPr><w:b/><w:sz w:val="26"/></w:rPr></w:style>
+</w:styles>`
+
+var (
+	headingRe  = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	bulletRe   = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	numberedRe = regexp.MustCompile(`^(\s*)(\d+[.)])\s+(.*)$`)
+)
+
+// FromMarkdown renders md as a .docx file.
+func FromMarkdown(md string) ([]byte, error) {
+	var body strings.Builder
+	for _, line := range strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "" || isRule(trimmed):
+			continue
+		case headingRe.MatchString(trimmed):
+			m := headingRe.FindStringSubmatch(trimmed)
+			level := len(m[1])
+			if level > 3 {
+				level = 3
+			}
+			paragraph(&body, `<w:pStyle w:val="Heading`+strconv.Itoa(level)+`"/>`, m[2])
+		case bulletRe.MatchString(line):
+			m := bulletRe.FindStringSubmatch(line)
+			paragraph(&body, indent(m[1]), "• "+m[2])
+		case numberedRe.MatchString(line):
+			m := numberedRe.FindStringSubmatch(line)
+			paragraph(&body, indent(m[1]), m[2]+" "+m[3])
+		default:
+			paragraph(&body, "", trimmed)
+		}
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	parts := []struct{ name, data string }{
+		{"[Content_Types].xml", contentTypes},
+		{"_rels/.rels", rootRels},
+		{"word/_rels/document.xml.rels", docRels},
+		{"word/styles.xml", styles},
+		{"word/document.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+			body.String() + `<w:sectPr><w:pgSz w:w="11906" w:h="16838"/><w:pgMar w:top="1440" w:right="1440" w:bottom="1440" w:left="1440"/></w:sectPr></w:body></w:document>`},
+	}
+	for _, p := range parts {
+		w, err := zw.Create(p.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write([]byte(p.data)); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// paragraph appends a paragraph with the given properties, splitting text on
+// ** into alternating plain and bold runs.
+func paragraph(sb *strings.Builder, props, text string) {
+	sb.WriteString("<w:p>")
+	if props != "" {
+		sb.WriteString("<w:pPr>" + props + "</w:pPr>")
+	}
+	for i, part := range strings.Split(text, "**") {
+		if part == "" {
+			continue
+		}
+		sb.WriteString("<w:r>")
+		if i%2 == 1 {
+			sb.WriteString("<w:rPr><w:b/></w:rPr>")
+		}
+		sb.WriteString(`<w:t xml:space="preserve">`)
+		xml.EscapeText(sb, []byte(part))
+		sb.WriteString("</w:t></w:r>")
+	}
+	sb.WriteString("</w:p>")
+}
+
+// indent returns paragraph properties for a list item nested by its leading
+// whitespace (two spaces or a tab per level).
+func indent(lead string) string {
+	level := 1 + len(strings.ReplaceAll(lead, "\t", "  "))/2
+	return `<w:ind w:left="` + strconv.Itoa(level*360) + `" w:hanging="240"/>`
+}
+
+// isRule reports whether line is a Markdown horizontal rule.
+func isRule(line string) bool {
+	line = strings.ReplaceAll(line, " ", "")
+	if len(line) < 3 {
+		return false
+	}
+	return strings.Trim(line, "-") == "" || strings.Trim(line, "*") == "" || strings.Trim(line, "_") == ""
+}
@@ -0,0 +1,61 @@
+package docx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestFromMarkdown(t *testing.T) {
+	md := "# 团队周报 - 研发部\n\n## 重点进展\n### MOI\n- **张三**：完成导入接口 <v2> & 联调\n  - 子项\n1. 第一条\n---\n普通段落\n"
+	data, err := FromMarkdown(md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("not a zip: %v", err)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(b)
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "word/_rels/document.xml.rels", "word/styles.xml", "word/document.xml"} {
+		if _, ok := files[name]; !ok {
+			t.Fatalf("missing part %s", name)
+		}
+	}
+	doc := files["word/document.xml"]
+	if err := xml.Unmarshal([]byte(doc), new(struct{})); err != nil {
+		t.Fatalf("document.xml is not well-formed: %v", err)
+	}
+
+	var paras []string
+	for _, p := range strings.Split(doc, "<w:p>")[1:] {
+		paras = append(paras, p[:strings.Index(p, "</w:p>")])
+	}
+	if len(paras) != 7 {
+		t.Fatalf("got %d paragraphs, want 7 (blank lines and rules dropped):\n%s", len(paras), doc)
+	}
+	for i, want := range []string{`Heading1"/>`, `Heading2"/>`, `Heading3"/>`} {
+		if !strings.Contains(paras[i], want) {
+			t.Errorf("paragraph %d: want %s in %s", i, want, paras[i])
+		}
+	}
+	if !strings.Contains(paras[3], `<w:rPr><w:b/></w:rPr><w:t xml:space="preserve">张三</w:t>`) ||
+		!strings.Contains(paras[3], "&lt;v2&gt; &amp; 联调") || !strings.Contains(paras[3], "• ") {
+		t.Errorf("bullet with bold run: %s", paras[3])
+	}
+	if !strings.Contains(paras[3], `w:left="360"`) || !strings.Contains(paras[4], `w:left="720"`) {
+		t.Errorf("list nesting: %s / %s", paras[3], paras[4])
+	}
+	if !strings.Contains(paras[5], "1. 第一条") || !strings.Contains(paras[6], "普通段落") {
+		t.Errorf("numbered item and paragraph: %s / %s", paras[5], paras[6])
+	}
+}
@@ -0,0 +1,202 @@
+// Package docxparse reads daily-report Word documents for import: a date
+// heading paragraph ("2026年2月13日 ...") followed by tables with one member
+// per row. It splits the document into one Section per date, each table row a
+// tab-separated line.
+//
+// It reads word/document.xml directly, like the python-docx script it
+// replaces, so merged cells are not repeated: a horizontally merged cell is a
+// single column and the continuation of a vertically merged cell is empty.
+package docxparse
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ns is the WordprocessingML main namespace.
+const ns = "http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+
+var ErrNotDocx = errors.New("not a docx document")
+
+// Section is one date's part of the document: Date is the full text of the
+// heading paragraph, Text the rows of the tables below it, cells separated by
+// tabs and rows by newlines.
+type Section struct {
+	Date string `json:"date"`
+	Text string `json:"text"`
+}
+
+var dateRe = regexp.MustCompile(`(\d{4})年(\d{1,2})月(\d{1,2})日`)
+
+// ParseFile parses the .docx file at path.
+func ParseFile(path string) ([]Section, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return Parse(f, st.Size())
+}
+
+// Parse parses a .docx document of the given size.
+func Parse(r io.ReaderAt, size int64) ([]Section, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, ErrNotDocx
+	}
+	var doc *zip.File
+	for _, f := range zr.File {
+		if f.Name == "word/document.xml" {
+			doc = f
+			break
+		}
+	}
+	if doc == nil {
+		return nil, ErrNotDocx
+	}
+	rc, err := doc.Open()
+	if err != nil {
+		return nil, fmt.Errorf("open document.xml: %w", err)
+	}
+	defer rc.Close()
+
+	var root node
+	if err := xml.NewDecoder(rc).Decode(&root); err != nil {
+		return nil, fmt.Errorf("decode document.xml: %w", err)
+	}
+	body := root.child("body")
+	if root.XMLName.Space != ns || root.XMLName.Local != "document" || body == nil {
+		return nil, ErrNotDocx
+	}
+	return sections(body), nil
+}
+
+// sections walks the body's top-level paragraphs and tables: a paragraph
+// containing a date starts a section, the rows of the tables that follow
+// belong to it. Tables before the first date and dates without any table
+// rows are dropped.
+func sections(body *node) []Section {
+	var out []Section
+	date := ""
+	var rows []string
+	flush := func() {
+		if date != "" && len(rows) > 0 {
+			out = append(out, Section{Date: date, Text: strings.Join(rows, "\n")})
+		}
+	}
+	for i := range body.Nodes {
+		el := &body.Nodes[i]
+		if el.XMLName.Space != ns {
+			continue
+		}
+		switch el.XMLName.Local {
+		case "p":
+			if text := strings.TrimSpace(paragraphText(el, " ")); dateRe.MatchString(text) {
+				flush()
+				date, rows = text, nil
+			}
+		case "tbl":
+			if date == "" {
+				continue
+			}
+			for _, tr := range el.children("tr") {
+				var cells []string
+				for _, tc := range tr.children("tc") {
+					cells = append(cells, cellText(tc))
+				}
+				rows = append(rows, strings.Join(cells, "\t"))
+			}
+		}
+	}
+	flush()
+	return out
+}
+
+// cellText joins a cell's non-empty paragraphs with "; ". Joining them with
+// newlines would be mistaken for a new table row (a new member) downstream.
+// Tables nested in a cell are not read.
+func cellText(tc *node) string {
+	var parts []string
+	for _, p := range tc.children("p") {
+		for _, line := range strings.Split(paragraphText(p, "\n"), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				parts = append(parts, line)
+			}
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// paragraphText concatenates the text of a paragraph's runs, including runs
+// inside hyperlinks, insertions and content controls; a line break inside a
+// run is written as lineBreak. Tabs become spaces so they cannot split
+// columns.
+func paragraphText(p *node, lineBreak string) string {
+	var sb strings.Builder
+	var walk func(n *node)
+	walk = func(n *node) {
+		for i := range n.Nodes {
+			c := &n.Nodes[i]
+			if c.XMLName.Space != ns {
+				continue
+			}
+			switch c.XMLName.Local {
+			case "r":
+				for j := range c.Nodes {
+					t := &c.Nodes[j]
+					if t.XMLName.Space != ns {
+						continue
+					}
+					switch t.XMLName.Local {
+					case "t":
+						sb.WriteString(t.Text)
+					case "tab":
+						sb.WriteString(" ")
+					case "br", "cr":
+						sb.WriteString(lineBreak)
+					}
+				}
+			case "hyperlink", "ins", "smartTag", "fldSimple", "customXml", "sdt", "sdtContent":
+				walk(c)
+			}
+		}
+	}
+	walk(p)
+	return sb.String()
+}
+
+// node is a generic XML element.
+type node struct {
+	XMLName xml.Name
+	Text    string `xml:",chardata"`
+	Nodes   []node `xml:",any"`
+}
+
+func (n *node) child(local string) *node {
+	for i := range n.Nodes {
+		if n.Nodes[i].XMLName.Space == ns && n.Nodes[i].XMLName.Local == local {
+			return &n.Nodes[i]
+		}
+	}
+	return nil
+}
+
+func (n *node) children(local string) []*node {
+	var out []*node
+	for i := range n.Nodes {
+		if n.Nodes[i].XMLName.Space == ns && n.Nodes[i].XMLName.Local == local {
+			out = append(out, &n.Nodes[i])
+		}
+	}
+	return out
+}
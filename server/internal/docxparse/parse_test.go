@@ -0,0 +1,86 @@
+package docxparse
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The .golden.json files are the output of the python-docx script this
+// package replaced (cmd/docx_parser/main.py, since removed) for the .docx
+// next to them.
+func TestGolden(t *testing.T) {
+	docs, _ := filepath.Glob("testdata/*.docx")
+	if len(docs) == 0 {
+		t.Fatal("no fixtures")
+	}
+	for _, doc := range docs {
+		t.Run(filepath.Base(doc), func(t *testing.T) {
+			raw, err := os.ReadFile(strings.TrimSuffix(doc, ".docx") + ".golden.json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want []Section
+			if err := json.Unmarshal(raw, &want); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ParseFile(doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				g, _ := json.MarshalIndent(got, "", "  ")
+				w, _ := json.MarshalIndent(want, "", "  ")
+				t.Errorf("got\n%s\nwant\n%s", g, w)
+			}
+		})
+	}
+}
+
+func TestLineBreaksAndHyperlinks(t *testing.T) {
+	// Unlike the script, soft line breaks and hyperlinked text are kept; a
+	// line break inside a cell must not start a new row
+	got := parseBody(t, `<w:p><w:r><w:t>2026年3月2日</w:t></w:r></w:p>
+<w:tbl><w:tr>
+<w:tc><w:p><w:r><w:t>张三</w:t></w:r></w:p></w:tc>
+<w:tc><w:p><w:r><w:t>导入接口</w:t><w:br/><w:t>分页修复</w:t></w:r><w:hyperlink r:id="rId9"><w:r><w:t>（PR）</w:t></w:r></w:hyperlink></w:p></w:tc>
+</w:tr></w:tbl>`)
+	want := []Section{{Date: "2026年3月2日", Text: "张三\t导入接口; 分页修复（PR）"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestNotDocx(t *testing.T) {
+	if _, err := Parse(strings.NewReader("plain text"), 10); !errors.Is(err, ErrNotDocx) {
+		t.Errorf("plain text: %v", err)
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("xl/workbook.xml")
+	w.Write([]byte("<workbook/>"))
+	zw.Close()
+	if _, err := Parse(bytes.NewReader(buf.Bytes()), int64(buf.Len())); !errors.Is(err, ErrNotDocx) {
+		t.Errorf("xlsx-like zip: %v", err)
+	}
+}
+
+func parseBody(t *testing.T, body string) []Section {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("word/document.xml")
+	w.Write([]byte(`<w:document xmlns:w="` + ns + `" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><w:body>` + body + `</w:body></w:document>`))
+	zw.Close()
+	sections, err := Parse(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sections
+}
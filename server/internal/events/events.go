@@ -0,0 +1,30 @@
+// Package events names the domain events other tools can subscribe to through
+// outbound webhooks, and the interface used to publish them.
+package events
+
+import "context"
+
+const (
+	ReportConfirmed = "report.confirmed" // a member confirmed a daily report in chat
+	ReportImported  = "report.imported"  // a history import was confirmed
+	RiskDetected    = "risk.detected"    // a risk was filed from a report
+	TopicResolved   = "topic.resolved"
+	TopicReopened   = "topic.reopened" // manually, or automatically on new activity
+	TopicMerged     = "topic.merged"
+	Ping            = "ping" // test delivery, only sent on request
+)
+
+// All lists the subscribable events.
+var All = []string{ReportConfirmed, ReportImported, RiskDetected, TopicResolved, TopicReopened, TopicMerged}
+
+// Publisher receives events. Publish must not block on delivery.
+type Publisher interface {
+	Publish(ctx context.Context, event string, data any)
+}
+
+// Publish sends to p if it is set, so callers can hold an optional publisher.
+func Publish(ctx context.Context, p Publisher, event string, data any) {
+	if p != nil {
+		p.Publish(ctx, event, data)
+	}
+}
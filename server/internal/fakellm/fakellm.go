@@ -0,0 +1,208 @@
+// Package fakellm is a deterministic stand-in for the MOI LLM proxy.
+//
+// Responses are scripted per system prompt: each rule is keyed by
+// Fingerprint(system) and may narrow on the last user message. The HTTP server
+// speaks the OpenAI chat-completions protocol (plain JSON and SSE chunks), so it
+// can be used as moi.base_url or as an openai llm_base_url.
+package fakellm
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+//go:embed default_script.json
+var defaultScript []byte
+
+// Rule is one scripted response.
+type Rule struct {
+	Name         string   `json:"name"`
+	Fingerprint  string   `json:"fingerprint"`
+	UserContains []string `json:"user_contains,omitempty"` // any-of match on the last user message
+	Reply        string   `json:"reply"`                   // "{{user}}" is replaced by the last user message
+}
+
+// Script is an ordered rule list; the first matching rule wins.
+type Script struct {
+	Rules   []Rule `json:"rules"`
+	Default string `json:"default"` // used when nothing matches; empty echoes the last user message
+}
+
+// DefaultScript returns the built-in script covering the intent, validate,
+// completeness, summarize, risk, topic, merge, weekly, team digest, review
+// and date-range prompts.
+func DefaultScript() *Script {
+	var s Script
+	if err := json.Unmarshal(defaultScript, &s); err != nil {
+		panic(fmt.Sprintf("fakellm: bad default script: %v", err))
+	}
+	return &s
+}
+
+// LoadScript reads a JSON script from disk.
+func LoadScript(path string) (*Script, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s Script
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parse script: %w", err)
+	}
+	return &s, nil
+}
+
+var (
+	digitRe   = regexp.MustCompile(`\d`)
+	weekdayRe = regexp.MustCompile(`星期[一二三四五六日]`)
+)
+
+// Fingerprint identifies a system prompt by its first non-empty line, with
+// digits and weekdays masked so the date context injected into prompts
+// (todayContext, ExtractDateRange) does not change the key from day to day.
+func Fingerprint(system string) string {
+	line := ""
+	for _, l := range strings.Split(system, "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			line = l
+			break
+		}
+	}
+	line = weekdayRe.ReplaceAllString(line, "星期#")
+	line = digitRe.ReplaceAllString(line, "#")
+	sum := sha256.Sum256([]byte(line))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// Match returns the rule for a request, or nil.
+func (s *Script) Match(msgs []map[string]string) *Rule {
+	system, user := split(msgs)
+	fp := Fingerprint(system)
+	for i := range s.Rules {
+		r := &s.Rules[i]
+		if r.Fingerprint != fp {
+			continue
+		}
+		if len(r.UserContains) == 0 {
+			return r
+		}
+		for _, kw := range r.UserContains {
+			if strings.Contains(user, kw) {
+				return r
+			}
+		}
+	}
+	return nil
+}
+
+// Reply renders the scripted response. Its signature matches service.FakeReply,
+// so a script can also back the in-process fake provider.
+func (s *Script) Reply(_ string, msgs []map[string]string) string {
+	_, user := split(msgs)
+	reply := s.Default
+	if r := s.Match(msgs); r != nil {
+		reply = r.Reply
+	} else if reply == "" {
+		return user
+	}
+	return strings.ReplaceAll(reply, "{{user}}", user)
+}
+
+func split(msgs []map[string]string) (system, user string) {
+	for _, m := range msgs {
+		switch m["role"] {
+		case "system":
+			if system == "" {
+				system = m["content"]
+			}
+		case "user":
+			user = m["content"]
+		}
+	}
+	return system, user
+}
+
+// Server serves the script over POST .../chat/completions.
+type Server struct {
+	script *Script
+	// Delay is slept between streamed chunks (zero in tests).
+	Delay time.Duration
+	// OnMiss is called for requests no rule matched, to help authoring scripts.
+	OnMiss func(fingerprint, firstLine string)
+}
+
+func NewServer(script *Script) *Server { return &Server{script: script} }
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || !strings.HasSuffix(r.URL.Path, "/chat/completions") {
+		http.NotFound(w, r)
+		return
+	}
+	var req struct {
+		Model    string              `json:"model"`
+		Stream   bool                `json:"stream"`
+		Messages []map[string]string `json:"messages"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	if s.OnMiss != nil && s.script.Match(req.Messages) == nil {
+		system, _ := split(req.Messages)
+		first, _, _ := strings.Cut(strings.TrimSpace(system), "\n")
+		s.OnMiss(Fingerprint(system), first)
+	}
+	reply := s.script.Reply(req.Model, req.Messages)
+
+	if !req.Stream {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"model": req.Model,
+			"choices": []map[string]interface{}{
+				{"index": 0, "message": map[string]string{"role": "assistant", "content": reply}, "finish_reason": "stop"},
+			},
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher, _ := w.(http.Flusher)
+	for _, chunk := range chunks(reply, 8) {
+		data, _ := json.Marshal(map[string]interface{}{
+			"model":   req.Model,
+			"choices": []map[string]interface{}{{"index": 0, "delta": map[string]string{"content": chunk}}},
+		})
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if s.Delay > 0 {
+			time.Sleep(s.Delay)
+		}
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+// chunks splits text into pieces of at most n runes, like a token stream.
+func chunks(text string, n int) []string {
+	runes := []rune(text)
+	var out []string
+	for len(runes) > 0 {
+		k := min(n, len(runes))
+		out = append(out, string(runes[:k]))
+		runes = runes[k:]
+	}
+	return out
+}
@@ -0,0 +1,90 @@
+package fakellm_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"smart-daily/internal/fakellm"
+	"smart-daily/internal/service"
+)
+
+// newAI wires AIService to the fake server through the given provider constructor.
+func newAI(t *testing.T, moi bool) *service.AIService {
+	t.Helper()
+	srv := fakellm.NewServer(fakellm.DefaultScript())
+	srv.OnMiss = func(fp, line string) { t.Errorf("unscripted prompt %s: %s", fp, line) }
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+
+	var llm service.LLMProvider
+	if moi {
+		llm = service.NewMOIProvider(ts.URL, "test-key", "main", "fast")
+	} else {
+		llm = service.NewOpenAIProvider(ts.URL+"/v1", "", "main", "fast")
+	}
+	return service.NewAIService(llm, "smart_daily", nil)
+}
+
+func TestReportPipeline(t *testing.T) {
+	for _, moi := range []bool{true, false} {
+		ai := newAI(t, moi)
+		ctx := context.Background()
+
+		if intent, _ := ai.ClassifyIntent(ctx, "今天修复了登录页的bug", nil); intent != "report" {
+			t.Errorf("intent report: got %q", intent)
+		}
+		if intent, _ := ai.ClassifyIntent(ctx, "张三最近做了什么", nil); intent != "query" {
+			t.Errorf("intent query: got %q", intent)
+		}
+
+		if valid, reply, err := ai.ValidateWorkContent(ctx, "你觉得今天天气怎么样"); err != nil || valid || reply == "" {
+			t.Errorf("validate chitchat: valid=%v reply=%q err=%v", valid, reply, err)
+		}
+		if valid, _, err := ai.ValidateWorkContent(ctx, "修复了登录页面验证码不刷新的bug"); err != nil || !valid {
+			t.Errorf("validate work: valid=%v err=%v", valid, err)
+		}
+
+		var tokens []string
+		summary, err := ai.StreamSummarize(ctx, "完成MOI导入接口开发", func(tok string) { tokens = append(tokens, tok) })
+		if err != nil {
+			t.Fatalf("summarize: %v", err)
+		}
+		if summary != "- 完成MOI导入接口开发" || strings.Join(tokens, "") != summary || len(tokens) < 2 {
+			t.Errorf("summarize: summary=%q tokens=%q", summary, tokens)
+		}
+
+		if a, err := ai.AnalyzeReport(ctx, "- 联调被阻塞，等待后端接口"); err != nil || a.Status != "blocked" || len(a.Blockers) != 1 || len(a.Risks) != 1 || a.Risks[0].Category != "blocked" {
+			t.Errorf("analyze blocked: %+v err=%v", a, err)
+		}
+		if a, err := ai.AnalyzeReport(ctx, "- 上线可能延期"); err != nil || a.Status != "at-risk" || len(a.Blockers) != 0 || len(a.Risks) != 1 {
+			t.Errorf("analyze at-risk: %+v err=%v", a, err)
+		}
+		if a, err := ai.AnalyzeReport(ctx, summary); err != nil || a.Status != "on-track" || len(a.Risks) != 0 {
+			t.Errorf("analyze on-track: %+v err=%v", a, err)
+		}
+
+		if topics, _ := ai.ExtractTopics(ctx, summary, nil); len(topics) != 1 || topics[0] != "MOI" {
+			t.Errorf("topics: %v", topics)
+		}
+
+		if md, err := ai.StreamTeamDigest(ctx, "团队：研发部\n", func(string) {}); err != nil || !strings.HasPrefix(md, "# 团队周报") {
+			t.Errorf("team digest: %q err=%v", md, err)
+		}
+		if week, err := ai.SummarizeWeek(ctx, "[2026-03-02] 导入接口\n"); err != nil || !strings.HasPrefix(week, "- ") {
+			t.Errorf("week rollup: %q err=%v", week, err)
+		}
+		if md, err := ai.StreamPeriodReview(ctx, "张三", "2026年3月", "### 2026-03-02 ~ 2026-03-08\n- 导入接口", func(string) {}); err != nil || !strings.HasPrefix(md, "# 阶段总结") {
+			t.Errorf("period review: %q err=%v", md, err)
+		}
+	}
+}
+
+func TestFingerprintIgnoresDates(t *testing.T) {
+	a := fakellm.Fingerprint("今天是2026-03-02（星期一）。你是意图分类器。\n规则")
+	b := fakellm.Fingerprint("今天是2027-11-20（星期六）。你是意图分类器。\n其他")
+	if a != b {
+		t.Fatalf("fingerprints differ: %s vs %s", a, b)
+	}
+}
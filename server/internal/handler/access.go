@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"smart-daily/internal/authz"
+	"smart-daily/internal/middleware"
+	"smart-daily/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// canAccessMember reports whether the caller may use p on memberID's data,
+// looking up the member's team only when the answer depends on it.
+func canAccessMember(c *gin.Context, members *repository.MemberRepo, p authz.Permission, memberID int) bool {
+	sub := middleware.Subject(c)
+	switch {
+	case memberID == sub.MemberID:
+		return true
+	case !sub.Can(p):
+		return false
+	case sub.IsAdmin():
+		return true
+	}
+	teamID, err := members.TeamOf(c.Request.Context(), memberID)
+	return err == nil && sub.CanAccess(p, memberID, teamID)
+}
+
+// viewScope is the set of teams the caller may browse in the feed, insights
+// and risk views: the report.read scope, and for members their own team, so
+// they see what their teammates report but nothing of other teams. Callers
+// without either only see their own data.
+func viewScope(sub authz.Subject) authz.Scope {
+	scope := sub.TeamScope(authz.ReportRead)
+	if !scope.All && len(scope.TeamIDs) == 0 && sub.TeamID != 0 {
+		scope.TeamIDs = []int{sub.TeamID}
+	}
+	return scope
+}
+
+// canViewMember reports whether the caller may read memberID's entries and
+// risks in those views.
+func canViewMember(c *gin.Context, members *repository.MemberRepo, memberID int) bool {
+	if canAccessMember(c, members, authz.ReportRead, memberID) {
+		return true
+	}
+	sub := middleware.Subject(c)
+	if sub.TeamID == 0 {
+		return false
+	}
+	teamID, err := members.TeamOf(c.Request.Context(), memberID)
+	return err == nil && teamID == sub.TeamID
+}
@@ -1,20 +1,36 @@
 package handler
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
 	"net/http"
+	"net/url"
+	"smart-daily/internal/audit"
+	"smart-daily/internal/authz"
 	"smart-daily/internal/logger"
 	"smart-daily/internal/middleware"
 	"smart-daily/internal/model"
+	"smart-daily/internal/repository"
 	"smart-daily/internal/service"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
 )
 
-type AuthHandler struct{ auth *service.AuthService }
+type AuthHandler struct {
+	auth   *service.AuthService
+	tokens *repository.TokenRepo
+}
 
-func NewAuthHandler(auth *service.AuthService) *AuthHandler { return &AuthHandler{auth: auth} }
+func NewAuthHandler(auth *service.AuthService, tokens *repository.TokenRepo) *AuthHandler {
+	return &AuthHandler{auth: auth, tokens: tokens}
+}
 
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req model.LoginRequest
@@ -25,22 +41,235 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 	m, err := h.auth.Login(c.Request.Context(), req.Username, req.Password)
 	if err != nil {
-		logger.Warn("login.failed", "username", req.Username)
+		logger.Warn("login.failed", "username", req.Username, "err", err)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
 
 	logger.Info("login.ok", "uid", m.ID, "name", m.Name)
 
-	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"uid":      m.ID,
-		"name":     m.Name,
-		"is_admin": m.IsAdmin,
-		"exp":      time.Now().Add(middleware.TokenTTL()).Unix(),
-	}).SignedString(middleware.JWTSecret)
-
-	c.JSON(http.StatusOK, model.LoginResponse{
-		Token: token,
-		User:  model.User{ID: m.ID, Name: m.Name, Avatar: m.Avatar, Role: m.Role, IsAdmin: m.IsAdmin},
-	})
+	h.respondWithToken(c, m)
+}
+
+func (h *AuthHandler) respondWithToken(c *gin.Context, m *model.Member) {
+	token, err := middleware.IssueToken(m.ID, m.Name, m.IsAdmin, m.MustChangePassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, model.LoginResponse{Token: token, User: userOf(m)})
+}
+
+func userOf(m *model.Member) model.User {
+	role := authz.RoleOf(m.AccessRole, m.IsAdmin)
+	return model.User{ID: m.ID, Name: m.Name, Avatar: m.Avatar, Role: m.Role, IsAdmin: m.IsAdmin,
+		AccessRole: string(role), Permissions: authz.Permissions(role), MustChangePassword: m.MustChangePassword}
+}
+
+// Me handles GET /api/me: the caller's profile, e.g. after an SSO redirect
+// that only hands the frontend a token.
+func (h *AuthHandler) Me(c *gin.Context) {
+	m, err := h.auth.Member(c.Request.Context(), c.GetInt("user_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "member not found"})
+		return
+	}
+	c.JSON(http.StatusOK, userOf(m))
+}
+
+// Providers handles GET /api/auth/providers (public): what the login page offers.
+func (h *AuthHandler) Providers(c *gin.Context) {
+	resp := gin.H{"password": h.auth.Providers()}
+	if p := h.auth.OIDC(); p != nil {
+		resp["oidc"] = gin.H{"name": p.DisplayName(), "login_url": "/api/auth/oidc/login"}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+const oidcStateCookie = "oidc_state"
+
+// OIDCLogin handles GET /api/auth/oidc/login: redirects to the IdP. State and
+// nonce are kept in a short-lived cookie scoped to the callback.
+func (h *AuthHandler) OIDCLogin(c *gin.Context) {
+	p := h.auth.OIDC()
+	if p == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "oidc not configured"})
+		return
+	}
+	state, nonce := randomToken(), randomToken()
+	target, err := p.AuthCodeURL(c.Request.Context(), state, nonce)
+	if err != nil {
+		logger.Error("oidc.login failed", "err", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "单点登录服务暂不可用"})
+		return
+	}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state+"."+nonce, 600, "/api/auth/oidc", "", isHTTPS(c), true)
+	c.Redirect(http.StatusFound, target)
+}
+
+// OIDCCallback handles GET /api/auth/oidc/callback: verifies state, exchanges
+// the code, maps the identity to a member and sends the browser back to the
+// frontend with the token in the URL fragment (never sent to servers).
+func (h *AuthHandler) OIDCCallback(c *gin.Context) {
+	p := h.auth.OIDC()
+	if p == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "oidc not configured"})
+		return
+	}
+	fail := func(reason string, err error) {
+		logger.Warn("login.failed", "provider", "oidc", "reason", reason, "err", err, "ip", c.ClientIP())
+		c.Redirect(http.StatusFound, p.FrontendURL()+"#sso_error="+url.QueryEscape(reason))
+	}
+	cookie, _ := c.Cookie(oidcStateCookie)
+	c.SetCookie(oidcStateCookie, "", -1, "/api/auth/oidc", "", isHTTPS(c), true)
+	if e := c.Query("error"); e != "" {
+		fail(e, errors.New(c.Query("error_description")))
+		return
+	}
+	state, nonce, ok := strings.Cut(cookie, ".")
+	if !ok || state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(c.Query("state"))) != 1 {
+		fail("invalid_state", nil)
+		return
+	}
+	ctx := c.Request.Context()
+	id, err := p.Exchange(ctx, c.Query("code"), nonce)
+	if err != nil {
+		fail("exchange_failed", err)
+		return
+	}
+	m, err := h.auth.ResolveMember(ctx, id)
+	if errors.Is(err, service.ErrIdentityConflict) {
+		fail("account_conflict", err)
+		return
+	}
+	if err != nil {
+		fail("account_unavailable", err)
+		return
+	}
+	token, err := middleware.IssueToken(m.ID, m.Name, m.IsAdmin, m.MustChangePassword)
+	if err != nil {
+		fail("token_failed", err)
+		return
+	}
+	logger.Info("login.ok", "uid", m.ID, "name", m.Name, "provider", "oidc")
+	c.Redirect(http.StatusFound, p.FrontendURL()+"#sso_token="+url.QueryEscape(token))
+}
+
+func randomToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func isHTTPS(c *gin.Context) bool {
+	return c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+}
+
+// ChangePassword handles PUT /api/me/password {old_password, new_password}.
+// Every token of the member is revoked, logging out other sessions, and a fresh
+// one returned, like a login.
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	var req struct {
+		OldPassword string `json:"old_password" binding:"required"`
+		NewPassword string `json:"new_password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "old_password and new_password required"})
+		return
+	}
+	ctx := c.Request.Context()
+	uid := c.GetInt("user_id")
+	m, err := h.auth.ChangePassword(ctx, uid, req.OldPassword, req.NewPassword)
+	switch {
+	case errors.Is(err, service.ErrWrongPassword):
+		logger.Warn("password.change.failed", "uid", uid, "reason", "wrong old password", "ip", c.ClientIP())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "旧密码错误"})
+		return
+	case errors.Is(err, service.ErrWeakPassword):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "新密码至少 8 位且不能与旧密码相同"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	logger.Info("password.change", "uid", uid, "ip", c.ClientIP())
+	recordAudit(c, audit.PasswordChange, audit.TargetMember, uid, nil, nil)
+	// log out every other session too, like a reset does
+	if err := h.revokeMember(ctx, uid, uid); err != nil {
+		logger.Warn("revoke tokens after password change failed", "uid", uid, "err", err)
+	}
+	h.respondWithToken(c, m)
+}
+
+// ResetPassword handles POST /api/members/:id/password/reset (admin): sets a
+// one-time temporary password, logs the member out everywhere and returns
+// the password once.
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	ctx := c.Request.Context()
+	temp, err := h.auth.ResetPassword(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "member not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.revokeMember(ctx, id, c.GetInt("user_id")); err != nil {
+		logger.Warn("revoke tokens after password reset failed", "member_id", id, "err", err)
+	}
+	logger.Info("password.reset", "uid", c.GetInt("user_id"), "member_id", id, "ip", c.ClientIP())
+	recordAudit(c, audit.MemberPasswordReset, audit.TargetMember, id, nil, nil)
+	c.JSON(http.StatusOK, gin.H{"temporary_password": temp})
+}
+
+// revokeMember logs a member out everywhere. The revocation covers the current
+// second, as token iat has whole seconds, so it returns only once that second
+// is over: tokens issued afterwards, e.g. with a new password, stay valid.
+func (h *AuthHandler) revokeMember(ctx context.Context, id, by int) error {
+	if err := h.tokens.RevokeMember(ctx, id, middleware.TokenTTL(), by); err != nil {
+		return err
+	}
+	middleware.ForgetAllTokens()
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+	return nil
+}
+
+// Logout handles POST /api/logout: revokes the caller's current token.
+func (h *AuthHandler) Logout(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	jti := c.GetString("token_jti")
+	exp, ok := c.Get("token_exp")
+	if !ok {
+		exp = time.Now().Add(middleware.TokenTTL())
+	}
+	if err := h.tokens.Revoke(c.Request.Context(), jti, uid, exp.(time.Time), uid); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	middleware.ForgetToken(jti)
+	logger.Info("logout", "uid", uid)
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// ForceLogout handles POST /api/members/:id/logout (admin): revokes every
+// token the member holds. Other replicas notice within the revocation cache TTL.
+func (h *AuthHandler) ForceLogout(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if err := h.revokeMember(c.Request.Context(), id, c.GetInt("user_id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(c, audit.MemberForceLogout, audit.TargetMember, id, nil, nil)
+	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
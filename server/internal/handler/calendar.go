@@ -25,6 +25,7 @@ type calendarDay struct {
 	IsWorkday bool   `json:"is_workday"`
 	Holiday   string `json:"holiday,omitempty"` // e.g. "春节", "元旦"
 	Submitted bool   `json:"submitted"`
+	Status    string `json:"status,omitempty"` // on-track / at-risk / blocked / off, when summarised
 }
 
 // Calendar handles GET /api/calendar?month=2026-03
@@ -40,6 +41,7 @@ func (h *CalendarHandler) Calendar(c *gin.Context) {
 
 	uid := c.GetInt("user_id")
 	submitted, _ := h.daily.SubmittedDates(c.Request.Context(), uid, start.Format("2006-01-02"), end.Format("2006-01-02"))
+	statuses, _ := h.daily.DayStatuses(c.Request.Context(), uid, start.Format("2006-01-02"), end.Format("2006-01-02"))
 
 	h.holiday.EnsureYear(start.Year())
 
@@ -60,7 +62,7 @@ func (h *CalendarHandler) Calendar(c *gin.Context) {
 		sub := submitted[ds]
 		days = append(days, calendarDay{
 			Date: ds, Weekday: int(d.Weekday()), IsWorkday: isWork,
-			Holiday: holiday, Submitted: sub,
+			Holiday: holiday, Submitted: sub, Status: statuses[ds],
 		})
 		if isWork && ds < today {
 			workdays++
@@ -78,21 +80,32 @@ func (h *CalendarHandler) Calendar(c *gin.Context) {
 	})
 }
 
-// DaySummary handles GET /api/calendar/day?date=2026-03-02
+// DaySummary handles GET /api/calendar/day?date=2026-03-02&status=blocked,at-risk&blocked=true
+// A day whose summary does not pass the status filters comes back as
+// submitted but with matched false and no summary.
 func (h *CalendarHandler) DaySummary(c *gin.Context) {
 	date := c.Query("date")
 	if date == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "date required"})
 		return
 	}
+	var f repository.SummaryFilter
+	if !statusFilter(c, &f) {
+		return
+	}
 	uid := c.GetInt("user_id")
 	s, err := h.daily.GetSummary(c.Request.Context(), uid, date)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{"date": date, "submitted": false})
 		return
 	}
+	if !f.Matches(s) {
+		c.JSON(http.StatusOK, gin.H{"date": date, "submitted": true, "matched": false})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"date": date, "submitted": true,
 		"summary": s.Summary, "risk": s.Risk,
+		"status": s.Status, "blocker": s.Blocker,
 	})
 }
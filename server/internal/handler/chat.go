@@ -3,11 +3,16 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"path/filepath"
+	"smart-daily/internal/audit"
+	"smart-daily/internal/authz"
+	"smart-daily/internal/events"
 	"smart-daily/internal/logger"
+	"smart-daily/internal/middleware"
 	"smart-daily/internal/model"
 	"smart-daily/internal/repository"
 	"smart-daily/internal/service"
@@ -16,6 +21,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type ChatHandler struct {
@@ -23,17 +29,36 @@ type ChatHandler struct {
 	daily      *service.DailyService
 	catalog    *service.CatalogSync
 	session    *service.SessionService
-	topicRepo  *repository.TopicRepo
 	memberRepo *repository.MemberRepo
-	pending    sync.Map
+	drafts     *repository.DraftRepo
+	digest     *service.DigestService
+	review     *service.ReviewService
+	reports    *service.ReportService
+	events     events.Publisher
+	files      sync.Map // generated file name -> member ID allowed to download it
 }
 
-func NewChatHandler(ai *service.AIService, daily *service.DailyService, catalog *service.CatalogSync, topicRepo *repository.TopicRepo, memberRepo *repository.MemberRepo) *ChatHandler {
-	return &ChatHandler{ai: ai, daily: daily, catalog: catalog, topicRepo: topicRepo, memberRepo: memberRepo}
+// draftTTL is how long an unconfirmed report draft stays available.
+const draftTTL = 24 * time.Hour
+
+func NewChatHandler(ai *service.AIService, daily *service.DailyService, catalog *service.CatalogSync, memberRepo *repository.MemberRepo, drafts *repository.DraftRepo) *ChatHandler {
+	return &ChatHandler{ai: ai, daily: daily, catalog: catalog, memberRepo: memberRepo, drafts: drafts}
 }
 
 func (h *ChatHandler) SetSessionService(s *service.SessionService) { h.session = s }
 
+// SetDigestService enables team digests (chat mode "digest" and /api/digest).
+func (h *ChatHandler) SetDigestService(d *service.DigestService) { h.digest = d }
+
+// SetReviewService enables monthly/quarterly reviews (chat mode "review" and /api/review).
+func (h *ChatHandler) SetReviewService(r *service.ReviewService) { h.review = r }
+
+// SetReportService saves weekly reports (chat mode "summary") for reuse and re-download.
+func (h *ChatHandler) SetReportService(r *service.ReportService) { h.reports = r }
+
+// SetPublisher enables report.confirmed events.
+func (h *ChatHandler) SetPublisher(p events.Publisher) { h.events = p }
+
 func (h *ChatHandler) Chat(c *gin.Context) {
 	var req model.ChatRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -47,54 +72,77 @@ func (h *ChatHandler) Chat(c *gin.Context) {
 	}
 
 	uid := c.GetInt("user_id")
-	val, ok := h.pending.LoadAndDelete(uid)
-	if !ok {
+	ctx := c.Request.Context()
+	var p *model.ReportDraft
+	var err error
+	if req.DraftID > 0 {
+		p, err = h.drafts.Get(ctx, req.DraftID, uid)
+	} else {
+		p, err = h.drafts.Latest(ctx, uid)
+	}
+	if err == nil {
+		var claimed bool
+		if claimed, err = h.drafts.Claim(ctx, p.ID); err == nil && !claimed {
+			err = gorm.ErrRecordNotFound
+		}
+	}
+	if err != nil {
+		if err != gorm.ErrRecordNotFound {
+			logger.Error("load draft failed", "err", err, "draft_id", req.DraftID)
+		}
 		c.JSON(http.StatusOK, model.ChatResponse{Content: "没有待确认的日报，请先输入工作内容。", Type: "text"})
 		return
 	}
-	p := val.(*model.PendingReport)
-	ctx := c.Request.Context()
-	date := p.Date
-	if date == "" {
-		date = time.Now().Format("2006-01-02")
-	}
-	logger.Info("chat.confirm", "uid", uid, "member_id", p.MemberID, "date", date, "summary", p.Summary)
+	date := p.DailyDate
+	logger.Info("chat.confirm", "uid", uid, "member_id", p.MemberID, "draft_id", p.ID, "date", date, "summary", p.Summary)
 
-	risk := strings.Join(p.Risks, "; ")
-	entryID, err := h.daily.Save(ctx, p.MemberID, date, p.Content, p.Summary, risk)
+	name := c.GetString("user_name")
+	entryID, err := h.daily.Save(ctx, p.ID, p.MemberID, name, date, p.Content, p.Summary, p.Risks)
 	if err != nil {
 		logger.Error("save daily failed", "err", err)
+		// hand the draft back so the user can retry
+		if err := h.drafts.Release(context.Background(), p.ID); err != nil {
+			logger.Warn("release draft failed", "err", err, "draft_id", p.ID)
+		}
 		c.JSON(http.StatusOK, model.ChatResponse{Content: "保存失败：" + err.Error(), Type: "text"})
 		return
 	}
 
 	// 取今天所有提交记录，带时间戳传给 LLM 合并
-	mergedSummary := p.Summary
-	allEntries, _ := h.daily.GetDayEntries(ctx, p.MemberID, date)
-	if len(allEntries) > 1 {
-		if merged, err := h.ai.MergeDailySummary(ctx, allEntries); err == nil {
-			mergedSummary = merged
-		} else {
-			logger.Warn("merge summary failed, using latest", "err", err)
-		}
+	ds := &model.DailySummary{
+		MemberID: p.MemberID, DailyDate: date, Summary: p.Summary, Status: p.Status,
+		Risk: strings.Join(model.RiskDescriptions(p.Risks), "; "), Blocker: model.BlockerText(p.Blockers),
 	}
-	if err := h.daily.UpdateDailySummary(ctx, p.MemberID, date, mergedSummary, risk); err != nil {
+	hint := &model.ReportAnalysis{Status: p.Status, Blockers: p.Blockers}
+	if rebuilt, err := h.daily.RebuildSummary(ctx, p.MemberID, date, hint); err != nil {
 		logger.Error("update daily summary failed", "err", err)
+	} else if rebuilt != nil {
+		ds = rebuilt
 	}
+	mergedSummary := ds.Summary
 
 	if h.catalog != nil {
-		h.catalog.SyncDailySummary(ctx, entryID, p.MemberID, date, p.Content, mergedSummary, risk)
+		h.catalog.SyncDailySummary(ctx, entryID, p.Content, ds)
+		h.catalog.ResyncTables("risks")
 	}
 
+	recordAudit(c, audit.ReportConfirm, audit.TargetReport, entryID, nil, gin.H{
+		"draft_id": p.ID, "member_id": p.MemberID, "daily_date": date, "summary": p.Summary, "status": p.Status,
+	})
+	events.Publish(ctx, h.events, events.ReportConfirmed, map[string]any{
+		"entry_id": entryID, "member_id": p.MemberID, "member_name": name, "daily_date": date,
+		"summary": mergedSummary, "status": ds.Status, "blocker": ds.Blocker, "risks": p.Risks,
+	})
+
 	// Extract topics async
-	go h.extractAndSaveTopics(p.MemberID, c.GetString("user_name"), date, mergedSummary, entryID)
+	go h.extractAndSaveTopics(p.MemberID, name, date, mergedSummary, entryID)
 
 	c.JSON(http.StatusOK, model.ChatResponse{Content: "日报已提交成功！", Type: "text"})
 
 	// Save confirm messages to session
 	if req.SessionID != nil {
-		cfgJSON, _ := json.Marshal(map[string]interface{}{"type": "summary_confirm", "summary": mergedSummary, "risks": p.Risks})
-		h.saveMessages(c.GetString("user_name"), req.SessionID, "确认提交", "日报已提交成功！", string(cfgJSON), req.Mode)
+		cfgJSON, _ := json.Marshal(map[string]interface{}{"type": "summary_confirm", "summary": mergedSummary, "risks": model.RiskDescriptions(p.Risks)})
+		h.saveMessages(name, req.SessionID, "确认提交", "日报已提交成功！", string(cfgJSON), req.Mode)
 	}
 }
 
@@ -147,6 +195,7 @@ func (h *ChatHandler) ChatStream(c *gin.Context) {
 	ctx := c.Request.Context()
 	uid := c.GetInt("user_id")
 	name := c.GetString("user_name")
+	sub := middleware.Subject(c)
 	sse := &sseWriter{w: c.Writer, f: c.Writer}
 
 	switch req.Mode {
@@ -170,12 +219,25 @@ func (h *ChatHandler) ChatStream(c *gin.Context) {
 			h.saveMessages(name, req.SessionID, req.Text, "这看起来是在汇报工作内容，建议切换到「汇报今日工作」模式。\n如果确实是在查询数据，请换个方式提问。", "", req.Mode)
 			return
 		}
-		question := injectUserIdentity(req.Text, name)
-		reply, cfg := h.streamQueryCapture(ctx, sse, question, req)
+		question, scope, denied := h.scopeQuestion(ctx, sub, injectUserIdentity(req.Text, name), name)
+		if denied != "" {
+			logger.Warn("chat.query.denied", "uid", uid, "question", req.Text)
+			sse.token(denied)
+			sse.done()
+			h.saveMessages(name, req.SessionID, req.Text, denied, "", req.Mode)
+			return
+		}
+		reply, cfg := h.streamQueryCapture(ctx, sse, question, scope, req)
 		h.saveMessages(name, req.SessionID, req.Text, reply, cfg, req.Mode)
 	case "summary":
 		logger.Info("chat.stream", "uid", uid, "name", name, "mode", "summary")
-		h.streamSummary(ctx, sse, uid, name, req.Text)
+		h.streamSummary(ctx, sse, sub, name, req.Text)
+	case "digest":
+		logger.Info("chat.stream", "uid", uid, "name", name, "mode", "digest", "text", req.Text)
+		h.chatDigest(ctx, sse, sub, req.Text)
+	case "review":
+		logger.Info("chat.stream", "uid", uid, "name", name, "mode", "review", "text", req.Text)
+		h.chatReview(ctx, sse, sub, name, req.Text)
 	default:
 		// 无模式：直接闲聊（StreamChat prompt 内含引导逻辑）
 		history := buildHistory(req, 5)
@@ -218,18 +280,39 @@ func (h *ChatHandler) streamReportCapture(ctx context.Context, sse *sseWriter, u
 		return "抱歉，摘要生成失败，请稍后重试。", ""
 	}
 
-	risks, _ := h.ai.DetectRisks(ctx, summary)
-	logger.Info("chat.report.done", "uid", uid, "summary", summary, "risks", risks)
+	analysis, err := h.ai.AnalyzeReport(ctx, summary)
+	if err != nil {
+		logger.Warn("analyze report failed", "err", err)
+		analysis = &model.ReportAnalysis{}
+	}
+	risks := analysis.Risks
+	logger.Info("chat.report.done", "uid", uid, "summary", summary, "status", analysis.Status, "blockers", analysis.Blockers, "risks", risks)
 
-	h.pending.Store(uid, &model.PendingReport{
-		Content: extracted, Summary: summary, Risks: risks,
-		Date: req.Date, MemberID: uid,
-	})
+	date := req.Date
+	if date == "" {
+		date = time.Now().Format("2006-01-02")
+	}
+	draft := &model.ReportDraft{
+		MemberID: uid, DailyDate: date, Mode: req.Mode,
+		Content: extracted, Summary: summary, Status: analysis.Status,
+		Blockers: analysis.Blockers, Risks: risks,
+		ExpiresAt: time.Now().Add(draftTTL),
+	}
+	if err := h.drafts.Create(ctx, draft); err != nil {
+		logger.Error("save draft failed", "err", err)
+		sse.token("\n\n抱歉，草稿保存失败，请稍后重试。")
+		sse.done()
+		return summary, ""
+	}
 
 	meta := map[string]interface{}{
-		"type":    "summary_confirm",
-		"summary": summary,
-		"risks":   risks,
+		"type":      "summary_confirm",
+		"summary":   summary,
+		"status":    analysis.Status,
+		"blockers":  analysis.Blockers,
+		"risks":     model.RiskDescriptions(risks),
+		"riskItems": risks,
+		"draftId":   draft.ID,
 	}
 	if req.Mode == "supplement" && req.Date != "" {
 		meta["isSupplement"] = true
@@ -290,12 +373,12 @@ func injectUserIdentity(question, userName string) string {
 	return question
 }
 
-func (h *ChatHandler) streamQueryCapture(ctx context.Context, sse *sseWriter, question string, req model.ChatRequest) (string, string) {
+func (h *ChatHandler) streamQueryCapture(ctx context.Context, sse *sseWriter, question string, scope *service.QueryScope, req model.ChatRequest) (string, string) {
 	var answer strings.Builder
 	var steps []string
 	queryStart := time.Now()
 	// Data Asking 用独立 session，不共用聊天 session（避免 agent 内部消息污染聊天历史）
-	if err := h.ai.StreamQueryAnswer(ctx, question, "", func(t string) {
+	if err := h.ai.StreamQueryAnswer(ctx, question, "", scope, func(t string) {
 		answer.WriteString(t)
 		sse.token(t)
 	}, func(t string) {
@@ -342,7 +425,12 @@ func (h *ChatHandler) streamQueryCapture(ctx context.Context, sse *sseWriter, qu
 	return answer.String(), cfgJSON
 }
 
-func (h *ChatHandler) streamSummary(ctx context.Context, sse *sseWriter, uid int, name string, text string) {
+// dateRange extracts the date range a summary request asks for; both are
+// empty (the current week) without text or when extraction fails.
+func (h *ChatHandler) dateRange(ctx context.Context, text string) (start, end string) {
+	if strings.TrimSpace(text) == "" {
+		return "", ""
+	}
 	now := time.Now()
 	today := now.Format("2006-01-02")
 	weekday := [...]string{"日", "一", "二", "三", "四", "五", "六"}[now.Weekday()]
@@ -352,17 +440,24 @@ func (h *ChatHandler) streamSummary(ctx context.Context, sse *sseWriter, uid int
 	}
 	monday := now.AddDate(0, 0, -(weekdayNum - 1)).Format("2006-01-02")
 
-	// 有用户输入则提取日期范围，否则默认最近7天
-	var start, end string
-	if strings.TrimSpace(text) != "" {
-		dr, err := h.ai.ExtractDateRange(ctx, text, today, "星期"+weekday, monday)
-		if err != nil {
-			logger.Warn("extract date range fallback", "err", err)
-		} else {
-			start, end = dr.Start, dr.End
-			logger.Info("chat.summary.dateRange", "input", text, "start", start, "end", end)
-		}
+	dr, err := h.ai.ExtractDateRange(ctx, text, today, "星期"+weekday, monday)
+	if err != nil {
+		logger.Warn("extract date range fallback", "err", err)
+		return "", ""
+	}
+	logger.Info("chat.summary.dateRange", "input", text, "start", dr.Start, "end", dr.End)
+	return dr.Start, dr.End
+}
+
+func (h *ChatHandler) streamSummary(ctx context.Context, sse *sseWriter, sub authz.Subject, name string, text string) {
+	uid := sub.MemberID
+	if h.reports == nil {
+		sse.token("周报功能未启用。")
+		sse.done()
+		return
 	}
+	// 有用户输入则提取日期范围，否则默认本周（周一至周日）
+	start, end := h.dateRange(ctx, text)
 
 	// Match target member name from message (e.g. "帮我生成彭振的周报")
 	targetUID, targetName := uid, name
@@ -370,6 +465,12 @@ func (h *ChatHandler) streamSummary(ctx context.Context, sse *sseWriter, uid int
 		members, _ := h.memberRepo.ListActive(ctx)
 		for _, m := range members {
 			if m.ID != uid && strings.Contains(text, m.Name) {
+				if !sub.CanAccess(authz.ReportRead, m.ID, m.TeamID) {
+					logger.Warn("chat.summary.denied", "uid", uid, "target_id", m.ID)
+					sse.token(fmt.Sprintf("你没有权限生成%s的周报。组长可以生成本团队成员的周报，其他成员只能生成自己的周报。", m.Name))
+					sse.done()
+					return
+				}
 				targetUID, targetName = m.ID, m.Name
 				logger.Info("chat.summary.targetMember", "from", name, "target", targetName, "target_id", targetUID)
 				break
@@ -377,65 +478,105 @@ func (h *ChatHandler) streamSummary(ctx context.Context, sse *sseWriter, uid int
 		}
 	}
 
-	data, err := h.daily.GetMemberDateRangeData(ctx, targetUID, start, end)
-	if err != nil {
-		logger.Error("get data failed", "err", err)
-	}
-	if strings.TrimSpace(data) == "" {
+	logger.Info("chat.summary", "uid", targetUID, "name", targetName, "start", start, "end", end)
+	rep, err := h.reports.Weekly(ctx, targetUID, targetName, uid, start, end, sse.token)
+	if errors.Is(err, service.ErrNoReportData) {
 		sse.token("该时间段暂无日报记录，无法生成周报。请先提交日报后再试。")
 		sse.done()
 		return
 	}
-
-	logger.Info("chat.summary", "uid", targetUID, "name", targetName, "start", start, "end", end, "dataLen", len(data))
-	md, err := h.ai.StreamWeeklySummary(ctx, targetName, data, sse.token)
 	if err != nil {
 		logger.Error("stream summary failed", "err", err)
 		sse.token("抱歉，周报生成失败，请稍后重试。")
 		sse.done()
 		return
 	}
+	if rep.Stale {
+		sse.token("\n\n> 该周报已被手动编辑，之后日报又有更新，这里保留的是编辑后的版本。如需按最新日报重新生成，请先在周报中放弃修改。")
+	}
+	sse.event("meta", reportDownloads(rep))
+	sse.done()
+}
 
-	filename := fmt.Sprintf("周报_%s_%s.md", targetName, time.Now().Format("20060102"))
+// saveFile stores a generated file for a single download by member uid and
+// returns its URL. The stored name gets a random prefix, so files generated
+// with the same title (e.g. the same team's digest on one day) never replace
+// each other.
+func (h *ChatHandler) saveFile(uid int, filename string, data []byte) string {
 	dir := filepath.Join(".", "exports")
 	os.MkdirAll(dir, 0755)
+	filename = randomToken()[:12] + "_" + filename
 	fpath := filepath.Join(dir, filename)
-	os.WriteFile(fpath, []byte(md), 0644)
+	os.WriteFile(fpath, data, 0644)
+	h.files.Store(filename, uid)
 	// 5 分钟后自动清理未下载的文件
-	time.AfterFunc(5*time.Minute, func() { os.Remove(fpath) })
-
-	sse.event("meta", map[string]string{
-		"downloadUrl":   "/api/files/" + filename,
-		"downloadTitle": filename,
-	})
-	sse.done()
+	time.AfterFunc(5*time.Minute, func() { os.Remove(fpath); h.files.Delete(filename) })
+	return "/api/files/" + filename
 }
 
+// DownloadFile serves a generated file once, only to the member who generated it.
 func (h *ChatHandler) DownloadFile(c *gin.Context) {
-	name := c.Param("name")
+	name := filepath.Base(c.Param("name"))
 	path := filepath.Join(".", "exports", name)
-	if _, err := os.Stat(path); err != nil {
+	owner, ok := h.files.Load(name)
+	if _, err := os.Stat(path); err != nil || !ok || owner.(int) != c.GetInt("user_id") {
 		c.JSON(http.StatusNotFound, gin.H{"error": "file not found"})
 		return
 	}
 	c.File(path)
+	h.files.Delete(name)
 	defer os.Remove(path)
 }
 
-func (h *ChatHandler) extractAndSaveTopics(memberID int, memberName, date, content string, entryID int) {
-	ctx := context.Background()
-	existingTopics, _ := h.topicRepo.ListDistinctTopics(ctx)
-	topics, _ := h.ai.ExtractTopics(ctx, content, existingTopics)
-
-	h.topicRepo.DeleteByEntryID(ctx, entryID)
-	h.topicRepo.EnsureTopics(ctx, topics)
-	var items []model.TopicActivity
-	for _, t := range topics {
-		items = append(items, model.TopicActivity{
-			Topic: t, MemberID: memberID, MemberName: memberName,
-			DailyDate: date, Content: content, EntryID: entryID,
-		})
+// scopeQuestion keeps Data Asking within the caller's scope: without
+// data.query_all a question may only name members the caller can read reports
+// of (the own team and its sub-teams for team leads, otherwise only the
+// caller). The agent is told that limit, but only the returned QueryScope,
+// which filters the result rows, enforces it; nil means unrestricted.
+// It returns the question to ask, or a reply explaining the refusal.
+func (h *ChatHandler) scopeQuestion(ctx context.Context, sub authz.Subject, question, userName string) (string, *service.QueryScope, string) {
+	if sub.Can(authz.DataQueryAll) {
+		return question, nil, ""
 	}
-	h.topicRepo.BatchCreate(ctx, items)
-	logger.Info("topics extracted", "entry_id", entryID, "topics", topics)
+	if h.memberRepo == nil {
+		return "", nil, "暂时无法确认你的查询范围，请稍后再试。"
+	}
+	members, err := h.memberRepo.ListActive(ctx)
+	if err != nil {
+		logger.Warn("load members for query scope failed", "err", err)
+		return "", nil, "暂时无法确认你的查询范围，请稍后再试。"
+	}
+	scope := &service.QueryScope{Names: map[string]bool{}, IDs: map[int]bool{}}
+	var allowed, outside []string
+	teamScope := sub.TeamScope(authz.ReportRead)
+	for _, m := range members {
+		switch {
+		case m.ID == sub.MemberID || teamScope.Includes(m.TeamID):
+			allowed = append(allowed, m.Name)
+			scope.Names[m.Name] = true
+			scope.IDs[m.ID] = true
+		case m.Name != "":
+			scope.Outside = append(scope.Outside, m.Name)
+			if strings.Contains(question, m.Name) {
+				outside = append(outside, m.Name)
+			}
+		}
+	}
+	if len(outside) > 0 {
+		return "", nil, fmt.Sprintf("你只能查询本团队成员的数据，无法查询：%s。", strings.Join(outside, "、"))
+	}
+	const columns = "，结果中须包含成员姓名列 member_name"
+	if len(teamScope.TeamIDs) == 0 || len(allowed) <= 1 {
+		return question + fmt.Sprintf("（注：只查询%s本人的数据%s）", userName, columns), scope, ""
+	}
+	teams, _ := h.memberRepo.TeamMap(ctx)
+	team := teams[sub.TeamID]
+	if len(sub.SubTeams) > 0 {
+		team += "及下属团队"
+	}
+	return question + fmt.Sprintf("（注：只查询%s成员的数据，成员：%s%s）", team, strings.Join(allowed, "、"), columns), scope, ""
+}
+
+func (h *ChatHandler) extractAndSaveTopics(memberID int, memberName, date, content string, entryID int) {
+	h.daily.ExtractTopics(context.Background(), memberID, memberName, date, content, entryID)
 }
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"smart-daily/internal/authz"
+	"smart-daily/internal/middleware"
+	"smart-daily/internal/service"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+// ComplianceHandler reports daily report submission per team member.
+type ComplianceHandler struct{ svc *service.ComplianceService }
+
+func NewComplianceHandler(svc *service.ComplianceService) *ComplianceHandler {
+	return &ComplianceHandler{svc: svc}
+}
+
+// Get handles GET /api/compliance?team_id=&subtree=true&month=2026-03
+func (h *ComplianceHandler) Get(c *gin.Context) {
+	report, ok := h.report(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// Export handles GET /api/compliance/export?team_id=&subtree=true&month=2026-03 (xlsx).
+func (h *ComplianceHandler) Export(c *gin.Context) {
+	report, ok := h.report(c)
+	if !ok {
+		return
+	}
+	f := excelize.NewFile()
+	sheet := "提交统计"
+	f.SetSheetName("Sheet1", sheet)
+	headerStyle, wrapStyle := sheetStyles(f)
+
+	f.SetColWidth(sheet, "A", "A", 14)
+	f.SetColWidth(sheet, "B", "E", 12)
+	f.SetColWidth(sheet, "F", "F", 60)
+	for i, title := range []string{"成员", "应填天数", "已填天数", "填报率", "连续提交", "缺失日期"} {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
+		f.SetCellValue(sheet, cell, title)
+	}
+	f.SetCellStyle(sheet, "A1", "F1", headerStyle)
+	f.SetRowHeight(sheet, 1, 28)
+	for i, m := range report.Members {
+		r := i + 2
+		rate := "-"
+		if m.Workdays > 0 {
+			rate = fmt.Sprintf("%.0f%%", float64(m.FilledDays)*100/float64(m.Workdays))
+		}
+		f.SetCellValue(sheet, fmt.Sprintf("A%d", r), m.MemberName)
+		f.SetCellValue(sheet, fmt.Sprintf("B%d", r), m.Workdays)
+		f.SetCellValue(sheet, fmt.Sprintf("C%d", r), m.FilledDays)
+		f.SetCellValue(sheet, fmt.Sprintf("D%d", r), rate)
+		f.SetCellValue(sheet, fmt.Sprintf("E%d", r), m.Streak)
+		f.SetCellValue(sheet, fmt.Sprintf("F%d", r), strings.Join(m.MissingDates, ", "))
+		f.SetCellStyle(sheet, fmt.Sprintf("A%d", r), fmt.Sprintf("F%d", r), wrapStyle)
+	}
+	writeXLSX(c, f, fmt.Sprintf("提交统计_%s.xlsx", report.Month))
+}
+
+func (h *ComplianceHandler) report(c *gin.Context) (*service.ComplianceReport, bool) {
+	teamID, ok := h.scopeTeam(c)
+	if !ok {
+		return nil, false
+	}
+	month := c.DefaultQuery("month", time.Now().Format("2006-01"))
+	report, err := h.svc.Month(c.Request.Context(), teamID, c.Query("subtree") == "true", month)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return report, true
+}
+
+// scopeTeam resolves ?team_id= for the caller: admins may see any team (or all
+// with team_id omitted), team leads only their own team and its sub-teams.
+func (h *ComplianceHandler) scopeTeam(c *gin.Context) (int, bool) {
+	teamID, _ := strconv.Atoi(c.Query("team_id"))
+	sub := middleware.Subject(c)
+	if sub.IsAdmin() && sub.Can(authz.ComplianceView) {
+		return teamID, true
+	}
+	if teamID == 0 {
+		teamID = sub.TeamID
+	}
+	if !sub.CanAccessTeam(authz.ComplianceView, teamID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only admins and the team's lead can view compliance"})
+		return 0, false
+	}
+	return teamID, true
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"net/http"
+	"smart-daily/internal/audit"
+	"smart-daily/internal/authz"
+	"smart-daily/internal/logger"
+	"smart-daily/internal/model"
+	"smart-daily/internal/repository"
+	"smart-daily/internal/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// DailyHandler corrects or retracts submitted daily entries.
+type DailyHandler struct {
+	daily      *service.DailyService
+	memberRepo *repository.MemberRepo
+}
+
+func NewDailyHandler(daily *service.DailyService, memberRepo *repository.MemberRepo) *DailyHandler {
+	return &DailyHandler{daily: daily, memberRepo: memberRepo}
+}
+
+// ListEntries handles GET /api/daily/entries?date=2026-03-02[&member_id=3]
+// member_id needs report.read on that member (admins, or the member's team lead).
+func (h *DailyHandler) ListEntries(c *gin.Context) {
+	date := c.Query("date")
+	if date == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "date required"})
+		return
+	}
+	memberID := c.GetInt("user_id")
+	if id, err := strconv.Atoi(c.Query("member_id")); err == nil && id != memberID {
+		if !canAccessMember(c, h.memberRepo, authz.ReportRead, id) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "permission denied", "permission": authz.ReportRead})
+			return
+		}
+		memberID = id
+	}
+	entries, err := h.daily.GetDayEntries(c.Request.Context(), memberID, date)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for i := range entries {
+		if len(entries[i].DailyDate) > 10 {
+			entries[i].DailyDate = entries[i].DailyDate[:10]
+		}
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+// UpdateEntry handles PUT /api/daily/entries/:id {content, summary}.
+// Omitting summary regenerates it from content.
+func (h *DailyHandler) UpdateEntry(c *gin.Context) {
+	var req struct {
+		Content string `json:"content"`
+		Summary string `json:"summary"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || (req.Content == "" && req.Summary == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "content or summary required"})
+		return
+	}
+	e, ok := h.ownedEntry(c)
+	if !ok {
+		return
+	}
+	content := req.Content
+	if content == "" {
+		content = e.Content
+	}
+	ctx := c.Request.Context()
+	name := c.GetString("user_name")
+	if m, err := h.memberRepo.FindByID(ctx, e.MemberID); err == nil {
+		name = m.Name
+	}
+	before := *e
+	if err := h.daily.UpdateEntry(ctx, e, name, content, req.Summary); err != nil {
+		logger.Error("update entry failed", "entry_id", e.ID, "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(c, audit.ReportUpdate, audit.TargetReport, e.ID, before, e)
+	c.JSON(http.StatusOK, e)
+}
+
+// DeleteEntry handles DELETE /api/daily/entries/:id.
+func (h *DailyHandler) DeleteEntry(c *gin.Context) {
+	e, ok := h.ownedEntry(c)
+	if !ok {
+		return
+	}
+	if err := h.daily.DeleteEntry(c.Request.Context(), e); err != nil {
+		logger.Error("delete entry failed", "entry_id", e.ID, "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(c, audit.ReportDelete, audit.TargetReport, e.ID, e, nil)
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// ownedEntry loads :id and checks the caller owns it or holds report.edit for its member.
+func (h *DailyHandler) ownedEntry(c *gin.Context) (*model.DailyEntry, bool) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	e, err := h.daily.GetEntry(c.Request.Context(), id)
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "entry not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if !canAccessMember(c, h.memberRepo, authz.ReportEdit, e.MemberID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "not your entry"})
+		return nil, false
+	}
+	return e, true
+}
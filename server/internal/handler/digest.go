@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"smart-daily/internal/authz"
+	"smart-daily/internal/docx"
+	"smart-daily/internal/logger"
+	"smart-daily/internal/middleware"
+	"smart-daily/internal/service"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const docxContentType = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+
+// Digest handles GET /api/digest?team_id=&subtree=true&start=&end=&format=.
+// Without format the digest streams over SSE like chat mode "digest";
+// format=md or format=docx returns the finished digest as a download.
+// Admins may digest any team (or everyone with team_id omitted), team leads
+// their own team and its sub-teams.
+func (h *ChatHandler) Digest(c *gin.Context) {
+	if h.digest == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "team digest is not enabled"})
+		return
+	}
+	format := c.Query("format")
+	if format != "" && format != "md" && format != "docx" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be md or docx"})
+		return
+	}
+	sub := middleware.Subject(c)
+	teamID, _ := strconv.Atoi(c.Query("team_id"))
+	scope := sub.TeamScope(authz.ReportRead)
+	if teamID == 0 && !scope.All {
+		teamID = sub.TeamID
+	}
+	if !scope.Includes(teamID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only admins and the team's lead can generate a team digest"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	d, err := h.digest.Collect(ctx, teamID, c.Query("subtree") == "true", c.Query("start"), c.Query("end"))
+	switch {
+	case errors.Is(err, service.ErrDigestRange):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, service.ErrTeamNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		logger.Error("collect digest failed", "err", err, "team_id", teamID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	logger.Info("digest", "uid", sub.MemberID, "team_id", teamID, "subtree", d.Subtree, "start", d.Start, "end", d.End, "format", format)
+
+	if format == "" {
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		h.streamDigest(ctx, &sseWriter{w: c.Writer, f: c.Writer}, sub.MemberID, d)
+		return
+	}
+	if d.Empty() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no reports in this date range"})
+		return
+	}
+	md, err := h.ai.StreamTeamDigest(ctx, d.PromptData(), func(string) {})
+	if err != nil {
+		logger.Error("generate digest failed", "err", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "digest generation failed"})
+		return
+	}
+	name := digestFileName(d)
+	if format == "md" {
+		writeAttachment(c, "text/markdown; charset=utf-8", name+".md", []byte(md))
+		return
+	}
+	data, err := docx.FromMarkdown(md)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	writeAttachment(c, docxContentType, name+".docx", data)
+}
+
+// chatDigest answers chat mode "digest". The team is one named in text,
+// otherwise everyone for admins and the own team with its sub-teams for team
+// leads; the date range is extracted from text like the weekly report's.
+func (h *ChatHandler) chatDigest(ctx context.Context, sse *sseWriter, sub authz.Subject, text string) {
+	reply := func(msg string) {
+		sse.token(msg)
+		sse.done()
+	}
+	if h.digest == nil {
+		reply("团队周报功能未启用。")
+		return
+	}
+	scope := sub.TeamScope(authz.ReportRead)
+	if !scope.All && len(scope.TeamIDs) == 0 {
+		reply("只有组长和管理员可以生成团队周报。你可以使用「生成周报总结」生成自己的周报。")
+		return
+	}
+
+	teamID, subtree := 0, false
+	if !scope.All {
+		teamID, subtree = sub.TeamID, true
+	}
+	if named, name := h.namedTeam(ctx, text); named != 0 {
+		if !scope.Includes(named) {
+			logger.Warn("chat.digest.denied", "uid", sub.MemberID, "team_id", named)
+			reply(fmt.Sprintf("你没有权限生成%s的团队周报。组长只能生成本团队及下属团队的周报。", name))
+			return
+		}
+		teamID, subtree = named, true
+	}
+
+	start, end := h.dateRange(ctx, text)
+	d, err := h.digest.Collect(ctx, teamID, subtree, start, end)
+	if errors.Is(err, service.ErrDigestRange) {
+		// an unusable extracted range falls back to the last 7 days
+		d, err = h.digest.Collect(ctx, teamID, subtree, "", "")
+	}
+	if err != nil {
+		logger.Error("collect digest failed", "err", err, "team_id", teamID)
+		reply("抱歉，团队周报生成失败，请稍后重试。")
+		return
+	}
+	logger.Info("chat.digest", "uid", sub.MemberID, "team_id", teamID, "subtree", d.Subtree, "start", d.Start, "end", d.End)
+	h.streamDigest(ctx, sse, sub.MemberID, d)
+}
+
+// namedTeam returns the team whose name text mentions, preferring the
+// longest name so "研发一组" wins over "研发".
+func (h *ChatHandler) namedTeam(ctx context.Context, text string) (int, string) {
+	if h.memberRepo == nil || strings.TrimSpace(text) == "" {
+		return 0, ""
+	}
+	teams, err := h.memberRepo.TeamMap(ctx)
+	if err != nil {
+		logger.Warn("load teams for digest failed", "err", err)
+		return 0, ""
+	}
+	id, name := 0, ""
+	for tid, tname := range teams {
+		if tname != "" && strings.Contains(text, tname) && len(tname) > len(name) {
+			id, name = tid, tname
+		}
+	}
+	return id, name
+}
+
+// streamDigest streams the digest of d to sse and offers it as Markdown and
+// DOCX downloads for member uid.
+func (h *ChatHandler) streamDigest(ctx context.Context, sse *sseWriter, uid int, d *service.TeamDigest) {
+	if d.Empty() {
+		sse.token(fmt.Sprintf("%s 至 %s 期间%s暂无日报记录，无法生成团队周报。", d.Start, d.End, d.Title()))
+		sse.done()
+		return
+	}
+	md, err := h.ai.StreamTeamDigest(ctx, d.PromptData(), sse.token)
+	if err != nil {
+		logger.Error("stream digest failed", "err", err)
+		sse.token("抱歉，团队周报生成失败，请稍后重试。")
+		sse.done()
+		return
+	}
+
+	name := digestFileName(d)
+	meta := map[string]string{
+		"downloadUrl":   h.saveFile(uid, name+".md", []byte(md)),
+		"downloadTitle": name + ".md",
+	}
+	if data, err := docx.FromMarkdown(md); err != nil {
+		logger.Warn("render digest docx failed", "err", err)
+	} else {
+		meta["docxUrl"] = h.saveFile(uid, name+".docx", data)
+		meta["docxTitle"] = name + ".docx"
+	}
+	sse.event("meta", meta)
+	sse.done()
+}
+
+// digestFileName names a digest's download files, without extension.
+func digestFileName(d *service.TeamDigest) string {
+	team := d.TeamName
+	if d.TeamID == 0 {
+		team = "全员"
+	}
+	team = strings.NewReplacer("/", "_", "\\", "_").Replace(team)
+	return fmt.Sprintf("团队周报_%s_%s", team, time.Now().Format("20060102"))
+}
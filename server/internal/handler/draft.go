@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+	"smart-daily/internal/model"
+	"smart-daily/internal/repository"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// DraftHandler lets members review report drafts they have not confirmed yet.
+type DraftHandler struct{ repo *repository.DraftRepo }
+
+func NewDraftHandler(repo *repository.DraftRepo) *DraftHandler { return &DraftHandler{repo: repo} }
+
+// List returns the caller's unexpired drafts.
+func (h *DraftHandler) List(c *gin.Context) {
+	drafts, err := h.repo.List(c.Request.Context(), c.GetInt("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, drafts)
+}
+
+// Update edits a draft's summary, content, status, blockers, risks or target date and extends its expiry.
+func (h *DraftHandler) Update(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var req struct {
+		DailyDate *string           `json:"daily_date"`
+		Content   *string           `json:"content"`
+		Summary   *string           `json:"summary"`
+		Status    *string           `json:"status"`
+		Blockers  *[]string         `json:"blockers"`
+		Risks     *[]model.RiskItem `json:"risks"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	ctx := c.Request.Context()
+	d, err := h.repo.Get(ctx, id, c.GetInt("user_id"))
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "draft not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if req.DailyDate != nil {
+		if _, err := time.Parse("2006-01-02", *req.DailyDate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "daily_date must be YYYY-MM-DD"})
+			return
+		}
+		if *req.DailyDate > time.Now().Format("2006-01-02") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "daily_date cannot be in the future"})
+			return
+		}
+		d.DailyDate = *req.DailyDate
+	}
+	if req.Content != nil {
+		d.Content = *req.Content
+	}
+	if req.Summary != nil {
+		if *req.Summary == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "summary required"})
+			return
+		}
+		d.Summary = *req.Summary
+	}
+	if req.Status != nil {
+		if !slices.Contains(model.WorkStatuses, *req.Status) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
+			return
+		}
+		d.Status = *req.Status
+	}
+	if req.Blockers != nil {
+		d.Blockers = *req.Blockers
+	}
+	if req.Risks != nil {
+		d.Risks = *req.Risks
+	}
+	d.ExpiresAt = time.Now().Add(draftTTL)
+	if err := h.repo.Update(ctx, d); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
+
+// Delete discards a draft.
+func (h *DraftHandler) Delete(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	ok, err := h.repo.Delete(c.Request.Context(), id, c.GetInt("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "draft not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"smart-daily/internal/authz"
+	"smart-daily/internal/middleware"
 	"smart-daily/internal/repository"
 	"time"
 
@@ -17,8 +19,11 @@ func NewExportHandler(dailyRepo *repository.DailyRepo) *ExportHandler {
 	return &ExportHandler{dailyRepo: dailyRepo}
 }
 
+// ExportDaily handles GET /api/export/daily: admins get every member, team
+// leads their team and its sub-teams, everyone else only their own reports.
 func (h *ExportHandler) ExportDaily(c *gin.Context) {
-	rows, err := h.dailyRepo.ListSummariesWithMembers(c.Request.Context())
+	sub := middleware.Subject(c)
+	rows, err := h.dailyRepo.ListSummariesWithMembers(c.Request.Context(), sub.TeamScope(authz.ReportRead), sub.MemberID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -28,14 +33,7 @@ func (h *ExportHandler) ExportDaily(c *gin.Context) {
 	sheet := "日报"
 	f.SetSheetName("Sheet1", sheet)
 
-	headerStyle, _ := f.NewStyle(&excelize.Style{
-		Font:      &excelize.Font{Bold: true, Size: 12},
-		Fill:      excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"#E5E7EB"}},
-		Alignment: &excelize.Alignment{Vertical: "center"},
-	})
-	wrapStyle, _ := f.NewStyle(&excelize.Style{
-		Alignment: &excelize.Alignment{WrapText: true, Vertical: "top"},
-	})
+	headerStyle, wrapStyle := sheetStyles(f)
 
 	f.SetColWidth(sheet, "A", "A", 14)
 	f.SetColWidth(sheet, "B", "B", 50)
@@ -68,10 +66,33 @@ func (h *ExportHandler) ExportDaily(c *gin.Context) {
 		r++
 	}
 
-	filename := fmt.Sprintf("日报导出_%s.xlsx", time.Now().Format("2006-01-02"))
+	writeXLSX(c, f, fmt.Sprintf("日报导出_%s.xlsx", time.Now().Format("2006-01-02")))
+}
+
+// sheetStyles registers the header and wrapped-cell styles shared by xlsx exports.
+func sheetStyles(f *excelize.File) (header, wrap int) {
+	header, _ = f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: 12},
+		Fill:      excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"#E5E7EB"}},
+		Alignment: &excelize.Alignment{Vertical: "center"},
+	})
+	wrap, _ = f.NewStyle(&excelize.Style{
+		Alignment: &excelize.Alignment{WrapText: true, Vertical: "top"},
+	})
+	return header, wrap
+}
+
+// writeXLSX sends f as a download named filename.
+func writeXLSX(c *gin.Context, f *excelize.File, filename string) {
 	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
 	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename*=UTF-8''%s`, url.PathEscape(filename)))
 	if err := f.Write(c.Writer); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 	}
 }
+
+// writeAttachment sends data as a download named filename.
+func writeAttachment(c *gin.Context, contentType, filename string, data []byte) {
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename*=UTF-8''%s`, url.PathEscape(filename)))
+	c.Data(http.StatusOK, contentType, data)
+}
@@ -2,17 +2,64 @@ package handler
 
 import (
 	"net/http"
+	"slices"
+	"smart-daily/internal/audit"
+	"smart-daily/internal/authz"
+	"smart-daily/internal/middleware"
+	"smart-daily/internal/model"
 	"smart-daily/internal/repository"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-type FeedHandler struct{ topicRepo *repository.TopicRepo }
+type FeedHandler struct {
+	topicRepo  *repository.TopicRepo
+	riskRepo   *repository.RiskRepo
+	memberRepo *repository.MemberRepo
+}
 
-func NewFeedHandler(topicRepo *repository.TopicRepo) *FeedHandler {
-	return &FeedHandler{topicRepo: topicRepo}
+func NewFeedHandler(topicRepo *repository.TopicRepo, riskRepo *repository.RiskRepo, memberRepo *repository.MemberRepo) *FeedHandler {
+	return &FeedHandler{topicRepo: topicRepo, riskRepo: riskRepo, memberRepo: memberRepo}
+}
+
+// teamFilter reads ?team_id=&subtree=true: the team, and with subtree also the
+// teams nested below it. Without team_id it returns every team the caller may
+// view (nil for admins). Callers without a team only see their own data,
+// returned as memberID; a team outside the scope is refused.
+func (h *FeedHandler) teamFilter(c *gin.Context) (teamIDs []int, memberID int, ok bool) {
+	sub := middleware.Subject(c)
+	scope := viewScope(sub)
+	if !scope.All && len(scope.TeamIDs) == 0 {
+		memberID = sub.MemberID
+	}
+	raw := c.Query("team_id")
+	if raw == "" {
+		return scope.TeamIDs, memberID, true
+	}
+	teamID, err := strconv.Atoi(raw)
+	if err != nil || teamID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid team_id"})
+		return nil, 0, false
+	}
+	if memberID == 0 && !scope.Includes(teamID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "team is outside your scope"})
+		return nil, 0, false
+	}
+	if c.Query("subtree") != "true" {
+		return []int{teamID}, memberID, true
+	}
+	ids, err := h.memberRepo.SubtreeTeamIDs(c.Request.Context(), teamID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, 0, false
+	}
+	if memberID == 0 && !scope.All {
+		ids = slices.DeleteFunc(ids, func(id int) bool { return !scope.Includes(id) })
+	}
+	return ids, memberID, true
 }
 
 // defaultDateRange returns (last Monday, yesterday) as default range.
@@ -37,13 +84,37 @@ func parseDateRange(c *gin.Context) (string, string) {
 	return start, end
 }
 
+// statusFilter reads ?status=blocked,at-risk&blocked=true into f: work
+// statuses to keep, and whether to keep only days that report a blocker.
+func statusFilter(c *gin.Context, f *repository.SummaryFilter) bool {
+	if status := c.Query("status"); status != "" {
+		for _, s := range strings.Split(status, ",") {
+			if s = strings.TrimSpace(s); !slices.Contains(model.WorkStatuses, s) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status: " + s})
+				return false
+			}
+			f.Statuses = append(f.Statuses, s)
+		}
+	}
+	f.Blocked = c.Query("blocked") == "true"
+	return true
+}
+
 // --- 团队动态 ---
 
 // FeedByMember returns daily summaries grouped by member.
-// GET /api/feed/by-member?start=&end=
+// GET /api/feed/by-member?start=&end=&status=blocked,at-risk&blocked=true&team_id=&subtree=true
 func (h *FeedHandler) FeedByMember(c *gin.Context) {
 	start, end := parseDateRange(c)
-	rows, err := h.topicRepo.ListSummariesByDateRange(c.Request.Context(), start, end)
+	var f repository.SummaryFilter
+	var ok bool
+	if f.TeamIDs, f.MemberID, ok = h.teamFilter(c); !ok {
+		return
+	}
+	if !statusFilter(c, &f) {
+		return
+	}
+	rows, err := h.topicRepo.ListSummariesByDateRange(c.Request.Context(), start, end, f)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -73,10 +144,14 @@ func (h *FeedHandler) FeedByMember(c *gin.Context) {
 }
 
 // FeedByTopic returns topic activities grouped by topic.
-// GET /api/feed/by-topic?start=&end=
+// GET /api/feed/by-topic?start=&end=&team_id=&subtree=true
 func (h *FeedHandler) FeedByTopic(c *gin.Context) {
 	start, end := parseDateRange(c)
-	activities, err := h.topicRepo.ListByDateRange(c.Request.Context(), start, end)
+	teamIDs, memberID, ok := h.teamFilter(c)
+	if !ok {
+		return
+	}
+	activities, err := h.topicRepo.ListByDateRange(c.Request.Context(), start, end, teamIDs, memberID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -126,38 +201,62 @@ func (h *FeedHandler) FeedByTopic(c *gin.Context) {
 // --- 数据洞察 ---
 
 // Insights returns topic risk dashboard data.
-// GET /api/insights
+// GET /api/insights?team_id=&subtree=true
 func (h *FeedHandler) Insights(c *gin.Context) {
 	ctx := c.Request.Context()
-	insights, err := h.topicRepo.ListInsights(ctx)
+	teamIDs, memberID, ok := h.teamFilter(c)
+	if !ok {
+		return
+	}
+	insights, err := h.topicRepo.ListInsights(ctx, teamIDs, memberID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	risks, _ := h.topicRepo.ListTopicRisks(ctx)
+	risks, _ := h.riskRepo.ListOpenForActiveTopics(ctx, teamIDs, memberID)
 
-	// Group risks by topic
-	riskMap := map[string][]repository.TopicRiskItem{}
+	// Group open risks by topic
+	type riskItem struct {
+		ID         int    `json:"id"`
+		Topic      string `json:"topic"`
+		MemberName string `json:"member_name"`
+		DailyDate  string `json:"daily_date"`
+		Risk       string `json:"risk"`
+		Severity   string `json:"severity"`
+		Category   string `json:"category"`
+		Status     string `json:"status"`
+	}
+	riskMap := map[string][]riskItem{}
+	highRisk := map[string]bool{}
 	for _, r := range risks {
-		riskMap[r.Topic] = append(riskMap[r.Topic], r)
+		riskMap[r.Topic] = append(riskMap[r.Topic], riskItem{
+			ID: r.ID, Topic: r.Topic, MemberName: r.MemberName, DailyDate: r.DailyDate,
+			Risk: r.Description, Severity: r.Severity, Category: r.Category, Status: r.Status,
+		})
+		if r.Severity == "high" {
+			highRisk[r.Topic] = true
+		}
 	}
 
 	type insightItem struct {
 		repository.TopicInsight
-		RiskLevel string                       `json:"risk_level"` // high / medium / low
-		Risks     []repository.TopicRiskItem   `json:"risks"`
+		RiskLevel string     `json:"risk_level"` // high / medium / low
+		OpenRisks int        `json:"open_risks"`
+		Risks     []riskItem `json:"risks"`
 	}
 	result := make([]insightItem, 0, len(insights))
 	for _, ins := range insights {
+		open := len(riskMap[ins.Topic])
 		level := "low"
-		if ins.Days > 15 && ins.MemberCnt >= 3 {
+		if highRisk[ins.Topic] || ins.Days > 15 && ins.MemberCnt >= 3 {
 			level = "high"
-		} else if ins.Days > 7 || ins.MemberCnt >= 3 {
+		} else if open > 0 || ins.Days > 7 || ins.MemberCnt >= 3 {
 			level = "medium"
 		}
 		result = append(result, insightItem{
 			TopicInsight: ins,
 			RiskLevel:    level,
+			OpenRisks:    open,
 			Risks:        riskMap[ins.Topic],
 		})
 	}
@@ -205,10 +304,15 @@ func (h *FeedHandler) UpdateTopic(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "nothing to update"})
 		return
 	}
+	if !h.canManageTopic(c, id, "") {
+		return
+	}
+	before, _ := h.topicRepo.FindTopic(c.Request.Context(), id)
 	if err := h.topicRepo.UpdateTopic(c.Request.Context(), id, updates); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	recordAudit(c, audit.TopicUpdate, audit.TargetTopic, id, before, updates)
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
 
@@ -216,10 +320,15 @@ func (h *FeedHandler) UpdateTopic(c *gin.Context) {
 // PUT /api/topics/:id/resolve
 func (h *FeedHandler) ResolveTopic(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
+	if !h.canManageTopic(c, id, "") {
+		return
+	}
+	before, _ := h.topicRepo.FindTopic(c.Request.Context(), id)
 	if err := h.topicRepo.ResolveTopic(c.Request.Context(), id); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	recordAudit(c, audit.TopicResolve, audit.TargetTopic, id, before, gin.H{"status": "resolved"})
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
 
@@ -227,10 +336,15 @@ func (h *FeedHandler) ResolveTopic(c *gin.Context) {
 // PUT /api/topics/:id/reopen
 func (h *FeedHandler) ReopenTopic(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
+	if !h.canManageTopic(c, id, "") {
+		return
+	}
+	before, _ := h.topicRepo.FindTopic(c.Request.Context(), id)
 	if err := h.topicRepo.ReopenTopic(c.Request.Context(), id); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	recordAudit(c, audit.TopicReopen, audit.TargetTopic, id, before, gin.H{"status": "active"})
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
 
@@ -245,9 +359,49 @@ func (h *FeedHandler) MergeTopic(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "source_id and target_name required"})
 		return
 	}
+	if !h.canManageTopic(c, req.SourceID, req.TargetName) {
+		return
+	}
+	before, _ := h.topicRepo.FindTopic(c.Request.Context(), req.SourceID)
 	if err := h.topicRepo.MergeTopic(c.Request.Context(), req.SourceID, req.TargetName); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	recordAudit(c, audit.TopicMerge, audit.TargetTopic, req.SourceID, before, gin.H{"target_name": req.TargetName})
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
+
+// canManageTopic lets admins manage any topic and team leads only topics that
+// members of their team reported on; for a merge an existing target must
+// qualify too.
+// It writes the error response when the answer is no.
+func (h *FeedHandler) canManageTopic(c *gin.Context, id int, mergeTarget string) bool {
+	sub := middleware.Subject(c)
+	if sub.IsAdmin() {
+		return true
+	}
+	ctx := c.Request.Context()
+	name, err := h.topicRepo.TopicName(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "topic not found"})
+		return false
+	}
+	for _, topic := range []string{name, mergeTarget} {
+		if topic == "" {
+			continue
+		}
+		teams, err := h.topicRepo.TeamIDs(ctx, topic)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return false
+		}
+		if len(teams) == 0 && topic == mergeTarget {
+			continue // merging into a new name is a rename
+		}
+		if !slices.ContainsFunc(teams, func(t int) bool { return sub.CanAccessTeam(authz.TopicManage, t) }) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "topic is outside your team: " + topic})
+			return false
+		}
+	}
+	return true
+}
@@ -7,6 +7,9 @@ import (
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 
+	"smart-daily/internal/audit"
+	"smart-daily/internal/authz"
+	"smart-daily/internal/middleware"
 	"smart-daily/internal/model"
 )
 
@@ -35,7 +38,7 @@ func (h *FeedbackHandler) Submit(c *gin.Context) {
 func (h *FeedbackHandler) List(c *gin.Context) {
 	var items []model.Feedback
 	q := h.db.Order("created_at DESC")
-	if !c.GetBool("is_admin") {
+	if !middleware.Subject(c).Can(authz.SystemAdmin) {
 		q = q.Where("member_id = ?", c.GetInt("user_id"))
 	}
 	q.Find(&items)
@@ -44,12 +47,24 @@ func (h *FeedbackHandler) List(c *gin.Context) {
 
 func (h *FeedbackHandler) Close(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
+	var before model.Feedback
+	if err := h.db.First(&before, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "feedback not found"})
+		return
+	}
 	h.db.Model(&model.Feedback{}).Where("id = ?", id).Update("status", "closed")
+	recordAudit(c, audit.FeedbackClose, audit.TargetFeedback, id, before, gin.H{"status": "closed"})
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
 
 func (h *FeedbackHandler) Delete(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
+	var before model.Feedback
+	if err := h.db.First(&before, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "feedback not found"})
+		return
+	}
 	h.db.Where("id = ?", id).Delete(&model.Feedback{})
+	recordAudit(c, audit.FeedbackDelete, audit.TargetFeedback, id, before, nil)
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
@@ -1,172 +1,467 @@
 package handler
 
 import (
-	"crypto/rand"
-	"encoding/hex"
-	"encoding/json"
+	"errors"
 	"net/http"
-	"os"
-	"os/exec"
-	"path/filepath"
+	"smart-daily/internal/audit"
+	"smart-daily/internal/authz"
 	"smart-daily/internal/logger"
 	"smart-daily/internal/middleware"
 	"smart-daily/internal/model"
+	"smart-daily/internal/repository"
 	"smart-daily/internal/service"
-	"sync"
-	"time"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
 type ImportHandler struct {
-	importSvc *service.ImportService
-	cache     sync.Map // token -> *previewCache
-}
-
-type previewCache struct {
-	entries   []service.ExtractedEntry
-	members   []model.Member
-	createdAt time.Time
-}
-
-func NewImportHandler(importSvc *service.ImportService) *ImportHandler {
-	h := &ImportHandler{importSvc: importSvc}
-	go func() {
-		for range time.Tick(5 * time.Minute) {
-			h.cache.Range(func(k, v any) bool {
-				if time.Since(v.(*previewCache).createdAt) > 10*time.Minute {
-					h.cache.Delete(k)
-				}
-				return true
-			})
-		}
-	}()
-	return h
+	importSvc  *service.ImportService
+	memberRepo *repository.MemberRepo
 }
 
-// Preview handles POST /api/import/preview
-func (h *ImportHandler) Preview(c *gin.Context) {
-	file, err := c.FormFile("file")
+func NewImportHandler(importSvc *service.ImportService, memberRepo *repository.MemberRepo) *ImportHandler {
+	return &ImportHandler{importSvc: importSvc, memberRepo: memberRepo}
+}
+
+// CreateJob handles POST /api/import/jobs: parses the uploaded file and
+// starts extracting it in the background. Follow the job with JobEvents.
+func (h *ImportHandler) CreateJob(c *gin.Context) {
+	if job, ok := h.startJob(c); ok {
+		c.JSON(http.StatusAccepted, job)
+	}
+}
+
+// ListJobs handles GET /api/import/jobs: the caller's recent jobs.
+func (h *ImportHandler) ListJobs(c *gin.Context) {
+	jobs, err := h.importSvc.ListJobs(c.Request.Context(), c.GetInt("user_id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "请上传文件"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	logger.Info("import preview: start", "file", file.Filename, "size", file.Size)
+	c.JSON(http.StatusOK, jobs)
+}
 
-	tmp := filepath.Join(os.TempDir(), "import_"+file.Filename)
-	if err := c.SaveUploadedFile(file, tmp); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存文件失败"})
+// GetJob handles GET /api/import/jobs/:id: the job's progress, plus its
+// preview once extracted and its result once done.
+func (h *ImportHandler) GetJob(c *gin.Context) {
+	job, ok := h.loadJob(c)
+	if !ok {
+		return
+	}
+	view, err := h.jobView(c, job)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	defer os.Remove(tmp)
+	c.JSON(http.StatusOK, view)
+}
 
-	// Parse docx via Python
-	parserPath := filepath.Join("server", "cmd", "docx_parser", "main.py")
-	if _, err := os.Stat(parserPath); err != nil {
-		parserPath = filepath.Join("cmd", "docx_parser", "main.py")
+// JobEvents handles GET /api/import/jobs/:id/events: streams "progress"
+// events until the job settles, then "preview", "result" or "failed" with the
+// job as GetJob returns it. Reconnecting resumes following the job.
+func (h *ImportHandler) JobEvents(c *gin.Context) {
+	job, ok := h.loadJob(c)
+	if !ok {
+		return
 	}
-	out, err := exec.CommandContext(c.Request.Context(), "python3", parserPath, tmp).Output()
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	sse := &sseWriter{w: c.Writer, f: c.Writer}
+
+	ctx := c.Request.Context()
+	last, err := h.importSvc.WatchJob(ctx, job.ID, func(p *service.ImportProgress) { sse.event("progress", p) })
 	if err != nil {
-		stderr := ""
-		if ee, ok := err.(*exec.ExitError); ok {
-			stderr = string(ee.Stderr)
+		if ctx.Err() == nil {
+			sse.event("failed", gin.H{"error": err.Error()})
 		}
-		logger.Error("docx parse failed", "err", err, "stderr", stderr)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "文档解析失败"})
 		return
 	}
-	var sections []service.DocxSection
-	if err := json.Unmarshal(out, &sections); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "解析结果格式错误"})
+	if job, err = h.importSvc.GetJob(ctx, job.ID); err != nil {
+		sse.event("failed", gin.H{"error": err.Error()})
 		return
 	}
-	logger.Info("import preview: parsed", "sections", len(sections))
-
-	result, err := h.importSvc.Extract(c.Request.Context(), sections)
+	view, err := h.jobView(c, job)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		sse.event("failed", gin.H{"error": err.Error()})
 		return
 	}
+	switch last.Status {
+	case service.ImportPreview:
+		sse.event("preview", view)
+	case service.ImportDone:
+		sse.event("result", view)
+	default:
+		sse.event("failed", view)
+	}
+	sse.done()
+}
 
-	token := genToken()
-	h.cache.Store(token, &previewCache{entries: result.Entries, members: result.Members, createdAt: time.Now()})
+// ConfirmJob handles POST /api/import/jobs/:id/confirm: saves the previewed
+// entries the caller may import in the background. policies maps preview row
+// keys to skip, replace or merge for days that already have data.
+func (h *ImportHandler) ConfirmJob(c *gin.Context) {
+	var req struct {
+		MemberDecisions map[string]service.MemberDecision `json:"member_decisions"`
+		Policies        map[string]string                 `json:"policies"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	if !checkPolicies(c, req.Policies) {
+		return
+	}
+	job, ok := h.loadJob(c)
+	if !ok {
+		return
+	}
+	if h.startConfirm(c, job, req.MemberDecisions, req.Policies) {
+		c.JSON(http.StatusAccepted, gin.H{"id": job.ID, "status": service.ImportConfirming})
+	}
+}
 
-	// Non-admin: filter to only current user's entries
-	entries := result.Entries
-	if !middleware.IsAdmin(c) {
-		userName := c.GetString("user_name")
-		var filtered []service.ExtractedEntry
-		for _, e := range entries {
-			if e.Name == userName {
-				filtered = append(filtered, e)
-			}
+// ResumeJob handles POST /api/import/jobs/:id/resume: continues a failed job,
+// re-extracting only the sections that did not finish, or repeating the
+// confirm with the decisions and policies it was given.
+func (h *ImportHandler) ResumeJob(c *gin.Context) {
+	job, ok := h.loadJob(c)
+	if !ok {
+		return
+	}
+	if job.Status != service.ImportFailed {
+		c.JSON(http.StatusConflict, gin.H{"error": "只能继续失败的导入任务"})
+		return
+	}
+	if job.Decisions != "" {
+		if !h.startConfirm(c, job, service.JobDecisions(job), service.JobPolicies(job)) {
+			return
 		}
-		entries = filtered
+	} else if err := h.importSvc.ResumeExtract(c.Request.Context(), job); err != nil {
+		writeJobError(c, err)
+		return
 	}
+	logger.Info("import job: resumed", "job", job.ID, "confirm", job.Decisions != "")
+	c.JSON(http.StatusAccepted, gin.H{"id": job.ID})
+}
 
-	type memberInfo struct {
-		ID   int    `json:"id"`
-		Name string `json:"name"`
+// ListBatches handles GET /api/import/batches: the caller's recent import
+// batches, everyone's for admins.
+func (h *ImportHandler) ListBatches(c *gin.Context) {
+	createdBy := c.GetInt("user_id")
+	if middleware.Subject(c).IsAdmin() {
+		createdBy = 0
 	}
-	var memberList []memberInfo
-	for _, m := range result.Members {
-		memberList = append(memberList, memberInfo{m.ID, m.Name})
+	batches, err := h.importSvc.ListBatches(c.Request.Context(), createdBy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
+	c.JSON(http.StatusOK, batches)
+}
 
-	logger.Info("import preview: done", "token", token, "entries", len(entries), "unmatched", len(result.Unmatched))
-	c.JSON(http.StatusOK, gin.H{
-		"token":             token,
-		"entries":           entries,
-		"unmatched_members": result.Unmatched,
-		"members":           memberList,
-	})
+// RevertBatch handles DELETE /api/import/batches/:id: undoes everything one
+// import confirm wrote. Only its creator or an admin may revert a batch.
+func (h *ImportHandler) RevertBatch(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	ctx := c.Request.Context()
+	batch, err := h.importSvc.GetBatch(ctx, id)
+	if err == nil && batch.CreatedBy != c.GetInt("user_id") && !middleware.Subject(c).IsAdmin() {
+		err = service.ErrImportBatchNotFound
+	}
+	if err != nil {
+		writeJobError(c, err)
+		return
+	}
+	result, err := h.importSvc.RevertBatch(ctx, id, c.GetInt("user_id"))
+	if err != nil {
+		writeJobError(c, err)
+		return
+	}
+	recordAudit(c, audit.ImportRevert, audit.TargetBatch, id, gin.H{
+		"file": batch.FileName, "job_id": batch.JobID, "result": service.BatchResult(batch),
+	}, result)
+	c.JSON(http.StatusOK, result)
 }
 
-// Confirm handles POST /api/import/confirm
+// Preview handles POST /api/import/preview: CreateJob, waiting for the
+// preview. The returned token is the job ID; if the client goes away the job
+// carries on and can be picked up through the job endpoints.
+func (h *ImportHandler) Preview(c *gin.Context) {
+	job, ok := h.startJob(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	p, err := h.importSvc.WatchJob(ctx, job.ID, func(*service.ImportProgress) {})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if p.Status == service.ImportFailed {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": p.Error, "job_id": job.ID})
+		return
+	}
+	preview, err := h.importSvc.JobPreview(ctx, job.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	view, err := h.previewView(c, preview)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	view["token"] = strconv.Itoa(job.ID)
+	logger.Info("import preview: done", "job", job.ID, "entries", len(preview.Entries), "unmatched", len(preview.Unmatched))
+	c.JSON(http.StatusOK, view)
+}
+
+// Confirm handles POST /api/import/confirm: ConfirmJob for the job named by
+// the preview token, waiting for the result.
 func (h *ImportHandler) Confirm(c *gin.Context) {
 	var req struct {
 		Token           string                            `json:"token"`
 		MemberDecisions map[string]service.MemberDecision `json:"member_decisions"`
+		Policies        map[string]string                 `json:"policies"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil || req.Token == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少 token"})
 		return
 	}
-
-	val, ok := h.cache.LoadAndDelete(req.Token)
-	if !ok {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "预览已过期，请重新上传"})
+	if !checkPolicies(c, req.Policies) {
+		return
+	}
+	c.AddParam("id", req.Token)
+	job, ok := h.loadJob(c)
+	if !ok || !h.startConfirm(c, job, req.MemberDecisions, req.Policies) {
 		return
 	}
-	cached := val.(*previewCache)
+	ctx := c.Request.Context()
+	p, err := h.importSvc.WatchJob(ctx, job.ID, func(*service.ImportProgress) {})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if p.Status != service.ImportDone {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": p.Error, "job_id": job.ID})
+		return
+	}
+	if job, err = h.importSvc.GetJob(ctx, job.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, service.JobResult(job))
+}
+
+// startJob parses the uploaded file and starts its job, writing the error
+// response if it cannot.
+func (h *ImportHandler) startJob(c *gin.Context) (*model.ImportJob, bool) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请上传文件"})
+		return nil, false
+	}
+	logger.Info("import: upload", "file", file.Filename, "size", file.Size)
 
-	// Non-admin: filter to only current user's entries
-	entries := cached.entries
-	if !middleware.IsAdmin(c) {
-		userName := c.GetString("user_name")
-		var filtered []service.ExtractedEntry
-		for _, e := range entries {
-			if e.Name == userName {
-				filtered = append(filtered, e)
+	f, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "读取文件失败"})
+		return nil, false
+	}
+	defer f.Close()
+	parsed, err := service.ParseImportFile(file.Filename, f, file.Size)
+	if errors.Is(err, service.ErrUnsupportedImport) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的文件格式，请上传 .docx / .md / .txt / .xlsx / .csv 日报文件"})
+		return nil, false
+	}
+	if errors.Is(err, service.ErrNoImportDates) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "未找到日期（如 2026-03-02 或 2026年3月2日），无法按天拆分日报"})
+		return nil, false
+	}
+	if err != nil {
+		logger.Error("import parse failed", "err", err, "file", file.Filename)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "文档解析失败"})
+		return nil, false
+	}
+	logger.Info("import: parsed", "format", parsed.Format, "sections", len(parsed.Sections), "entries", len(parsed.Entries))
+
+	job, err := h.importSvc.StartJob(c.Request.Context(), c.GetInt("user_id"), file.Filename, parsed)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return job, true
+}
+
+// startConfirm starts confirming job's preview entries the caller may
+// import, writing the error response if it cannot.
+func (h *ImportHandler) startConfirm(c *gin.Context, job *model.ImportJob, decisions map[string]service.MemberDecision, policies map[string]string) bool {
+	if !h.checkDecisions(c, decisions) {
+		return false
+	}
+	ctx := c.Request.Context()
+	preview, err := h.importSvc.JobPreview(ctx, job.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	entries := scopeImportEntries(c, preview.Entries, preview.Members)
+	if err := h.importSvc.StartConfirm(ctx, job, entries, preview.Members, decisions, policies); err != nil {
+		writeJobError(c, err)
+		return false
+	}
+	recordAudit(c, audit.ImportConfirm, audit.TargetImport, job.ID, nil, gin.H{
+		"file": job.FileName, "entries": len(entries), "member_decisions": decisions, "policies": policies,
+	})
+	return true
+}
+
+// checkDecisions writes a 403 unless the caller may carry out every member
+// decision: mapping a name imports into that member's reports, so the target
+// must be in the caller's report.import scope, and creating members needs
+// member management.
+func (h *ImportHandler) checkDecisions(c *gin.Context, decisions map[string]service.MemberDecision) bool {
+	sub := middleware.Subject(c)
+	for name, d := range decisions {
+		switch d.Action {
+		case "map":
+			if d.MemberID > 0 && !canAccessMember(c, h.memberRepo, authz.ReportImport, d.MemberID) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "无权将「" + name + "」关联到该成员"})
+				return false
+			}
+		case "create":
+			if !sub.Can(authz.MemberManage) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "无权创建成员「" + name + "」"})
+				return false
 			}
 		}
-		entries = filtered
 	}
+	return true
+}
 
-	logger.Info("import confirm: start", "token", req.Token, "entries", len(entries), "decisions", len(req.MemberDecisions))
+// loadJob returns the job named by the :id parameter if the caller created
+// it, otherwise writes a 404.
+func (h *ImportHandler) loadJob(c *gin.Context) (*model.ImportJob, bool) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	job, err := h.importSvc.GetJob(c.Request.Context(), id)
+	if err == nil && job.CreatedBy != c.GetInt("user_id") {
+		err = service.ErrImportJobNotFound
+	}
+	if err != nil {
+		writeJobError(c, err)
+		return nil, false
+	}
+	return job, true
+}
 
-	result, err := h.importSvc.Confirm(c.Request.Context(), entries, cached.members, req.MemberDecisions)
+// jobView is a job with its progress, its preview (entries scoped to the
+// caller) from the preview on, and its result once done.
+func (h *ImportHandler) jobView(c *gin.Context, job *model.ImportJob) (gin.H, error) {
+	ctx := c.Request.Context()
+	p, err := h.importSvc.Progress(ctx, job.ID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+		return nil, err
+	}
+	view := gin.H{"job": job, "progress": p}
+	if job.Status == service.ImportPreview || job.Status == service.ImportConfirming || job.Decisions != "" {
+		preview, err := h.importSvc.JobPreview(ctx, job.ID)
+		if err != nil {
+			return nil, err
+		}
+		pv, err := h.previewView(c, preview)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range pv {
+			view[k] = v
+		}
 	}
+	if result := service.JobResult(job); result != nil {
+		view["result"] = result
+	}
+	return view, nil
+}
 
-	logger.Info("import confirm: done", "imported", result.Imported, "merged", result.Merged, "skipped", result.Skipped)
-	c.JSON(http.StatusOK, result)
+// previewView is the preview response: the entries the caller may import,
+// each compared with the stored day, the number per diff status, the names
+// matching no member and the members to map them to.
+func (h *ImportHandler) previewView(c *gin.Context, preview *service.PreviewResult) (gin.H, error) {
+	type memberInfo struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	memberList := make([]memberInfo, 0, len(preview.Members))
+	for _, m := range preview.Members {
+		memberList = append(memberList, memberInfo{m.ID, m.Name})
+	}
+	entries, err := h.importSvc.DiffEntries(c.Request.Context(), scopeImportEntries(c, preview.Entries, preview.Members), preview.Members)
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	for _, e := range entries {
+		counts[e.Status]++
+	}
+	return gin.H{
+		"entries":           entries,
+		"diff_counts":       counts,
+		"unmatched_members": preview.Unmatched,
+		"members":           memberList,
+	}, nil
+}
+
+// checkPolicies writes a 400 unless every confirm policy is known.
+func checkPolicies(c *gin.Context, policies map[string]string) bool {
+	for key, p := range policies {
+		if !service.ValidPolicy(p) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的导入策略: " + key})
+			return false
+		}
+	}
+	return true
+}
+
+func writeJobError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrImportJobNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "导入任务不存在"})
+	case errors.Is(err, service.ErrImportBatchNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "导入批次不存在"})
+	case errors.Is(err, service.ErrImportBatchReverted):
+		c.JSON(http.StatusConflict, gin.H{"error": "导入批次已撤销"})
+	case errors.Is(err, service.ErrImportBatchSuperseded):
+		c.JSON(http.StatusConflict, gin.H{"error": "该批次的部分日期已被之后的导入覆盖，请先撤销之后的批次"})
+	case errors.Is(err, service.ErrImportJobState):
+		c.JSON(http.StatusConflict, gin.H{"error": "导入任务当前状态不能执行该操作"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
 }
 
-func genToken() string {
-	b := make([]byte, 16)
-	rand.Read(b)
-	return hex.EncodeToString(b)
+// scopeImportEntries keeps the entries the caller may import: all for admins,
+// their team's members for team leads (new, unmatched names need an admin),
+// and only their own otherwise.
+func scopeImportEntries(c *gin.Context, entries []service.ExtractedEntry, members []model.Member) []service.ExtractedEntry {
+	sub := middleware.Subject(c)
+	if sub.IsAdmin() && sub.Can(authz.ReportImport) {
+		return entries
+	}
+	userName := c.GetString("user_name")
+	teamOf := make(map[int]int, len(members))
+	for _, m := range members {
+		teamOf[m.ID] = m.TeamID
+	}
+	var filtered []service.ExtractedEntry
+	for _, e := range entries {
+		if e.Name == userName {
+			filtered = append(filtered, e)
+			continue
+		}
+		if id := repository.MatchByName(e.Name, members); id != 0 && sub.CanAccess(authz.ReportImport, id, teamOf[id]) {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
 }
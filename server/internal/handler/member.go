@@ -1,21 +1,45 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"smart-daily/internal/audit"
+	"smart-daily/internal/authz"
+	"smart-daily/internal/logger"
+	"smart-daily/internal/middleware"
 	"smart-daily/internal/model"
 	"smart-daily/internal/repository"
+	"smart-daily/internal/service"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
-type MemberHandler struct{ repo *repository.MemberRepo }
+type MemberHandler struct {
+	repo   *repository.MemberRepo
+	svc    *service.MemberService
+	teams  *service.TeamService
+	tokens *repository.TokenRepo
+}
 
-func NewMemberHandler(repo *repository.MemberRepo) *MemberHandler { return &MemberHandler{repo: repo} }
+func NewMemberHandler(repo *repository.MemberRepo, svc *service.MemberService, teams *service.TeamService, tokens *repository.TokenRepo) *MemberHandler {
+	return &MemberHandler{repo: repo, svc: svc, teams: teams, tokens: tokens}
+}
 
+// List handles GET /api/members; ?status=deleted lists deleted members
+// instead (member.manage only).
 func (h *MemberHandler) List(c *gin.Context) {
 	ctx := c.Request.Context()
-	members, err := h.repo.ListActive(ctx)
+	list := h.repo.ListActive
+	if c.Query("status") == "deleted" {
+		if !middleware.Subject(c).Can(authz.MemberManage) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "permission denied", "permission": authz.MemberManage})
+			return
+		}
+		list = h.repo.ListDeleted
+	}
+	members, err := list(ctx)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -42,32 +66,210 @@ func (h *MemberHandler) ListTeams(c *gin.Context) {
 	c.JSON(http.StatusOK, teams)
 }
 
+// CreateTeam handles POST /api/teams {name, parent_id}.
 func (h *MemberHandler) CreateTeam(c *gin.Context) {
 	var req struct {
-		Name string `json:"name" binding:"required"`
+		Name     string `json:"name" binding:"required"`
+		ParentID int    `json:"parent_id"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "name required"})
 		return
 	}
-	team := model.Team{Name: req.Name}
+	if req.ParentID != 0 {
+		if _, err := h.repo.FindTeam(c.Request.Context(), req.ParentID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": service.ErrNoSuchParent.Error()})
+			return
+		}
+	}
+	team := model.Team{Name: strings.TrimSpace(req.Name), ParentID: req.ParentID}
 	if err := h.repo.CreateTeam(c.Request.Context(), &team); err != nil {
 		c.JSON(http.StatusConflict, gin.H{"error": "team already exists"})
 		return
 	}
+	recordAudit(c, audit.TeamCreate, audit.TargetTeam, team.ID, nil, team)
 	c.JSON(http.StatusOK, team)
 }
 
+// UpdateTeam handles PUT /api/teams/:id {name, parent_id, lead_id}: rename,
+// move under another team (0 for top level) or designate the lead (0 for none).
+func (h *MemberHandler) UpdateTeam(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req service.TeamUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	before, after, err := h.teams.Update(c.Request.Context(), id, req)
+	if !teamOK(c, err) {
+		return
+	}
+	recordAudit(c, audit.TeamUpdate, audit.TargetTeam, id, before, after)
+	c.JSON(http.StatusOK, after)
+}
+
+// DeleteTeam handles DELETE /api/teams/:id for a team without active members
+// or sub-teams.
+func (h *MemberHandler) DeleteTeam(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	team, err := h.teams.Delete(c.Request.Context(), id)
+	if !teamOK(c, err) {
+		return
+	}
+	recordAudit(c, audit.TeamDelete, audit.TargetTeam, id, team, nil)
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// MergeTeam handles POST /api/teams/merge {source_id, target_id}: source's
+// members and sub-teams move to target and source is deleted.
+func (h *MemberHandler) MergeTeam(c *gin.Context) {
+	var req struct {
+		SourceID int `json:"source_id" binding:"required"`
+		TargetID int `json:"target_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "source_id and target_id required"})
+		return
+	}
+	source, target, err := h.teams.Merge(c.Request.Context(), req.SourceID, req.TargetID)
+	if !teamOK(c, err) {
+		return
+	}
+	recordAudit(c, audit.TeamMerge, audit.TargetTeam, req.SourceID, source, gin.H{"merged_into": target})
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// teamOK writes the error response for a team service error.
+func teamOK(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrTeamNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrTeamNameTaken), errors.Is(err, service.ErrTeamNotEmpty):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrTeamNameEmpty), errors.Is(err, service.ErrNoSuchParent),
+		errors.Is(err, service.ErrTeamCycle), errors.Is(err, service.ErrLeadNotMember):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		return true
+	}
+	return false
+}
+
+// Create handles POST /api/members {username, name, role, team_id, email, access_role}.
+// The temporary password is only returned here.
+func (h *MemberHandler) Create(c *gin.Context) {
+	var req service.NewMember
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	m, temp, err := h.svc.Create(c.Request.Context(), req)
+	switch {
+	case errors.Is(err, service.ErrUsernameTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, service.ErrTeamNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(c, audit.MemberCreate, audit.TargetMember, m.ID, nil, m)
+	c.JSON(http.StatusOK, gin.H{"member": m, "temporary_password": temp})
+}
+
+// Restore handles POST /api/members/:id/restore for a deleted member.
+func (h *MemberHandler) Restore(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	m, err := h.svc.Restore(c.Request.Context(), id)
+	if !memberLifecycleOK(c, err) {
+		return
+	}
+	recordAudit(c, audit.MemberRestore, audit.TargetMember, id, gin.H{"status": "deleted"}, gin.H{"status": m.Status})
+	c.JSON(http.StatusOK, m)
+}
+
+// Purge handles DELETE /api/members/:id/purge?dry_run=true: permanently
+// removes a deleted member and their data. With dry_run it only reports what
+// would be removed.
+func (h *MemberHandler) Purge(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	dryRun := c.Query("dry_run") == "true"
+	report, err := h.svc.Purge(c.Request.Context(), id, dryRun)
+	if !memberLifecycleOK(c, err) {
+		return
+	}
+	if !dryRun {
+		// purging drops the member's revocation rows along with their data
+		h.revokeTokens(c, id)
+		recordAudit(c, audit.MemberPurge, audit.TargetMember, id, report.Member, report.Removed)
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// revokeTokens logs a member out everywhere after they were deleted or purged.
+func (h *MemberHandler) revokeTokens(c *gin.Context, id int) {
+	if err := h.tokens.RevokeMember(c.Request.Context(), id, middleware.TokenTTL(), c.GetInt("user_id")); err != nil {
+		logger.Warn("revoke tokens of removed member failed", "member_id", id, "err", err)
+	}
+	middleware.ForgetAllTokens()
+}
+
+// memberLifecycleOK writes the error response for a restore or purge error.
+func memberLifecycleOK(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrMemberNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrNotDeleted):
+		c.JSON(http.StatusConflict, gin.H{"error": "member must be deleted first"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		return true
+	}
+	return false
+}
+
 func (h *MemberHandler) Delete(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
+	before, err := h.repo.FindByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "member not found"})
+		return
+	}
 	if err := h.repo.SoftDelete(c.Request.Context(), id); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	h.revokeTokens(c, id)
+	recordAudit(c, audit.MemberDelete, audit.TargetMember, id, before, nil)
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
 
@@ -78,10 +280,12 @@ func (h *MemberHandler) Update(c *gin.Context) {
 		return
 	}
 	var req struct {
-		Status string `json:"status"`
-		Team   string `json:"team"`
-		TeamID *int   `json:"team_id"`
-		Role   string `json:"role"`
+		Status string  `json:"status"`
+		TeamID *int    `json:"team_id"`
+		Role   string  `json:"role"`
+		Email  *string `json:"email"`
+		// AccessRole is member / team_lead / admin
+		AccessRole string `json:"access_role"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
@@ -91,22 +295,64 @@ func (h *MemberHandler) Update(c *gin.Context) {
 	if req.Status != "" {
 		updates["status"] = req.Status
 	}
-	if req.Team != "" {
-		updates["team"] = req.Team
-	}
 	if req.TeamID != nil {
 		updates["team_id"] = *req.TeamID
 	}
 	if req.Role != "" {
 		updates["role"] = req.Role
 	}
+	if req.Email != nil {
+		updates["email"] = strings.TrimSpace(*req.Email)
+	}
+	if req.AccessRole != "" {
+		role := authz.Role(req.AccessRole)
+		if !role.Valid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "access_role must be member, team_lead or admin"})
+			return
+		}
+		if id == c.GetInt("user_id") && role != authz.RoleAdmin {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "不能取消自己的管理员角色"})
+			return
+		}
+		updates["access_role"] = string(role)
+		updates["is_admin"] = role == authz.RoleAdmin
+	}
 	if len(updates) == 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "nothing to update"})
 		return
 	}
-	if err := h.repo.Update(c.Request.Context(), id, updates); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	before, err := h.repo.FindByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "member not found"})
 		return
 	}
+	switch {
+	case updates["access_role"] == before.AccessRole && before.AccessRole == string(authz.RoleTeamLead):
+		// unchanged, e.g. sent along with a team move that has to demote the lead
+		delete(updates, "access_role")
+		delete(updates, "is_admin")
+	case updates["access_role"] == string(authz.RoleTeamLead):
+		// lead rights follow teams.lead_id; the role alone grants nothing
+		teamID := before.TeamID
+		if req.TeamID != nil {
+			teamID = *req.TeamID
+		}
+		teams, err := h.repo.ListTeams(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !repository.LeadsTeam(teams, id, teamID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "请先在团队设置中将该成员指定为组长"})
+			return
+		}
+	}
+	if len(updates) > 0 {
+		if err := h.repo.Update(c.Request.Context(), id, updates); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	recordAudit(c, audit.MemberUpdate, audit.TargetMember, id, before, updates)
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
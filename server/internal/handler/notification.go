@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"net/http"
+	"smart-daily/internal/repository"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationHandler serves the caller's in-app notifications.
+type NotificationHandler struct{ repo *repository.NotificationRepo }
+
+func NewNotificationHandler(repo *repository.NotificationRepo) *NotificationHandler {
+	return &NotificationHandler{repo: repo}
+}
+
+// List handles GET /api/notifications?unread=true&limit=50
+func (h *NotificationHandler) List(c *gin.Context) {
+	ctx := c.Request.Context()
+	uid := c.GetInt("user_id")
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	items, err := h.repo.List(ctx, uid, c.Query("unread") == "true", limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	unread, _ := h.repo.UnreadCount(ctx, uid)
+	c.JSON(http.StatusOK, gin.H{"items": items, "unread": unread})
+}
+
+// MarkRead handles PUT /api/notifications/:id/read
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	ok, err := h.repo.MarkRead(c.Request.Context(), c.GetInt("user_id"), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "notification not found or already read"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// MarkAllRead handles PUT /api/notifications/read-all
+func (h *NotificationHandler) MarkAllRead(c *gin.Context) {
+	if _, err := h.repo.MarkRead(c.Request.Context(), c.GetInt("user_id"), 0); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
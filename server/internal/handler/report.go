@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"smart-daily/internal/audit"
+	"smart-daily/internal/authz"
+	"smart-daily/internal/docx"
+	"smart-daily/internal/middleware"
+	"smart-daily/internal/model"
+	"smart-daily/internal/repository"
+	"smart-daily/internal/service"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReportHandler lists, shows, edits and downloads saved weekly reports.
+// A report is visible to its member, to whoever generated it and to those
+// who may read the member's reports; only the member and the generator may
+// edit it.
+type ReportHandler struct {
+	svc        *service.ReportService
+	memberRepo *repository.MemberRepo
+}
+
+func NewReportHandler(svc *service.ReportService, memberRepo *repository.MemberRepo) *ReportHandler {
+	return &ReportHandler{svc: svc, memberRepo: memberRepo}
+}
+
+// List returns the caller's own reports and those the caller generated for others.
+func (h *ReportHandler) List(c *gin.Context) {
+	reports, err := h.svc.ListFor(c.Request.Context(), c.GetInt("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, reports)
+}
+
+// Get returns a report with its Markdown content.
+func (h *ReportHandler) Get(c *gin.Context) {
+	if rep, ok := h.load(c); ok {
+		c.JSON(http.StatusOK, rep)
+	}
+}
+
+// Update edits a report's title and/or Markdown content; "edited": false
+// discards the edits so the report is regenerated next time.
+func (h *ReportHandler) Update(c *gin.Context) {
+	var req struct {
+		Title   *string `json:"title"`
+		Content *string `json:"content"`
+		Edited  *bool   `json:"edited"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	if req.Title != nil && strings.TrimSpace(*req.Title) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title required"})
+		return
+	}
+	rep, ok := h.load(c)
+	if !ok {
+		return
+	}
+	if uid := c.GetInt("user_id"); rep.MemberID != uid && rep.CreatedBy != uid {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the report's member or its author can edit it"})
+		return
+	}
+	before := *rep
+	var err error
+	if req.Edited != nil && !*req.Edited {
+		err = h.svc.DiscardEdits(c.Request.Context(), rep)
+	} else {
+		err = h.svc.Edit(c.Request.Context(), rep, req.Title, req.Content)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(c, audit.WeeklyReportUpdate, audit.TargetWeekly, rep.ID, before, rep)
+	c.JSON(http.StatusOK, rep)
+}
+
+// Download sends a report as Markdown (default) or with format=docx as Word.
+func (h *ReportHandler) Download(c *gin.Context) {
+	format := c.DefaultQuery("format", "md")
+	if format != "md" && format != "docx" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be md or docx"})
+		return
+	}
+	rep, ok := h.load(c)
+	if !ok {
+		return
+	}
+	name := strings.NewReplacer("/", "_", "\\", "_").Replace(rep.Title)
+	if format == "md" {
+		writeAttachment(c, "text/markdown; charset=utf-8", name+".md", []byte(rep.Content))
+		return
+	}
+	data, err := docx.FromMarkdown(rep.Content)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	writeAttachment(c, docxContentType, name+".docx", data)
+}
+
+// load returns the report named by the :id parameter if the caller may see
+// it, otherwise writes a 404 (also for reports the caller may not see).
+func (h *ReportHandler) load(c *gin.Context) (*model.Report, bool) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	ctx := c.Request.Context()
+	rep, err := h.svc.Get(ctx, id)
+	if errors.Is(err, service.ErrReportNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	sub := middleware.Subject(c)
+	if rep.MemberID == sub.MemberID || rep.CreatedBy == sub.MemberID {
+		return rep, true
+	}
+	if teamID, err := h.memberRepo.TeamOf(ctx, rep.MemberID); err == nil && sub.CanAccess(authz.ReportRead, rep.MemberID, teamID) {
+		return rep, true
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": service.ErrReportNotFound.Error()})
+	return nil, false
+}
+
+// reportDownloads is the chat meta offering a saved report's downloads.
+func reportDownloads(rep *model.Report) map[string]interface{} {
+	url := fmt.Sprintf("/api/reports/%d/download", rep.ID)
+	return map[string]interface{}{
+		"reportId":      rep.ID,
+		"stale":         rep.Stale,
+		"downloadUrl":   url + "?format=md",
+		"downloadTitle": rep.Title + ".md",
+		"docxUrl":       url + "?format=docx",
+		"docxTitle":     rep.Title + ".docx",
+	}
+}
@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"smart-daily/internal/authz"
+	"smart-daily/internal/docx"
+	"smart-daily/internal/logger"
+	"smart-daily/internal/middleware"
+	"smart-daily/internal/service"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Review handles GET /api/review?period=&member_id=&team_id=&format=.
+// period is YYYY-MM or YYYY-Q1..Q4 (default last month). The review covers
+// member_id, or team_id with its sub-teams (team_id=0 is everyone, admins
+// only), or else the caller. Without format it streams over SSE like chat
+// mode "review"; format=md or format=docx returns it as a download.
+func (h *ChatHandler) Review(c *gin.Context) {
+	if h.review == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "period review is not enabled"})
+		return
+	}
+	format := c.Query("format")
+	if format != "" && format != "md" && format != "docx" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be md or docx"})
+		return
+	}
+	p := service.PeriodFromText("", time.Now())
+	if key := c.Query("period"); key != "" {
+		var err error
+		if p, err = service.ParsePeriod(key); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	sub := middleware.Subject(c)
+	subj := service.ReviewSubject{MemberID: sub.MemberID, Name: c.GetString("user_name")}
+	switch {
+	case c.Query("member_id") != "":
+		id, _ := strconv.Atoi(c.Query("member_id"))
+		m, err := h.memberRepo.FindByID(ctx, id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": service.ErrMemberNotFound.Error()})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !sub.CanAccess(authz.ReportRead, m.ID, m.TeamID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "no permission to review this member"})
+			return
+		}
+		subj = service.ReviewSubject{MemberID: m.ID, Name: m.Name}
+	case c.Query("team_id") != "":
+		teamID, _ := strconv.Atoi(c.Query("team_id"))
+		if !sub.TeamScope(authz.ReportRead).Includes(teamID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "only admins and the team's lead can review a team"})
+			return
+		}
+		subj = service.ReviewSubject{TeamID: teamID, Name: "全员"}
+		if teamID != 0 {
+			team, err := h.memberRepo.FindTeam(ctx, teamID)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": service.ErrTeamNotFound.Error()})
+				return
+			} else if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			subj.Name = team.Name + "（含下属团队）"
+		}
+	}
+	logger.Info("review", "uid", sub.MemberID, "member_id", subj.MemberID, "team_id", subj.TeamID, "period", p.Key, "format", format)
+
+	if format == "" {
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		h.streamReview(ctx, &sseWriter{w: c.Writer, f: c.Writer}, sub.MemberID, subj, p)
+		return
+	}
+	md, err := h.review.Generate(ctx, subj, p, func(string) {}, func(string) {})
+	switch {
+	case errors.Is(err, service.ErrNoReviewData):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		logger.Error("generate review failed", "err", err, "period", p.Key)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "review generation failed"})
+		return
+	}
+	name := reviewFileName(subj, p)
+	if format == "md" {
+		writeAttachment(c, "text/markdown; charset=utf-8", name+".md", []byte(md))
+		return
+	}
+	data, err := docx.FromMarkdown(md)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	writeAttachment(c, docxContentType, name+".docx", data)
+}
+
+// chatReview answers chat mode "review": the period is read from text
+// (default last month), the subject is a member or team named in text the
+// caller may read reports of, otherwise the caller.
+func (h *ChatHandler) chatReview(ctx context.Context, sse *sseWriter, sub authz.Subject, name, text string) {
+	reply := func(msg string) {
+		sse.token(msg)
+		sse.done()
+	}
+	if h.review == nil {
+		reply("阶段总结功能未启用。")
+		return
+	}
+	p := service.PeriodFromText(text, time.Now())
+	subj := service.ReviewSubject{MemberID: sub.MemberID, Name: name}
+
+	named := false
+	if h.memberRepo != nil && strings.TrimSpace(text) != "" {
+		members, _ := h.memberRepo.ListActive(ctx)
+		for _, m := range members {
+			if m.ID != sub.MemberID && strings.Contains(text, m.Name) {
+				if !sub.CanAccess(authz.ReportRead, m.ID, m.TeamID) {
+					logger.Warn("chat.review.denied", "uid", sub.MemberID, "target_id", m.ID)
+					reply(fmt.Sprintf("你没有权限生成%s的阶段总结。组长可以生成本团队成员的总结，其他成员只能生成自己的总结。", m.Name))
+					return
+				}
+				subj, named = service.ReviewSubject{MemberID: m.ID, Name: m.Name}, true
+				break
+			}
+		}
+	}
+	if !named {
+		if teamID, teamName := h.namedTeam(ctx, text); teamID != 0 {
+			if !sub.TeamScope(authz.ReportRead).Includes(teamID) {
+				logger.Warn("chat.review.denied", "uid", sub.MemberID, "team_id", teamID)
+				reply(fmt.Sprintf("你没有权限生成%s的阶段总结。组长只能生成本团队及下属团队的总结。", teamName))
+				return
+			}
+			subj = service.ReviewSubject{TeamID: teamID, Name: teamName + "（含下属团队）"}
+		} else if strings.Contains(text, "全员") && sub.TeamScope(authz.ReportRead).All {
+			subj = service.ReviewSubject{Name: "全员"}
+		}
+	}
+	logger.Info("chat.review", "uid", sub.MemberID, "member_id", subj.MemberID, "team_id", subj.TeamID, "period", p.Key)
+	h.streamReview(ctx, sse, sub.MemberID, subj, p)
+}
+
+// streamReview streams the review of subj for p to sse, with each weekly
+// rollup as a thinking step, and offers it as Markdown and DOCX downloads for
+// member uid.
+func (h *ChatHandler) streamReview(ctx context.Context, sse *sseWriter, uid int, subj service.ReviewSubject, p service.Period) {
+	md, err := h.review.Generate(ctx, subj, p,
+		func(step string) { sse.event("thinking", map[string]string{"text": step}) },
+		sse.token)
+	if errors.Is(err, service.ErrNoReviewData) {
+		sse.token(fmt.Sprintf("%s（%s ~ %s）期间%s暂无日报记录，无法生成阶段总结。", p.Label, p.Start, p.End, subj.Name))
+		sse.done()
+		return
+	}
+	if err != nil {
+		logger.Error("stream review failed", "err", err, "period", p.Key)
+		sse.token("抱歉，阶段总结生成失败，请稍后重试。")
+		sse.done()
+		return
+	}
+
+	name := reviewFileName(subj, p)
+	meta := map[string]string{
+		"downloadUrl":   h.saveFile(uid, name+".md", []byte(md)),
+		"downloadTitle": name + ".md",
+	}
+	if data, err := docx.FromMarkdown(md); err != nil {
+		logger.Warn("render review docx failed", "err", err)
+	} else {
+		meta["docxUrl"] = h.saveFile(uid, name+".docx", data)
+		meta["docxTitle"] = name + ".docx"
+	}
+	sse.event("meta", meta)
+	sse.done()
+}
+
+// reviewFileName names a review's download files, without extension, e.g.
+// "月度总结_张三_2026-03".
+func reviewFileName(subj service.ReviewSubject, p service.Period) string {
+	kind := "月度总结"
+	if p.Type == "quarter" {
+		kind = "季度总结"
+	}
+	name := strings.NewReplacer("/", "_", "\\", "_").Replace(subj.Name)
+	return fmt.Sprintf("%s_%s_%s", kind, name, p.Key)
+}
@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+	"smart-daily/internal/audit"
+	"smart-daily/internal/authz"
+	"smart-daily/internal/middleware"
+	"smart-daily/internal/model"
+	"smart-daily/internal/repository"
+	"smart-daily/internal/service"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var (
+	riskSeverities = []string{"high", "medium", "low"}
+	riskCategories = []string{"blocked", "delay", "incident", "dependency"}
+	riskStatuses   = []string{"open", "mitigated", "closed"}
+)
+
+// RiskHandler lists, triages and closes risks filed from daily reports.
+type RiskHandler struct {
+	repo       *repository.RiskRepo
+	memberRepo *repository.MemberRepo
+	topicRepo  *repository.TopicRepo
+	catalog    *service.CatalogSync
+}
+
+func NewRiskHandler(repo *repository.RiskRepo, memberRepo *repository.MemberRepo, topicRepo *repository.TopicRepo, catalog *service.CatalogSync) *RiskHandler {
+	return &RiskHandler{repo: repo, memberRepo: memberRepo, topicRepo: topicRepo, catalog: catalog}
+}
+
+// List handles GET /api/risks?status=open&severity=&category=&member_id=&owner_id=&topic_id=&start=&end=
+// Besides the risks they reported or own, members see their team's risks, team
+// leads those of their teams and admins all of them.
+func (h *RiskHandler) List(c *gin.Context) {
+	f := repository.RiskFilter{
+		Status: c.Query("status"), Severity: c.Query("severity"), Category: c.Query("category"),
+		Start: c.Query("start"), End: c.Query("end"),
+	}
+	f.MemberID, _ = strconv.Atoi(c.Query("member_id"))
+	f.OwnerID, _ = strconv.Atoi(c.Query("owner_id"))
+	f.TopicID, _ = strconv.Atoi(c.Query("topic_id"))
+	sub := middleware.Subject(c)
+	if scope := viewScope(sub); !scope.All {
+		f.TeamIDs, f.ViewerID = scope.TeamIDs, sub.MemberID
+	}
+	risks, err := h.repo.List(c.Request.Context(), f)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, risks)
+}
+
+// Get handles GET /api/risks/:id for the reporter, the owner, the reporter's
+// teammates and team lead, or an admin.
+func (h *RiskHandler) Get(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	risk, err := h.repo.Get(c.Request.Context(), id)
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "risk not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if risk.OwnerID != c.GetInt("user_id") && !canViewMember(c, h.memberRepo, risk.MemberID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "risk is outside your scope"})
+		return
+	}
+	c.JSON(http.StatusOK, risk)
+}
+
+// Update handles PUT /api/risks/:id (triage): severity, category, owner, topic, status, resolution.
+func (h *RiskHandler) Update(c *gin.Context) {
+	var req struct {
+		Severity   *string `json:"severity"`
+		Category   *string `json:"category"`
+		OwnerID    *int    `json:"owner_id"`
+		TopicID    *int    `json:"topic_id"`
+		Status     *string `json:"status"`
+		Resolution *string `json:"resolution"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	risk, ok := h.editableRisk(c)
+	if !ok {
+		return
+	}
+	updates := map[string]interface{}{}
+	for _, f := range []struct {
+		name    string
+		val     *string
+		allowed []string
+	}{
+		{"severity", req.Severity, riskSeverities},
+		{"category", req.Category, riskCategories},
+		{"status", req.Status, riskStatuses},
+	} {
+		if f.val == nil {
+			continue
+		}
+		if !slices.Contains(f.allowed, *f.val) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + f.name})
+			return
+		}
+		updates[f.name] = *f.val
+	}
+	// 0 leaves the risk without owner or topic
+	if req.OwnerID != nil {
+		if *req.OwnerID != 0 && !h.assignable(c, *req.OwnerID) {
+			return
+		}
+		updates["owner_id"] = *req.OwnerID
+	}
+	if req.TopicID != nil {
+		if *req.TopicID != 0 && !h.topicExists(c, *req.TopicID) {
+			return
+		}
+		updates["topic_id"] = *req.TopicID
+	}
+	if req.Resolution != nil {
+		updates["resolution"] = *req.Resolution
+	}
+	if req.Status != nil && *req.Status != risk.Status {
+		if *req.Status == "closed" {
+			updates["closed_at"] = time.Now()
+		} else {
+			updates["closed_at"] = nil
+		}
+	}
+	if len(updates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "nothing to update"})
+		return
+	}
+	h.save(c, audit.RiskUpdate, risk, updates)
+}
+
+// Close handles PUT /api/risks/:id/close {resolution}.
+func (h *RiskHandler) Close(c *gin.Context) {
+	var req struct {
+		Resolution string `json:"resolution"`
+	}
+	c.ShouldBindJSON(&req)
+	risk, ok := h.editableRisk(c)
+	if !ok {
+		return
+	}
+	h.save(c, audit.RiskClose, risk, map[string]interface{}{"status": "closed", "resolution": req.Resolution, "closed_at": time.Now()})
+}
+
+func (h *RiskHandler) save(c *gin.Context, action string, before *model.Risk, updates map[string]interface{}) {
+	ctx := c.Request.Context()
+	id := before.ID
+	if err := h.repo.Update(ctx, id, updates); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(c, action, audit.TargetRisk, id, before, updates)
+	if h.catalog != nil {
+		h.catalog.ResyncTables("risks")
+	}
+	risk, _ := h.repo.Get(ctx, id)
+	c.JSON(http.StatusOK, risk)
+}
+
+// assignable checks that a risk may be handed to ownerID: an active member the
+// caller holds risk.manage for, or the caller themselves.
+func (h *RiskHandler) assignable(c *gin.Context, ownerID int) bool {
+	m, err := h.memberRepo.FindByID(c.Request.Context(), ownerID)
+	if err == gorm.ErrRecordNotFound || (err == nil && m.Status != "active") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "owner is not an active member"})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if !canAccessMember(c, h.memberRepo, authz.RiskManage, ownerID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "owner is outside your scope"})
+		return false
+	}
+	return true
+}
+
+// topicExists checks that a risk may be linked to topicID.
+func (h *RiskHandler) topicExists(c *gin.Context, topicID int) bool {
+	_, err := h.topicRepo.FindTopic(c.Request.Context(), topicID)
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "topic not found"})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// editableRisk loads :id and checks the caller owns it or holds risk.manage
+// for its reporter (admins, or the reporter's team lead).
+func (h *RiskHandler) editableRisk(c *gin.Context) (*model.Risk, bool) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	risk, err := h.repo.Get(c.Request.Context(), id)
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "risk not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	uid := c.GetInt("user_id")
+	if risk.OwnerID != uid && !canAccessMember(c, h.memberRepo, authz.RiskManage, risk.MemberID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the reporter, owner, their team lead or an admin can update this risk"})
+		return nil, false
+	}
+	return risk, true
+}
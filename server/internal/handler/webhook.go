@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"smart-daily/internal/audit"
+	"smart-daily/internal/events"
+	"smart-daily/internal/model"
+	"smart-daily/internal/repository"
+	"smart-daily/internal/service"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// WebhookHandler manages outbound webhook subscriptions (admin only).
+type WebhookHandler struct {
+	repo *repository.WebhookRepo
+	svc  *service.WebhookService
+}
+
+func NewWebhookHandler(repo *repository.WebhookRepo, svc *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{repo: repo, svc: svc}
+}
+
+type webhookRequest struct {
+	Name   *string   `json:"name"`
+	URL    *string   `json:"url"`
+	Secret *string   `json:"secret"`
+	Events *[]string `json:"events"`
+	Active *bool     `json:"active"`
+}
+
+// List handles GET /api/webhooks
+func (h *WebhookHandler) List(c *gin.Context) {
+	hooks, err := h.repo.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"webhooks": hooks, "events": events.All})
+}
+
+// Create handles POST /api/webhooks {name, url, events, secret?, active?}.
+// The secret (generated when omitted) is only returned here.
+func (h *WebhookHandler) Create(c *gin.Context) {
+	var req webhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.URL == nil || req.Events == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "url and events required"})
+		return
+	}
+	hook := model.Webhook{Active: true, CreatedBy: c.GetInt("user_id"), Secret: service.NewWebhookSecret()}
+	if !h.apply(c, &hook, req) {
+		return
+	}
+	if err := h.repo.Create(c.Request.Context(), &hook); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(c, audit.WebhookCreate, audit.TargetWebhook, hook.ID, nil, hook)
+	c.JSON(http.StatusOK, gin.H{"webhook": hook, "secret": hook.Secret})
+}
+
+// Update handles PUT /api/webhooks/:id; omitted fields are kept.
+func (h *WebhookHandler) Update(c *gin.Context) {
+	var req webhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	hook, ok := h.load(c)
+	if !ok {
+		return
+	}
+	before := *hook
+	if !h.apply(c, hook, req) {
+		return
+	}
+	hook.UpdatedAt = time.Now()
+	if err := h.repo.Update(c.Request.Context(), hook); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(c, audit.WebhookUpdate, audit.TargetWebhook, hook.ID, before, hook)
+	c.JSON(http.StatusOK, hook)
+}
+
+// Delete handles DELETE /api/webhooks/:id, with its delivery log.
+func (h *WebhookHandler) Delete(c *gin.Context) {
+	hook, ok := h.load(c)
+	if !ok {
+		return
+	}
+	ok, err := h.repo.Delete(c.Request.Context(), hook.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+		return
+	}
+	recordAudit(c, audit.WebhookDelete, audit.TargetWebhook, hook.ID, hook, nil)
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// Deliveries handles GET /api/webhooks/:id/deliveries?limit=50
+func (h *WebhookHandler) Deliveries(c *gin.Context) {
+	hook, ok := h.load(c)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	items, err := h.repo.ListDeliveries(c.Request.Context(), hook.ID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+// Ping handles POST /api/webhooks/:id/ping: queues a test "ping" delivery.
+func (h *WebhookHandler) Ping(c *gin.Context) {
+	hook, ok := h.load(c)
+	if !ok {
+		return
+	}
+	d, err := h.svc.Ping(c.Request.Context(), hook)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
+
+func (h *WebhookHandler) load(c *gin.Context) (*model.Webhook, bool) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	hook, err := h.repo.Get(c.Request.Context(), id)
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return hook, true
+}
+
+// apply validates req and copies the set fields onto hook.
+func (h *WebhookHandler) apply(c *gin.Context, hook *model.Webhook, req webhookRequest) bool {
+	if req.URL != nil {
+		u, err := url.Parse(*req.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "url must be http(s)"})
+			return false
+		}
+		hook.URL = *req.URL
+	}
+	if req.Events != nil {
+		if len(*req.Events) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "events required"})
+			return false
+		}
+		for _, e := range *req.Events {
+			if e != "*" && !slices.Contains(events.All, e) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "unknown event: " + e})
+				return false
+			}
+		}
+		hook.Events = *req.Events
+	}
+	if req.Name != nil {
+		hook.Name = *req.Name
+	}
+	if req.Secret != nil && *req.Secret != "" {
+		hook.Secret = *req.Secret
+	}
+	if req.Active != nil {
+		hook.Active = *req.Active
+	}
+	return true
+}
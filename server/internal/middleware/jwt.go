@@ -1,26 +1,93 @@
 package middleware
 
 import (
-	"crypto/rand"
+	"context"
+	"errors"
 	"net/http"
+	"smart-daily/internal/authz"
+	"smart-daily/internal/logger"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// JWTSecret is regenerated on each server start, invalidating all existing tokens.
-var JWTSecret = generateSecret()
-
 const tokenTTL = 36 * time.Hour // daily users stay logged in; skip a day → re-login
 
 func TokenTTL() time.Duration { return tokenTTL }
 
-func generateSecret() []byte {
-	b := make([]byte, 32)
-	rand.Read(b)
-	return b
+// RevocationStore answers whether a token was revoked, by its jti or by a
+// force-logout of its member after it was issued.
+type RevocationStore interface {
+	IsRevoked(ctx context.Context, jti string, memberID int, issuedAt time.Time) (bool, error)
+}
+
+// SubjectStore loads a member's current access role and team.
+type SubjectStore interface {
+	Subject(ctx context.Context, memberID int) (authz.Subject, error)
+}
+
+var (
+	keys     = randomKeyRing()
+	revoked  RevocationStore
+	subjects SubjectStore
+)
+
+// SetKeyRing replaces the signing keys (default: a random per-process key).
+func SetKeyRing(r *KeyRing) { keys = r }
+
+// SetStores enables token revocation checks and loading roles from the DB.
+func SetStores(r RevocationStore, s SubjectStore) { revoked, subjects = r, s }
+
+// IssueToken signs a login token for a member. A token issued with
+// mustChangePassword only reaches the routes in passwordChangeRoutes.
+func IssueToken(uid int, name string, isAdmin, mustChangePassword bool) (string, error) {
+	claims := jwt.MapClaims{"uid": uid, "name": name, "is_admin": isAdmin}
+	if mustChangePassword {
+		claims["mcp"] = true
+	}
+	return keys.Sign(claims)
+}
+
+// passwordChangeRoutes stay reachable while a password change is pending.
+var passwordChangeRoutes = map[string]bool{
+	"GET /api/me":          true,
+	"PUT /api/me/password": true,
+	"POST /api/logout":     true,
+}
+
+// notRevoked caches negative revocation lookups briefly so a busy client does
+// not hit the DB on every request; logouts on this process clear it at once.
+var notRevoked sync.Map // jti -> time.Time checked
+
+const revocationCacheTTL = 30 * time.Second
+
+// ForgetToken drops a jti from the revocation cache after it is revoked.
+func ForgetToken(jti string) { notRevoked.Delete(jti) }
+
+// ForgetAllTokens clears the revocation cache, e.g. after a force logout.
+func ForgetAllTokens() { notRevoked.Clear() }
+
+// isRevoked reports whether a token was revoked. A failed lookup is returned
+// as an error rather than taken as "not revoked", so that an outage of the
+// revocation store cannot bring revoked tokens back.
+func isRevoked(ctx context.Context, jti string, uid int, iat time.Time) (bool, error) {
+	if revoked == nil {
+		return false, nil
+	}
+	if t, ok := notRevoked.Load(jti); ok && time.Since(t.(time.Time)) < revocationCacheTTL {
+		return false, nil
+	}
+	r, err := revoked.IsRevoked(ctx, jti, uid, iat)
+	if err != nil {
+		return false, err
+	}
+	if !r {
+		notRevoked.Store(jti, time.Now())
+	}
+	return r, nil
 }
 
 func JWTAuth() gin.HandlerFunc {
@@ -30,30 +97,66 @@ func JWTAuth() gin.HandlerFunc {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 			return
 		}
-		token, err := jwt.Parse(auth[7:], func(t *jwt.Token) (interface{}, error) {
-			return JWTSecret, nil
-		})
+		token, err := keys.Parse(auth[7:])
 		if err != nil || !token.Valid {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
 			return
 		}
 		claims := token.Claims.(jwt.MapClaims)
-		c.Set("user_id", int(claims["uid"].(float64)))
+		uid := int(claims["uid"].(float64))
+		jti, _ := claims["jti"].(string)
+		var iat time.Time
+		if v, ok := claims["iat"].(float64); ok {
+			iat = time.Unix(int64(v), 0)
+		}
+		if jti == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token revoked"})
+			return
+		}
+		r, err := isRevoked(c.Request.Context(), jti, uid, iat)
+		if err != nil {
+			logger.Warn("check revoked token failed", "uid", uid, "err", err)
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "token check unavailable"})
+			return
+		}
+		if r {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token revoked"})
+			return
+		}
+		c.Set("user_id", uid)
+		if subjects != nil {
+			// Deleted members keep valid signatures; load their subject now so
+			// such tokens stop working at once.
+			s, err := subjects.Subject(c.Request.Context(), uid)
+			if errors.Is(err, authz.ErrNoSubject) {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "account disabled"})
+				return
+			}
+			if err == nil {
+				c.Set(subjectKey, s)
+			}
+		}
 		c.Set("user_name", claims["name"].(string))
+		c.Set("token_jti", jti)
+		if exp, ok := claims["exp"].(float64); ok {
+			c.Set("token_exp", time.Unix(int64(exp), 0))
+		}
 		if admin, ok := claims["is_admin"].(bool); ok && admin {
 			c.Set("is_admin", true)
 		}
+		mustChange, _ := claims["mcp"].(bool)
+		if mustChange && !passwordChangeRoutes[c.Request.Method+" "+c.FullPath()] {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "password change required", "code": "password_change_required"})
+			return
+		}
 
 		// Auto-renew when less than 12 hours remaining
 		if exp, ok := claims["exp"].(float64); ok {
 			if time.Until(time.Unix(int64(exp), 0)) < 12*time.Hour {
-				newToken, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-					"uid":      claims["uid"],
-					"name":     claims["name"],
-					"is_admin": claims["is_admin"],
-					"exp":      time.Now().Add(tokenTTL).Unix(),
-				}).SignedString(JWTSecret)
-				c.Header("X-New-Token", newToken)
+				admin, _ := claims["is_admin"].(bool)
+				if newToken, err := IssueToken(uid, claims["name"].(string), admin, mustChange); err == nil {
+					c.Header("X-New-Token", newToken)
+				}
 			}
 		}
 
@@ -61,10 +164,36 @@ func JWTAuth() gin.HandlerFunc {
 	}
 }
 
-func AdminOnly() gin.HandlerFunc {
+const subjectKey = "authz_subject"
+
+// Subject returns the caller's role and team. With a store set it is read from
+// the DB once per request, so role changes apply without waiting for the token
+// to expire; if that fails the caller gets member rights only.
+func Subject(c *gin.Context) authz.Subject {
+	if v, ok := c.Get(subjectKey); ok {
+		return v.(authz.Subject)
+	}
+	uid := c.GetInt("user_id")
+	s := authz.Subject{MemberID: uid, Role: authz.RoleMember}
+	if subjects != nil {
+		loaded, err := subjects.Subject(c.Request.Context(), uid)
+		if err != nil {
+			logger.Warn("load subject failed", "uid", uid, "err", err)
+		} else {
+			s = loaded
+		}
+	} else if c.GetBool("is_admin") {
+		s.Role = authz.RoleAdmin
+	}
+	c.Set(subjectKey, s)
+	return s
+}
+
+// Require rejects callers whose role lacks p. Team scoping is left to the handler.
+func Require(p authz.Permission) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if admin, _ := c.Get("is_admin"); admin != true {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin only"})
+		if !Subject(c).Can(p) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "permission denied", "permission": p})
 			return
 		}
 		c.Next()
@@ -72,6 +201,5 @@ func AdminOnly() gin.HandlerFunc {
 }
 
 func IsAdmin(c *gin.Context) bool {
-	admin, _ := c.Get("is_admin")
-	return admin == true
+	return Subject(c).IsAdmin()
 }
@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"smart-daily/internal/authz"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type fakeSubjects map[int]authz.Subject
+
+func (f fakeSubjects) Subject(_ context.Context, id int) (authz.Subject, error) {
+	s, ok := f[id]
+	if !ok {
+		return authz.Subject{}, authz.ErrNoSubject
+	}
+	return s, nil
+}
+
+func TestRequire(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	defer SetStores(nil, nil)
+	SetStores(nil, fakeSubjects{
+		1: {MemberID: 1, Role: authz.RoleAdmin},
+		2: {MemberID: 2, TeamID: 5, Role: authz.RoleTeamLead},
+		3: {MemberID: 3, TeamID: 5, Role: authz.RoleMember},
+	})
+
+	for _, tc := range []struct {
+		uid  int
+		p    authz.Permission
+		want int
+	}{
+		{1, authz.MemberManage, http.StatusOK},
+		{2, authz.TopicManage, http.StatusOK},
+		{2, authz.MemberManage, http.StatusForbidden},
+		{3, authz.TopicManage, http.StatusForbidden},
+		{4, authz.TopicManage, http.StatusForbidden}, // unknown member: no rights
+	} {
+		r := gin.New()
+		r.GET("/", func(c *gin.Context) { c.Set("user_id", tc.uid) }, Require(tc.p), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if w.Code != tc.want {
+			t.Errorf("uid %d %s: status %d, want %d", tc.uid, tc.p, w.Code, tc.want)
+		}
+	}
+}
+
+func TestSubjectWithoutStoreUsesClaim(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/", nil)
+	c.Set("user_id", 7)
+	c.Set("is_admin", true)
+	if s := Subject(c); s.Role != authz.RoleAdmin || s.MemberID != 7 {
+		t.Errorf("Subject = %+v, want admin 7", s)
+	}
+}
+
+func TestJWTAuthRejectsInactiveMember(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	defer SetStores(nil, nil)
+	SetStores(nil, fakeSubjects{1: {MemberID: 1, Role: authz.RoleMember}})
+
+	r := gin.New()
+	r.GET("/", JWTAuth(), func(c *gin.Context) { c.Status(http.StatusOK) })
+	for _, tc := range []struct {
+		uid  int
+		want int
+	}{
+		{1, http.StatusOK},
+		{2, http.StatusUnauthorized}, // deleted or purged since the token was issued
+	} {
+		token, err := IssueToken(tc.uid, "u", false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Errorf("uid %d: status %d, want %d", tc.uid, w.Code, tc.want)
+		}
+	}
+}
+
+type failingRevocations struct{}
+
+func (failingRevocations) IsRevoked(context.Context, string, int, time.Time) (bool, error) {
+	return false, errors.New("db down")
+}
+
+func TestJWTAuthFailsClosedOnRevocationError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	defer SetStores(nil, nil)
+	SetStores(failingRevocations{}, nil)
+	ForgetAllTokens()
+
+	r := gin.New()
+	r.GET("/", JWTAuth(), func(c *gin.Context) { c.Status(http.StatusOK) })
+	token, err := IssueToken(1, "u", false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+}
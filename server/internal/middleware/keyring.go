@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"smart-daily/internal/config"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type signingKey struct {
+	id       string
+	secret   []byte
+	notAfter time.Time // zero = no expiry
+}
+
+// KeyRing signs tokens with the active key and verifies them with any key
+// whose kid is known and not past its not_after.
+type KeyRing struct {
+	active signingKey
+	keys   map[string]signingKey
+}
+
+// NewKeyRing builds a key ring from config keys. An empty list yields a random
+// per-process key (tokens do not survive a restart).
+func NewKeyRing(keys []config.JWTKey, activeKID string) (*KeyRing, error) {
+	if len(keys) == 0 {
+		return randomKeyRing(), nil
+	}
+	r := &KeyRing{keys: map[string]signingKey{}}
+	for _, k := range keys {
+		if k.ID == "" {
+			return nil, fmt.Errorf("jwt key: id is required")
+		}
+		if len(k.Secret) < 32 {
+			return nil, fmt.Errorf("jwt key %q: secret must be at least 32 bytes", k.ID)
+		}
+		if _, dup := r.keys[k.ID]; dup {
+			return nil, fmt.Errorf("jwt key %q: duplicate id", k.ID)
+		}
+		sk := signingKey{id: k.ID, secret: []byte(k.Secret)}
+		if k.NotAfter != "" {
+			t, err := parseNotAfter(k.NotAfter)
+			if err != nil {
+				return nil, fmt.Errorf("jwt key %q: not_after: %w", k.ID, err)
+			}
+			sk.notAfter = t
+		}
+		r.keys[k.ID] = sk
+	}
+	if activeKID == "" {
+		activeKID = keys[0].ID
+	}
+	active, ok := r.keys[activeKID]
+	if !ok {
+		return nil, fmt.Errorf("active jwt key %q not found", activeKID)
+	}
+	if !active.notAfter.IsZero() {
+		return nil, fmt.Errorf("active jwt key %q must not have not_after", activeKID)
+	}
+	r.active = active
+	return r, nil
+}
+
+func randomKeyRing() *KeyRing {
+	b := make([]byte, 32)
+	rand.Read(b)
+	k := signingKey{id: "ephemeral", secret: b}
+	return &KeyRing{active: k, keys: map[string]signingKey{k.id: k}}
+}
+
+func parseNotAfter(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", s, time.Local)
+}
+
+// Ephemeral reports whether the ring is the random per-process fallback.
+func (r *KeyRing) Ephemeral() bool { return r.active.id == "ephemeral" }
+
+// Sign issues a token for the claims, adding kid, jti, iat and exp.
+func (r *KeyRing) Sign(claims jwt.MapClaims) (string, error) {
+	now := time.Now()
+	claims["jti"] = newJTI()
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(tokenTTL).Unix()
+	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	t.Header["kid"] = r.active.id
+	return t.SignedString(r.active.secret)
+}
+
+// Parse verifies a token against the key named by its kid.
+func (r *KeyRing) Parse(s string) (*jwt.Token, error) {
+	return jwt.Parse(s, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		k, ok := r.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown kid %q", kid)
+		}
+		if !k.notAfter.IsZero() && time.Now().After(k.notAfter) {
+			return nil, fmt.Errorf("key %q retired", kid)
+		}
+		return k.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+}
+
+func newJTI() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
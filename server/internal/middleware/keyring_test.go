@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"smart-daily/internal/config"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	secretA = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	secretB = "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+)
+
+func TestKeyRingRotation(t *testing.T) {
+	old, err := NewKeyRing([]config.JWTKey{{ID: "k1", Secret: secretA}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok, err := old.Sign(jwt.MapClaims{"uid": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// k2 now signs; k1 still verifies during the grace period
+	grace := time.Now().Add(time.Hour).Format(time.RFC3339)
+	rotated, err := NewKeyRing([]config.JWTKey{{ID: "k2", Secret: secretB}, {ID: "k1", Secret: secretA, NotAfter: grace}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rotated.Parse(tok); err != nil {
+		t.Errorf("old token in grace period: %v", err)
+	}
+	tok2, _ := rotated.Sign(jwt.MapClaims{"uid": 1})
+	parsed, err := rotated.Parse(tok2)
+	if err != nil || parsed.Header["kid"] != "k2" {
+		t.Errorf("new token: kid=%v err=%v", parsed.Header["kid"], err)
+	}
+	claims := parsed.Claims.(jwt.MapClaims)
+	if claims["jti"] == "" || claims["exp"] == nil || claims["iat"] == nil {
+		t.Errorf("missing registered claims: %v", claims)
+	}
+
+	// after not_after the old key is refused
+	retired, _ := NewKeyRing([]config.JWTKey{{ID: "k2", Secret: secretB}, {ID: "k1", Secret: secretA, NotAfter: "2000-01-01"}}, "")
+	if _, err := retired.Parse(tok); err == nil {
+		t.Error("retired key: expected error")
+	}
+	// keys dropped from config are unknown
+	dropped, _ := NewKeyRing([]config.JWTKey{{ID: "k2", Secret: secretB}}, "")
+	if _, err := dropped.Parse(tok); err == nil || !strings.Contains(err.Error(), "unknown kid") {
+		t.Errorf("dropped key: got %v", err)
+	}
+}
+
+func TestNewKeyRingErrors(t *testing.T) {
+	cases := map[string][]config.JWTKey{
+		"short secret": {{ID: "k1", Secret: "short"}},
+		"missing id":   {{Secret: secretA}},
+		"duplicate":    {{ID: "k1", Secret: secretA}, {ID: "k1", Secret: secretB}},
+		"retired head": {{ID: "k1", Secret: secretA, NotAfter: "2000-01-01"}},
+	}
+	for name, keys := range cases {
+		if _, err := NewKeyRing(keys, ""); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	if _, err := NewKeyRing([]config.JWTKey{{ID: "k1", Secret: secretA}}, "k9"); err == nil {
+		t.Error("unknown active kid: expected error")
+	}
+	r, err := NewKeyRing(nil, "")
+	if err != nil || !r.Ephemeral() {
+		t.Errorf("empty config: want ephemeral ring, got %v", err)
+	}
+}
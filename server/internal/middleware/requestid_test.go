@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestID())
+	var seen string
+	r.GET("/", func(c *gin.Context) { seen = GetRequestID(c) })
+
+	for _, tc := range []struct{ in, want string }{
+		{"", ""},
+		{"abc-123.x_y", "abc-123.x_y"},
+		{"bad id\nX-Injected: 1", ""},
+		{strings.Repeat("a", 65), ""},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tc.in != "" {
+			req.Header.Set(RequestIDHeader, tc.in)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		got := w.Header().Get(RequestIDHeader)
+		if got == "" || got != seen {
+			t.Errorf("%q: header %q, context %q", tc.in, got, seen)
+		}
+		if tc.want != "" && got != tc.want {
+			t.Errorf("%q: got %q, want it reused", tc.in, got)
+		}
+		if tc.want == "" && got == tc.in {
+			t.Errorf("%q: malformed ID was reused", tc.in)
+		}
+	}
+}
@@ -0,0 +1,376 @@
+// Package migrate applies numbered SQL migrations and records them in the
+// schema_migrations table.
+//
+// Files are named NNNN_name.up.sql / NNNN_name.down.sql. DDL is not
+// transactional in MySQL/MatrixOne, so a migration is recorded as dirty before
+// it runs and marked clean once every statement succeeded; a dirty version has
+// to be fixed by hand and then cleared with Force.
+//
+// "ALTER TABLE t ADD COLUMN c ..." statements are skipped when t already has c,
+// so migrations also bring databases created by the old init.sql and startup
+// DDL up to date.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const createTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version BIGINT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    dirty BOOL NOT NULL DEFAULT FALSE,
+    applied_at DATETIME DEFAULT NOW()
+)`
+
+var (
+	fileRe      = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+	addColumnRe = regexp.MustCompile("(?is)^ALTER\\s+TABLE\\s+`?(\\w+)`?\\s+ADD\\s+COLUMN\\s+`?(\\w+)`?\\s[^,]*$")
+)
+
+var (
+	ErrDirty   = errors.New("dirty migration")
+	ErrPending = errors.New("pending migrations")
+)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status is one migration and whether it has been applied.
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	Dirty     bool
+	AppliedAt time.Time
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// New loads the migrations in fsys.
+func New(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	ms, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: ms}, nil
+}
+
+// Load reads NNNN_name.{up,down}.sql files from the root of fsys, sorted by
+// version. Every version needs an up file; a down file is optional.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Migration{}
+	for _, e := range entries {
+		m := fileRe.FindStringSubmatch(e.Name())
+		if e.IsDir() || m == nil {
+			continue
+		}
+		v, _ := strconv.ParseInt(m[1], 10, 64)
+		body, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+		mg := byVersion[v]
+		if mg == nil {
+			mg = &Migration{Version: v, Name: m[2]}
+			byVersion[v] = mg
+		} else if mg.Name != m[2] {
+			return nil, fmt.Errorf("migration %d: conflicting names %q and %q", v, mg.Name, m[2])
+		}
+		if m[3] == "up" {
+			mg.Up = string(body)
+		} else {
+			mg.Down = string(body)
+		}
+	}
+	out := make([]Migration, 0, len(byVersion))
+	for _, mg := range byVersion {
+		if strings.TrimSpace(mg.Up) == "" {
+			return nil, fmt.Errorf("migration %d_%s: missing up file", mg.Version, mg.Name)
+		}
+		out = append(out, *mg)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+type record struct {
+	name      string
+	dirty     bool
+	appliedAt time.Time
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int64]record, error) {
+	if _, err := m.db.ExecContext(ctx, createTable); err != nil {
+		return nil, fmt.Errorf("create schema_migrations: %w", err)
+	}
+	rows, err := m.db.QueryContext(ctx, "SELECT version, name, dirty, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[int64]record{}
+	for rows.Next() {
+		var v int64
+		var r record
+		var at sql.NullTime
+		if err := rows.Scan(&v, &r.name, &r.dirty, &at); err != nil {
+			return nil, err
+		}
+		r.appliedAt = at.Time
+		out[v] = r
+	}
+	return out, rows.Err()
+}
+
+// Status lists known migrations plus any applied version missing from the files.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []Status
+	for _, mg := range m.migrations {
+		r, ok := applied[mg.Version]
+		out = append(out, Status{Version: mg.Version, Name: mg.Name, Applied: ok, Dirty: r.dirty, AppliedAt: r.appliedAt})
+		delete(applied, mg.Version)
+	}
+	for v, r := range applied {
+		out = append(out, Status{Version: v, Name: r.name + " (no file)", Applied: true, Dirty: r.dirty, AppliedAt: r.appliedAt})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// Check returns ErrDirty or ErrPending unless the schema is fully migrated.
+func (m *Migrator) Check(ctx context.Context) error {
+	st, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	var pending []string
+	for _, s := range st {
+		if s.Dirty {
+			return fmt.Errorf("%w: version %d (%s)", ErrDirty, s.Version, s.Name)
+		}
+		if !s.Applied {
+			pending = append(pending, fmt.Sprintf("%d_%s", s.Version, s.Name))
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %s", ErrPending, strings.Join(pending, ", "))
+	}
+	return nil
+}
+
+// Up applies up to n pending migrations in order (n <= 0 means all) and
+// returns the ones applied.
+func (m *Migrator) Up(ctx context.Context, n int) ([]Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDirty(applied); err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for _, mg := range m.migrations {
+		if _, ok := applied[mg.Version]; ok {
+			continue
+		}
+		if n > 0 && len(done) == n {
+			break
+		}
+		if _, err := m.db.ExecContext(ctx, "INSERT INTO schema_migrations (version, name, dirty) VALUES (?, ?, TRUE)", mg.Version, mg.Name); err != nil {
+			return done, fmt.Errorf("record %d_%s: %w", mg.Version, mg.Name, err)
+		}
+		if err := m.exec(ctx, mg.Up); err != nil {
+			return done, fmt.Errorf("apply %d_%s: %w", mg.Version, mg.Name, err)
+		}
+		if _, err := m.db.ExecContext(ctx, "UPDATE schema_migrations SET dirty = FALSE, applied_at = NOW() WHERE version = ?", mg.Version); err != nil {
+			return done, err
+		}
+		done = append(done, mg)
+	}
+	return done, nil
+}
+
+// Down reverts the n most recently applied migrations (n <= 0 means 1).
+func (m *Migrator) Down(ctx context.Context, n int) ([]Migration, error) {
+	if n <= 0 {
+		n = 1
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDirty(applied); err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(done) < n; i-- {
+		mg := m.migrations[i]
+		if _, ok := applied[mg.Version]; !ok {
+			continue
+		}
+		if strings.TrimSpace(mg.Down) == "" {
+			return done, fmt.Errorf("migration %d_%s has no down file", mg.Version, mg.Name)
+		}
+		if _, err := m.db.ExecContext(ctx, "UPDATE schema_migrations SET dirty = TRUE WHERE version = ?", mg.Version); err != nil {
+			return done, err
+		}
+		if err := m.exec(ctx, mg.Down); err != nil {
+			return done, fmt.Errorf("revert %d_%s: %w", mg.Version, mg.Name, err)
+		}
+		if _, err := m.db.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = ?", mg.Version); err != nil {
+			return done, err
+		}
+		done = append(done, mg)
+	}
+	return done, nil
+}
+
+// Force records the schema as being exactly at version: known migrations up to
+// it are marked applied and clean, later records are removed. Use it after
+// repairing a dirty migration by hand; no SQL from the files is run.
+func (m *Migrator) Force(ctx context.Context, version int64) error {
+	if _, err := m.applied(ctx); err != nil {
+		return err
+	}
+	if _, err := m.db.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version > ?", version); err != nil {
+		return err
+	}
+	if _, err := m.db.ExecContext(ctx, "UPDATE schema_migrations SET dirty = FALSE WHERE version <= ?", version); err != nil {
+		return err
+	}
+	for _, mg := range m.migrations {
+		if mg.Version > version {
+			break
+		}
+		var n int
+		if err := m.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM schema_migrations WHERE version = ?", mg.Version).Scan(&n); err != nil {
+			return err
+		}
+		if n == 0 {
+			if _, err := m.db.ExecContext(ctx, "INSERT INTO schema_migrations (version, name, dirty) VALUES (?, ?, FALSE)", mg.Version, mg.Name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) exec(ctx context.Context, script string) error {
+	for _, stmt := range SplitStatements(script) {
+		if table, column, ok := addedColumn(stmt); ok {
+			exists, err := m.hasColumn(ctx, table, column)
+			if err != nil {
+				return fmt.Errorf("%w\n  in: %s", err, firstLine(stmt))
+			}
+			if exists {
+				continue
+			}
+		}
+		if _, err := m.db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("%w\n  in: %s", err, firstLine(stmt))
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) hasColumn(ctx context.Context, table, column string) (bool, error) {
+	var n int
+	err := m.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?`, table, column).Scan(&n)
+	return n > 0, err
+}
+
+// addedColumn returns the table and column of a single-clause
+// "ALTER TABLE t ADD COLUMN c ..." statement.
+func addedColumn(stmt string) (table, column string, ok bool) {
+	m := addColumnRe.FindStringSubmatch(stmt)
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], m[2], true
+}
+
+func checkDirty(applied map[int64]record) error {
+	for v, r := range applied {
+		if r.dirty {
+			return fmt.Errorf("%w: version %d (%s); fix the schema by hand, then run `migrate force %d`", ErrDirty, v, r.name, v)
+		}
+	}
+	return nil
+}
+
+// SplitStatements splits a script on semicolons outside quotes and comments,
+// dropping "--" and "#" line comments and blank statements. The driver runs
+// one statement per Exec, and MatrixOne has no multi-statement mode to rely on.
+func SplitStatements(script string) []string {
+	var out []string
+	var b strings.Builder
+	var quote rune
+	flush := func() {
+		if s := strings.TrimSpace(b.String()); s != "" {
+			out = append(out, s)
+		}
+		b.Reset()
+	}
+	rs := []rune(script)
+	for i := 0; i < len(rs); i++ {
+		c := rs[i]
+		if quote != 0 {
+			b.WriteRune(c)
+			if c == '\\' && i+1 < len(rs) {
+				i++
+				b.WriteRune(rs[i])
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+			b.WriteRune(c)
+		case c == '#' || (c == '-' && i+1 < len(rs) && rs[i+1] == '-'):
+			for i < len(rs) && rs[i] != '\n' {
+				i++
+			}
+			b.WriteRune('\n')
+		case c == ';':
+			flush()
+		default:
+			b.WriteRune(c)
+		}
+	}
+	flush()
+	return out
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i] + " ..."
+	}
+	return s
+}
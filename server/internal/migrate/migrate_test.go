@@ -0,0 +1,219 @@
+package migrate
+
+import (
+	"os"
+	"regexp"
+	"smart-daily/migration"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_add_x.up.sql":   {Data: []byte("ALTER TABLE t ADD COLUMN x INT;")},
+		"0002_add_x.down.sql": {Data: []byte("ALTER TABLE t DROP COLUMN x;")},
+		"0001_init.up.sql":    {Data: []byte("CREATE TABLE t (id INT);")},
+		"seed.sql":            {Data: []byte("INSERT INTO t VALUES (1);")},
+		"README.md":           {Data: []byte("ignored")},
+	}
+	ms, err := Load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 2 || ms[0].Version != 1 || ms[1].Version != 2 {
+		t.Fatalf("got %+v", ms)
+	}
+	if ms[0].Down != "" || ms[1].Name != "add_x" || !strings.Contains(ms[1].Down, "DROP COLUMN") {
+		t.Errorf("unexpected migrations: %+v", ms)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	if _, err := Load(fstest.MapFS{"0001_a.down.sql": {Data: []byte("DROP TABLE t;")}}); err == nil {
+		t.Error("down without up: expected error")
+	}
+	if _, err := Load(fstest.MapFS{
+		"0001_a.up.sql": {Data: []byte("SELECT 1;")},
+		"0001_b.up.sql": {Data: []byte("SELECT 2;")},
+	}); err == nil {
+		t.Error("duplicate version: expected error")
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	script := `-- header; not a statement
+CREATE TABLE a (
+    name VARCHAR(50) DEFAULT 'x;y', -- trailing; comment
+    note TEXT DEFAULT "it\"s;"
+);
+# mysql comment;
+INSERT INTO a (name) VALUES ('it''s');
+
+;`
+	got := SplitStatements(script)
+	if len(got) != 2 {
+		t.Fatalf("got %d statements: %q", len(got), got)
+	}
+	if !strings.Contains(got[0], "'x;y'") || !strings.Contains(got[0], `"it\"s;"`) || strings.Contains(got[0], "trailing") {
+		t.Errorf("statement 1 = %q", got[0])
+	}
+	if !strings.HasPrefix(got[1], "INSERT") || !strings.HasSuffix(got[1], "('it''s')") {
+		t.Errorf("statement 2 = %q", got[1])
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	ms, err := Load(migration.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) == 0 || ms[0].Version != 1 {
+		t.Fatalf("expected baseline as version 1, got %+v", ms)
+	}
+	for i, m := range ms {
+		if i > 0 && m.Version == ms[i-1].Version {
+			t.Errorf("duplicate version %d", m.Version)
+		}
+		if m.Down == "" {
+			t.Errorf("%d_%s: missing down file", m.Version, m.Name)
+		}
+		for _, stmt := range SplitStatements(m.Up) {
+			if strings.HasPrefix(strings.ToUpper(stmt), "DROP DATABASE") {
+				t.Errorf("%d_%s: must not drop the database", m.Version, m.Name)
+			}
+		}
+	}
+}
+
+func TestAddedColumn(t *testing.T) {
+	for stmt, want := range map[string]string{
+		"ALTER TABLE members ADD COLUMN email VARCHAR(100) DEFAULT ''": "members.email",
+		"alter table `t` add column `x` INT":                           "t.x",
+		"ALTER TABLE t ADD COLUMN a INT, ADD COLUMN b INT":             "",
+		"ALTER TABLE t ADD INDEX idx_a (a)":                            "",
+		"ALTER TABLE t DROP COLUMN a":                                  "",
+	} {
+		table, column, ok := addedColumn(stmt)
+		if got := table + "." + column; ok && got != want || !ok && want != "" {
+			t.Errorf("addedColumn(%q) = %q, %v; want %q", stmt, got, ok, want)
+		}
+	}
+}
+
+// schema models table columns, enough to replay the DDL of the migrations.
+type schema map[string]map[string]bool
+
+var (
+	createRe     = regexp.MustCompile(`(?is)^CREATE TABLE (IF NOT EXISTS )?(\w+) \((.*)\)$`)
+	dropColumnRe = regexp.MustCompile(`(?is)^ALTER TABLE (\w+) DROP COLUMN (\w+)$`)
+	updateRe     = regexp.MustCompile(`(?is)^UPDATE (\w+) SET (.*)$`)
+	quotedRe     = regexp.MustCompile(`'[^']*'`)
+	identRe      = regexp.MustCompile(`\b[a-z]+_[a-z_]+\b`)
+)
+
+// apply replays stmt like the migrator would against a real database and
+// fails when it references a missing table or column.
+func (s schema) apply(t *testing.T, stmt string) {
+	t.Helper()
+	if table, column, ok := addedColumn(stmt); ok {
+		if s[table] == nil {
+			t.Errorf("add column to missing table: %s", firstLine(stmt))
+		} else {
+			s[table][column] = true // an existing column is skipped
+		}
+		return
+	}
+	if m := createRe.FindStringSubmatch(stmt); m != nil {
+		if s[m[2]] != nil {
+			if m[1] == "" {
+				t.Errorf("table %s already exists", m[2])
+			}
+			return
+		}
+		s[m[2]] = map[string]bool{}
+		for _, def := range splitDefs(m[3]) {
+			name := strings.Trim(strings.Fields(def)[0], "`")
+			switch strings.ToUpper(name) {
+			case "INDEX", "KEY", "UNIQUE", "PRIMARY", "CONSTRAINT", "FOREIGN":
+			default:
+				s[m[2]][name] = true
+			}
+		}
+		return
+	}
+	if m := dropColumnRe.FindStringSubmatch(stmt); m != nil {
+		if !s[m[1]][m[2]] {
+			t.Errorf("drop missing column %s.%s", m[1], m[2])
+		}
+		delete(s[m[1]], m[2])
+		return
+	}
+	if m := updateRe.FindStringSubmatch(stmt); m != nil {
+		for _, col := range identRe.FindAllString(quotedRe.ReplaceAllString(m[2], ""), -1) {
+			if !s[m[1]][col] {
+				t.Errorf("update references missing column %s.%s: %s", m[1], col, firstLine(stmt))
+			}
+		}
+	}
+}
+
+// splitDefs splits a CREATE TABLE body on commas outside parentheses.
+func splitDefs(body string) []string {
+	var out []string
+	depth, start := 0, 0
+	for i, c := range body {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				out = append(out, strings.TrimSpace(body[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if s := strings.TrimSpace(body[start:]); s != "" {
+		out = append(out, s)
+	}
+	return out
+}
+
+func TestUpgradeFromInitSQL(t *testing.T) {
+	ms, err := Load(migration.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrateAll := func(s schema) {
+		for _, m := range ms {
+			for _, stmt := range SplitStatements(m.Up) {
+				s.apply(t, stmt)
+			}
+		}
+	}
+	fresh := schema{}
+	migrateAll(fresh)
+
+	// A database from the old init.sql plus the startup DDL of that time,
+	// which created report_drafts before it had status and blockers.
+	initSQL, err := os.ReadFile("testdata/init.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := schema{}
+	for _, stmt := range SplitStatements(string(initSQL)) {
+		old.apply(t, stmt)
+	}
+	old.apply(t, "CREATE TABLE IF NOT EXISTS report_drafts (id INT AUTO_INCREMENT PRIMARY KEY, member_id INT NOT NULL, daily_date DATE NOT NULL, mode VARCHAR(20) DEFAULT 'report', content TEXT, summary TEXT, risks TEXT, expires_at DATETIME NOT NULL, created_at DATETIME DEFAULT NOW(), updated_at DATETIME DEFAULT NOW(), INDEX idx_member (member_id))")
+	migrateAll(old)
+
+	for table, cols := range fresh {
+		for col := range cols {
+			if !old[table][col] {
+				t.Errorf("upgraded database misses %s.%s", table, col)
+			}
+		}
+	}
+}
@@ -1,14 +1,17 @@
 package model
 
 import (
+	"encoding/json"
 	"time"
 
 	"gorm.io/gorm"
 )
 
 type Team struct {
-	ID   int    `gorm:"primaryKey" json:"id"`
-	Name string `gorm:"uniqueIndex" json:"name"`
+	ID       int    `gorm:"primaryKey" json:"id"`
+	Name     string `gorm:"uniqueIndex" json:"name"`
+	ParentID int    `gorm:"default:0" json:"parent_id"` // 0 for a top-level department
+	LeadID   int    `gorm:"default:0" json:"lead_id"`   // designated lead (members.id), 0 for none
 }
 
 type Member struct {
@@ -19,9 +22,15 @@ type Member struct {
 	Avatar   string `json:"avatar"`
 	Role     string `json:"role"`
 	TeamID   int    `json:"team_id"`
-	Team     string `json:"team"`
 	Status   string `gorm:"default:active" json:"status"`
-	IsAdmin  bool   `gorm:"default:false" json:"is_admin"`
+	IsAdmin  bool   `gorm:"default:false" json:"is_admin"` // mirrors AccessRole == "admin"
+	Email    string `json:"email"`
+	// AccessRole is member / team_lead / admin, see package authz
+	AccessRole string `gorm:"default:member" json:"access_role"`
+	// MustChangePassword blocks everything but a password change after login
+	MustChangePassword bool `gorm:"default:false" json:"must_change_password"`
+	// BatchID is the import batch that created the account, 0 otherwise
+	BatchID int `gorm:"default:0" json:"batch_id,omitempty"`
 }
 
 type DailyEntry struct {
@@ -31,6 +40,7 @@ type DailyEntry struct {
 	Content   string    `json:"content"`
 	Summary   string    `json:"summary"`
 	Source    string    `gorm:"default:chat" json:"source"`
+	BatchID   int       `gorm:"default:0" json:"batch_id,omitempty"` // import batch, 0 for chat
 	CreatedAt time.Time `json:"created_at"`
 }
 
@@ -42,6 +52,7 @@ type DailySummary struct {
 	Status    string `json:"status"`
 	Risk      string `json:"risk"`
 	Blocker   string `json:"blocker"`
+	BatchID   int    `gorm:"default:0" json:"batch_id,omitempty"` // import batch that last wrote it
 }
 
 type TopicActivity struct {
@@ -52,6 +63,7 @@ type TopicActivity struct {
 	DailyDate  string `gorm:"type:date;index" json:"daily_date"`
 	Content    string `json:"content"`
 	EntryID    int    `json:"entry_id"`
+	BatchID    int    `gorm:"default:0" json:"batch_id,omitempty"`
 }
 
 type Topic struct {
@@ -70,6 +82,15 @@ func (Member) TableName() string          { return "members" }
 func (TopicActivity) TableName() string   { return "topic_activities" }
 func (Topic) TableName() string           { return "topics" }
 func (Feedback) TableName() string        { return "feedback" }
+func (ReportDraft) TableName() string     { return "report_drafts" }
+func (Risk) TableName() string            { return "risks" }
+func (Notification) TableName() string    { return "notifications" }
+func (Webhook) TableName() string         { return "webhooks" }
+func (WebhookDelivery) TableName() string { return "webhook_deliveries" }
+func (RevokedToken) TableName() string    { return "revoked_tokens" }
+func (AuditEvent) TableName() string      { return "audit_events" }
+func (PeriodSummary) TableName() string   { return "period_summaries" }
+func (Report) TableName() string          { return "reports" }
 
 type Feedback struct {
 	ID         int       `gorm:"primaryKey" json:"id"`
@@ -80,8 +101,222 @@ type Feedback struct {
 	CreatedAt  time.Time `json:"created_at"`
 }
 
+// ReportDraft is a summarized report waiting for the member to confirm it.
+// Drafts expire after ExpiresAt and are purged lazily.
+type ReportDraft struct {
+	ID        int        `gorm:"primaryKey" json:"id"`
+	MemberID  int        `gorm:"index" json:"member_id"`
+	DailyDate string     `gorm:"type:date" json:"daily_date"`
+	Mode      string     `gorm:"default:report" json:"mode"` // report / supplement
+	Content   string     `json:"content"`
+	Summary   string     `json:"summary"`
+	Status    string     `json:"status"` // on-track / at-risk / blocked / off
+	Blockers  []string   `gorm:"serializer:json;type:text" json:"blockers"`
+	Risks     []RiskItem `gorm:"serializer:json;type:text" json:"risks"`
+	State     string     `gorm:"default:pending" json:"-"` // pending / confirming (claimed by a confirm in progress)
+	ClaimedAt *time.Time `json:"-"`                        // when a confirm claimed it; the claim lapses after a lease
+	ExpiresAt time.Time  `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Risk is one risk raised in a daily report, tracked until closed.
+type Risk struct {
+	ID          int        `gorm:"primaryKey" json:"id"`
+	MemberID    int        `gorm:"index" json:"member_id"` // reporter
+	MemberName  string     `json:"member_name"`
+	DailyDate   string     `gorm:"type:date;index" json:"daily_date"`
+	EntryID     int        `gorm:"index" json:"entry_id"` // source daily_entries.id
+	TopicID     int        `json:"topic_id"`
+	Topic       string     `gorm:"->;-:migration" json:"topic"` // joined from topics.name
+	Description string     `json:"description"`
+	Severity    string     `gorm:"default:medium" json:"severity"` // high / medium / low
+	Category    string     `json:"category"`                       // blocked / delay / incident / dependency
+	OwnerID     int        `json:"owner_id"`
+	Status      string     `gorm:"default:open" json:"status"` // open / mitigated / closed
+	Resolution  string     `json:"resolution"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	ClosedAt    *time.Time `json:"closed_at,omitempty"`
+}
+
+// Notification is an in-app message to a member, e.g. a submission reminder.
+type Notification struct {
+	ID        int        `gorm:"primaryKey" json:"id"`
+	MemberID  int        `gorm:"index" json:"member_id"`
+	Kind      string     `json:"kind"` // reminder / ...
+	Title     string     `json:"title"`
+	Content   string     `json:"content"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Webhook is an outbound subscription: matching events are POSTed to URL,
+// signed with Secret.
+type Webhook struct {
+	ID        int       `gorm:"primaryKey" json:"id"`
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"-"`
+	Events    []string  `gorm:"serializer:json;type:text" json:"events"` // event names, or "*" for all
+	Active    bool      `json:"active"`
+	CreatedBy int       `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery is one event sent (or to be sent) to a webhook, with its retry state.
+type WebhookDelivery struct {
+	ID            int        `gorm:"primaryKey" json:"id"`
+	WebhookID     int        `gorm:"index" json:"webhook_id"`
+	Event         string     `json:"event"`
+	Payload       string     `json:"payload"` // JSON of the event data
+	Status        string     `json:"status"`  // pending / success / failed
+	Attempts      int        `json:"attempts"`
+	ResponseCode  int        `json:"response_code"`
+	Error         string     `json:"error"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+}
+
+// RevokedToken blocks one token (JTI set) or, with an empty JTI, every token of
+// the member issued before IssuedBefore (force logout). Rows can be purged once
+// ExpiresAt has passed since the tokens they block have expired too.
+type RevokedToken struct {
+	ID           int        `gorm:"primaryKey" json:"id"`
+	JTI          string     `gorm:"column:jti" json:"jti"`
+	MemberID     int        `json:"member_id"`
+	IssuedBefore *time.Time `json:"issued_before,omitempty"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	RevokedBy    int        `json:"revoked_by"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// MemberIdentity binds an external login (an OIDC issuer and subject) to a
+// member, so later logins find the member without trusting mutable claims.
+type MemberIdentity struct {
+	ID        int       `gorm:"primaryKey" json:"id"`
+	MemberID  int       `json:"member_id"`
+	Provider  string    `json:"provider"`
+	Issuer    string    `json:"issuer"`
+	Subject   string    `json:"subject"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AuditEvent records one administrative or data-changing action. Before and
+// After are JSON snapshots of the target (empty when not applicable).
+type AuditEvent struct {
+	ID         int64           `gorm:"primaryKey" json:"id"`
+	ActorID    int             `json:"actor_id"`
+	ActorName  string          `json:"actor_name"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   string          `json:"target_id"`
+	Before     json.RawMessage `gorm:"column:before_data;type:text" json:"before,omitempty"`
+	After      json.RawMessage `gorm:"column:after_data;type:text" json:"after,omitempty"`
+	RequestID  string          `json:"request_id"`
+	IP         string          `gorm:"column:ip" json:"ip"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
 // ActiveMembers is a GORM scope that excludes logically deleted members.
 // Use: db.Scopes(model.ActiveMembers).Find(&members)
 func ActiveMembers(db *gorm.DB) *gorm.DB {
 	return db.Where("members.status != 'deleted'")
 }
+
+// PeriodSummary caches one level of the review pipeline (daily reports →
+// weekly rollups → monthly/quarterly reviews) for a member (MemberID > 0) or
+// a team with its sub-teams (TeamID, 0 for everyone). SourceHash identifies
+// the input Content was generated from.
+type PeriodSummary struct {
+	ID          int       `gorm:"primaryKey" json:"id"`
+	MemberID    int       `json:"member_id"`
+	TeamID      int       `json:"team_id"`
+	PeriodType  string    `json:"period_type"` // week / month / quarter
+	PeriodStart string    `gorm:"type:date" json:"period_start"`
+	PeriodEnd   string    `gorm:"type:date" json:"period_end"`
+	Content     string    `json:"content"`
+	SourceHash  string    `json:"source_hash"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Report is a generated weekly report of a member for a date range, kept for
+// re-download and editing. SourceHash identifies the entries Content was
+// generated from; Edited marks content changed by hand.
+type Report struct {
+	ID          int       `gorm:"primaryKey" json:"id"`
+	MemberID    int       `json:"member_id"`
+	MemberName  string    `gorm:"->;-:migration" json:"member_name"` // joined from members.name
+	CreatedBy   int       `json:"created_by"`
+	PeriodStart string    `gorm:"type:date" json:"period_start"`
+	PeriodEnd   string    `gorm:"type:date" json:"period_end"`
+	Title       string    `json:"title"`
+	Content     string    `json:"content,omitempty"`
+	SourceHash  string    `json:"-"`
+	Edited      bool      `json:"edited"`
+	Stale       bool      `gorm:"-" json:"stale,omitempty"` // edited, and the entries changed since it was generated
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ImportJob is an uploaded import processed in the background: its sections
+// are extracted into a preview, which is then confirmed into daily entries.
+// Decisions and Policies (JSON) are the member decisions and per-row policies
+// of the last confirm, Result (JSON) its ConfirmResult.
+type ImportJob struct {
+	ID        int       `gorm:"primaryKey" json:"id"`
+	CreatedBy int       `json:"created_by"`
+	FileName  string    `json:"file_name"`
+	Format    string    `json:"format"`
+	Status    string    `json:"status"` // parsing / extracting / preview / confirming / done / failed
+	Error     string    `json:"error"`
+	Decisions string    `json:"-"`
+	Policies  string    `json:"-"`
+	Result    string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ImportJobSection is one dated section of an import job and, once done,
+// the entries extracted from it (JSON).
+type ImportJobSection struct {
+	ID      int    `gorm:"primaryKey" json:"id"`
+	JobID   int    `json:"job_id"`
+	Seq     int    `json:"seq"`
+	Date    string `json:"date"`
+	Text    string `json:"text"`
+	Status  string `json:"status"` // pending / done / failed
+	Entries string `json:"entries"`
+	Error   string `json:"error"`
+}
+
+// ImportBatch is the data one import confirm wrote: entries, summaries,
+// topic activities and members carry its ID so the batch can be reverted.
+// Result (JSON) is its ConfirmResult.
+type ImportBatch struct {
+	ID         int        `gorm:"primaryKey" json:"id"`
+	JobID      int        `json:"job_id"`
+	CreatedBy  int        `json:"created_by"`
+	FileName   string     `json:"file_name"`
+	Status     string     `gorm:"default:applied" json:"status"` // applied / reverted
+	Result     string     `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RevertedBy int        `json:"reverted_by,omitempty"`
+	RevertedAt *time.Time `json:"reverted_at,omitempty"`
+}
+
+// ImportBatchSnapshot is a day as it was before a batch wrote it: the
+// summary (JSON DailySummary, empty if there was none) and the import
+// entries the batch replaced with their risks (JSON).
+type ImportBatchSnapshot struct {
+	ID        int    `gorm:"primaryKey" json:"id"`
+	BatchID   int    `json:"batch_id"`
+	MemberID  int    `json:"member_id"`
+	DailyDate string `gorm:"type:date" json:"daily_date"`
+	Summary   string `json:"summary"`
+	Entries   string `json:"entries"`
+	Risks     string `json:"risks"`
+}
@@ -1,10 +1,16 @@
 package model
 
+import (
+	"encoding/json"
+	"strings"
+)
+
 type ChatRequest struct {
 	Text      string        `json:"text"`
 	Mode      string        `json:"mode"`
 	Date      string        `json:"date"`
 	Action    string        `json:"action"`
+	DraftID   int           `json:"draft_id,omitempty"` // action=confirm; 0 confirms the latest draft
 	SessionID *int64        `json:"session_id,omitempty"`
 	History   []HistoryItem `json:"history,omitempty"`
 }
@@ -37,12 +43,50 @@ type User struct {
 	Avatar  string `json:"avatar"`
 	Role    string `json:"role"`
 	IsAdmin bool   `json:"is_admin"`
+
+	AccessRole  string   `json:"access_role"`
+	Permissions []string `json:"permissions"`
+
+	MustChangePassword bool `json:"must_change_password,omitempty"`
 }
 
-type PendingReport struct {
-	Content  string
-	Summary  string
-	Risks    []string
-	Date     string
-	MemberID int
+// RiskItem is a risk detected in a report, before it is filed as a Risk.
+type RiskItem struct {
+	Description string `json:"description"`
+	Severity    string `json:"severity"` // high / medium / low
+	Category    string `json:"category"` // blocked / delay / incident / dependency
+}
+
+// UnmarshalJSON also accepts a bare string, the format of older drafts and
+// of models that ignore the requested object shape.
+func (r *RiskItem) UnmarshalJSON(data []byte) error {
+	var text string
+	if json.Unmarshal(data, &text) == nil {
+		*r = RiskItem{Description: text}
+		return nil
+	}
+	type plain RiskItem
+	return json.Unmarshal(data, (*plain)(r))
+}
+
+// WorkStatuses are the values of DailySummary.Status.
+var WorkStatuses = []string{"on-track", "at-risk", "blocked", "off"}
+
+// ReportAnalysis is the structured reading of a report summary.
+type ReportAnalysis struct {
+	Status   string     `json:"status"` // on-track / at-risk / blocked / off
+	Blockers []string   `json:"blockers"`
+	Risks    []RiskItem `json:"risks"`
+}
+
+// BlockerText joins blockers the way daily_summaries.blocker stores them.
+func BlockerText(blockers []string) string { return strings.Join(blockers, "; ") }
+
+// RiskDescriptions flattens risk items to their descriptions.
+func RiskDescriptions(items []RiskItem) []string {
+	out := make([]string, 0, len(items))
+	for _, r := range items {
+		out = append(out, r.Description)
+	}
+	return out
 }
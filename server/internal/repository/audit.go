@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"smart-daily/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AuditRepo stores the audit log (see package audit).
+type AuditRepo struct{ db *gorm.DB }
+
+func NewAuditRepo(db *gorm.DB) *AuditRepo { return &AuditRepo{db: db} }
+
+// AuditFilter narrows List; zero values match everything. Start and End are
+// inclusive dates (2006-01-02).
+type AuditFilter struct {
+	ActorID    int
+	Actor      string // actor name
+	Actions    []string
+	TargetType string
+	TargetID   string
+	Start      string
+	End        string
+	Limit      int
+	Offset     int
+}
+
+// Create implements audit.Store.
+func (r *AuditRepo) Create(ctx context.Context, e *model.AuditEvent) error {
+	return r.db.WithContext(ctx).Create(e).Error
+}
+
+// List returns matching events, newest first, and how many match in total.
+func (r *AuditRepo) List(ctx context.Context, f AuditFilter) ([]model.AuditEvent, int64, error) {
+	q := r.db.WithContext(ctx).Model(&model.AuditEvent{})
+	if f.ActorID > 0 {
+		q = q.Where("actor_id = ?", f.ActorID)
+	}
+	if f.Actor != "" {
+		q = q.Where("actor_name = ?", f.Actor)
+	}
+	if len(f.Actions) > 0 {
+		q = q.Where("action IN ?", f.Actions)
+	}
+	if f.TargetType != "" {
+		q = q.Where("target_type = ?", f.TargetType)
+	}
+	if f.TargetID != "" {
+		q = q.Where("target_id = ?", f.TargetID)
+	}
+	if f.Start != "" {
+		q = q.Where("created_at >= ?", f.Start)
+	}
+	if f.End != "" {
+		if end, err := time.Parse("2006-01-02", f.End); err == nil {
+			q = q.Where("created_at < ?", end.AddDate(0, 0, 1).Format("2006-01-02"))
+		}
+	}
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var items []model.AuditEvent
+	err := q.Order("id DESC").Limit(f.Limit).Offset(f.Offset).Find(&items).Error
+	return items, total, err
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"smart-daily/internal/authz"
 	"smart-daily/internal/model"
 
 	"gorm.io/gorm"
@@ -20,37 +21,115 @@ type SummaryRow struct {
 	Risk      string
 }
 
-// ListSummariesWithMembers returns all summaries joined with active members, for export.
-func (r *DailyRepo) ListSummariesWithMembers(ctx context.Context) ([]SummaryRow, error) {
+// ListSummariesWithMembers returns summaries joined with active members, for
+// export: those of members in scope plus selfID's own.
+func (r *DailyRepo) ListSummariesWithMembers(ctx context.Context, scope authz.Scope, selfID int) ([]SummaryRow, error) {
 	var rows []SummaryRow
-	err := r.db.WithContext(ctx).Model(&model.DailySummary{}).
+	q := r.db.WithContext(ctx).Model(&model.DailySummary{}).
 		Select("daily_summaries.daily_date, members.name, daily_summaries.summary, daily_summaries.risk").
 		Joins("JOIN members ON members.id = daily_summaries.member_id").
-		Scopes(model.ActiveMembers).
-		Order("daily_summaries.daily_date DESC, members.name").
-		Scan(&rows).Error
+		Scopes(model.ActiveMembers)
+	switch {
+	case scope.All:
+	case len(scope.TeamIDs) > 0:
+		q = q.Where("members.team_id IN ? OR members.id = ?", scope.TeamIDs, selfID)
+	default:
+		q = q.Where("members.id = ?", selfID)
+	}
+	err := q.Order("daily_summaries.daily_date DESC, members.name").Scan(&rows).Error
 	return rows, err
 }
 
-// CreateEntry inserts a daily entry.
-func (r *DailyRepo) CreateEntry(ctx context.Context, e *model.DailyEntry) error {
-	return r.db.WithContext(ctx).Create(e).Error
+// SaveEntry creates an entry with its risks and deletes the confirmed draft it
+// came from (draftID 0 for none) in one transaction.
+func (r *DailyRepo) SaveEntry(ctx context.Context, e *model.DailyEntry, risks []model.Risk, draftID int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(e).Error; err != nil {
+			return err
+		}
+		if len(risks) > 0 {
+			for i := range risks {
+				risks[i].EntryID = e.ID
+			}
+			if err := tx.Create(&risks).Error; err != nil {
+				return fmt.Errorf("insert risks: %w", err)
+			}
+		}
+		if draftID == 0 {
+			return nil
+		}
+		return tx.Where("id = ?", draftID).Delete(&model.ReportDraft{}).Error
+	})
+}
+
+// GetEntry returns a daily entry by ID.
+func (r *DailyRepo) GetEntry(ctx context.Context, id int) (*model.DailyEntry, error) {
+	var e model.DailyEntry
+	if err := r.db.WithContext(ctx).First(&e, id).Error; err != nil {
+		return nil, err
+	}
+	if len(e.DailyDate) > 10 {
+		e.DailyDate = e.DailyDate[:10]
+	}
+	return &e, nil
+}
+
+// UpdateEntry saves an entry's content and summary, files its newly detected
+// risks and deletes its open risks no longer detected, in one transaction.
+func (r *DailyRepo) UpdateEntry(ctx context.Context, id int, content, summary string, risks []model.Risk, staleRiskIDs []int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.DailyEntry{}).Where("id = ?", id).
+			Updates(map[string]interface{}{"content": content, "summary": summary}).Error
+		if err != nil {
+			return err
+		}
+		if len(staleRiskIDs) > 0 {
+			if err := tx.Where("id IN ? AND entry_id = ? AND status = 'open'", staleRiskIDs, id).Delete(&model.Risk{}).Error; err != nil {
+				return fmt.Errorf("delete risks: %w", err)
+			}
+		}
+		if len(risks) == 0 {
+			return nil
+		}
+		if err := tx.Create(&risks).Error; err != nil {
+			return fmt.Errorf("insert risks: %w", err)
+		}
+		return nil
+	})
+}
+
+// DeleteEntry removes a daily entry with its risks and topic activities in
+// one transaction.
+func (r *DailyRepo) DeleteEntry(ctx context.Context, id int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("entry_id = ?", id).Delete(&model.Risk{}).Error; err != nil {
+			return fmt.Errorf("delete risks: %w", err)
+		}
+		if err := tx.Where("entry_id = ?", id).Delete(&model.TopicActivity{}).Error; err != nil {
+			return fmt.Errorf("delete topic activities: %w", err)
+		}
+		return tx.Delete(&model.DailyEntry{}, id).Error
+	})
 }
 
-// UpsertSummary creates or updates a daily summary for a member+date.
-func (r *DailyRepo) UpsertSummary(ctx context.Context, memberID int, date, summary, risk string) error {
+// DeleteSummary removes the summary for a member+date.
+func (r *DailyRepo) DeleteSummary(ctx context.Context, memberID int, date string) error {
+	return r.db.WithContext(ctx).Where("member_id = ? AND daily_date = ?", memberID, date).Delete(&model.DailySummary{}).Error
+}
+
+// UpsertSummary creates or updates the summary for s.MemberID+s.DailyDate and sets s.ID.
+func (r *DailyRepo) UpsertSummary(ctx context.Context, s *model.DailySummary) error {
 	var existing model.DailySummary
-	err := r.db.WithContext(ctx).Where("member_id = ? AND daily_date = ?", memberID, date).First(&existing).Error
+	err := r.db.WithContext(ctx).Where("member_id = ? AND daily_date = ?", s.MemberID, s.DailyDate).First(&existing).Error
 	if err == gorm.ErrRecordNotFound {
-		return r.db.WithContext(ctx).Create(&model.DailySummary{
-			MemberID: memberID, DailyDate: date, Summary: summary, Risk: risk,
-		}).Error
+		return r.db.WithContext(ctx).Create(s).Error
 	}
 	if err != nil {
 		return fmt.Errorf("query summary: %w", err)
 	}
+	s.ID = existing.ID
 	return r.db.WithContext(ctx).Model(&existing).Updates(map[string]interface{}{
-		"summary": summary, "risk": risk,
+		"summary": s.Summary, "status": s.Status, "risk": s.Risk, "blocker": s.Blocker, "batch_id": s.BatchID,
 	}).Error
 }
 
@@ -80,15 +159,40 @@ func (r *DailyRepo) GetMemberEntries(ctx context.Context, memberID int, start, e
 	return entries, err
 }
 
-// BulkReplaceImportEntries deletes existing import entries for given keys, then bulk creates new ones.
-func (r *DailyRepo) BulkReplaceImportEntries(ctx context.Context, delKeys [][]interface{}, entries []model.DailyEntry) error {
-	if len(delKeys) > 0 {
-		r.db.WithContext(ctx).Where("source = 'import' AND (member_id, daily_date) IN ?", delKeys).Delete(&model.DailyEntry{})
-	}
-	if len(entries) > 0 {
-		return r.db.WithContext(ctx).Create(&entries).Error
-	}
-	return nil
+// BulkReplaceImportEntries deletes existing import entries for given keys with
+// their risks, then bulk creates new ones and the risks filed against them (risks[i] belongs to
+// entries[i]) in one transaction, returning the created risks.
+func (r *DailyRepo) BulkReplaceImportEntries(ctx context.Context, delKeys [][]interface{}, entries []model.DailyEntry, risks [][]model.Risk) ([]model.Risk, error) {
+	var rows []model.Risk
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(delKeys) > 0 {
+			old := tx.Session(&gorm.Session{NewDB: true}).Model(&model.DailyEntry{}).Select("id").
+				Where("source = 'import' AND (member_id, daily_date) IN ?", delKeys)
+			if err := tx.Where("entry_id IN (?)", old).Delete(&model.Risk{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("source = 'import' AND (member_id, daily_date) IN ?", delKeys).Delete(&model.DailyEntry{}).Error; err != nil {
+				return err
+			}
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+		if err := tx.Create(&entries).Error; err != nil {
+			return err
+		}
+		for i := range risks {
+			for _, risk := range risks[i] {
+				risk.EntryID = entries[i].ID
+				rows = append(rows, risk)
+			}
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(&rows, 200).Error
+	})
+	return rows, err
 }
 
 // BulkReplaceSummaries deletes summaries matching delKeys then batch-creates new ones.
@@ -102,17 +206,86 @@ func (r *DailyRepo) BulkReplaceSummaries(ctx context.Context, delKeys [][]interf
 	return nil
 }
 
-// FindExistingImportKeys returns member_id+daily_date pairs for existing import entries.
-func (r *DailyRepo) FindExistingImportKeys(ctx context.Context) (map[[2]interface{}]bool, error) {
-	var existing []model.DailyEntry
-	if err := r.db.WithContext(ctx).Where("source = 'import'").Select("member_id, daily_date").Find(&existing).Error; err != nil {
-		return nil, err
+// EntriesByKeys returns the entries of the given member_id+daily_date pairs
+// in submission order.
+func (r *DailyRepo) EntriesByKeys(ctx context.Context, keys [][]interface{}) ([]model.DailyEntry, error) {
+	var entries []model.DailyEntry
+	const chunk = 500
+	for i := 0; i < len(keys); i += chunk {
+		var part []model.DailyEntry
+		err := r.db.WithContext(ctx).Where("(member_id, daily_date) IN ?", keys[i:min(i+chunk, len(keys))]).
+			Order("created_at, id").Find(&part).Error
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, part...)
 	}
-	m := make(map[[2]interface{}]bool, len(existing))
-	for _, e := range existing {
-		m[[2]interface{}{e.MemberID, e.DailyDate}] = true
+	return entries, nil
+}
+
+// SummariesByKeys returns the summaries of the given member_id+daily_date pairs.
+func (r *DailyRepo) SummariesByKeys(ctx context.Context, keys [][]interface{}) ([]model.DailySummary, error) {
+	var summaries []model.DailySummary
+	const chunk = 500
+	for i := 0; i < len(keys); i += chunk {
+		var part []model.DailySummary
+		if err := r.db.WithContext(ctx).Where("(member_id, daily_date) IN ?", keys[i:min(i+chunk, len(keys))]).Find(&part).Error; err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, part...)
 	}
-	return m, nil
+	return summaries, nil
+}
+
+// DayStatuses returns the work status of each summarised date for a member in a date range.
+func (r *DailyRepo) DayStatuses(ctx context.Context, memberID int, start, end string) (map[string]string, error) {
+	var rows []model.DailySummary
+	err := r.db.WithContext(ctx).Select("daily_date", "status").
+		Where("member_id = ? AND daily_date BETWEEN ? AND ?", memberID, start, end).Find(&rows).Error
+	m := make(map[string]string, len(rows))
+	for _, s := range rows {
+		if len(s.DailyDate) >= 10 {
+			m[s.DailyDate[:10]] = s.Status
+		}
+	}
+	return m, err
+}
+
+// MembersWithEntries returns the IDs of members with at least one entry on date.
+func (r *DailyRepo) MembersWithEntries(ctx context.Context, date string) (map[int]bool, error) {
+	var ids []int
+	err := r.db.WithContext(ctx).Model(&model.DailyEntry{}).Where("daily_date = ?", date).
+		Distinct("member_id").Pluck("member_id", &ids).Error
+	m := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		m[id] = true
+	}
+	return m, err
+}
+
+// SubmittedDatesByMember is SubmittedDates for several members at once: member ID → date set.
+func (r *DailyRepo) SubmittedDatesByMember(ctx context.Context, memberIDs []int, start, end string) (map[int]map[string]bool, error) {
+	m := make(map[int]map[string]bool, len(memberIDs))
+	if len(memberIDs) == 0 {
+		return m, nil
+	}
+	var rows []struct {
+		MemberID  int
+		DailyDate string
+	}
+	err := r.db.WithContext(ctx).Raw(`SELECT member_id, daily_date FROM daily_summaries WHERE member_id IN ? AND daily_date BETWEEN ? AND ?
+		UNION SELECT member_id, daily_date FROM daily_entries WHERE member_id IN ? AND daily_date BETWEEN ? AND ?`,
+		memberIDs, start, end, memberIDs, start, end).Scan(&rows).Error
+	for _, row := range rows {
+		if len(row.DailyDate) < 10 {
+			continue
+		}
+		if m[row.MemberID] == nil {
+			m[row.MemberID] = map[string]bool{}
+		}
+		m[row.MemberID][row.DailyDate[:10]] = true
+	}
+	return m, err
 }
 
 // SubmittedDates returns the set of dates with daily data (summaries or entries) for a member in a date range.
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"smart-daily/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type DraftRepo struct{ db *gorm.DB }
+
+func NewDraftRepo(db *gorm.DB) *DraftRepo { return &DraftRepo{db: db} }
+
+// draftClaimLease is how long a confirm may hold a draft. A claim older than
+// that was left by a confirm that crashed, and the draft is pending again.
+const draftClaimLease = time.Minute
+
+// pendingDraft matches drafts that are pending or whose claim has lapsed.
+func pendingDraft(db *gorm.DB) *gorm.DB {
+	return db.Where("(state = 'pending' OR (state = 'confirming' AND claimed_at < ?))", time.Now().Add(-draftClaimLease))
+}
+
+// Create stores a new draft and purges expired ones.
+func (r *DraftRepo) Create(ctx context.Context, d *model.ReportDraft) error {
+	r.db.WithContext(ctx).Where("expires_at < ?", time.Now()).Delete(&model.ReportDraft{})
+	return r.db.WithContext(ctx).Create(d).Error
+}
+
+// List returns a member's unexpired pending drafts, newest first.
+func (r *DraftRepo) List(ctx context.Context, memberID int) ([]model.ReportDraft, error) {
+	var drafts []model.ReportDraft
+	err := r.db.WithContext(ctx).Scopes(pendingDraft).Where("member_id = ? AND expires_at >= ?", memberID, time.Now()).
+		Order("id DESC").Find(&drafts).Error
+	for i := range drafts {
+		trimDraftDate(&drafts[i])
+	}
+	return drafts, err
+}
+
+// Get returns an unexpired pending draft owned by memberID.
+func (r *DraftRepo) Get(ctx context.Context, id, memberID int) (*model.ReportDraft, error) {
+	var d model.ReportDraft
+	err := r.db.WithContext(ctx).Scopes(pendingDraft).Where("id = ? AND member_id = ? AND expires_at >= ?", id, memberID, time.Now()).
+		First(&d).Error
+	trimDraftDate(&d)
+	return &d, err
+}
+
+// Latest returns the member's most recent unexpired pending draft.
+func (r *DraftRepo) Latest(ctx context.Context, memberID int) (*model.ReportDraft, error) {
+	var d model.ReportDraft
+	err := r.db.WithContext(ctx).Scopes(pendingDraft).Where("member_id = ? AND expires_at >= ?", memberID, time.Now()).
+		Order("id DESC").First(&d).Error
+	trimDraftDate(&d)
+	return &d, err
+}
+
+// Update saves the editable fields of a draft that is not being confirmed.
+func (r *DraftRepo) Update(ctx context.Context, d *model.ReportDraft) error {
+	return r.db.WithContext(ctx).Model(d).Scopes(pendingDraft).Select("daily_date", "content", "summary", "status", "blockers", "risks", "expires_at", "updated_at").
+		Updates(d).Error
+}
+
+// Delete discards a draft owned by memberID.
+func (r *DraftRepo) Delete(ctx context.Context, id, memberID int) (bool, error) {
+	res := r.db.WithContext(ctx).Where("id = ? AND member_id = ?", id, memberID).Delete(&model.ReportDraft{})
+	return res.RowsAffected > 0, res.Error
+}
+
+// Claim marks a pending draft as being confirmed and reports whether this
+// caller did, so two concurrent confirms of the same draft submit it only
+// once. The draft is deleted with the saved entry (DailyRepo.SaveEntry) or
+// handed back with Release; if neither happens within draftClaimLease, the
+// claim lapses and another confirm may take the draft.
+func (r *DraftRepo) Claim(ctx context.Context, id int) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&model.ReportDraft{}).Scopes(pendingDraft).Where("id = ?", id).
+		Updates(map[string]interface{}{"state": "confirming", "claimed_at": time.Now()})
+	return res.RowsAffected == 1, res.Error
+}
+
+// Release returns a claimed draft to pending after its confirm failed.
+func (r *DraftRepo) Release(ctx context.Context, id int) error {
+	return r.db.WithContext(ctx).Model(&model.ReportDraft{}).Where("id = ? AND state = 'confirming'", id).
+		Updates(map[string]interface{}{"state": "pending", "claimed_at": nil}).Error
+}
+
+func trimDraftDate(d *model.ReportDraft) {
+	if len(d.DailyDate) > 10 {
+		d.DailyDate = d.DailyDate[:10]
+	}
+}
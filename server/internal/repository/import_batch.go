@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"smart-daily/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ImportBatchRepo struct{ db *gorm.DB }
+
+func NewImportBatchRepo(db *gorm.DB) *ImportBatchRepo { return &ImportBatchRepo{db: db} }
+
+func (r *ImportBatchRepo) Create(ctx context.Context, b *model.ImportBatch) error {
+	return r.db.WithContext(ctx).Create(b).Error
+}
+
+func (r *ImportBatchRepo) Get(ctx context.Context, id int) (*model.ImportBatch, error) {
+	var b model.ImportBatch
+	if err := r.db.WithContext(ctx).First(&b, id).Error; err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// List returns the latest batches, newest first: the member's, or everyone's
+// for createdBy 0.
+func (r *ImportBatchRepo) List(ctx context.Context, createdBy, limit int) ([]model.ImportBatch, error) {
+	var batches []model.ImportBatch
+	q := r.db.WithContext(ctx).Order("id DESC").Limit(limit)
+	if createdBy > 0 {
+		q = q.Where("created_by = ?", createdBy)
+	}
+	return batches, q.Find(&batches).Error
+}
+
+func (r *ImportBatchRepo) SetResult(ctx context.Context, id int, result string) error {
+	return r.db.WithContext(ctx).Model(&model.ImportBatch{}).Where("id = ?", id).Update("result", result).Error
+}
+
+// SaveSnapshots records the days a batch is about to write as they are now.
+func (r *ImportBatchRepo) SaveSnapshots(ctx context.Context, snapshots []model.ImportBatchSnapshot) error {
+	if len(snapshots) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).CreateInBatches(&snapshots, 200).Error
+}
+
+// RevertCounts is what Revert removed and restored.
+type RevertCounts struct {
+	DailyEntries      int64 `json:"daily_entries"`
+	DailySummaries    int64 `json:"daily_summaries"`
+	TopicActivities   int64 `json:"topic_activities"`
+	Risks             int64 `json:"risks"`
+	RestoredSummaries int64 `json:"restored_summaries"`
+	RestoredEntries   int64 `json:"restored_entries"`
+	RestoredRisks     int64 `json:"restored_risks"`
+	Members           int64 `json:"members"`
+	KeptMembers       int64 `json:"kept_members"` // created by the batch but with data of their own since
+
+	Restored []model.DailyEntry `json:"-"` // entries put back, for rebuilding their topic activities
+}
+
+// SupersededError is returned by Revert while later applied batches rewrote
+// some of the batch's days: their snapshots hold this batch's data, so they
+// have to be reverted first or it would come back.
+type SupersededError struct{ Later []int }
+
+func (e *SupersededError) Error() string {
+	return fmt.Sprintf("days rewritten by later import batches %v", e.Later)
+}
+
+// Revert undoes a batch in one transaction and reports whether it was still
+// applied: its entries with their topic activities and risks are deleted, the summaries and
+// import entries it replaced are restored with their risks (summaries rewritten since are
+// left alone), and the members it created are purged unless they have
+// reports of their own by now. Only the latest applied batch of a day can be
+// reverted; otherwise a *SupersededError names the later ones.
+func (r *ImportBatchRepo) Revert(ctx context.Context, id, by int) (RevertCounts, bool, error) {
+	var c RevertCounts
+	applied := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&model.ImportBatch{}).Where("id = ? AND status = 'applied'", id).
+			Updates(map[string]interface{}{"status": "reverted", "reverted_by": by, "reverted_at": time.Now()})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		applied = true
+
+		var later []int
+		err := tx.Table("import_batch_snapshots AS s").Joins("JOIN import_batches b ON b.id = s.batch_id").
+			Where("s.batch_id > ? AND b.status = 'applied'", id).
+			Where("EXISTS (SELECT 1 FROM import_batch_snapshots o WHERE o.batch_id = ? AND o.member_id = s.member_id AND o.daily_date = s.daily_date)", id).
+			Distinct("s.batch_id").Order("s.batch_id").Pluck("s.batch_id", &later).Error
+		if err != nil {
+			return err
+		}
+		if len(later) > 0 {
+			return &SupersededError{Later: later}
+		}
+
+		sub := tx.Session(&gorm.Session{NewDB: true})
+		res = tx.Where("entry_id IN (?)", sub.Model(&model.DailyEntry{}).Select("id").Where("batch_id = ?", id)).Delete(&model.Risk{})
+		if res.Error != nil {
+			return res.Error
+		}
+		c.Risks = res.RowsAffected
+		for _, t := range []struct {
+			model any
+			n     *int64
+		}{{&model.TopicActivity{}, &c.TopicActivities}, {&model.DailyEntry{}, &c.DailyEntries}} {
+			res := tx.Where("batch_id = ?", id).Delete(t.model)
+			if res.Error != nil {
+				return res.Error
+			}
+			*t.n = res.RowsAffected
+		}
+
+		var snapshots []model.ImportBatchSnapshot
+		if err := tx.Where("batch_id = ?", id).Find(&snapshots).Error; err != nil {
+			return err
+		}
+		for _, snap := range snapshots {
+			date := snap.DailyDate
+			if len(date) > 10 {
+				date = date[:10]
+			}
+			if snap.Summary != "" {
+				var prev model.DailySummary
+				if err := json.Unmarshal([]byte(snap.Summary), &prev); err != nil {
+					return err
+				}
+				res := tx.Model(&model.DailySummary{}).Where("member_id = ? AND daily_date = ? AND batch_id = ?", snap.MemberID, date, id).
+					Updates(map[string]interface{}{
+						"summary": prev.Summary, "status": prev.Status, "risk": prev.Risk, "blocker": prev.Blocker, "batch_id": prev.BatchID,
+					})
+				if res.Error != nil {
+					return res.Error
+				}
+				c.RestoredSummaries += res.RowsAffected
+			}
+			var entries []model.DailyEntry
+			if snap.Entries != "" {
+				if err := json.Unmarshal([]byte(snap.Entries), &entries); err != nil {
+					return err
+				}
+			}
+			if len(entries) > 0 {
+				if err := tx.Create(&entries).Error; err != nil {
+					return err
+				}
+				c.RestoredEntries += int64(len(entries))
+				c.Restored = append(c.Restored, entries...)
+			}
+			var risks []model.Risk
+			if snap.Risks != "" {
+				if err := json.Unmarshal([]byte(snap.Risks), &risks); err != nil {
+					return err
+				}
+			}
+			if len(risks) > 0 {
+				if err := tx.Create(&risks).Error; err != nil {
+					return err
+				}
+				c.RestoredRisks += int64(len(risks))
+			}
+		}
+		// Summaries the batch wrote on days that had none
+		res = tx.Where("batch_id = ?", id).Delete(&model.DailySummary{})
+		if res.Error != nil {
+			return res.Error
+		}
+		c.DailySummaries = res.RowsAffected
+
+		var created, junk []int
+		if err := tx.Model(&model.Member{}).Where("batch_id = ?", id).Pluck("id", &created).Error; err != nil {
+			return err
+		}
+		if len(created) == 0 {
+			return nil
+		}
+		err = tx.Model(&model.Member{}).Where("id IN ?", created).
+			Where("id NOT IN (?)", sub.Model(&model.DailyEntry{}).Select("member_id")).
+			Where("id NOT IN (?)", sub.Model(&model.DailySummary{}).Select("member_id")).
+			Pluck("id", &junk).Error
+		if err != nil {
+			return err
+		}
+		c.KeptMembers = int64(len(created) - len(junk))
+		if len(junk) == 0 {
+			return nil
+		}
+		var purged PurgeCounts
+		for _, t := range purgeTargets(&purged) {
+			if err := tx.Where("member_id IN ?", junk).Delete(t.model).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&model.Risk{}).Where("owner_id IN ?", junk).Update("owner_id", 0).Error; err != nil {
+			return err
+		}
+		res = tx.Delete(&model.Member{}, junk)
+		c.Members = res.RowsAffected
+		return res.Error
+	})
+	return c, applied, err
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"smart-daily/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type ImportJobRepo struct{ db *gorm.DB }
+
+func NewImportJobRepo(db *gorm.DB) *ImportJobRepo { return &ImportJobRepo{db: db} }
+
+// Create inserts a job and then its sections.
+func (r *ImportJobRepo) Create(ctx context.Context, job *model.ImportJob, sections []model.ImportJobSection) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(job).Error; err != nil {
+			return err
+		}
+		if len(sections) == 0 {
+			return nil
+		}
+		for i := range sections {
+			sections[i].JobID = job.ID
+		}
+		return tx.CreateInBatches(&sections, 200).Error
+	})
+}
+
+func (r *ImportJobRepo) Get(ctx context.Context, id int) (*model.ImportJob, error) {
+	var job model.ImportJob
+	if err := r.db.WithContext(ctx).First(&job, id).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ListFor returns the member's latest jobs, newest first.
+func (r *ImportJobRepo) ListFor(ctx context.Context, createdBy, limit int) ([]model.ImportJob, error) {
+	var jobs []model.ImportJob
+	err := r.db.WithContext(ctx).Select("id, created_by, file_name, format, status, error, created_at, updated_at").
+		Where("created_by = ?", createdBy).Order("id DESC").Limit(limit).Find(&jobs).Error
+	return jobs, err
+}
+
+// Transition moves a job from one of the from statuses to status, setting
+// updates as well, and reports whether it was in one of them. Concurrent
+// requests cannot both start the same phase.
+func (r *ImportJobRepo) Transition(ctx context.Context, id int, from []string, status string, updates map[string]interface{}) (bool, error) {
+	if updates == nil {
+		updates = map[string]interface{}{}
+	}
+	updates["status"] = status
+	res := r.db.WithContext(ctx).Model(&model.ImportJob{}).Where("id = ? AND status IN ?", id, from).Updates(updates)
+	return res.RowsAffected > 0, res.Error
+}
+
+// FailRunning marks jobs left in a running status as failed with msg, for
+// jobs interrupted by a restart.
+func (r *ImportJobRepo) FailRunning(ctx context.Context, running []string, msg string) (int64, error) {
+	res := r.db.WithContext(ctx).Model(&model.ImportJob{}).Where("status IN ?", running).
+		Updates(map[string]interface{}{"status": "failed", "error": msg})
+	return res.RowsAffected, res.Error
+}
+
+// Sections returns a job's sections in document order.
+func (r *ImportJobRepo) Sections(ctx context.Context, jobID int) ([]model.ImportJobSection, error) {
+	var sections []model.ImportJobSection
+	err := r.db.WithContext(ctx).Where("job_id = ?", jobID).Order("seq").Find(&sections).Error
+	return sections, err
+}
+
+func (r *ImportJobRepo) UpdateSection(ctx context.Context, id int, updates map[string]interface{}) error {
+	return r.db.WithContext(ctx).Model(&model.ImportJobSection{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// SectionCounts returns the number of a job's sections per status.
+func (r *ImportJobRepo) SectionCounts(ctx context.Context, jobID int) (map[string]int, error) {
+	var rows []struct {
+		Status string
+		N      int
+	}
+	err := r.db.WithContext(ctx).Model(&model.ImportJobSection{}).Select("status, COUNT(*) AS n").
+		Where("job_id = ?", jobID).Group("status").Scan(&rows).Error
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.N
+	}
+	return counts, err
+}
@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"smart-daily/internal/authz"
 	"smart-daily/internal/model"
 	"strings"
 
@@ -12,15 +14,40 @@ type MemberRepo struct{ db *gorm.DB }
 
 func NewMemberRepo(db *gorm.DB) *MemberRepo { return &MemberRepo{db: db} }
 
-// ListActive returns non-deleted members, ordered: has-team first, leader first, test accounts last.
+// ListActive returns non-deleted members, ordered: has-team first, team lead
+// first, test accounts last.
 func (r *MemberRepo) ListActive(ctx context.Context) ([]model.Member, error) {
+	return r.ListActiveInTeams(ctx, nil)
+}
+
+// ListActiveInTeams is ListActive restricted to the given teams; none means all teams.
+func (r *MemberRepo) ListActiveInTeams(ctx context.Context, teamIDs []int) ([]model.Member, error) {
 	var members []model.Member
-	err := r.db.WithContext(ctx).Scopes(model.ActiveMembers).
-		Order("CASE WHEN name LIKE '测试%' THEN 2 WHEN team_id = 0 THEN 1 ELSE 0 END, team_id, CASE WHEN role = 'Leader' THEN 0 ELSE 1 END, name").
+	q := r.db.WithContext(ctx).Select("members.*").Scopes(model.ActiveMembers).
+		Joins("LEFT JOIN teams ON teams.id = members.team_id")
+	if len(teamIDs) > 0 {
+		q = q.Where("members.team_id IN ?", teamIDs)
+	}
+	err := q.Order("CASE WHEN members.name LIKE '测试%' THEN 2 WHEN members.team_id = 0 THEN 1 ELSE 0 END, members.team_id, " +
+		"CASE WHEN teams.lead_id = members.id THEN 0 ELSE 1 END, members.name").
 		Find(&members).Error
 	return members, err
 }
 
+// ListDeleted returns soft-deleted members (candidates for restore or purge).
+func (r *MemberRepo) ListDeleted(ctx context.Context) ([]model.Member, error) {
+	var members []model.Member
+	err := r.db.WithContext(ctx).Where("status = 'deleted'").Order("name").Find(&members).Error
+	return members, err
+}
+
+// UsernameExists reports whether any member, deleted or not, has username.
+func (r *MemberRepo) UsernameExists(ctx context.Context, username string) (bool, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Model(&model.Member{}).Where("username = ?", username).Count(&n).Error
+	return n > 0, err
+}
+
 // FindByUsername finds an active member by username (for login).
 func (r *MemberRepo) FindByUsername(ctx context.Context, username string) (*model.Member, error) {
 	var m model.Member
@@ -28,44 +55,235 @@ func (r *MemberRepo) FindByUsername(ctx context.Context, username string) (*mode
 	return &m, err
 }
 
+// FindByLogin finds a member, including deleted ones, by username or else by
+// email (for external logins). Empty values are not matched.
+func (r *MemberRepo) FindByLogin(ctx context.Context, username, email string) (*model.Member, error) {
+	var m model.Member
+	if username != "" {
+		err := r.db.WithContext(ctx).Where("username = ?", username).First(&m).Error
+		if err != gorm.ErrRecordNotFound {
+			return &m, err
+		}
+	}
+	if email == "" {
+		return &m, gorm.ErrRecordNotFound
+	}
+	err := r.db.WithContext(ctx).Where("LOWER(email) = LOWER(?)", email).
+		Order("CASE WHEN status = 'deleted' THEN 1 ELSE 0 END, id").First(&m).Error
+	return &m, err
+}
+
+// FindByIdentity returns the member, including deleted ones, bound to an
+// external identity.
+func (r *MemberRepo) FindByIdentity(ctx context.Context, provider, issuer, subject string) (*model.Member, error) {
+	var m model.Member
+	err := r.db.WithContext(ctx).
+		Where("id = (SELECT member_id FROM member_identities WHERE provider = ? AND issuer = ? AND subject = ?)", provider, issuer, subject).
+		First(&m).Error
+	return &m, err
+}
+
+// BindIdentity records that an external identity belongs to memberID.
+func (r *MemberRepo) BindIdentity(ctx context.Context, memberID int, provider, issuer, subject string) error {
+	return r.db.WithContext(ctx).Create(&model.MemberIdentity{MemberID: memberID, Provider: provider, Issuer: issuer, Subject: subject}).Error
+}
+
+// FindByID returns a member by ID, including deleted ones.
+func (r *MemberRepo) FindByID(ctx context.Context, id int) (*model.Member, error) {
+	var m model.Member
+	err := r.db.WithContext(ctx).First(&m, id).Error
+	return &m, err
+}
+
+// Subject returns an active member's current access role, team and the teams
+// nested below it.
+func (r *MemberRepo) Subject(ctx context.Context, id int) (authz.Subject, error) {
+	var m model.Member
+	err := r.db.WithContext(ctx).Select("id", "team_id", "access_role", "is_admin").
+		Scopes(model.ActiveMembers).Where("id = ?", id).First(&m).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return authz.Subject{}, authz.ErrNoSubject
+	}
+	if err != nil {
+		return authz.Subject{}, err
+	}
+	if m.TeamID == 0 || authz.RoleOf(m.AccessRole, m.IsAdmin) != authz.RoleTeamLead {
+		return SubjectOf(m, nil), nil
+	}
+	teams, err := r.ListTeams(ctx)
+	if err != nil {
+		return authz.Subject{}, err
+	}
+	return SubjectOf(m, teams), nil
+}
+
+// SubjectOf builds m's Subject. Team lead rights follow teams.lead_id: a
+// team_lead who is not the designated lead of their own team acts as a member.
+func SubjectOf(m model.Member, teams []model.Team) authz.Subject {
+	s := authz.Subject{MemberID: m.ID, TeamID: m.TeamID, Role: authz.RoleOf(m.AccessRole, m.IsAdmin)}
+	if s.Role != authz.RoleTeamLead {
+		return s
+	}
+	if !LeadsTeam(teams, m.ID, m.TeamID) {
+		s.Role = authz.RoleMember
+		return s
+	}
+	s.SubTeams = SubTeamIDs(teams, s.TeamID)
+	return s
+}
+
+// TeamOf returns a member's team ID (0 for none), including deleted members.
+func (r *MemberRepo) TeamOf(ctx context.Context, id int) (int, error) {
+	var m model.Member
+	err := r.db.WithContext(ctx).Select("team_id").Where("id = ?", id).First(&m).Error
+	return m.TeamID, err
+}
+
 // Create inserts a new member.
 func (r *MemberRepo) Create(ctx context.Context, m *model.Member) error {
 	return r.db.WithContext(ctx).Create(m).Error
 }
 
-// Update updates specified fields for a member.
+// Update updates specified fields for a member. Moving a member to another
+// team drops them as lead of the teams they led and, unless the update sets
+// access_role itself, demotes a team_lead to member.
 func (r *MemberRepo) Update(ctx context.Context, id int, updates map[string]interface{}) error {
-	return r.db.WithContext(ctx).Model(&model.Member{}).Where("id = ?", id).Updates(updates).Error
+	teamID, moved := updates["team_id"]
+	if !moved {
+		return r.db.WithContext(ctx).Model(&model.Member{}).Where("id = ?", id).Updates(updates).Error
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Member{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Team{}).Where("lead_id = ? AND id != ?", id, teamID).Update("lead_id", 0).Error; err != nil {
+			return err
+		}
+		if _, ok := updates["access_role"]; ok {
+			return nil
+		}
+		return demoteFormerLeads(tx, id)
+	})
 }
 
-// SoftDelete marks a member as deleted.
+// SoftDelete marks a member as deleted; teams they led are left without a lead.
 func (r *MemberRepo) SoftDelete(ctx context.Context, id int) error {
-	return r.db.WithContext(ctx).Model(&model.Member{}).Where("id = ?", id).Update("status", "deleted").Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Member{}).Where("id = ?", id).Update("status", "deleted").Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.Team{}).Where("lead_id = ?", id).Update("lead_id", 0).Error
+	})
 }
 
-// ListTeams returns all teams ordered by name.
-func (r *MemberRepo) ListTeams(ctx context.Context) ([]model.Team, error) {
-	var teams []model.Team
-	err := r.db.WithContext(ctx).Order("name").Find(&teams).Error
-	return teams, err
+// PurgeCounts is what purging a member removes, per table.
+type PurgeCounts struct {
+	DailyEntries    int64 `json:"daily_entries"`
+	DailySummaries  int64 `json:"daily_summaries"`
+	TopicActivities int64 `json:"topic_activities"`
+	Risks           int64 `json:"risks"`
+	Drafts          int64 `json:"report_drafts"`
+	Notifications   int64 `json:"notifications"`
+	RevokedTokens   int64 `json:"revoked_tokens"`
+	PeriodSummaries int64 `json:"period_summaries"`
+	Reports         int64 `json:"reports"`
+	Identities      int64 `json:"member_identities"`
+	ImportSnapshots int64 `json:"import_batch_snapshots"`
+	TeamSummaries   int64 `json:"team_period_summaries"` // team rollups covering the member's days; regenerated on demand
+	OwnedRisks      int64 `json:"owned_risks"`           // others' risks the member owned; only the owner is cleared
 }
 
-// CreateTeam inserts a new team.
-func (r *MemberRepo) CreateTeam(ctx context.Context, t *model.Team) error {
-	return r.db.WithContext(ctx).Create(t).Error
+// purgeTargets maps each table a member's rows are removed from to its count.
+func purgeTargets(c *PurgeCounts) []struct {
+	model any
+	n     *int64
+} {
+	return []struct {
+		model any
+		n     *int64
+	}{
+		{&model.DailyEntry{}, &c.DailyEntries},
+		{&model.DailySummary{}, &c.DailySummaries},
+		{&model.TopicActivity{}, &c.TopicActivities},
+		{&model.Risk{}, &c.Risks},
+		{&model.ReportDraft{}, &c.Drafts},
+		{&model.Notification{}, &c.Notifications},
+		{&model.RevokedToken{}, &c.RevokedTokens},
+		{&model.PeriodSummary{}, &c.PeriodSummaries},
+		{&model.Report{}, &c.Reports},
+		{&model.MemberIdentity{}, &c.Identities},
+		{&model.ImportBatchSnapshot{}, &c.ImportSnapshots},
+	}
 }
 
-// TeamMap returns a map of team ID → team name.
-func (r *MemberRepo) TeamMap(ctx context.Context) (map[int]string, error) {
-	teams, err := r.ListTeams(ctx)
-	if err != nil {
+// teamSummariesOf scopes the team-level period summaries (member_id 0) that
+// may quote the member's daily summaries: those of the member's team, of the
+// teams it is nested in and org-wide ones (team 0), for periods overlapping
+// the member's reports.
+func teamSummariesOf(db *gorm.DB, id int) (*gorm.DB, error) {
+	var m model.Member
+	if err := db.Session(&gorm.Session{NewDB: true}).Select("team_id").Where("id = ?", id).First(&m).Error; err != nil {
+		return nil, err
+	}
+	var teams []model.Team
+	if err := db.Session(&gorm.Session{NewDB: true}).Find(&teams).Error; err != nil {
 		return nil, err
 	}
-	m := make(map[int]string, len(teams))
-	for _, t := range teams {
-		m[t.ID] = t.Name
+	teamIDs := append(AncestorTeamIDs(teams, m.TeamID), 0)
+	return db.Where("member_id = 0 AND team_id IN ? AND period_end >= (SELECT MIN(daily_date) FROM daily_summaries WHERE member_id = ?) AND period_start <= (SELECT MAX(daily_date) FROM daily_summaries WHERE member_id = ?)", teamIDs, id, id), nil
+}
+
+// CountPurge reports what Purge would remove, without changing anything.
+func (r *MemberRepo) CountPurge(ctx context.Context, id int) (PurgeCounts, error) {
+	var c PurgeCounts
+	db := r.db.WithContext(ctx)
+	q, err := teamSummariesOf(db.Model(&model.PeriodSummary{}), id)
+	if err != nil {
+		return c, err
+	}
+	if err := q.Count(&c.TeamSummaries).Error; err != nil {
+		return c, err
 	}
-	return m, nil
+	for _, t := range purgeTargets(&c) {
+		if err := db.Model(t.model).Where("member_id = ?", id).Count(t.n).Error; err != nil {
+			return c, err
+		}
+	}
+	err = db.Model(&model.Risk{}).Where("owner_id = ? AND member_id != ?", id, id).Count(&c.OwnedRisks).Error
+	return c, err
+}
+
+// Purge permanently deletes a member with their reports, daily and period
+// summaries, saved weekly reports, topic activities, risks, drafts,
+// notifications, SSO bindings and import snapshots in one transaction, along
+// with the team summaries built from their reports. Feedback and the audit log are kept.
+func (r *MemberRepo) Purge(ctx context.Context, id int) (PurgeCounts, error) {
+	var c PurgeCounts
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		q, err := teamSummariesOf(tx, id)
+		if err != nil {
+			return err
+		}
+		res := q.Delete(&model.PeriodSummary{})
+		if res.Error != nil {
+			return res.Error
+		}
+		c.TeamSummaries = res.RowsAffected
+		for _, t := range purgeTargets(&c) {
+			res := tx.Where("member_id = ?", id).Delete(t.model)
+			if res.Error != nil {
+				return res.Error
+			}
+			*t.n = res.RowsAffected
+		}
+		res = tx.Model(&model.Risk{}).Where("owner_id = ?", id).Update("owner_id", 0)
+		if res.Error != nil {
+			return res.Error
+		}
+		c.OwnedRisks = res.RowsAffected
+		return tx.Delete(&model.Member{}, id).Error
+	})
+	return c, err
 }
 
 // MatchByName finds a member ID by name. Exact match first, then substring match. Returns 0 if not found.
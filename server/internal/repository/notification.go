@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"smart-daily/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type NotificationRepo struct{ db *gorm.DB }
+
+func NewNotificationRepo(db *gorm.DB) *NotificationRepo { return &NotificationRepo{db: db} }
+
+func (r *NotificationRepo) BatchCreate(ctx context.Context, items []model.Notification) error {
+	if len(items) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).CreateInBatches(&items, 200).Error
+}
+
+// List returns a member's most recent notifications, optionally unread only.
+func (r *NotificationRepo) List(ctx context.Context, memberID int, unreadOnly bool, limit int) ([]model.Notification, error) {
+	q := r.db.WithContext(ctx).Where("member_id = ?", memberID)
+	if unreadOnly {
+		q = q.Where("read_at IS NULL")
+	}
+	var items []model.Notification
+	err := q.Order("id DESC").Limit(limit).Find(&items).Error
+	return items, err
+}
+
+// UnreadCount returns how many notifications a member has not read.
+func (r *NotificationRepo) UnreadCount(ctx context.Context, memberID int) (int64, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Model(&model.Notification{}).Where("member_id = ? AND read_at IS NULL", memberID).Count(&n).Error
+	return n, err
+}
+
+// MarkRead marks a member's notification as read; id 0 marks all of them.
+func (r *NotificationRepo) MarkRead(ctx context.Context, memberID, id int) (bool, error) {
+	q := r.db.WithContext(ctx).Model(&model.Notification{}).Where("member_id = ? AND read_at IS NULL", memberID)
+	if id > 0 {
+		q = q.Where("id = ?", id)
+	}
+	res := q.Update("read_at", time.Now())
+	return res.RowsAffected > 0, res.Error
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"smart-daily/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type PeriodSummaryRepo struct{ db *gorm.DB }
+
+func NewPeriodSummaryRepo(db *gorm.DB) *PeriodSummaryRepo { return &PeriodSummaryRepo{db: db} }
+
+// Find returns the cached summary of a member or team for a period.
+func (r *PeriodSummaryRepo) Find(ctx context.Context, memberID, teamID int, periodType, start, end string) (*model.PeriodSummary, error) {
+	var s model.PeriodSummary
+	err := r.db.WithContext(ctx).
+		Where("member_id = ? AND team_id = ? AND period_type = ? AND period_start = ? AND period_end = ?", memberID, teamID, periodType, start, end).
+		First(&s).Error
+	return &s, err
+}
+
+// Upsert creates or replaces the summary for s's member/team and period and sets s.ID.
+func (r *PeriodSummaryRepo) Upsert(ctx context.Context, s *model.PeriodSummary) error {
+	existing, err := r.Find(ctx, s.MemberID, s.TeamID, s.PeriodType, s.PeriodStart, s.PeriodEnd)
+	if err == gorm.ErrRecordNotFound {
+		return r.db.WithContext(ctx).Create(s).Error
+	}
+	if err != nil {
+		return fmt.Errorf("query period summary: %w", err)
+	}
+	s.ID = existing.ID
+	return r.db.WithContext(ctx).Model(existing).Updates(map[string]interface{}{
+		"content": s.Content, "source_hash": s.SourceHash,
+	}).Error
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"smart-daily/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type ReportRepo struct{ db *gorm.DB }
+
+func NewReportRepo(db *gorm.DB) *ReportRepo { return &ReportRepo{db: db} }
+
+// withMember selects reports with the member's name.
+func (r *ReportRepo) withMember(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Model(&model.Report{}).
+		Select("reports.*, members.name AS member_name").
+		Joins("LEFT JOIN members ON members.id = reports.member_id")
+}
+
+// Get returns a report with its content.
+func (r *ReportRepo) Get(ctx context.Context, id int) (*model.Report, error) {
+	var rep model.Report
+	err := r.withMember(ctx).Where("reports.id = ?", id).First(&rep).Error
+	trimReportDates(&rep)
+	return &rep, err
+}
+
+// Find returns the member's report for exactly start..end.
+func (r *ReportRepo) Find(ctx context.Context, memberID int, start, end string) (*model.Report, error) {
+	var rep model.Report
+	err := r.withMember(ctx).
+		Where("reports.member_id = ? AND reports.period_start = ? AND reports.period_end = ?", memberID, start, end).
+		First(&rep).Error
+	trimReportDates(&rep)
+	return &rep, err
+}
+
+// ListFor returns reports about member uid or generated by uid, latest period
+// first, without content.
+func (r *ReportRepo) ListFor(ctx context.Context, uid int) ([]model.Report, error) {
+	var reports []model.Report
+	err := r.withMember(ctx).
+		Select("reports.id, reports.member_id, reports.created_by, reports.period_start, reports.period_end, reports.title, reports.edited, reports.created_at, reports.updated_at, members.name AS member_name").
+		Where("reports.member_id = ? OR reports.created_by = ?", uid, uid).
+		Order("reports.period_end DESC, reports.id DESC").Find(&reports).Error
+	for i := range reports {
+		trimReportDates(&reports[i])
+	}
+	return reports, err
+}
+
+// Upsert creates or regenerates the report for rep's member and period and
+// sets rep.ID; regenerating replaces content and clears the edited mark, so
+// callers keep edited reports away from it.
+func (r *ReportRepo) Upsert(ctx context.Context, rep *model.Report) error {
+	existing, err := r.Find(ctx, rep.MemberID, rep.PeriodStart, rep.PeriodEnd)
+	if err == gorm.ErrRecordNotFound {
+		return r.db.WithContext(ctx).Create(rep).Error
+	}
+	if err != nil {
+		return fmt.Errorf("query report: %w", err)
+	}
+	rep.ID = existing.ID
+	return r.db.WithContext(ctx).Model(&model.Report{ID: existing.ID}).Updates(map[string]interface{}{
+		"created_by": rep.CreatedBy, "title": rep.Title, "content": rep.Content,
+		"source_hash": rep.SourceHash, "edited": false,
+	}).Error
+}
+
+// Update sets the given fields on a report.
+func (r *ReportRepo) Update(ctx context.Context, id int, updates map[string]interface{}) error {
+	return r.db.WithContext(ctx).Model(&model.Report{}).Where("id = ?", id).Updates(updates).Error
+}
+
+func trimReportDates(rep *model.Report) {
+	if len(rep.PeriodStart) > 10 {
+		rep.PeriodStart = rep.PeriodStart[:10]
+	}
+	if len(rep.PeriodEnd) > 10 {
+		rep.PeriodEnd = rep.PeriodEnd[:10]
+	}
+}
@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"context"
+	"smart-daily/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type RiskRepo struct{ db *gorm.DB }
+
+func NewRiskRepo(db *gorm.DB) *RiskRepo { return &RiskRepo{db: db} }
+
+// RiskFilter narrows List; zero values match everything.
+type RiskFilter struct {
+	Status   string
+	Severity string
+	Category string
+	MemberID int
+	OwnerID  int
+	TopicID  int
+	Start    string
+	End      string
+	TeamIDs  []int // reported by members of any of these teams
+	ViewerID int   // with TeamIDs: also risks reported by or assigned to this member; alone: only those
+}
+
+// withTopic selects risks with the linked topic name.
+func (r *RiskRepo) withTopic(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Model(&model.Risk{}).
+		Select("risks.*, topics.name AS topic").
+		Joins("LEFT JOIN topics ON topics.id = risks.topic_id")
+}
+
+func (r *RiskRepo) BatchCreate(ctx context.Context, items []model.Risk) error {
+	if len(items) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).CreateInBatches(&items, 200).Error
+}
+
+// Get returns a risk by ID.
+func (r *RiskRepo) Get(ctx context.Context, id int) (*model.Risk, error) {
+	var risk model.Risk
+	if err := r.withTopic(ctx).Where("risks.id = ?", id).First(&risk).Error; err != nil {
+		return nil, err
+	}
+	trimRiskDate(&risk)
+	return &risk, nil
+}
+
+// List returns risks matching f, most severe and most recent first.
+func (r *RiskRepo) List(ctx context.Context, f RiskFilter) ([]model.Risk, error) {
+	q := r.withTopic(ctx)
+	if f.Status != "" {
+		q = q.Where("risks.status = ?", f.Status)
+	}
+	if f.Severity != "" {
+		q = q.Where("risks.severity = ?", f.Severity)
+	}
+	if f.Category != "" {
+		q = q.Where("risks.category = ?", f.Category)
+	}
+	if f.MemberID > 0 {
+		q = q.Where("risks.member_id = ?", f.MemberID)
+	}
+	if f.OwnerID > 0 {
+		q = q.Where("risks.owner_id = ?", f.OwnerID)
+	}
+	if f.TopicID > 0 {
+		q = q.Where("risks.topic_id = ?", f.TopicID)
+	}
+	if f.Start != "" && f.End != "" {
+		q = q.Where("risks.daily_date BETWEEN ? AND ?", f.Start, f.End)
+	}
+	switch {
+	case f.ViewerID > 0 && len(f.TeamIDs) > 0:
+		q = q.Where("(risks.member_id = ? OR risks.owner_id = ? OR risks.member_id IN (SELECT id FROM members WHERE team_id IN ?))",
+			f.ViewerID, f.ViewerID, f.TeamIDs)
+	case f.ViewerID > 0:
+		q = q.Where("(risks.member_id = ? OR risks.owner_id = ?)", f.ViewerID, f.ViewerID)
+	default:
+		q = q.Scopes(memberInTeams("risks.member_id", f.TeamIDs))
+	}
+	var risks []model.Risk
+	err := q.Order("CASE risks.severity WHEN 'high' THEN 0 WHEN 'medium' THEN 1 ELSE 2 END, risks.daily_date DESC, risks.id DESC").
+		Find(&risks).Error
+	for i := range risks {
+		trimRiskDate(&risks[i])
+	}
+	return risks, err
+}
+
+// ListForDay returns the risks reported by a member on a date.
+func (r *RiskRepo) ListForDay(ctx context.Context, memberID int, date string) ([]model.Risk, error) {
+	var risks []model.Risk
+	err := r.db.WithContext(ctx).Where("member_id = ? AND daily_date = ?", memberID, date).Order("id").Find(&risks).Error
+	return risks, err
+}
+
+// ListByEntry returns the risks filed for an entry, in any status.
+func (r *RiskRepo) ListByEntry(ctx context.Context, entryID int) ([]model.Risk, error) {
+	var risks []model.Risk
+	err := r.db.WithContext(ctx).Where("entry_id = ?", entryID).Order("id").Find(&risks).Error
+	return risks, err
+}
+
+// ListByEntries returns the risks filed for any of the entries.
+func (r *RiskRepo) ListByEntries(ctx context.Context, entryIDs []int) ([]model.Risk, error) {
+	var risks []model.Risk
+	if len(entryIDs) == 0 {
+		return risks, nil
+	}
+	err := r.db.WithContext(ctx).Where("entry_id IN ?", entryIDs).Order("id").Find(&risks).Error
+	for i := range risks {
+		trimRiskDate(&risks[i])
+	}
+	return risks, err
+}
+
+// ListOpenForActiveTopics returns open risks linked to active topics (last 90
+// days), reported by members of teamIDs only unless that is empty and by
+// memberID only unless that is zero.
+func (r *RiskRepo) ListOpenForActiveTopics(ctx context.Context, teamIDs []int, memberID int) ([]model.Risk, error) {
+	var risks []model.Risk
+	cutoff := time.Now().AddDate(0, 0, -90).Format("2006-01-02")
+	err := r.withTopic(ctx).Scopes(memberInTeams("risks.member_id", teamIDs), memberOnly("risks.member_id", memberID)).
+		Where("risks.status = 'open' AND topics.status = 'active' AND risks.daily_date >= ?", cutoff).
+		Order("risks.daily_date DESC").Find(&risks).Error
+	for i := range risks {
+		trimRiskDate(&risks[i])
+	}
+	return risks, err
+}
+
+// Update sets the given fields on a risk.
+func (r *RiskRepo) Update(ctx context.Context, id int, updates map[string]interface{}) error {
+	return r.db.WithContext(ctx).Model(&model.Risk{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// LinkTopic attaches an entry's not-yet-linked risks to a topic.
+func (r *RiskRepo) LinkTopic(ctx context.Context, entryID, topicID int) error {
+	return r.db.WithContext(ctx).Model(&model.Risk{}).Where("entry_id = ? AND topic_id = 0", entryID).
+		Update("topic_id", topicID).Error
+}
+
+// LegacyRiskRow is a daily_summaries.risk text written before risks were tracked.
+type LegacyRiskRow struct {
+	MemberID   int
+	MemberName string
+	DailyDate  string
+	Risk       string
+	EntryID    int // latest entry of the day
+	TopicID    int // first topic of the day
+}
+
+// ListLegacyRisks returns summaries with risk text when the risks table is still empty.
+func (r *RiskRepo) ListLegacyRisks(ctx context.Context) ([]LegacyRiskRow, error) {
+	var n int64
+	if err := r.db.WithContext(ctx).Model(&model.Risk{}).Count(&n).Error; err != nil || n > 0 {
+		return nil, err
+	}
+	var rows []LegacyRiskRow
+	err := r.db.WithContext(ctx).Raw(`SELECT ds.member_id, m.name AS member_name, ds.daily_date, ds.risk,
+		COALESCE((SELECT MAX(e.id) FROM daily_entries e WHERE e.member_id = ds.member_id AND e.daily_date = ds.daily_date), 0) AS entry_id,
+		COALESCE((SELECT MIN(t.id) FROM topic_activities ta JOIN topics t ON t.name = ta.topic
+			WHERE ta.member_id = ds.member_id AND ta.daily_date = ds.daily_date), 0) AS topic_id
+		FROM daily_summaries ds JOIN members m ON m.id = ds.member_id
+		WHERE ds.risk IS NOT NULL AND ds.risk != ''`).Scan(&rows).Error
+	for i := range rows {
+		if len(rows[i].DailyDate) > 10 {
+			rows[i].DailyDate = rows[i].DailyDate[:10]
+		}
+	}
+	return rows, err
+}
+
+func trimRiskDate(r *model.Risk) {
+	if len(r.DailyDate) > 10 {
+		r.DailyDate = r.DailyDate[:10]
+	}
+}
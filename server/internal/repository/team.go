@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"smart-daily/internal/authz"
+	"smart-daily/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// ListTeams returns all teams ordered by name.
+func (r *MemberRepo) ListTeams(ctx context.Context) ([]model.Team, error) {
+	var teams []model.Team
+	err := r.db.WithContext(ctx).Order("name").Find(&teams).Error
+	return teams, err
+}
+
+// CreateTeam inserts a new team.
+func (r *MemberRepo) CreateTeam(ctx context.Context, t *model.Team) error {
+	return r.db.WithContext(ctx).Create(t).Error
+}
+
+// FindTeam returns a team by ID.
+func (r *MemberRepo) FindTeam(ctx context.Context, id int) (*model.Team, error) {
+	var t model.Team
+	err := r.db.WithContext(ctx).First(&t, id).Error
+	return &t, err
+}
+
+// UpdateTeam sets the given fields on a team. Changing lead_id promotes a new
+// lead holding member rights to team_lead and demotes the previous lead to
+// member, in the same transaction.
+func (r *MemberRepo) UpdateTeam(ctx context.Context, id int, updates map[string]interface{}) error {
+	leadID, changesLead := updates["lead_id"]
+	if !changesLead {
+		return r.db.WithContext(ctx).Model(&model.Team{}).Where("id = ?", id).Updates(updates).Error
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var old model.Team
+		if err := tx.First(&old, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Team{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Member{}).Where("id = ? AND access_role = ?", leadID, authz.RoleMember).
+			Update("access_role", authz.RoleTeamLead).Error; err != nil {
+			return err
+		}
+		if old.LeadID == leadID {
+			return nil
+		}
+		return demoteFormerLeads(tx, old.LeadID)
+	})
+}
+
+// TeamMap returns a map of team ID → team name.
+func (r *MemberRepo) TeamMap(ctx context.Context) (map[int]string, error) {
+	teams, err := r.ListTeams(ctx)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[int]string, len(teams))
+	for _, t := range teams {
+		m[t.ID] = t.Name
+	}
+	return m, nil
+}
+
+// SubtreeTeamIDs returns root followed by every team nested below it.
+func (r *MemberRepo) SubtreeTeamIDs(ctx context.Context, root int) ([]int, error) {
+	teams, err := r.ListTeams(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return append([]int{root}, SubTeamIDs(teams, root)...), nil
+}
+
+// CountTeamMembers returns the number of active members in a team.
+func (r *MemberRepo) CountTeamMembers(ctx context.Context, id int) (int64, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Model(&model.Member{}).Scopes(model.ActiveMembers).
+		Where("team_id = ?", id).Count(&n).Error
+	return n, err
+}
+
+// DeleteTeam removes a team; deleted members still pointing at it are left
+// without a team. Callers check that no active member or sub-team remains.
+func (r *MemberRepo) DeleteTeam(ctx context.Context, id int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Member{}).Where("team_id = ?", id).Update("team_id", 0).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Team{}, id).Error
+	})
+}
+
+// MergeTeam moves every member and sub-team of source into target, hands
+// source's lead to target if target has none, and deletes source.
+func (r *MemberRepo) MergeTeam(ctx context.Context, sourceID, targetID int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var source, target model.Team
+		if err := tx.First(&source, sourceID).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&target, targetID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Member{}).Where("team_id = ?", sourceID).Update("team_id", targetID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Team{}).Where("parent_id = ?", sourceID).Update("parent_id", targetID).Error; err != nil {
+			return err
+		}
+		if target.LeadID == 0 && source.LeadID != 0 {
+			if err := tx.Model(&model.Team{}).Where("id = ?", targetID).Update("lead_id", source.LeadID).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Delete(&model.Team{}, sourceID).Error; err != nil {
+			return err
+		}
+		return demoteFormerLeads(tx, source.LeadID)
+	})
+}
+
+// LeadsTeam reports whether memberID is the designated lead of teamID.
+func LeadsTeam(teams []model.Team, memberID, teamID int) bool {
+	return memberID != 0 && slices.ContainsFunc(teams, func(t model.Team) bool { return t.ID == teamID && t.LeadID == memberID })
+}
+
+// demoteFormerLeads turns those of ids holding team_lead that lead no team
+// anymore into members.
+func demoteFormerLeads(tx *gorm.DB, ids ...int) error {
+	ids = slices.DeleteFunc(ids, func(id int) bool { return id == 0 })
+	if len(ids) == 0 {
+		return nil
+	}
+	return tx.Model(&model.Member{}).
+		Where("id IN ? AND access_role = ? AND id NOT IN (SELECT lead_id FROM teams)", ids, authz.RoleTeamLead).
+		Update("access_role", authz.RoleMember).Error
+}
+
+// AncestorTeamIDs returns id followed by the teams it is nested in, nearest
+// first; nil for team 0. A parent loop in the data does not make it run forever.
+func AncestorTeamIDs(teams []model.Team, id int) []int {
+	parent := make(map[int]int, len(teams))
+	for _, t := range teams {
+		parent[t.ID] = t.ParentID
+	}
+	var out []int
+	for id != 0 && !slices.Contains(out, id) {
+		out = append(out, id)
+		id = parent[id]
+	}
+	return out
+}
+
+// SubTeamIDs returns the IDs of all teams nested below root, breadth first.
+// A parent loop in the data does not make it run forever.
+func SubTeamIDs(teams []model.Team, root int) []int {
+	children := map[int][]int{}
+	for _, t := range teams {
+		if t.ParentID != 0 && t.ParentID != t.ID {
+			children[t.ParentID] = append(children[t.ParentID], t.ID)
+		}
+	}
+	var out []int
+	queue := []int{root}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, c := range children[id] {
+			if c != root && !slices.Contains(out, c) {
+				out = append(out, c)
+				queue = append(queue, c)
+			}
+		}
+	}
+	return out
+}
+
+// memberInTeams narrows a query to rows whose column, a member ID, belongs to
+// a member of one of teamIDs; no teams leaves the query unchanged.
+func memberInTeams(column string, teamIDs []int) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if len(teamIDs) == 0 {
+			return db
+		}
+		return db.Where(column+" IN (SELECT id FROM members WHERE team_id IN ?)", teamIDs)
+	}
+}
+
+// memberOnly narrows a query to rows whose column is memberID; zero leaves the
+// query unchanged.
+func memberOnly(column string, memberID int) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if memberID == 0 {
+			return db
+		}
+		return db.Where(column+" = ?", memberID)
+	}
+}
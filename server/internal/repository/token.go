@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"smart-daily/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TokenRepo records revoked login tokens (logout / force logout).
+type TokenRepo struct{ db *gorm.DB }
+
+func NewTokenRepo(db *gorm.DB) *TokenRepo { return &TokenRepo{db: db} }
+
+// Revoke blocks a single token until it would have expired anyway.
+func (r *TokenRepo) Revoke(ctx context.Context, jti string, memberID int, expiresAt time.Time, by int) error {
+	return r.db.WithContext(ctx).Create(&model.RevokedToken{JTI: jti, MemberID: memberID, ExpiresAt: expiresAt, RevokedBy: by}).Error
+}
+
+// RevokeMember blocks every token of a member issued up to now, including the
+// current second (token iat has whole seconds); ttl is the longest a token
+// lives, after which the row is no longer needed.
+func (r *TokenRepo) RevokeMember(ctx context.Context, memberID int, ttl time.Duration, by int) error {
+	now := time.Now().Truncate(time.Second) // a DATETIME column would round it up
+	return r.db.WithContext(ctx).Create(&model.RevokedToken{MemberID: memberID, IssuedBefore: &now, ExpiresAt: now.Add(ttl), RevokedBy: by}).Error
+}
+
+// IsRevoked implements middleware.RevocationStore.
+func (r *TokenRepo) IsRevoked(ctx context.Context, jti string, memberID int, issuedAt time.Time) (bool, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Model(&model.RevokedToken{}).
+		Where("jti = ? OR (member_id = ? AND jti = '' AND issued_before >= ?)", jti, memberID, issuedAt).
+		Count(&n).Error
+	return n > 0, err
+}
+
+// PurgeExpired deletes rows whose blocked tokens have all expired.
+func (r *TokenRepo) PurgeExpired(ctx context.Context) (int64, error) {
+	res := r.db.WithContext(ctx).Where("expires_at < ?", time.Now()).Delete(&model.RevokedToken{})
+	return res.RowsAffected, res.Error
+}
@@ -2,16 +2,24 @@ package repository
 
 import (
 	"context"
+	"slices"
+	"smart-daily/internal/events"
 	"smart-daily/internal/model"
 	"time"
 
 	"gorm.io/gorm"
 )
 
-type TopicRepo struct{ db *gorm.DB }
+type TopicRepo struct {
+	db     *gorm.DB
+	events events.Publisher
+}
 
 func NewTopicRepo(db *gorm.DB) *TopicRepo { return &TopicRepo{db: db} }
 
+// SetPublisher enables topic.resolved / topic.reopened / topic.merged events.
+func (r *TopicRepo) SetPublisher(p events.Publisher) { r.events = p }
+
 // --- topic_activities ---
 
 func (r *TopicRepo) BatchCreate(ctx context.Context, items []model.TopicActivity) error {
@@ -29,9 +37,19 @@ func (r *TopicRepo) BatchCreate(ctx context.Context, items []model.TopicActivity
 		}
 	}
 	for name, maxDate := range topicNames {
-		r.db.WithContext(ctx).Model(&model.Topic{}).
+		var t model.Topic
+		if err := r.db.WithContext(ctx).
 			Where("name = ? AND status = 'resolved' AND resolved_at IS NOT NULL AND resolved_at < ?", name, maxDate).
+			First(&t).Error; err != nil {
+			continue
+		}
+		res := r.db.WithContext(ctx).Model(&model.Topic{}).Where("id = ? AND status = 'resolved'", t.ID).
 			Updates(map[string]interface{}{"status": "active", "resolved_at": nil})
+		if res.Error == nil && res.RowsAffected > 0 {
+			events.Publish(ctx, r.events, events.TopicReopened, map[string]any{
+				"topic_id": t.ID, "topic": t.Name, "auto": true, "activity_date": maxDate,
+			})
+		}
 	}
 	return nil
 }
@@ -50,32 +68,64 @@ func (r *TopicRepo) ListDistinctTopics(ctx context.Context) ([]string, error) {
 	return topics, err
 }
 
-func (r *TopicRepo) ListByDateRange(ctx context.Context, start, end string) ([]model.TopicActivity, error) {
+// ListByDateRange returns topic activities in a date range, of members in
+// teamIDs only unless that is empty, and of memberID only unless that is zero.
+func (r *TopicRepo) ListByDateRange(ctx context.Context, start, end string, teamIDs []int, memberID int) ([]model.TopicActivity, error) {
 	var items []model.TopicActivity
-	err := r.db.WithContext(ctx).Where("daily_date BETWEEN ? AND ?", start, end).
+	err := r.db.WithContext(ctx).Scopes(memberInTeams("member_id", teamIDs), memberOnly("member_id", memberID)).
+		Where("daily_date BETWEEN ? AND ?", start, end).
 		Order("topic, daily_date DESC").Find(&items).Error
 	return items, err
 }
 
 // ListByMemberAndDateRange returns summaries for active members in a date range.
-func (r *TopicRepo) ListSummariesByDateRange(ctx context.Context, start, end string) ([]MemberDailySummary, error) {
+func (r *TopicRepo) ListSummariesByDateRange(ctx context.Context, start, end string, f SummaryFilter) ([]MemberDailySummary, error) {
 	var rows []MemberDailySummary
-	err := r.db.WithContext(ctx).Model(&model.DailySummary{}).
-		Select("daily_summaries.member_id, members.name as member_name, daily_summaries.daily_date, daily_summaries.summary, daily_summaries.risk").
+	q := r.db.WithContext(ctx).Model(&model.DailySummary{}).
+		Select("daily_summaries.member_id, members.name as member_name, daily_summaries.daily_date, daily_summaries.summary, daily_summaries.status, daily_summaries.risk, daily_summaries.blocker").
 		Joins("JOIN members ON members.id = daily_summaries.member_id").
 		Scopes(model.ActiveMembers).
-		Where("daily_summaries.daily_date BETWEEN ? AND ?", start, end).
-		Order("members.name, daily_summaries.daily_date DESC").
-		Scan(&rows).Error
+		Where("daily_summaries.daily_date BETWEEN ? AND ?", start, end)
+	if len(f.TeamIDs) > 0 {
+		q = q.Where("members.team_id IN ?", f.TeamIDs)
+	}
+	if f.MemberID > 0 {
+		q = q.Where("daily_summaries.member_id = ?", f.MemberID)
+	}
+	if len(f.Statuses) > 0 {
+		q = q.Where("daily_summaries.status IN ?", f.Statuses)
+	}
+	if f.Blocked {
+		q = q.Where("daily_summaries.blocker IS NOT NULL AND daily_summaries.blocker != ''")
+	}
+	err := q.Order("members.name, daily_summaries.daily_date DESC").Scan(&rows).Error
 	return rows, err
 }
 
+// SummaryFilter narrows ListSummariesByDateRange; zero values match everything.
+type SummaryFilter struct {
+	Statuses []string // daily_summaries.status in any of these
+	Blocked  bool     // only days that report a blocker
+	TeamIDs  []int    // members of any of these teams
+	MemberID int      // only this member
+}
+
+// Matches reports whether a day summary passes the Statuses and Blocked filters.
+func (f SummaryFilter) Matches(s *model.DailySummary) bool {
+	if len(f.Statuses) > 0 && !slices.Contains(f.Statuses, s.Status) {
+		return false
+	}
+	return !f.Blocked || s.Blocker != ""
+}
+
 type MemberDailySummary struct {
 	MemberID   int    `json:"member_id"`
 	MemberName string `json:"member_name"`
 	DailyDate  string `json:"daily_date"`
 	Summary    string `json:"summary"`
+	Status     string `json:"status"`
 	Risk       string `json:"risk"`
+	Blocker    string `json:"blocker"`
 }
 
 // MergeTopicActivities renames all activities from oldTopic to newTopic.
@@ -93,6 +143,31 @@ func (r *TopicRepo) EnsureTopics(ctx context.Context, names []string) {
 	}
 }
 
+// TopicID returns the ID of a topic by name.
+func (r *TopicRepo) TopicID(ctx context.Context, name string) (int, error) {
+	var t model.Topic
+	err := r.db.WithContext(ctx).Select("id").Where("name = ?", name).First(&t).Error
+	return t.ID, err
+}
+
+// TeamIDs returns the distinct teams of the members who reported on a topic,
+// matched by name so it also works for a merge target that has no ID yet.
+func (r *TopicRepo) TeamIDs(ctx context.Context, topic string) ([]int, error) {
+	var ids []int
+	err := r.db.WithContext(ctx).Model(&model.TopicActivity{}).
+		Joins("JOIN members ON members.id = topic_activities.member_id").
+		Where("topic_activities.topic = ?", topic).
+		Distinct("members.team_id").Pluck("members.team_id", &ids).Error
+	return ids, err
+}
+
+// TopicName returns the name of a topic by ID.
+func (r *TopicRepo) TopicName(ctx context.Context, id int) (string, error) {
+	var t model.Topic
+	err := r.db.WithContext(ctx).Select("name").First(&t, id).Error
+	return t.Name, err
+}
+
 func (r *TopicRepo) ListAllTopics(ctx context.Context) ([]model.Topic, error) {
 	var topics []model.Topic
 	err := r.db.WithContext(ctx).Order("CASE WHEN status = 'active' THEN 0 ELSE 1 END, name").Find(&topics).Error
@@ -105,19 +180,45 @@ func (r *TopicRepo) ListActiveTopicNames(ctx context.Context) ([]string, error)
 	return names, err
 }
 
+// FindTopic returns a topic by ID.
+func (r *TopicRepo) FindTopic(ctx context.Context, id int) (*model.Topic, error) {
+	var t model.Topic
+	err := r.db.WithContext(ctx).First(&t, id).Error
+	return &t, err
+}
+
 func (r *TopicRepo) UpdateTopic(ctx context.Context, id int, updates map[string]interface{}) error {
 	return r.db.WithContext(ctx).Model(&model.Topic{}).Where("id = ?", id).Updates(updates).Error
 }
 
 func (r *TopicRepo) ResolveTopic(ctx context.Context, id int) error {
 	now := time.Now()
-	return r.db.WithContext(ctx).Model(&model.Topic{}).Where("id = ?", id).
-		Updates(map[string]interface{}{"status": "resolved", "resolved_at": &now}).Error
+	if err := r.db.WithContext(ctx).Model(&model.Topic{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"status": "resolved", "resolved_at": &now}).Error; err != nil {
+		return err
+	}
+	r.publishTopic(ctx, events.TopicResolved, id)
+	return nil
 }
 
 func (r *TopicRepo) ReopenTopic(ctx context.Context, id int) error {
-	return r.db.WithContext(ctx).Model(&model.Topic{}).Where("id = ?", id).
-		Updates(map[string]interface{}{"status": "active", "resolved_at": nil}).Error
+	if err := r.db.WithContext(ctx).Model(&model.Topic{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"status": "active", "resolved_at": nil}).Error; err != nil {
+		return err
+	}
+	r.publishTopic(ctx, events.TopicReopened, id)
+	return nil
+}
+
+func (r *TopicRepo) publishTopic(ctx context.Context, event string, id int) {
+	if r.events == nil {
+		return
+	}
+	var t model.Topic
+	if err := r.db.WithContext(ctx).First(&t, id).Error; err != nil {
+		return
+	}
+	r.events.Publish(ctx, event, map[string]any{"topic_id": t.ID, "topic": t.Name, "status": t.Status, "auto": false})
 }
 
 func (r *TopicRepo) DeleteTopic(ctx context.Context, id int) error {
@@ -133,8 +234,19 @@ func (r *TopicRepo) MergeTopic(ctx context.Context, sourceID int, targetName str
 	if err := r.MergeTopicActivities(ctx, source.Name, targetName); err != nil {
 		return err
 	}
+	// Re-link risks to the target topic
+	var target model.Topic
+	if err := r.db.WithContext(ctx).Where("name = ?", targetName).First(&target).Error; err == nil {
+		r.db.WithContext(ctx).Model(&model.Risk{}).Where("topic_id = ?", source.ID).Update("topic_id", target.ID)
+	}
 	// Delete source topic
-	return r.db.WithContext(ctx).Delete(&source).Error
+	if err := r.db.WithContext(ctx).Delete(&source).Error; err != nil {
+		return err
+	}
+	events.Publish(ctx, r.events, events.TopicMerged, map[string]any{
+		"source_id": source.ID, "source": source.Name, "target_id": target.ID, "target": targetName,
+	})
+	return nil
 }
 
 // --- insights ---
@@ -149,37 +261,19 @@ type TopicInsight struct {
 	EntryCnt  int    `json:"entry_count"`
 }
 
-// ListInsights returns aggregated stats for active topics (only recently active ones).
-func (r *TopicRepo) ListInsights(ctx context.Context) ([]TopicInsight, error) {
+// ListInsights returns aggregated stats for active topics (only recently active
+// ones), counting only members of teamIDs unless that is empty and only
+// memberID unless that is zero.
+func (r *TopicRepo) ListInsights(ctx context.Context, teamIDs []int, memberID int) ([]TopicInsight, error) {
 	var results []TopicInsight
 	cutoff := time.Now().AddDate(0, 0, -90).Format("2006-01-02")
 	err := r.db.WithContext(ctx).Model(&model.TopicActivity{}).
 		Select("topics.id as topic_id, topic_activities.topic, MIN(topic_activities.daily_date) as first_date, MAX(topic_activities.daily_date) as last_date, COUNT(DISTINCT topic_activities.daily_date) as days, COUNT(DISTINCT topic_activities.member_id) as member_cnt, COUNT(*) as entry_cnt").
 		Joins("JOIN topics ON topics.name = topic_activities.topic AND topics.status = 'active'").
+		Scopes(memberInTeams("topic_activities.member_id", teamIDs), memberOnly("topic_activities.member_id", memberID)).
 		Where("topic_activities.daily_date >= ?", cutoff).
 		Group("topics.id, topic_activities.topic").
 		Order("days DESC, member_cnt DESC").
 		Find(&results).Error
 	return results, err
 }
-
-// ListTopicRisks returns risk items associated with active topics (last 90 days).
-func (r *TopicRepo) ListTopicRisks(ctx context.Context) ([]TopicRiskItem, error) {
-	var results []TopicRiskItem
-	cutoff := time.Now().AddDate(0, 0, -90).Format("2006-01-02")
-	err := r.db.WithContext(ctx).Model(&model.TopicActivity{}).
-		Select("topic_activities.topic, topic_activities.member_name, topic_activities.daily_date, daily_summaries.risk").
-		Joins("JOIN topics ON topics.name = topic_activities.topic AND topics.status = 'active'").
-		Joins("JOIN daily_summaries ON daily_summaries.member_id = topic_activities.member_id AND daily_summaries.daily_date = topic_activities.daily_date").
-		Where("daily_summaries.risk != '' AND daily_summaries.risk IS NOT NULL AND topic_activities.daily_date >= ?", cutoff).
-		Order("topic_activities.daily_date DESC").
-		Scan(&results).Error
-	return results, err
-}
-
-type TopicRiskItem struct {
-	Topic      string `json:"topic"`
-	MemberName string `json:"member_name"`
-	DailyDate  string `json:"daily_date"`
-	Risk       string `json:"risk"`
-}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"smart-daily/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type WebhookRepo struct{ db *gorm.DB }
+
+func NewWebhookRepo(db *gorm.DB) *WebhookRepo { return &WebhookRepo{db: db} }
+
+func (r *WebhookRepo) List(ctx context.Context) ([]model.Webhook, error) {
+	var hooks []model.Webhook
+	err := r.db.WithContext(ctx).Order("id").Find(&hooks).Error
+	return hooks, err
+}
+
+func (r *WebhookRepo) Get(ctx context.Context, id int) (*model.Webhook, error) {
+	var h model.Webhook
+	if err := r.db.WithContext(ctx).First(&h, id).Error; err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+func (r *WebhookRepo) Create(ctx context.Context, h *model.Webhook) error {
+	return r.db.WithContext(ctx).Create(h).Error
+}
+
+// Update saves a webhook's editable fields.
+func (r *WebhookRepo) Update(ctx context.Context, h *model.Webhook) error {
+	return r.db.WithContext(ctx).Model(h).Select("name", "url", "secret", "events", "active", "updated_at").Updates(h).Error
+}
+
+// Delete removes a webhook and its delivery log.
+func (r *WebhookRepo) Delete(ctx context.Context, id int) (bool, error) {
+	res := r.db.WithContext(ctx).Delete(&model.Webhook{}, id)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return false, res.Error
+	}
+	return true, r.db.WithContext(ctx).Where("webhook_id = ?", id).Delete(&model.WebhookDelivery{}).Error
+}
+
+// ListActiveFor returns active webhooks subscribed to event.
+func (r *WebhookRepo) ListActiveFor(ctx context.Context, event string) ([]model.Webhook, error) {
+	var hooks []model.Webhook
+	if err := r.db.WithContext(ctx).Where("active = ?", true).Find(&hooks).Error; err != nil {
+		return nil, err
+	}
+	out := hooks[:0]
+	for _, h := range hooks {
+		if slices.Contains(h.Events, "*") || slices.Contains(h.Events, event) {
+			out = append(out, h)
+		}
+	}
+	return out, nil
+}
+
+func (r *WebhookRepo) CreateDeliveries(ctx context.Context, items []model.WebhookDelivery) error {
+	if len(items) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&items).Error
+}
+
+// ClaimDueDeliveries returns pending deliveries whose next attempt is due,
+// oldest first, and pushes their next attempt to now+lease so another worker
+// does not send them as well. It also reports how many were due: rows claimed
+// by someone else in between are left out. A claimed delivery whose outcome is
+// never recorded is due again once the lease runs out.
+func (r *WebhookRepo) ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]model.WebhookDelivery, int, error) {
+	var due []model.WebhookDelivery
+	err := r.db.WithContext(ctx).Where("status = 'pending' AND next_attempt_at <= ?", now).
+		Order("next_attempt_at, id").Limit(limit).Find(&due).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	until := now.Add(lease)
+	claimed := due[:0]
+	for _, d := range due {
+		res := r.db.WithContext(ctx).Model(&model.WebhookDelivery{}).
+			Where("id = ? AND status = 'pending' AND next_attempt_at <= ?", d.ID, now).
+			Update("next_attempt_at", until)
+		if res.Error != nil {
+			return claimed, len(due), res.Error
+		}
+		if res.RowsAffected == 1 {
+			d.NextAttemptAt = &until
+			claimed = append(claimed, d)
+		}
+	}
+	return claimed, len(due), nil
+}
+
+func (r *WebhookRepo) UpdateDelivery(ctx context.Context, id int, updates map[string]interface{}) error {
+	return r.db.WithContext(ctx).Model(&model.WebhookDelivery{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// ListDeliveries returns a webhook's most recent deliveries.
+func (r *WebhookRepo) ListDeliveries(ctx context.Context, webhookID, limit int) ([]model.WebhookDelivery, error) {
+	var items []model.WebhookDelivery
+	err := r.db.WithContext(ctx).Where("webhook_id = ?", webhookID).Order("id DESC").Limit(limit).Find(&items).Error
+	return items, err
+}
@@ -0,0 +1,170 @@
+// Package scheduler runs jobs on cron-like schedules, checked once a minute.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"smart-daily/internal/logger"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Spec is a parsed 5-field cron expression: minute hour day-of-month month day-of-week.
+// Each field accepts *, numbers, ranges (1-5), lists (1,3,5) and steps (*/15, 0-30/10).
+// Day-of-week is 0-6 with 0 = Sunday (7 is accepted as Sunday too).
+type Spec struct {
+	fields [5]map[int]bool
+	expr   string
+}
+
+var fieldBounds = [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+
+// Parse parses a cron expression such as "30 17 * * 1-5".
+func Parse(expr string) (*Spec, error) {
+	parts := strings.Fields(expr)
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("cron %q: want 5 fields, got %d", expr, len(parts))
+	}
+	s := &Spec{expr: expr}
+	for i, p := range parts {
+		set, err := parseField(p, fieldBounds[i][0], fieldBounds[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("cron %q: %w", expr, err)
+		}
+		s.fields[i] = set
+	}
+	if s.fields[4][7] {
+		s.fields[4][0] = true
+	}
+	return s, nil
+}
+
+func parseField(f string, lo, hi int) (map[int]bool, error) {
+	set := map[int]bool{}
+	for _, part := range strings.Split(f, ",") {
+		rng, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("bad step in %q", part)
+			}
+			rng, step = part[:i], n
+		}
+		from, to := lo, hi
+		if rng != "*" {
+			a, b, isRange := strings.Cut(rng, "-")
+			var err error
+			if from, err = strconv.Atoi(a); err != nil {
+				return nil, fmt.Errorf("bad value %q", part)
+			}
+			to = from
+			if isRange {
+				if to, err = strconv.Atoi(b); err != nil {
+					return nil, fmt.Errorf("bad range %q", part)
+				}
+			} else if step > 1 {
+				to = hi // "5/10" means from 5 every 10
+			}
+		}
+		if from < lo || to > hi || from > to {
+			return nil, fmt.Errorf("%q out of range %d-%d", part, lo, hi)
+		}
+		for v := from; v <= to; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+// Match reports whether t (to the minute) is on the schedule. As in cron, when
+// both day-of-month and day-of-week are restricted, either may match.
+func (s *Spec) Match(t time.Time) bool {
+	if !s.fields[0][t.Minute()] || !s.fields[1][t.Hour()] || !s.fields[3][int(t.Month())] {
+		return false
+	}
+	dom, dow := s.fields[2][t.Day()], s.fields[4][int(t.Weekday())]
+	domAll, dowAll := len(s.fields[2]) == 31, len(s.fields[4]) == 8
+	switch {
+	case domAll && dowAll:
+		return true
+	case domAll:
+		return dow
+	case dowAll:
+		return dom
+	}
+	return dom || dow
+}
+
+func (s *Spec) String() string { return s.expr }
+
+type job struct {
+	name string
+	spec *Spec
+	run  func(ctx context.Context)
+}
+
+// Scheduler runs registered jobs when their spec matches the current minute.
+// A job still running from the previous match is skipped rather than stacked.
+type Scheduler struct {
+	mu      sync.Mutex
+	jobs    []job
+	running map[string]bool
+}
+
+func New() *Scheduler { return &Scheduler{running: map[string]bool{}} }
+
+// Add registers a job under a cron expression.
+func (s *Scheduler) Add(name, expr string, run func(ctx context.Context)) error {
+	spec, err := Parse(expr)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.jobs = append(s.jobs, job{name: name, spec: spec, run: run})
+	s.mu.Unlock()
+	logger.Info("scheduler: job added", "job", name, "cron", expr)
+	return nil
+}
+
+// Start checks the jobs at the top of every minute until ctx is done.
+func (s *Scheduler) Start(ctx context.Context) {
+	go func() {
+		for {
+			now := time.Now()
+			next := now.Truncate(time.Minute).Add(time.Minute)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(next.Sub(now)):
+				s.tick(ctx, next)
+			}
+		}
+	}()
+}
+
+func (s *Scheduler) tick(ctx context.Context, t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range s.jobs {
+		if !j.spec.Match(t) || s.running[j.name] {
+			continue
+		}
+		s.running[j.name] = true
+		go func(j job) {
+			defer func() {
+				if r := recover(); r != nil {
+					logger.Error("scheduler: job panicked", "job", j.name, "panic", r)
+				}
+				s.mu.Lock()
+				delete(s.running, j.name)
+				s.mu.Unlock()
+			}()
+			start := time.Now()
+			logger.Info("scheduler: job start", "job", j.name)
+			j.run(ctx)
+			logger.Info("scheduler: job done", "job", j.name, "elapsed", time.Since(start).Round(time.Millisecond))
+		}(j)
+	}
+}
@@ -0,0 +1,48 @@
+package scheduler_test
+
+import (
+	"testing"
+	"time"
+
+	"smart-daily/internal/scheduler"
+)
+
+func TestSpecMatch(t *testing.T) {
+	at := func(s string) time.Time {
+		tm, err := time.ParseInLocation("2006-01-02 15:04", s, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+	for _, c := range []struct {
+		expr string
+		when string
+		want bool
+	}{
+		{"30 17 * * 1-5", "2026-03-02 17:30", true},  // Monday
+		{"30 17 * * 1-5", "2026-03-01 17:30", false}, // Sunday
+		{"30 17 * * 1-5", "2026-03-02 17:31", false},
+		{"*/15 9-18 * * *", "2026-03-02 10:45", true},
+		{"*/15 9-18 * * *", "2026-03-02 10:50", false},
+		{"0 10 1,15 * *", "2026-03-15 10:00", true},
+		{"0 10 1 * 0", "2026-03-08 10:00", true}, // either day field matches
+		{"0 10 * * 7", "2026-03-08 10:00", true}, // 7 = Sunday
+	} {
+		spec, err := scheduler.Parse(c.expr)
+		if err != nil {
+			t.Fatalf("%s: %v", c.expr, err)
+		}
+		if got := spec.Match(at(c.when)); got != c.want {
+			t.Errorf("%s at %s: got %v, want %v", c.expr, c.when, got, c.want)
+		}
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "5-1 * * * *", "*/0 * * * *", "a * * * *"} {
+		if _, err := scheduler.Parse(expr); err == nil {
+			t.Errorf("%q: expected error", expr)
+		}
+	}
+}
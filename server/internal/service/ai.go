@@ -1,13 +1,10 @@
 package service
 
 import (
-	"bufio"
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"net/http"
 	"smart-daily/internal/logger"
 	"smart-daily/internal/model"
 	"sort"
@@ -19,18 +16,16 @@ import (
 )
 
 type AIService struct {
-	baseURL     string
-	apiKey      string
+	llm         LLMProvider
 	model       string
 	fastModel   string
 	dbName      string
 	catalogDBID int
-	client      *http.Client
 	raw         *sdk.RawClient
 }
 
-func NewAIService(baseURL, apiKey, model, fastModel, dbName string, raw *sdk.RawClient) *AIService {
-	return &AIService{baseURL: baseURL, apiKey: apiKey, model: model, fastModel: fastModel, dbName: dbName, client: &http.Client{}, raw: raw}
+func NewAIService(llm LLMProvider, dbName string, raw *sdk.RawClient) *AIService {
+	return &AIService{llm: llm, model: llm.Model(), fastModel: llm.FastModel(), dbName: dbName, raw: raw}
 }
 
 func (s *AIService) SetCatalogDBID(id int) { s.catalogDBID = id }
@@ -86,78 +81,10 @@ func (s *AIService) doChatWithHistory(ctx context.Context, model, system string,
 	msgs = append(msgs, history...)
 	msgs = append(msgs, map[string]string{"role": "user", "content": user})
 
-	body := map[string]interface{}{
-		"model":    model,
-		"stream":   stream,
-		"messages": msgs,
+	if stream {
+		return s.llm.Stream(ctx, model, msgs, flush)
 	}
-	payload, _ := json.Marshal(body)
-
-	req, err := http.NewRequestWithContext(ctx, "POST", s.baseURL+"/llm-proxy/v1/chat/completions", bytes.NewReader(payload))
-	if err != nil {
-		return "", fmt.Errorf("build request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("moi-key", s.apiKey)
-
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("llm call: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		data, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("llm status %d: %s", resp.StatusCode, data)
-	}
-
-	if !stream {
-		data, _ := io.ReadAll(resp.Body)
-		var result struct {
-			Choices []struct {
-				Message struct {
-					Content string `json:"content"`
-				} `json:"message"`
-			} `json:"choices"`
-		}
-		if err := json.Unmarshal(data, &result); err != nil {
-			return "", fmt.Errorf("decode response: %w", err)
-		}
-		if len(result.Choices) == 0 {
-			return "", fmt.Errorf("empty choices")
-		}
-		return result.Choices[0].Message.Content, nil
-	}
-
-	scanner := bufio.NewScanner(resp.Body)
-	var full strings.Builder
-	for scanner.Scan() {
-		line := scanner.Text()
-		if !strings.HasPrefix(line, "data: ") {
-			continue
-		}
-		data := line[6:]
-		if data == "[DONE]" {
-			break
-		}
-		var chunk struct {
-			Choices []struct {
-				Delta struct {
-					Content string `json:"content"`
-				} `json:"delta"`
-			} `json:"choices"`
-		}
-		if json.Unmarshal([]byte(data), &chunk) == nil && len(chunk.Choices) > 0 {
-			token := chunk.Choices[0].Delta.Content
-			if token != "" {
-				full.WriteString(token)
-				if flush != nil {
-					flush(token)
-				}
-			}
-		}
-	}
-	return full.String(), nil
+	return s.llm.Chat(ctx, model, msgs)
 }
 
 func (s *AIService) chat(ctx context.Context, system, user string) (string, error) {
@@ -275,35 +202,78 @@ func (s *AIService) StreamSummarize(ctx context.Context, content string, flush f
 	return result, nil
 }
 
-// DetectRisks 从摘要中检测风险项
-func (s *AIService) DetectRisks(ctx context.Context, summary string) ([]string, error) {
-	system := `从以下工作摘要中提取明确的风险项。只有以下情况才算风险：
-- 明确提到"阻塞"、"卡住"、"无法继续"
-- 明确提到"延期"、"来不及"、"deadline 赶不上"
-- 明确提到线上故障、生产环境问题仍未解决
-- 明确提到需要其他人/团队支持但未获得
+// AnalyzeReport 从摘要中提取工作状态、阻塞问题和风险项（带严重程度和分类）
+func (s *AIService) AnalyzeReport(ctx context.Context, summary string) (*model.ReportAnalysis, error) {
+	system := `分析以下工作摘要，判断工作状态并提取明确的阻塞问题和风险项。
+
+status 工作状态（四选一）：
+- off：请假、休假、调休，当天没有工作
+- blocked：有工作被明确阻塞、卡住、无法继续
+- at-risk：没有阻塞，但明确提到延期、来不及、线上故障未解决等风险
+- on-track：正常推进
+
+blockers 阻塞问题：只列出明确提到"阻塞"、"卡住"、"无法继续"、"等待某人/某事才能继续"的事项，每条一句话。
+
+risks 风险项，只有以下情况才算风险：
+- 明确提到"阻塞"、"卡住"、"无法继续"（category: blocked）
+- 明确提到"延期"、"来不及"、"deadline 赶不上"（category: delay）
+- 明确提到线上故障、生产环境问题仍未解决（category: incident）
+- 明确提到需要其他人/团队支持但未获得（category: dependency）
 
 以下情况不算风险：
 - 修复了 bug、解决了问题 — 这是正常工作成果
 - 任务进行中、完成一部分 — 正常进展
 - 计划明天做、下周做 — 正常排期
 
-返回 JSON：{"risks":["风险描述"]}，无风险则空数组。只返回 JSON。`
+severity：影响线上或整体交付为 high，影响个人任务进度为 medium，其余为 low。
+返回 JSON：{"status":"on-track|at-risk|blocked|off","blockers":["阻塞描述"],"risks":[{"description":"风险描述","severity":"high|medium|low","category":"blocked|delay|incident|dependency"}]}，无阻塞或无风险则空数组。只返回 JSON。`
 	result, err := s.chat(ctx, system, summary)
 	if err != nil {
-		return nil, fmt.Errorf("detect risks: %w", err)
-	}
-	var parsed struct {
-		Risks []string `json:"risks"`
+		return nil, fmt.Errorf("analyze report: %w", err)
 	}
+	var parsed model.ReportAnalysis
 	if json.Unmarshal([]byte(result), &parsed) != nil {
-		return nil, nil
+		return &model.ReportAnalysis{Status: "on-track"}, nil
 	}
-	return parsed.Risks, nil
+	items := parsed.Risks[:0]
+	for _, r := range parsed.Risks {
+		if r.Description == "" {
+			continue
+		}
+		r.Severity = normalize(r.Severity, "medium", "high", "medium", "low")
+		r.Category = normalize(r.Category, "blocked", "blocked", "delay", "incident", "dependency")
+		items = append(items, r)
+	}
+	parsed.Risks = items
+	blockers := parsed.Blockers[:0]
+	for _, b := range parsed.Blockers {
+		if b = strings.TrimSpace(b); b != "" {
+			blockers = append(blockers, b)
+		}
+	}
+	parsed.Blockers = blockers
+	parsed.Status = normalize(parsed.Status, "on-track", model.WorkStatuses...)
+	// a reported blocker outranks whatever status the model picked, except a day off
+	if len(parsed.Blockers) > 0 && parsed.Status != "off" {
+		parsed.Status = "blocked"
+	}
+	return &parsed, nil
 }
 
-// StreamQueryAnswer 通过 Data Asking 流式回答查询（带 session 上下文）
-func (s *AIService) StreamQueryAnswer(ctx context.Context, question string, sessionID string, flush func(string), thinkFlush func(string)) error {
+// normalize returns v if it is one of allowed, else def.
+func normalize(v, def string, allowed ...string) string {
+	v = strings.ToLower(strings.TrimSpace(v))
+	for _, a := range allowed {
+		if v == a {
+			return v
+		}
+	}
+	return def
+}
+
+// StreamQueryAnswer 通过 Data Asking 流式回答查询（带 session 上下文）。
+// scope 非 nil 时只输出范围内成员的结果行（见 QueryScope），不输出 agent 的推理和文字结论
+func (s *AIService) StreamQueryAnswer(ctx context.Context, question string, sessionID string, scope *QueryScope, flush func(string), thinkFlush func(string)) error {
 	if s.raw == nil || s.catalogDBID == 0 {
 		flush("Data Asking 未配置，无法查询。")
 		return nil
@@ -347,6 +317,8 @@ func (s *AIService) StreamQueryAnswer(ctx context.Context, question string, sess
 			thinkFlush("正在分析问题...")
 		case event.StepType == "exploration":
 			thinkFlush("正在探索数据表结构...")
+		case event.StepType == "agent_reasoning" && scope != nil:
+			thinkFlush("正在推理...")
 		case event.StepType == "agent_reasoning":
 			if msg, ok := event.Data["message"].(string); ok {
 				runes := []rune(msg)
@@ -360,7 +332,7 @@ func (s *AIService) StreamQueryAnswer(ctx context.Context, question string, sess
 		case event.StepType == "sql_execution":
 			thinkFlush("查询完成，正在整理结果...")
 		case event.StepType == "insight":
-			s.flushInsightBlocks(event.Data, flush)
+			s.flushInsightBlocks(event.Data, scope, flush)
 		}
 	}
 	return nil
@@ -373,7 +345,7 @@ func strPtr(s string) *string {
 	return &s
 }
 
-func (s *AIService) flushInsightBlocks(data map[string]interface{}, flush func(string)) {
+func (s *AIService) flushInsightBlocks(data map[string]interface{}, scope *QueryScope, flush func(string)) {
 	blocks, ok := data["blocks"].([]interface{})
 	if !ok {
 		return
@@ -388,8 +360,9 @@ func (s *AIService) flushInsightBlocks(data map[string]interface{}, flush func(s
 		if tables, ok := block["tables"].([]interface{}); ok && len(tables) > 0 {
 			hasTables = true
 		}
-		// Render text only when no tables (otherwise it's redundant)
-		if !hasTables {
+		// Render text only when no tables (otherwise it's redundant); a scoped
+		// answer's text may describe rows outside the scope
+		if !hasTables && scope == nil {
 			if text, ok := block["text"].(map[string]interface{}); ok {
 				if content, ok := text["content"].(string); ok {
 					flush(content)
@@ -405,6 +378,9 @@ func (s *AIService) flushInsightBlocks(data map[string]interface{}, flush func(s
 				}
 				rows, _ := tbl["rowValues"].([]interface{})
 				headers, _ := tbl["columnHeaders"].([]interface{})
+				if scope != nil {
+					rows = scope.FilterRows(headers, rows)
+				}
 				if len(rows) == 0 {
 					continue
 				}
@@ -492,23 +468,86 @@ func (s *AIService) StreamWeeklySummary(ctx context.Context, userName, data stri
 	return s.stream(ctx, system, prompt, flush)
 }
 
+// StreamTeamDigest 流式生成团队周报，返回完整内容用于保存文件
+// data 为 TeamDigest.PromptData() 的输出。
+func (s *AIService) StreamTeamDigest(ctx context.Context, data string, flush func(string)) (string, error) {
+	system := `根据团队日报数据生成 Markdown 团队周报。
+
+输入包含三部分：按主题分组的工作记录（[日期] 成员：内容）、未解决的风险、未提交日报的成员。
+
+格式要求：
+# 团队周报 - {团队}（{开始日期} ~ {结束日期}）
+## 本期概览
+（概括团队整体进展，2-4条）
+## 重点进展
+（按主题分小节，每个主题用「### 主题名」，列出1-3条要点并注明相关成员；记录很少的主题合并到「### 其他」）
+## 未解决风险
+（逐条列出输入中的风险，注明严重程度和报告人；若无则写“无”）
+## 日报缺交
+（列出输入中未提交日报的成员及日期；若无则写“全员按时提交”）
+
+只使用输入中的内容，禁止编造进展、风险或计划。`
+	return s.stream(ctx, system, data, flush)
+}
+
+// SummarizeWeek 将一周日报压缩为带来源日期的周度要点（月度/季度总结的中间层）
+func (s *AIService) SummarizeWeek(ctx context.Context, data string) (string, error) {
+	system := `将一周的日报压缩为周度要点，供后续生成月度/季度总结。
+
+输入每行一条记录：[日期] 工作内容；团队数据为 [日期] 成员：工作内容。
+
+要求：
+- 输出 Markdown 列表，合并同一事项的多日记录，每条不超过 60 字
+- 每条末尾用方括号注明来源日期，如 [2026-03-02, 2026-03-04]
+- 团队数据需注明相关成员
+- 保留风险、阻塞和明确提到的计划，不得编造输入之外的内容`
+	return s.chat(ctx, system, data)
+}
+
+// StreamPeriodReview 根据周度要点流式生成月度/季度总结，返回完整内容用于保存文件
+func (s *AIService) StreamPeriodReview(ctx context.Context, subject, period, weeks string, flush func(string)) (string, error) {
+	system := `根据周度要点生成 Markdown 阶段总结，用于月度/季度绩效回顾。
+
+输入按周列出要点，每条末尾方括号内为来源日期。
+
+格式要求：
+# {周期}总结 - {对象}
+## 概述
+（概括本期主要成果，3-5条）
+## 重点工作
+（按事项归类而不是按周罗列，每条保留来源日期引用，如 [2026-03-02, 2026-03-16]）
+## 风险与问题
+（仅列出输入中出现的风险或问题，若无则省略此章节）
+## 后续计划
+（仅列出输入中明确提到的计划，若无则省略此章节）
+
+只使用输入中的内容，每条结论都要保留来源日期，禁止编造。`
+	prompt := fmt.Sprintf("对象：%s\n周期：%s\n\n%s", subject, period, weeks)
+	return s.stream(ctx, system, prompt, flush)
+}
+
 // DateRange 表示 LLM 从自然语言提取的日期范围
 type DateRange struct {
 	Start string `json:"start"` // YYYY-MM-DD
 	End   string `json:"end"`   // YYYY-MM-DD
 }
 
-// ExtractDateRange 从用户输入中提取日期范围，默认最近7天
+// ExtractDateRange 从用户输入中提取日期范围，默认本周（周一至周日），
+// 与 WeeklyRange 一致，同一周的周报对应同一份保存的周报。
 func (s *AIService) ExtractDateRange(ctx context.Context, text, today, weekday, monday string) (*DateRange, error) {
+	sunday := monday
+	if t, err := time.Parse("2006-01-02", monday); err == nil {
+		sunday = t.AddDate(0, 0, 6).Format("2006-01-02")
+	}
 	system := fmt.Sprintf(`你是日期解析助手。今天是 %s（%s），本周一是 %s。
 用户会用自然语言描述一个时间范围，请提取为精确日期。
 规则：
-- "本周"指 %s 到今天（%s）
+- "本周"指 %s 到本周日（%s）
 - "上周"指上周一到上周日
 - "最近一周"指过去7天
 - "前两周"指过去14天
-- 如果用户没有明确时间，默认最近7天
-只输出 JSON：{"start":"YYYY-MM-DD","end":"YYYY-MM-DD"}`, today, weekday, monday, monday, today)
+- 如果用户没有明确时间，默认本周（%s 到 %s）
+只输出 JSON：{"start":"YYYY-MM-DD","end":"YYYY-MM-DD"}`, today, weekday, monday, monday, sunday, monday, sunday)
 	result, err := s.doChatWithModel(ctx, s.fastModel, system, text, false, nil)
 	if err != nil {
 		return nil, err
@@ -2,24 +2,142 @@ package service
 
 import (
 	"context"
-	"fmt"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"smart-daily/internal/config"
+	"smart-daily/internal/logger"
 	"smart-daily/internal/model"
 	"smart-daily/internal/repository"
+	"unicode/utf8"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
-type AuthService struct{ repo *repository.MemberRepo }
+var (
+	ErrWrongPassword = errors.New("wrong password")
+	ErrWeakPassword  = errors.New("new password must be at least 8 characters and differ from the old one")
+)
+
+const minPasswordLen = 8
+
+type AuthService struct {
+	repo      *repository.MemberRepo
+	chain     []Authenticator
+	oidc      *OIDCProvider
+	provision config.ProvisionConfig
+}
+
+// NewAuthService checks passwords locally only until SetAuthenticators is called.
+func NewAuthService(repo *repository.MemberRepo) *AuthService {
+	return &AuthService{repo: repo, chain: []Authenticator{&LocalAuthenticator{repo: repo}}}
+}
+
+// SetAuthenticators replaces the password login chain and the provisioning
+// defaults for members first seen through an external provider.
+func (s *AuthService) SetAuthenticators(chain []Authenticator, provision config.ProvisionConfig) {
+	s.chain, s.provision = chain, provision
+}
+
+// SetOIDC enables the OpenID Connect login.
+func (s *AuthService) SetOIDC(p *OIDCProvider) { s.oidc = p }
 
-func NewAuthService(repo *repository.MemberRepo) *AuthService { return &AuthService{repo: repo} }
+// OIDC returns the OpenID Connect provider, or nil when it is not configured.
+func (s *AuthService) OIDC() *OIDCProvider { return s.oidc }
 
+// Providers lists the password authenticators in chain order.
+func (s *AuthService) Providers() []string {
+	names := make([]string, 0, len(s.chain))
+	for _, a := range s.chain {
+		names = append(names, a.Name())
+	}
+	return names
+}
+
+// Login tries each authenticator in turn and maps the first identity that
+// passes to a member. Backend failures are logged and the next one is tried.
 func (s *AuthService) Login(ctx context.Context, username, password string) (*model.Member, error) {
-	m, err := s.repo.FindByUsername(ctx, username)
+	if username == "" || password == "" {
+		return nil, ErrWrongPassword
+	}
+	for _, a := range s.chain {
+		id, err := a.Authenticate(ctx, username, password)
+		if errors.Is(err, ErrUnknownUser) || errors.Is(err, ErrWrongPassword) {
+			continue
+		}
+		if err != nil {
+			logger.Warn("login.provider_error", "provider", a.Name(), "username", username, "err", err)
+			continue
+		}
+		return s.ResolveMember(ctx, id)
+	}
+	return nil, ErrWrongPassword
+}
+
+// Member returns an active member by ID.
+func (s *AuthService) Member(ctx context.Context, id int) (*model.Member, error) {
+	m, err := s.repo.FindByID(ctx, id)
+	if err == nil && m.Status == "deleted" {
+		err = ErrAccountDisabled
+	}
+	return m, err
+}
+
+// ChangePassword sets a member's own password after verifying the old one,
+// and clears the must-change flag.
+func (s *AuthService) ChangePassword(ctx context.Context, memberID int, oldPassword, newPassword string) (*model.Member, error) {
+	m, err := s.repo.FindByID(ctx, memberID)
+	if err != nil {
+		return nil, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(m.Password), []byte(oldPassword)) != nil {
+		return nil, ErrWrongPassword
+	}
+	if utf8.RuneCountInString(newPassword) < minPasswordLen || newPassword == oldPassword {
+		return nil, ErrWeakPassword
+	}
+	hash, err := HashPassword(newPassword)
 	if err != nil {
-		return nil, fmt.Errorf("user not found: %w", err)
+		return nil, err
 	}
-	if bcrypt.CompareHashAndPassword([]byte(m.Password), []byte(password)) != nil {
-		return nil, fmt.Errorf("wrong password")
+	if err := s.repo.Update(ctx, memberID, map[string]interface{}{"password": hash, "must_change_password": false}); err != nil {
+		return nil, err
 	}
+	m.MustChangePassword = false
 	return m, nil
 }
+
+// ResetPassword gives a member a random temporary password that must be
+// changed at the next login, and returns it.
+func (s *AuthService) ResetPassword(ctx context.Context, memberID int) (string, error) {
+	if _, err := s.repo.FindByID(ctx, memberID); err != nil {
+		return "", err
+	}
+	temp := NewTempPassword()
+	hash, err := HashPassword(temp)
+	if err != nil {
+		return "", err
+	}
+	if err := s.repo.Update(ctx, memberID, map[string]interface{}{"password": hash, "must_change_password": true}); err != nil {
+		return "", err
+	}
+	return temp, nil
+}
+
+func HashPassword(pw string) (string, error) {
+	b, err := bcrypt.GenerateFromPassword([]byte(pw), bcrypt.DefaultCost)
+	return string(b), err
+}
+
+// tempPasswordChars omits look-alikes (0/O, 1/l/I) so it can be read out.
+const tempPasswordChars = "abcdefghjkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// NewTempPassword returns a random 12-character password.
+func NewTempPassword() string {
+	b := make([]byte, 12)
+	for i := range b {
+		n, _ := rand.Int(rand.Reader, big.NewInt(int64(len(tempPasswordChars))))
+		b[i] = tempPasswordChars[n.Int64()]
+	}
+	return string(b)
+}
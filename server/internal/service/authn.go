@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"smart-daily/internal/config"
+	"smart-daily/internal/logger"
+	"smart-daily/internal/model"
+	"smart-daily/internal/repository"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrUnknownUser tells the login chain to try the next authenticator.
+	ErrUnknownUser = errors.New("unknown user")
+	// ErrAccountDisabled is returned when an external identity maps to a deleted
+	// member, or no member exists and provisioning is off.
+	ErrAccountDisabled = errors.New("account disabled or not provisioned")
+	// ErrIdentityConflict is returned when an SSO identity is not bound yet and
+	// its username or email belongs to a member that signs in with a password.
+	ErrIdentityConflict = errors.New("account exists with a local password")
+)
+
+// Identity is a user authenticated by some provider, before it is mapped to a member.
+type Identity struct {
+	Provider      string // local / ldap / oidc
+	Issuer        string // OIDC issuer
+	Subject       string // provider-side id: LDAP DN, OIDC sub
+	Username      string
+	Email         string
+	EmailVerified bool // the IdP asserted email_verified=true
+	Name          string
+
+	member *model.Member // already resolved (local logins)
+}
+
+// Authenticator checks a username and password against one backend.
+// It returns ErrUnknownUser or ErrWrongPassword to let the next one try.
+type Authenticator interface {
+	Name() string
+	Authenticate(ctx context.Context, username, password string) (*Identity, error)
+}
+
+// NewAuthenticators builds the password login chain (local / ldap) from config.
+func NewAuthenticators(cfg config.AuthConfig, repo *repository.MemberRepo) ([]Authenticator, error) {
+	names := cfg.Providers
+	if len(names) == 0 {
+		names = []string{"local"}
+	}
+	var out []Authenticator
+	for _, name := range names {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "local":
+			out = append(out, &LocalAuthenticator{repo: repo})
+		case "ldap":
+			if cfg.LDAP.URL == "" || cfg.LDAP.BaseDN == "" {
+				return nil, fmt.Errorf("authenticator ldap: auth.ldap.url and base_dn are required")
+			}
+			out = append(out, NewLDAPAuthenticator(cfg.LDAP))
+		case "oidc":
+			return nil, fmt.Errorf("oidc is not a password provider; set auth.oidc.issuer instead")
+		default:
+			return nil, fmt.Errorf("unknown auth provider %q (want local or ldap)", name)
+		}
+	}
+	return out, nil
+}
+
+// LocalAuthenticator checks the bcrypt hash in members.password.
+type LocalAuthenticator struct{ repo *repository.MemberRepo }
+
+func (a *LocalAuthenticator) Name() string { return "local" }
+
+func (a *LocalAuthenticator) Authenticate(ctx context.Context, username, password string) (*Identity, error) {
+	m, err := a.repo.FindByUsername(ctx, username)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUnknownUser
+	}
+	if err != nil {
+		return nil, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(m.Password), []byte(password)) != nil {
+		return nil, ErrWrongPassword
+	}
+	return &Identity{Provider: "local", Subject: m.Username, Username: m.Username, Email: m.Email, Name: m.Name, member: m}, nil
+}
+
+// ResolveMember maps an external identity to a member, creating one with the
+// provisioning defaults when none matches. Directory (LDAP) logins match by
+// username, then email; OIDC logins go through resolveOIDC.
+func (s *AuthService) ResolveMember(ctx context.Context, id *Identity) (*model.Member, error) {
+	if id.member != nil {
+		return id.member, nil
+	}
+	if id.Provider == "oidc" {
+		return s.resolveOIDC(ctx, id)
+	}
+	m, err := s.repo.FindByLogin(ctx, id.Username, id.Email)
+	if err == nil {
+		if m.Status == "deleted" {
+			return nil, ErrAccountDisabled
+		}
+		return m, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return s.provisionMember(ctx, id)
+}
+
+// resolveOIDC finds the member bound to the identity's issuer and subject.
+// An unbound identity is linked to the member with its verified email when
+// that member has no password of their own, and provisioned otherwise; it is
+// never linked by username, which the user may be able to change at the IdP.
+func (s *AuthService) resolveOIDC(ctx context.Context, id *Identity) (*model.Member, error) {
+	if id.Subject == "" {
+		return nil, fmt.Errorf("oidc identity has no subject")
+	}
+	m, err := s.repo.FindByIdentity(ctx, id.Provider, id.Issuer, id.Subject)
+	if err == nil {
+		if m.Status == "deleted" {
+			return nil, ErrAccountDisabled
+		}
+		return m, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if id.EmailVerified && id.Email != "" {
+		m, err = s.repo.FindByLogin(ctx, "", id.Email)
+		switch {
+		case err == nil && m.Status == "deleted":
+			return nil, ErrAccountDisabled
+		case err == nil && hasLocalPassword(m):
+			return nil, ErrIdentityConflict
+		case err == nil:
+			return m, s.bind(ctx, m, id)
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return nil, err
+		}
+	}
+	if id.Username != "" {
+		if _, err := s.repo.FindByLogin(ctx, id.Username, ""); err == nil {
+			return nil, ErrIdentityConflict
+		}
+	}
+	m, err = s.provisionMember(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return m, s.bind(ctx, m, id)
+}
+
+func (s *AuthService) bind(ctx context.Context, m *model.Member, id *Identity) error {
+	if err := s.repo.BindIdentity(ctx, m.ID, id.Provider, id.Issuer, id.Subject); err != nil {
+		return fmt.Errorf("bind %s identity to member %d: %w", id.Provider, m.ID, err)
+	}
+	logger.Info("member.identity_bound", "member_id", m.ID, "provider", id.Provider, "issuer", id.Issuer, "subject", id.Subject)
+	return nil
+}
+
+// hasLocalPassword reports whether m signs in with a password of their own,
+// as opposed to an imported or SSO-provisioned account.
+func hasLocalPassword(m *model.Member) bool {
+	return m.Password != "" && m.Password != lockedPassword
+}
+
+// provisionMember creates a member for id with the provisioning defaults.
+func (s *AuthService) provisionMember(ctx context.Context, id *Identity) (*model.Member, error) {
+	if !s.provision.Enabled {
+		return nil, ErrAccountDisabled
+	}
+	username := id.Username
+	if username == "" {
+		username, _, _ = strings.Cut(id.Email, "@")
+	}
+	if username == "" {
+		return nil, fmt.Errorf("%s identity %q has no username or email", id.Provider, id.Subject)
+	}
+	name := id.Name
+	if name == "" {
+		name = username
+	}
+	m := &model.Member{
+		Username: username,
+		Password: lockedPassword,
+		Name:     name,
+		Email:    id.Email,
+		TeamID:   s.provision.TeamID,
+		Role:     s.provision.Role,
+		Status:   "active",
+	}
+	if err := s.repo.Create(ctx, m); err != nil {
+		return nil, fmt.Errorf("provision member %s: %w", username, err)
+	}
+	logger.Info("member.provision", "member_id", m.ID, "username", username, "provider", id.Provider, "subject", id.Subject)
+	return m, nil
+}
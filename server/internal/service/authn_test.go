@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"smart-daily/internal/config"
+	"smart-daily/internal/model"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type fakeAuthenticator struct {
+	name string
+	err  error
+	id   *Identity
+	hits int
+}
+
+func (f *fakeAuthenticator) Name() string { return f.name }
+
+func (f *fakeAuthenticator) Authenticate(context.Context, string, string) (*Identity, error) {
+	f.hits++
+	return f.id, f.err
+}
+
+func TestLoginChain(t *testing.T) {
+	alice := &model.Member{ID: 7, Username: "alice"}
+	local := &fakeAuthenticator{name: "local", err: ErrUnknownUser}
+	broken := &fakeAuthenticator{name: "ldap", err: errors.New("connection refused")}
+	ok := &fakeAuthenticator{name: "ldap2", id: &Identity{Provider: "ldap", member: alice}}
+	never := &fakeAuthenticator{name: "never", err: ErrWrongPassword}
+
+	s := &AuthService{}
+	s.SetAuthenticators([]Authenticator{local, broken, ok, never}, config.ProvisionConfig{})
+	m, err := s.Login(context.Background(), "alice", "secret")
+	if err != nil || m.ID != 7 {
+		t.Fatalf("Login = %v, %v; want member 7", m, err)
+	}
+	if local.hits != 1 || broken.hits != 1 || never.hits != 0 {
+		t.Errorf("hits local=%d broken=%d never=%d; want 1 1 0", local.hits, broken.hits, never.hits)
+	}
+	if got := strings.Join(s.Providers(), ","); got != "local,ldap,ldap2,never" {
+		t.Errorf("Providers = %s", got)
+	}
+
+	s.SetAuthenticators([]Authenticator{local, never}, config.ProvisionConfig{})
+	if _, err := s.Login(context.Background(), "alice", "secret"); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("all rejected: err = %v, want ErrWrongPassword", err)
+	}
+	if _, err := s.Login(context.Background(), "alice", ""); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("empty password: err = %v, want ErrWrongPassword", err)
+	}
+}
+
+func TestNewAuthenticators(t *testing.T) {
+	chain, err := NewAuthenticators(config.AuthConfig{}, nil)
+	if err != nil || len(chain) != 1 || chain[0].Name() != "local" {
+		t.Fatalf("default chain = %v, %v; want [local]", chain, err)
+	}
+	ldapCfg := config.LDAPConfig{URL: "ldap://localhost:389", BaseDN: "dc=example,dc=org"}
+	chain, err = NewAuthenticators(config.AuthConfig{Providers: []string{"ldap", " Local "}, LDAP: ldapCfg}, nil)
+	if err != nil || len(chain) != 2 || chain[0].Name() != "ldap" || chain[1].Name() != "local" {
+		t.Fatalf("chain = %v, %v; want [ldap local]", chain, err)
+	}
+	for _, providers := range [][]string{{"ldap"}, {"oidc"}, {"kerberos"}} {
+		if _, err := NewAuthenticators(config.AuthConfig{Providers: providers}, nil); err == nil {
+			t.Errorf("%v: want error", providers)
+		}
+	}
+}
+
+func TestLDAPFilterEscapes(t *testing.T) {
+	a := NewLDAPAuthenticator(config.LDAPConfig{UserFilter: "(&(objectClass=person)(|(uid=%s)(mail=%s)))"})
+	got := a.filter("a*)(uid=*")
+	want := `(&(objectClass=person)(|(uid=a\2a\29\28uid=\2a)(mail=a\2a\29\28uid=\2a)))`
+	if got != want {
+		t.Errorf("filter = %s\nwant %s", got, want)
+	}
+}
+
+func TestIdentityFromClaims(t *testing.T) {
+	id := identityFromClaims(map[string]interface{}{
+		"preferred_username": "bob", "email": "bob@example.com", "email_verified": false, "name": "Bob",
+	}, "sub-1", "preferred_username")
+	if id.Username != "bob" || id.Name != "Bob" || id.Email != "" || id.Subject != "sub-1" {
+		t.Errorf("identity = %+v; want unverified email dropped", id)
+	}
+	id = identityFromClaims(map[string]interface{}{"email": "bob@example.com"}, "sub-1", "preferred_username")
+	if id.Email != "bob@example.com" || id.EmailVerified {
+		t.Errorf("email without email_verified claim should be kept but not verified, got %+v", id)
+	}
+	id = identityFromClaims(map[string]interface{}{"email": "bob@example.com", "email_verified": true}, "sub-1", "preferred_username")
+	if !id.EmailVerified {
+		t.Errorf("email_verified=true not recorded: %+v", id)
+	}
+}
+
+func TestHasLocalPassword(t *testing.T) {
+	hash, err := HashPassword("s3cret-pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		password string
+		want     bool
+	}{{hash, true}, {lockedPassword, false}, {"", false}} {
+		if got := hasLocalPassword(&model.Member{Password: c.password}); got != c.want {
+			t.Errorf("hasLocalPassword(%q) = %v, want %v", c.password, got, c.want)
+		}
+	}
+}
+
+// mockIssuer is a minimal OpenID provider: discovery, JWKS and a token
+// endpoint that returns an RS256 ID token for whatever nonce it is given.
+func mockIssuer(t *testing.T, nonce *string) *httptest.Server {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var srv *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                srv.URL,
+			"authorization_endpoint":                srv.URL + "/authorize",
+			"token_endpoint":                        srv.URL + "/token",
+			"jwks_uri":                              srv.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		b64 := base64.RawURLEncoding.EncodeToString
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA", "alg": "RS256", "use": "sig", "kid": "k1",
+			"n": b64(key.N.Bytes()), "e": b64(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != "good-code" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		tok := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss": srv.URL, "aud": "smart-daily", "sub": "u-42", "nonce": *nonce,
+			"iat": time.Now().Unix(), "exp": time.Now().Add(time.Hour).Unix(),
+			"preferred_username": "carol", "email": "carol@example.com", "name": "Carol",
+		})
+		tok.Header["kid"] = "k1"
+		raw, err := tok.SignedString(key)
+		if err != nil {
+			t.Error(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "at", "token_type": "Bearer", "expires_in": 3600, "id_token": raw,
+		})
+	})
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestOIDCProvider(t *testing.T) {
+	issued := "n-1"
+	srv := mockIssuer(t, &issued)
+	p, err := NewOIDCProvider(config.OIDCConfig{
+		Issuer: srv.URL, ClientID: "smart-daily", ClientSecret: "s", RedirectURL: "http://app/api/auth/oidc/callback",
+		Scopes: []string{"openid", "email"}, UsernameClaim: "preferred_username",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	loginURL, err := p.AuthCodeURL(ctx, "st", "n-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(loginURL)
+	q := u.Query()
+	if u.Path != "/authorize" || q.Get("state") != "st" || q.Get("nonce") != "n-1" || q.Get("client_id") != "smart-daily" {
+		t.Errorf("AuthCodeURL = %s", loginURL)
+	}
+
+	id, err := p.Exchange(ctx, "good-code", "n-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Provider != "oidc" || id.Issuer != srv.URL || id.Subject != "u-42" || id.Username != "carol" || id.Email != "carol@example.com" || id.EmailVerified {
+		t.Errorf("identity = %+v", id)
+	}
+
+	if _, err := p.Exchange(ctx, "good-code", "other-nonce"); err == nil || !strings.Contains(err.Error(), "nonce") {
+		t.Errorf("nonce mismatch: err = %v", err)
+	}
+	if _, err := p.Exchange(ctx, "bad-code", "n-1"); err == nil {
+		t.Error("bad code: want error")
+	}
+}
+
+func TestOIDCProviderRequiresClient(t *testing.T) {
+	if _, err := NewOIDCProvider(config.OIDCConfig{Issuer: "http://idp"}); err == nil {
+		t.Error("want error without client_id and redirect_url")
+	}
+}
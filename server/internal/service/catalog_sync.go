@@ -10,6 +10,7 @@ import (
 	"smart-daily/internal/model"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	sdk "github.com/matrixorigin/moi-go-sdk"
@@ -24,8 +25,16 @@ type CatalogSync struct {
 	ready      bool
 	baseURL    string
 	apiKey     string
+
+	db            *gorm.DB
+	mu            sync.Mutex
+	resyncPending map[string]bool
+	resyncTimer   *time.Timer
 }
 
+// resyncDelay coalesces bursts of edits into a single table reload.
+const resyncDelay = 5 * time.Second
+
 func NewCatalogSync(raw *sdk.RawClient, catalogID int64, dbName, baseURL, apiKey string) *CatalogSync {
 	s := &CatalogSync{
 		raw:      raw,
@@ -83,26 +92,100 @@ func (s *CatalogSync) TruncateAll() {
 	if !s.ready {
 		return
 	}
+	for _, name := range syncTables {
+		s.truncate(name)
+	}
+	logger.Info("catalog: truncated all tables")
+}
+
+func (s *CatalogSync) truncate(name string) {
+	id, ok := s.tableIDs[name]
+	if !ok {
+		return
+	}
 	ctx := context.Background()
+	if _, err := s.raw.TruncateTable(ctx, &sdk.TableTruncateRequest{TableID: id}); err != nil {
+		logger.Warn("catalog: truncate failed, will retry", "table", name, "err", err)
+		// Retry once after short delay (task lock may release)
+		time.Sleep(3 * time.Second)
+		if _, err := s.raw.TruncateTable(ctx, &sdk.TableTruncateRequest{TableID: id}); err != nil {
+			logger.Warn("catalog: truncate retry failed", "table", name, "err", err)
+		}
+	}
+}
+
+// SetDB gives CatalogSync read access to the source tables for ResyncTables.
+func (s *CatalogSync) SetDB(db *gorm.DB) { s.db = db }
+
+// ResyncTables truncates the given Catalog tables and reloads them from the DB.
+// Catalog has no row-level delete, so this is how deletions and rewrites reach
+// Data Asking. Calls within resyncDelay of each other are merged into one reload.
+func (s *CatalogSync) ResyncTables(tables ...string) {
+	if !s.ready || s.db == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.resyncPending == nil {
+		s.resyncPending = make(map[string]bool)
+	}
+	for _, t := range tables {
+		s.resyncPending[t] = true
+	}
+	if s.resyncTimer == nil {
+		s.resyncTimer = time.AfterFunc(resyncDelay, s.flushResync)
+	} else {
+		s.resyncTimer.Reset(resyncDelay)
+	}
+}
+
+func (s *CatalogSync) flushResync() {
+	s.mu.Lock()
+	pending := s.resyncPending
+	s.resyncPending, s.resyncTimer = nil, nil
+	s.mu.Unlock()
+
 	for _, name := range syncTables {
-		id, ok := s.tableIDs[name]
-		if !ok {
+		if !pending[name] {
 			continue
 		}
-		if _, err := s.raw.TruncateTable(ctx, &sdk.TableTruncateRequest{TableID: id}); err != nil {
-			logger.Warn("catalog: truncate failed, will retry", "table", name, "err", err)
-			// Retry once after short delay (task lock may release)
-			time.Sleep(3 * time.Second)
-			if _, err := s.raw.TruncateTable(ctx, &sdk.TableTruncateRequest{TableID: id}); err != nil {
-				logger.Warn("catalog: truncate retry failed", "table", name, "err", err)
-			}
+		logger.Info("catalog: resync table", "table", name)
+		s.truncate(name)
+		switch name {
+		case "members":
+			var rows []model.Member
+			s.db.Scopes(model.ActiveMembers).Find(&rows)
+			s.SyncAllMembers(rows)
+		case "teams":
+			var rows []model.Team
+			s.db.Find(&rows)
+			s.SyncAllTeams(rows)
+		case "daily_entries":
+			var rows []model.DailyEntry
+			s.db.Find(&rows)
+			s.SyncAllEntries(rows)
+		case "daily_summaries":
+			var rows []model.DailySummary
+			s.db.Find(&rows)
+			s.SyncAllSummaries(rows)
+		case "topics":
+			var rows []model.Topic
+			s.db.Find(&rows)
+			s.SyncAllTopics(rows)
+		case "topic_activities":
+			var rows []model.TopicActivity
+			s.db.Find(&rows)
+			s.SyncAllTopicActivities(rows)
+		case "risks":
+			var rows []model.Risk
+			s.db.Find(&rows)
+			s.SyncAllRisks(rows)
 		}
 	}
-	logger.Info("catalog: truncated all tables")
 }
 
 // syncTables lists tables to sync to Catalog. Order matters for display.
-var syncTables = []string{"members", "teams", "daily_entries", "daily_summaries", "topics", "topic_activities"}
+var syncTables = []string{"members", "teams", "daily_entries", "daily_summaries", "topics", "topic_activities", "risks"}
 
 // columnComments provides semantic descriptions for Catalog and NL2SQL Knowledge.
 // This is the SINGLE source of truth — add new tables/columns here, everything auto-syncs.
@@ -110,21 +193,23 @@ var columnComments = map[string]map[string]string{
 	"members": {
 		"id": "主键", "username": "登录用户名", "password": "密码哈希",
 		"name": "中文姓名", "avatar": "头像URL", "role": "职位角色",
-		"team": "所属团队名称", "team_id": "所属团队ID,关联teams.id",
-		"status": "状态:active/deleted", "is_admin": "是否管理员",
+		"team_id": "所属团队ID,关联teams.id", "status": "状态:active/deleted", "is_admin": "是否管理员",
+		"batch_id": "导入时自动创建的账号所属导入批次,0为非导入创建",
 	},
 	"teams": {
 		"id": "主键", "name": "团队名称",
+		"parent_id": "上级团队ID,关联teams.id,0为顶级部门", "lead_id": "组长,关联members.id,0为未指定",
 	},
 	"daily_entries": {
 		"id": "主键", "member_id": "关联members.id", "daily_date": "日报日期",
 		"content": "原始工作内容", "summary": "AI摘要",
-		"source": "来源:chat/import", "created_at": "创建时间",
+		"source": "来源:chat/import", "created_at": "创建时间", "batch_id": "导入批次,0为非导入",
 	},
 	"daily_summaries": {
 		"id": "主键", "member_id": "关联members.id", "daily_date": "日报日期",
-		"summary": "当天合并总结", "status": "工作状态",
-		"risk": "风险项", "blocker": "阻塞问题",
+		"summary": "当天合并总结", "status": "工作状态:on-track(正常)/at-risk(有风险)/blocked(被阻塞)/off(休假)",
+		"risk": "风险项,多条以'; '分隔", "blocker": "阻塞问题,多条以'; '分隔,无阻塞为空",
+		"batch_id": "最后写入该总结的导入批次,0为非导入",
 	},
 	"topics": {
 		"id": "主键", "name": "Topic名称", "description": "描述",
@@ -133,7 +218,15 @@ var columnComments = map[string]map[string]string{
 	"topic_activities": {
 		"id": "主键", "topic": "Topic名称", "member_id": "成员ID",
 		"member_name": "成员姓名", "daily_date": "日期",
-		"content": "工作内容", "entry_id": "关联daily_entries.id",
+		"content": "工作内容", "entry_id": "关联daily_entries.id", "batch_id": "导入批次,0为非导入",
+	},
+	"risks": {
+		"id": "主键", "member_id": "上报人ID,关联members.id", "member_name": "上报人姓名",
+		"daily_date": "上报日期", "entry_id": "来源日报,关联daily_entries.id", "topic_id": "关联topics.id",
+		"description": "风险描述", "severity": "严重程度:high/medium/low",
+		"category": "分类:blocked阻塞/delay延期/incident故障/dependency依赖",
+		"owner_id": "负责人ID,关联members.id", "status": "状态:open/mitigated/closed",
+		"resolution": "处理说明", "created_at": "创建时间", "updated_at": "更新时间", "closed_at": "关闭时间",
 	},
 }
 
@@ -213,7 +306,8 @@ func KnowledgeEntries() []sdk.NL2SQLKnowledgeCreateRequest {
 		// glossary
 		{Type: "glossary", Key: "日报", Value: []string{"daily_entries表中的一条记录，代表某个团队成员某天提交的工作汇报"}},
 		{Type: "glossary", Key: "成员", Value: []string{"members表中的记录，代表团队中的一个人"}},
-		{Type: "glossary", Key: "风险", Value: []string{"daily_summaries.risk字段，AI从日报内容中检测到的潜在风险项"}},
+		{Type: "glossary", Key: "风险", Value: []string{"risks表中的记录，AI从日报中检测到的风险项，带严重程度、分类和处理状态；daily_summaries.risk是当天风险的文本汇总"}},
+		{Type: "glossary", Key: "阻塞", Value: []string{"daily_summaries.status='blocked'的日报，daily_summaries.blocker列出具体阻塞问题"}},
 		{Type: "glossary", Key: "摘要", Value: []string{"daily_summaries.summary字段，AI对日报原始内容的精炼总结"}},
 		{Type: "glossary", Key: "Topic", Value: []string{"topics表中的记录，代表一个研发主题（如MOI/问数/内核），从日报中自动提取"}},
 		{Type: "glossary", Key: "团队", Value: []string{"teams表中的记录，代表一个团队。通过members.team_id关联成员"}},
@@ -234,7 +328,9 @@ func KnowledgeEntries() []sdk.NL2SQLKnowledgeCreateRequest {
 		// case_library
 		{Type: "case_library", Key: "今天谁没交日报", Value: []string{"SELECT m.name FROM members m LEFT JOIN daily_entries de ON m.id = de.member_id AND de.daily_date = CURDATE() WHERE de.id IS NULL AND m.status != 'deleted'"}},
 		{Type: "case_library", Key: "彭振这周做了什么", Value: []string{"SELECT de.daily_date, de.summary FROM daily_entries de JOIN members m ON de.member_id = m.id WHERE m.name = '彭振' AND de.daily_date >= DATE_SUB(CURDATE(), INTERVAL WEEKDAY(CURDATE()) DAY)"}},
-		{Type: "case_library", Key: "本周有哪些风险", Value: []string{"SELECT m.name, ds.daily_date, ds.risk FROM daily_summaries ds JOIN members m ON ds.member_id = m.id WHERE ds.risk != '' AND ds.daily_date >= DATE_SUB(CURDATE(), INTERVAL WEEKDAY(CURDATE()) DAY)"}},
+		{Type: "case_library", Key: "本周有哪些风险", Value: []string{"SELECT member_name, daily_date, description, severity, status FROM risks WHERE daily_date >= DATE_SUB(CURDATE(), INTERVAL WEEKDAY(CURDATE()) DAY)"}},
+		{Type: "case_library", Key: "还有哪些未关闭的高风险", Value: []string{"SELECT r.member_name, r.daily_date, r.description, t.name AS topic FROM risks r LEFT JOIN topics t ON r.topic_id = t.id WHERE r.status = 'open' AND r.severity = 'high'"}},
+		{Type: "case_library", Key: "今天谁被阻塞了", Value: []string{"SELECT m.name, ds.blocker FROM daily_summaries ds JOIN members m ON ds.member_id = m.id WHERE ds.daily_date = CURDATE() AND ds.status = 'blocked'"}},
 		{Type: "case_library", Key: "最近一周的日报提交情况", Value: []string{"SELECT de.daily_date, COUNT(*) as submitted FROM daily_entries de WHERE de.daily_date >= DATE_SUB(CURDATE(), INTERVAL 7 DAY) GROUP BY de.daily_date ORDER BY de.daily_date"}},
 		{Type: "case_library", Key: "哪些topic持续超过一周", Value: []string{"SELECT topic, MIN(daily_date) as start_date, MAX(daily_date) as end_date, DATEDIFF(MAX(daily_date), MIN(daily_date)) as days, COUNT(DISTINCT member_id) as people FROM topic_activities GROUP BY topic HAVING days > 7 ORDER BY days DESC"}},
 		{Type: "case_library", Key: "某个团队有哪些人", Value: []string{"SELECT m.name, m.role FROM members m JOIN teams t ON m.team_id = t.id WHERE t.name = '某团队' AND m.status != 'deleted'"}},
@@ -244,7 +340,8 @@ func KnowledgeEntries() []sdk.NL2SQLKnowledgeCreateRequest {
 func (s *CatalogSync) Ready() bool     { return s.ready }
 func (s *CatalogSync) DatabaseID() int { return int(s.databaseID) }
 
-func (s *CatalogSync) SyncDailySummary(ctx context.Context, entryID, memberID int, date, content, summary, risk string) {
+// SyncDailySummary pushes a confirmed chat entry and the rebuilt day summary.
+func (s *CatalogSync) SyncDailySummary(ctx context.Context, entryID int, content string, ds *model.DailySummary) {
 	if !s.ready {
 		logger.Warn("catalog sync: skipped, not ready")
 		return
@@ -252,7 +349,7 @@ func (s *CatalogSync) SyncDailySummary(ctx context.Context, entryID, memberID in
 	now := time.Now().Format("2006-01-02 15:04:05")
 
 	entryCsv := fmt.Sprintf("%d,%d,%s,%s,%s,chat,%s\n",
-		entryID, memberID, dateOnly(date), esc(content), esc(summary), now)
+		entryID, ds.MemberID, dateOnly(ds.DailyDate), esc(content), esc(ds.Summary), now)
 	s.importCSV(ctx, s.tableIDs["daily_entries"], entryCsv, fmt.Sprintf("entry_%d.csv", entryID),
 		[]sdk.FileAndTableColumnMapping{
 			{TableColumn: "id", Column: "id", ColNumInFile: 1},
@@ -264,8 +361,8 @@ func (s *CatalogSync) SyncDailySummary(ctx context.Context, entryID, memberID in
 			{TableColumn: "created_at", Column: "created_at", ColNumInFile: 7},
 		})
 
-	sumCsv := fmt.Sprintf("%d,%d,%s,%s,,%s,\n",
-		entryID, memberID, dateOnly(date), esc(summary), esc(risk))
+	sumCsv := fmt.Sprintf("%d,%d,%s,%s,%s,%s,%s\n",
+		ds.ID, ds.MemberID, dateOnly(ds.DailyDate), esc(ds.Summary), esc(ds.Status), esc(ds.Risk), esc(ds.Blocker))
 	s.importCSV(ctx, s.tableIDs["daily_summaries"], sumCsv, fmt.Sprintf("sum_%d.csv", entryID),
 		[]sdk.FileAndTableColumnMapping{
 			{TableColumn: "id", Column: "id", ColNumInFile: 1},
@@ -278,7 +375,8 @@ func (s *CatalogSync) SyncDailySummary(ctx context.Context, entryID, memberID in
 		})
 }
 
-func (s *CatalogSync) SyncDailyEntries(ctx context.Context, entries []model.DailyEntry) {
+// SyncDailyEntries pushes imported entries and their day summaries.
+func (s *CatalogSync) SyncDailyEntries(ctx context.Context, entries []model.DailyEntry, summaries []model.DailySummary) {
 	if !s.ready || len(entries) == 0 {
 		return
 	}
@@ -287,8 +385,10 @@ func (s *CatalogSync) SyncDailyEntries(ctx context.Context, entries []model.Dail
 	for _, e := range entries {
 		fmt.Fprintf(&entryBuf, "%d,%d,%s,%s,%s,%s,%s\n",
 			e.ID, e.MemberID, dateOnly(e.DailyDate), esc(e.Content), esc(e.Summary), e.Source, now)
-		fmt.Fprintf(&sumBuf, "%d,%d,%s,%s,,,\n",
-			e.ID, e.MemberID, dateOnly(e.DailyDate), esc(e.Summary))
+	}
+	for _, sm := range summaries {
+		fmt.Fprintf(&sumBuf, "%d,%d,%s,%s,%s,%s,%s\n",
+			sm.ID, sm.MemberID, dateOnly(sm.DailyDate), esc(sm.Summary), esc(sm.Status), esc(sm.Risk), esc(sm.Blocker))
 	}
 	s.importCSV(ctx, s.tableIDs["daily_entries"], entryBuf.String(), "import_entries.csv",
 		[]sdk.FileAndTableColumnMapping{
@@ -319,7 +419,7 @@ func (s *CatalogSync) SyncMembers(ctx context.Context, members []MemberRow) {
 	}
 	var buf bytes.Buffer
 	for _, m := range members {
-		fmt.Fprintf(&buf, "%d,%s,,%s,,%s,%s,%d,%s,%v\n", m.ID, m.Username, esc(m.Name), esc(m.Role), esc(m.Team), m.TeamID, m.Status, m.IsAdmin)
+		fmt.Fprintf(&buf, "%d,%s,,%s,,%s,%d,%s,%v\n", m.ID, m.Username, esc(m.Name), esc(m.Role), m.TeamID, m.Status, m.IsAdmin)
 	}
 	s.importCSV(ctx, s.tableIDs["members"], buf.String(), "members.csv",
 		[]sdk.FileAndTableColumnMapping{
@@ -329,10 +429,9 @@ func (s *CatalogSync) SyncMembers(ctx context.Context, members []MemberRow) {
 			{TableColumn: "name", Column: "name", ColNumInFile: 4},
 			{TableColumn: "avatar", Column: "avatar", ColNumInFile: 5},
 			{TableColumn: "role", Column: "role", ColNumInFile: 6},
-			{TableColumn: "team", Column: "team", ColNumInFile: 7},
-			{TableColumn: "team_id", Column: "team_id", ColNumInFile: 8},
-			{TableColumn: "status", Column: "status", ColNumInFile: 9},
-			{TableColumn: "is_admin", Column: "is_admin", ColNumInFile: 10},
+			{TableColumn: "team_id", Column: "team_id", ColNumInFile: 7},
+			{TableColumn: "status", Column: "status", ColNumInFile: 8},
+			{TableColumn: "is_admin", Column: "is_admin", ColNumInFile: 9},
 		})
 }
 
@@ -341,7 +440,6 @@ type MemberRow struct {
 	Username string
 	Name     string
 	Role     string
-	Team     string
 	TeamID   int
 	Status   string
 	IsAdmin  bool
@@ -350,7 +448,7 @@ type MemberRow struct {
 func (s *CatalogSync) SyncAllMembers(members []model.Member) {
 	var rows []MemberRow
 	for _, m := range members {
-		rows = append(rows, MemberRow{ID: m.ID, Username: m.Username, Name: m.Name, Role: m.Role, Team: m.Team, TeamID: m.TeamID, Status: m.Status, IsAdmin: m.IsAdmin})
+		rows = append(rows, MemberRow{ID: m.ID, Username: m.Username, Name: m.Name, Role: m.Role, TeamID: m.TeamID, Status: m.Status, IsAdmin: m.IsAdmin})
 	}
 	s.SyncMembers(context.Background(), rows)
 }
@@ -367,12 +465,14 @@ func (s *CatalogSync) SyncAllTeams(teams []model.Team) {
 	logger.Info("catalog sync: full teams sync", "count", len(teams))
 	var buf bytes.Buffer
 	for _, t := range teams {
-		fmt.Fprintf(&buf, "%d,%s\n", t.ID, esc(t.Name))
+		fmt.Fprintf(&buf, "%d,%s,%d,%d\n", t.ID, esc(t.Name), t.ParentID, t.LeadID)
 	}
 	s.importCSV(context.Background(), tableID, buf.String(), "teams.csv",
 		[]sdk.FileAndTableColumnMapping{
 			{TableColumn: "id", Column: "id", ColNumInFile: 1},
 			{TableColumn: "name", Column: "name", ColNumInFile: 2},
+			{TableColumn: "parent_id", Column: "parent_id", ColNumInFile: 3},
+			{TableColumn: "lead_id", Column: "lead_id", ColNumInFile: 4},
 		})
 }
 
@@ -459,6 +559,35 @@ func (s *CatalogSync) SyncAllTopics(topics []model.Topic) {
 		})
 }
 
+func (s *CatalogSync) SyncAllRisks(risks []model.Risk) {
+	if !s.ready || len(risks) == 0 {
+		return
+	}
+	tableID, ok := s.tableIDs["risks"]
+	if !ok {
+		return
+	}
+	logger.Info("catalog sync: full risks sync", "count", len(risks))
+	var buf bytes.Buffer
+	for _, r := range risks {
+		closed := ""
+		if r.ClosedAt != nil {
+			closed = r.ClosedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(&buf, "%d,%d,%s,%s,%d,%d,%s,%s,%s,%d,%s,%s,%s,%s,%s\n",
+			r.ID, r.MemberID, esc(r.MemberName), dateOnly(r.DailyDate), r.EntryID, r.TopicID,
+			esc(r.Description), r.Severity, r.Category, r.OwnerID, r.Status, esc(r.Resolution),
+			r.CreatedAt.Format("2006-01-02 15:04:05"), r.UpdatedAt.Format("2006-01-02 15:04:05"), closed)
+	}
+	cols := []string{"id", "member_id", "member_name", "daily_date", "entry_id", "topic_id", "description",
+		"severity", "category", "owner_id", "status", "resolution", "created_at", "updated_at", "closed_at"}
+	mapping := make([]sdk.FileAndTableColumnMapping, len(cols))
+	for i, c := range cols {
+		mapping[i] = sdk.FileAndTableColumnMapping{TableColumn: c, Column: c, ColNumInFile: int32(i + 1)}
+	}
+	s.importCSV(context.Background(), tableID, buf.String(), "risks.csv", mapping)
+}
+
 func (s *CatalogSync) SyncTopicActivities(ctx context.Context, items []model.TopicActivity) {
 	if !s.ready || len(items) == 0 {
 		return
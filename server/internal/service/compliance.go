@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"smart-daily/internal/repository"
+	"time"
+)
+
+// streakLookback bounds how far back a submission streak is counted.
+const streakLookback = 180
+
+// ComplianceService computes daily report fill rates per member, using the
+// same workday rules (holidays and 调休) as the personal calendar.
+type ComplianceService struct {
+	dailyRepo  *repository.DailyRepo
+	memberRepo *repository.MemberRepo
+	holiday    *HolidayService
+}
+
+func NewComplianceService(dailyRepo *repository.DailyRepo, memberRepo *repository.MemberRepo, holiday *HolidayService) *ComplianceService {
+	return &ComplianceService{dailyRepo: dailyRepo, memberRepo: memberRepo, holiday: holiday}
+}
+
+// MemberCompliance is one member's submission record for a month.
+type MemberCompliance struct {
+	MemberID     int      `json:"member_id"`
+	MemberName   string   `json:"member_name"`
+	TeamID       int      `json:"team_id"`
+	Workdays     int      `json:"workdays"`
+	FilledDays   int      `json:"filled_days"`
+	MissingDates []string `json:"missing_dates"`
+	Streak       int      `json:"streak"` // consecutive workdays submitted up to today
+}
+
+// ComplianceReport is the month's compliance for a team (TeamID 0 = everyone),
+// with Subtree including the teams nested below it.
+type ComplianceReport struct {
+	Month    string             `json:"month"`
+	TeamID   int                `json:"team_id"`
+	Subtree  bool               `json:"subtree"`
+	Workdays int                `json:"workdays"`
+	Members  []MemberCompliance `json:"members"`
+}
+
+// Month returns compliance for month (YYYY-MM), with subtree rolling up the
+// teams nested below teamID. Like the calendar, only workdays before today are
+// due; today counts toward the streak once submitted.
+func (s *ComplianceService) Month(ctx context.Context, teamID int, subtree bool, month string) (*ComplianceReport, error) {
+	start, err := time.Parse("2006-01", month)
+	if err != nil {
+		return nil, fmt.Errorf("invalid month format, use YYYY-MM")
+	}
+	end := start.AddDate(0, 1, -1)
+	now := time.Now()
+	today := now.Format("2006-01-02")
+
+	var teamIDs []int
+	switch {
+	case teamID != 0 && subtree:
+		if teamIDs, err = s.memberRepo.SubtreeTeamIDs(ctx, teamID); err != nil {
+			return nil, fmt.Errorf("list teams: %w", err)
+		}
+	case teamID != 0:
+		teamIDs = []int{teamID}
+	}
+	members, err := s.memberRepo.ListActiveInTeams(ctx, teamIDs)
+	if err != nil {
+		return nil, fmt.Errorf("list members: %w", err)
+	}
+	ids := make([]int, len(members))
+	for i, m := range members {
+		ids[i] = m.ID
+	}
+	// one query covers both the month and the streak window
+	from, to := start, end
+	if lb := now.AddDate(0, 0, -streakLookback); lb.Before(from) {
+		from = lb
+	}
+	if now.After(to) {
+		to = now
+	}
+	submitted, err := s.dailyRepo.SubmittedDatesByMember(ctx, ids, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("query submissions: %w", err)
+	}
+
+	// the streak window can reach back into earlier years than the month
+	for y := from.Year(); y <= to.Year(); y++ {
+		s.holiday.EnsureYear(y)
+	}
+	var due []string
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		if ds := d.Format("2006-01-02"); ds < today && s.holiday.IsWorkday(ds) {
+			due = append(due, ds)
+		}
+	}
+
+	report := &ComplianceReport{Month: month, TeamID: teamID, Subtree: subtree && teamID != 0, Workdays: len(due), Members: make([]MemberCompliance, 0, len(members))}
+	for _, m := range members {
+		dates := submitted[m.ID]
+		mc := MemberCompliance{
+			MemberID: m.ID, MemberName: m.Name, TeamID: m.TeamID,
+			Workdays: len(due), MissingDates: []string{}, Streak: s.streak(dates, now),
+		}
+		for _, ds := range due {
+			if dates[ds] {
+				mc.FilledDays++
+			} else {
+				mc.MissingDates = append(mc.MissingDates, ds)
+			}
+		}
+		report.Members = append(report.Members, mc)
+	}
+	return report, nil
+}
+
+// streak counts consecutive submitted workdays back from today. Today only
+// counts once submitted; non-workdays neither count nor break the streak.
+func (s *ComplianceService) streak(dates map[string]bool, now time.Time) int {
+	n := 0
+	for i := 0; i <= streakLookback; i++ {
+		ds := now.AddDate(0, 0, -i).Format("2006-01-02")
+		if !s.holiday.IsWorkday(ds) {
+			continue
+		}
+		if dates[ds] {
+			n++
+		} else if i > 0 {
+			break
+		}
+	}
+	return n
+}
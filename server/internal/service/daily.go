@@ -3,17 +3,36 @@ package service
 import (
 	"context"
 	"fmt"
+	"smart-daily/internal/events"
+	"smart-daily/internal/logger"
 	"smart-daily/internal/model"
 	"smart-daily/internal/repository"
 	"strings"
 	"time"
 )
 
-type DailyService struct{ repo *repository.DailyRepo }
+type DailyService struct {
+	repo      *repository.DailyRepo
+	topicRepo *repository.TopicRepo
+	riskRepo  *repository.RiskRepo
+	ai        *AIService
+	catalog   *CatalogSync
+	events    events.Publisher
+}
+
+func NewDailyService(repo *repository.DailyRepo, topicRepo *repository.TopicRepo, riskRepo *repository.RiskRepo, ai *AIService) *DailyService {
+	return &DailyService{repo: repo, topicRepo: topicRepo, riskRepo: riskRepo, ai: ai}
+}
 
-func NewDailyService(repo *repository.DailyRepo) *DailyService { return &DailyService{repo: repo} }
+// SetCatalogSync enables pushing edits and deletions to Catalog.
+func (s *DailyService) SetCatalogSync(c *CatalogSync) { s.catalog = c }
 
-func (s *DailyService) Save(ctx context.Context, memberID int, date, content, summary, risk string) (int, error) {
+// SetPublisher enables risk.detected events.
+func (s *DailyService) SetPublisher(p events.Publisher) { s.events = p }
+
+// Save stores the entry of a confirmed draft with its risks and deletes the
+// draft, all in one transaction.
+func (s *DailyService) Save(ctx context.Context, draftID, memberID int, memberName, date, content, summary string, risks []model.RiskItem) (int, error) {
 	if date == "" {
 		date = time.Now().Format("2006-01-02")
 	}
@@ -21,14 +40,285 @@ func (s *DailyService) Save(ctx context.Context, memberID int, date, content, su
 		MemberID: memberID, DailyDate: date,
 		Content: content, Summary: summary, Source: "chat",
 	}
-	if err := s.repo.CreateEntry(ctx, entry); err != nil {
+	rows := riskRows(entry, memberName, risks)
+	if err := s.repo.SaveEntry(ctx, entry, rows, draftID); err != nil {
 		return 0, fmt.Errorf("insert entry: %w", err)
 	}
+	for _, r := range rows {
+		events.Publish(ctx, s.events, events.RiskDetected, r)
+	}
 	return entry.ID, nil
 }
 
-func (s *DailyService) UpdateDailySummary(ctx context.Context, memberID int, date, summary, risk string) error {
-	return s.repo.UpsertSummary(ctx, memberID, date, summary, risk)
+// riskRows builds open risk records for an entry; the reporter owns them until triaged.
+func riskRows(e *model.DailyEntry, memberName string, items []model.RiskItem) []model.Risk {
+	rows := make([]model.Risk, 0, len(items))
+	for _, r := range items {
+		rows = append(rows, model.Risk{
+			MemberID: e.MemberID, MemberName: memberName, DailyDate: e.DailyDate, EntryID: e.ID,
+			Description: r.Description, Severity: r.Severity, Category: r.Category,
+			OwnerID: e.MemberID, Status: "open",
+		})
+	}
+	return rows
+}
+
+// diffRisks returns the detected risks whose description the entry has no risk
+// for yet, and the IDs of its open risks whose description was not detected.
+func diffRisks(stored, detected []model.Risk) (added []model.Risk, staleIDs []int) {
+	have := map[string]bool{}
+	for _, r := range stored {
+		have[strings.TrimSpace(r.Description)] = true
+	}
+	found := map[string]bool{}
+	for _, r := range detected {
+		desc := strings.TrimSpace(r.Description)
+		if desc == "" || found[desc] {
+			continue
+		}
+		found[desc] = true
+		if !have[desc] {
+			added = append(added, r)
+		}
+	}
+	for _, r := range stored {
+		if r.Status == "open" && !found[strings.TrimSpace(r.Description)] {
+			staleIDs = append(staleIDs, r.ID)
+		}
+	}
+	return added, staleIDs
+}
+
+func (s *DailyService) GetEntry(ctx context.Context, id int) (*model.DailyEntry, error) {
+	return s.repo.GetEntry(ctx, id)
+}
+
+// UpdateEntry rewrites a submitted entry and rebuilds its day summary and topics.
+// An empty summary is regenerated from the new content. The entry's risks are
+// matched with the ones detected in the new text by description: risks
+// detected again are kept as triaged, new ones are filed and announced, and
+// open ones no longer detected are deleted; mitigated and closed risks are
+// never touched. When detection fails the entry keeps the risks it had.
+func (s *DailyService) UpdateEntry(ctx context.Context, e *model.DailyEntry, memberName, content, summary string) error {
+	if summary == "" {
+		var err error
+		if summary, err = s.ai.StreamSummarize(ctx, content, nil); err != nil {
+			return err
+		}
+	}
+	var added []model.Risk
+	var stale []int
+	analysis, err := s.ai.AnalyzeReport(ctx, summary)
+	if err != nil {
+		logger.Warn("analyze report failed", "entry_id", e.ID, "err", err)
+		analysis = nil
+	} else {
+		stored, err := s.riskRepo.ListByEntry(ctx, e.ID)
+		if err != nil {
+			return fmt.Errorf("list entry risks: %w", err)
+		}
+		added, stale = diffRisks(stored, riskRows(e, memberName, analysis.Risks))
+	}
+	if err := s.repo.UpdateEntry(ctx, e.ID, content, summary, added, stale); err != nil {
+		return fmt.Errorf("update entry: %w", err)
+	}
+	e.Content, e.Summary = content, summary
+	for _, r := range added {
+		events.Publish(ctx, s.events, events.RiskDetected, r)
+	}
+	if _, err := s.RebuildSummary(ctx, e.MemberID, e.DailyDate, analysis); err != nil {
+		return err
+	}
+	go func() {
+		s.ExtractTopics(context.Background(), e.MemberID, memberName, e.DailyDate, summary, e.ID)
+		s.resyncCatalog()
+	}()
+	return nil
+}
+
+// DeleteEntry retracts a submitted entry with its topic activities and risks,
+// and rebuilds the day summary.
+func (s *DailyService) DeleteEntry(ctx context.Context, e *model.DailyEntry) error {
+	if err := s.repo.DeleteEntry(ctx, e.ID); err != nil {
+		return fmt.Errorf("delete entry: %w", err)
+	}
+	if _, err := s.RebuildSummary(ctx, e.MemberID, e.DailyDate, nil); err != nil {
+		return err
+	}
+	s.resyncCatalog()
+	return nil
+}
+
+// RebuildSummary recomputes a day's summary from the entries left for that day:
+// one entry is used as is, several are merged by the LLM, none removes the summary
+// (and returns nil). The risk text lists the risks filed for the day; status and
+// blocker come from analysing the day summary, or from hint when the day has a
+// single entry and the caller already analysed it.
+func (s *DailyService) RebuildSummary(ctx context.Context, memberID int, date string, hint *model.ReportAnalysis) (*model.DailySummary, error) {
+	entries, err := s.repo.GetDayEntries(ctx, memberID, date)
+	if err != nil {
+		return nil, fmt.Errorf("query entries: %w", err)
+	}
+	if len(entries) == 0 {
+		if err := s.repo.DeleteSummary(ctx, memberID, date); err != nil {
+			return nil, fmt.Errorf("delete summary: %w", err)
+		}
+		return nil, nil
+	}
+	last := entries[len(entries)-1]
+	summary := last.Summary
+	if summary == "" {
+		summary = last.Content
+	}
+	if len(entries) > 1 {
+		hint = nil
+		if merged, err := s.ai.MergeDailySummary(ctx, entries); err == nil {
+			summary = merged
+		} else {
+			logger.Warn("merge summary failed, using latest", "err", err)
+		}
+	}
+	if hint == nil || hint.Status == "" {
+		if hint, err = s.ai.AnalyzeReport(ctx, summary); err != nil {
+			logger.Warn("analyze summary failed", "member_id", memberID, "date", date, "err", err)
+			hint = &model.ReportAnalysis{}
+		}
+	}
+	var descs []string
+	if risks, err := s.riskRepo.ListForDay(ctx, memberID, date); err == nil {
+		for _, r := range risks {
+			descs = append(descs, r.Description)
+		}
+	}
+	ds := &model.DailySummary{
+		MemberID: memberID, DailyDate: date, Summary: summary, Status: hint.Status,
+		Risk: strings.Join(descs, "; "), Blocker: model.BlockerText(hint.Blockers),
+	}
+	if err := s.repo.UpsertSummary(ctx, ds); err != nil {
+		return nil, fmt.Errorf("upsert summary: %w", err)
+	}
+	return ds, nil
+}
+
+// ExtractTopics replaces the topic activities of an entry with topics extracted from content.
+func (s *DailyService) ExtractTopics(ctx context.Context, memberID int, memberName, date, content string, entryID int) {
+	existingTopics, _ := s.topicRepo.ListDistinctTopics(ctx)
+	topics, _ := s.ai.ExtractTopics(ctx, content, existingTopics)
+
+	s.topicRepo.DeleteByEntryID(ctx, entryID)
+	s.topicRepo.EnsureTopics(ctx, topics)
+	var items []model.TopicActivity
+	for _, t := range topics {
+		items = append(items, model.TopicActivity{
+			Topic: t, MemberID: memberID, MemberName: memberName,
+			DailyDate: date, Content: content, EntryID: entryID,
+		})
+	}
+	s.topicRepo.BatchCreate(ctx, items)
+	// link the entry's risks to its main topic
+	if len(topics) > 0 {
+		if id, err := s.topicRepo.TopicID(ctx, topics[0]); err == nil {
+			s.riskRepo.LinkTopic(ctx, entryID, id)
+		}
+	}
+	logger.Info("topics extracted", "entry_id", entryID, "topics", topics)
+}
+
+// BackfillRisks files risk records for summaries written before the risks table
+// existed, splitting the "; "-joined text. It does nothing once any risk exists.
+func (s *DailyService) BackfillRisks(ctx context.Context) {
+	rows, err := s.riskRepo.ListLegacyRisks(ctx)
+	if err != nil || len(rows) == 0 {
+		return
+	}
+	var risks []model.Risk
+	for _, row := range rows {
+		for _, r := range splitRisks(row.MemberID, row.MemberName, row.DailyDate, row.Risk) {
+			r.EntryID, r.TopicID = row.EntryID, row.TopicID
+			risks = append(risks, r)
+		}
+	}
+	if err := s.riskRepo.BatchCreate(ctx, risks); err != nil {
+		logger.Error("risk backfill failed", "err", err)
+		return
+	}
+	logger.Info("risk backfill done", "summaries", len(rows), "risks", len(risks))
+}
+
+// splitRisks turns "; "-joined risk text of a day into open risk records owned
+// by the reporter, for text that did not come through AnalyzeReport.
+func splitRisks(memberID int, memberName, date, text string) []model.Risk {
+	var risks []model.Risk
+	for _, desc := range strings.Split(text, "; ") {
+		if desc = strings.TrimSpace(desc); desc == "" {
+			continue
+		}
+		risks = append(risks, model.Risk{
+			MemberID: memberID, MemberName: memberName, DailyDate: date, Description: desc,
+			Severity: "medium", Category: GuessRiskCategory(desc), OwnerID: memberID, Status: "open",
+		})
+	}
+	return risks
+}
+
+// GuessRiskCategory classifies risk text by keyword, for text that did not come
+// through AnalyzeReport.
+func GuessRiskCategory(text string) string {
+	for _, c := range []struct {
+		category string
+		keywords []string
+	}{
+		{"incident", []string{"故障", "线上", "生产", "宕机", "报警"}},
+		{"delay", []string{"延期", "来不及", "赶不上", "推迟", "deadline"}},
+		{"dependency", []string{"等待", "依赖", "需要支持", "协调", "对方"}},
+	} {
+		for _, kw := range c.keywords {
+			if strings.Contains(text, kw) {
+				return c.category
+			}
+		}
+	}
+	return "blocked"
+}
+
+// GuessWorkStatus derives a day's status and blockers from report text by keyword,
+// for bulk imports that skip AnalyzeReport.
+func GuessWorkStatus(text string) (status string, blockers []string) {
+	for _, line := range strings.FieldsFunc(text, func(r rune) bool { return strings.ContainsRune("\n；;。", r) }) {
+		line = strings.TrimLeft(strings.TrimSpace(line), "-*• ")
+		for _, kw := range []string{"阻塞", "卡住", "无法继续", "受阻"} {
+			if strings.Contains(line, kw) {
+				blockers = append(blockers, line)
+				break
+			}
+		}
+	}
+	switch {
+	case len(blockers) > 0:
+		return "blocked", blockers
+	case containsAny(text, "请假", "休假", "调休", "年假", "病假") && len([]rune(text)) < 20:
+		return "off", nil
+	case containsAny(text, "延期", "来不及", "赶不上", "推迟", "故障", "宕机"):
+		return "at-risk", nil
+	}
+	return "on-track", nil
+}
+
+func containsAny(s string, subs ...string) bool {
+	for _, sub := range subs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// resyncCatalog reloads the tables an entry edit touches. Catalog cannot delete
+// or rewrite single rows reliably, so edits go through a (debounced) table reload.
+func (s *DailyService) resyncCatalog() {
+	if s.catalog != nil {
+		s.catalog.ResyncTables("daily_entries", "daily_summaries", "topic_activities", "risks")
+	}
 }
 
 func (s *DailyService) GetDayEntries(ctx context.Context, memberID int, date string) ([]model.DailyEntry, error) {
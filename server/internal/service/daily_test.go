@@ -0,0 +1,30 @@
+package service
+
+import (
+	"reflect"
+	"smart-daily/internal/model"
+	"testing"
+)
+
+func TestDiffRisks(t *testing.T) {
+	stored := []model.Risk{
+		{ID: 1, Description: "接口联调延期", Status: "open", OwnerID: 9, Severity: "high"},
+		{ID: 2, Description: "测试环境不稳定", Status: "open"},
+		{ID: 3, Description: "依赖方未排期", Status: "closed"},
+		{ID: 4, Description: "线上告警", Status: "mitigated"},
+	}
+	detected := []model.Risk{
+		{Description: " 接口联调延期 ", Status: "open"}, // triaged risk detected again: kept as is
+		{Description: "依赖方未排期", Status: "open"},   // closed risk detected again: not reopened
+		{Description: "人力不足", Status: "open"},
+		{Description: "人力不足", Status: "open"},
+		{Description: "", Status: "open"},
+	}
+	added, stale := diffRisks(stored, detected)
+	if len(added) != 1 || added[0].Description != "人力不足" {
+		t.Errorf("added = %+v, want only 人力不足", added)
+	}
+	if !reflect.DeepEqual(stale, []int{2}) {
+		t.Errorf("stale = %v, want [2]", stale) // the mitigated risk 4 is kept
+	}
+}
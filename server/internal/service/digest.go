@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"smart-daily/internal/model"
+	"smart-daily/internal/repository"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// maxDigestDays bounds the date range of a team digest.
+const maxDigestDays = 92
+
+var ErrDigestRange = errors.New("invalid date range, use YYYY-MM-DD with start <= end and at most 92 days")
+
+// DigestService gathers what a team did over a date range (topic activity,
+// open risks, missing reports) for the AI-written team digest.
+type DigestService struct {
+	dailyRepo  *repository.DailyRepo
+	memberRepo *repository.MemberRepo
+	topicRepo  *repository.TopicRepo
+	riskRepo   *repository.RiskRepo
+	holiday    *HolidayService
+}
+
+func NewDigestService(dailyRepo *repository.DailyRepo, memberRepo *repository.MemberRepo, topicRepo *repository.TopicRepo, riskRepo *repository.RiskRepo, holiday *HolidayService) *DigestService {
+	return &DigestService{dailyRepo: dailyRepo, memberRepo: memberRepo, topicRepo: topicRepo, riskRepo: riskRepo, holiday: holiday}
+}
+
+// TeamDigest is the input of a team digest: a team (TeamID 0 = everyone),
+// with Subtree including the teams nested below it.
+type TeamDigest struct {
+	TeamID   int              `json:"team_id"`
+	TeamName string           `json:"team_name"`
+	Subtree  bool             `json:"subtree"`
+	Start    string           `json:"start"`
+	End      string           `json:"end"`
+	Members  int              `json:"members"`
+	Topics   []DigestTopic    `json:"topics"`
+	Risks    []model.Risk     `json:"risks"`
+	Missing  []MissingReports `json:"missing"`
+}
+
+// DigestTopic is one topic's activity in the range, latest first.
+type DigestTopic struct {
+	Topic      string                `json:"topic"`
+	Members    []string              `json:"members"`
+	Activities []model.TopicActivity `json:"activities"`
+}
+
+// MissingReports names a member who skipped due workdays in the range.
+type MissingReports struct {
+	MemberName string   `json:"member_name"`
+	Dates      []string `json:"dates"`
+}
+
+// Title names the digest's scope, e.g. "研发部（含下属团队）".
+func (d *TeamDigest) Title() string {
+	switch {
+	case d.TeamID == 0:
+		return "全员"
+	case d.Subtree:
+		return d.TeamName + "（含下属团队）"
+	default:
+		return d.TeamName
+	}
+}
+
+// Empty reports whether nobody logged any topic activity in the range.
+func (d *TeamDigest) Empty() bool { return len(d.Topics) == 0 }
+
+// DigestRange validates start/end, defaulting to the last 7 days.
+func DigestRange(start, end string, now time.Time) (string, string, error) {
+	if start == "" && end == "" {
+		return now.AddDate(0, 0, -6).Format("2006-01-02"), now.Format("2006-01-02"), nil
+	}
+	s, err1 := time.Parse("2006-01-02", start)
+	e, err2 := time.Parse("2006-01-02", end)
+	if err1 != nil || err2 != nil || e.Before(s) || e.Sub(s) > maxDigestDays*24*time.Hour {
+		return "", "", ErrDigestRange
+	}
+	return start, end, nil
+}
+
+// Collect gathers the digest of teamID (0 = everyone, subtree to include the
+// teams nested below it) between start and end (both YYYY-MM-DD, empty for
+// the last 7 days).
+func (s *DigestService) Collect(ctx context.Context, teamID int, subtree bool, start, end string) (*TeamDigest, error) {
+	start, end, err := DigestRange(start, end, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	d := &TeamDigest{TeamID: teamID, Subtree: subtree && teamID != 0, Start: start, End: end}
+
+	var teamIDs []int
+	if teamID != 0 {
+		team, err := s.memberRepo.FindTeam(ctx, teamID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTeamNotFound
+		} else if err != nil {
+			return nil, fmt.Errorf("find team: %w", err)
+		}
+		d.TeamName = team.Name
+		teamIDs = []int{teamID}
+		if subtree {
+			if teamIDs, err = s.memberRepo.SubtreeTeamIDs(ctx, teamID); err != nil {
+				return nil, fmt.Errorf("list teams: %w", err)
+			}
+		}
+	}
+	members, err := s.memberRepo.ListActiveInTeams(ctx, teamIDs)
+	if err != nil {
+		return nil, fmt.Errorf("list members: %w", err)
+	}
+	d.Members = len(members)
+
+	activities, err := s.topicRepo.ListByDateRange(ctx, start, end, teamIDs, 0)
+	if err != nil {
+		return nil, fmt.Errorf("list topic activities: %w", err)
+	}
+	d.Topics = groupTopics(activities)
+
+	if d.Risks, err = s.riskRepo.List(ctx, repository.RiskFilter{Status: "open", TeamIDs: teamIDs}); err != nil {
+		return nil, fmt.Errorf("list risks: %w", err)
+	}
+
+	ids := make([]int, len(members))
+	for i, m := range members {
+		ids[i] = m.ID
+	}
+	submitted, err := s.dailyRepo.SubmittedDatesByMember(ctx, ids, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("query submissions: %w", err)
+	}
+	due := s.dueDays(start, end, time.Now().Format("2006-01-02"))
+	for _, m := range members {
+		if missed := missingDates(due, submitted[m.ID]); len(missed) > 0 {
+			d.Missing = append(d.Missing, MissingReports{MemberName: m.Name, Dates: missed})
+		}
+	}
+	return d, nil
+}
+
+// dueDays lists the workdays in [start, end] before today; like compliance,
+// today is not due yet.
+func (s *DigestService) dueDays(start, end, today string) []string {
+	from, _ := time.Parse("2006-01-02", start)
+	to, _ := time.Parse("2006-01-02", end)
+	for y := from.Year(); y <= to.Year(); y++ {
+		s.holiday.EnsureYear(y)
+	}
+	var due []string
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		if ds := d.Format("2006-01-02"); ds < today && s.holiday.IsWorkday(ds) {
+			due = append(due, ds)
+		}
+	}
+	return due
+}
+
+func missingDates(due []string, submitted map[string]bool) []string {
+	var missed []string
+	for _, ds := range due {
+		if !submitted[ds] {
+			missed = append(missed, ds)
+		}
+	}
+	return missed
+}
+
+// groupTopics groups activities by topic, busiest topic first, keeping the
+// repository's latest-first order within a topic.
+func groupTopics(activities []model.TopicActivity) []DigestTopic {
+	var topics []DigestTopic
+	index := map[string]int{}
+	for _, a := range activities {
+		if len(a.DailyDate) > 10 {
+			a.DailyDate = a.DailyDate[:10]
+		}
+		i, ok := index[a.Topic]
+		if !ok {
+			i = len(topics)
+			index[a.Topic] = i
+			topics = append(topics, DigestTopic{Topic: a.Topic})
+		}
+		t := &topics[i]
+		t.Activities = append(t.Activities, a)
+		if !slices.Contains(t.Members, a.MemberName) {
+			t.Members = append(t.Members, a.MemberName)
+		}
+	}
+	sort.SliceStable(topics, func(i, j int) bool { return len(topics[i].Activities) > len(topics[j].Activities) })
+	return topics
+}
+
+// PromptData renders the digest as the plain-text input of StreamTeamDigest.
+func (d *TeamDigest) PromptData() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "团队：%s\n时间范围：%s ~ %s\n成员数：%d\n\n", d.Title(), d.Start, d.End, d.Members)
+	sb.WriteString("【按主题分组的工作记录】\n")
+	for _, t := range d.Topics {
+		fmt.Fprintf(&sb, "## %s（参与：%s）\n", t.Topic, strings.Join(t.Members, "、"))
+		for _, a := range t.Activities {
+			fmt.Fprintf(&sb, "[%s] %s：%s\n", a.DailyDate, a.MemberName, a.Content)
+		}
+	}
+	sb.WriteString("\n【未解决的风险】\n")
+	if len(d.Risks) == 0 {
+		sb.WriteString("无\n")
+	}
+	for _, r := range d.Risks {
+		topic := ""
+		if r.Topic != "" {
+			topic = "，主题：" + r.Topic
+		}
+		fmt.Fprintf(&sb, "- [%s] %s（报告人：%s，%s%s）\n", r.Severity, r.Description, r.MemberName, r.DailyDate, topic)
+	}
+	sb.WriteString("\n【未提交日报的成员】\n")
+	if len(d.Missing) == 0 {
+		sb.WriteString("无\n")
+	}
+	for _, m := range d.Missing {
+		fmt.Fprintf(&sb, "- %s：%s\n", m.MemberName, strings.Join(m.Dates, "、"))
+	}
+	return sb.String()
+}
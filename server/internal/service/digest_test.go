@@ -0,0 +1,79 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"smart-daily/internal/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDigestRange(t *testing.T) {
+	now := time.Date(2026, 3, 11, 15, 0, 0, 0, time.Local)
+	if s, e, err := DigestRange("", "", now); err != nil || s != "2026-03-05" || e != "2026-03-11" {
+		t.Errorf("default range: %s %s %v", s, e, err)
+	}
+	if s, e, err := DigestRange("2026-03-02", "2026-03-08", now); err != nil || s != "2026-03-02" || e != "2026-03-08" {
+		t.Errorf("explicit range: %s %s %v", s, e, err)
+	}
+	for _, bad := range [][2]string{{"2026-03-08", "2026-03-02"}, {"2026-03", "2026-03-08"}, {"2026-01-01", "2026-06-30"}, {"2026-03-02", ""}} {
+		if _, _, err := DigestRange(bad[0], bad[1], now); !errors.Is(err, ErrDigestRange) {
+			t.Errorf("DigestRange(%q, %q) = %v, want ErrDigestRange", bad[0], bad[1], err)
+		}
+	}
+}
+
+func TestGroupTopics(t *testing.T) {
+	// ListByDateRange orders by topic, then latest first
+	topics := groupTopics([]model.TopicActivity{
+		{Topic: "MOI", MemberName: "张三", DailyDate: "2026-03-04T00:00:00Z", Content: "导入接口"},
+		{Topic: "问数", MemberName: "李四", DailyDate: "2026-03-05", Content: "SQL 生成"},
+		{Topic: "问数", MemberName: "张三", DailyDate: "2026-03-04", Content: "评测集"},
+		{Topic: "问数", MemberName: "李四", DailyDate: "2026-03-03", Content: "需求评审"},
+	})
+	if len(topics) != 2 || topics[0].Topic != "问数" || topics[1].Topic != "MOI" {
+		t.Fatalf("want 问数 (3 activities) before MOI (1): %+v", topics)
+	}
+	if !slices.Equal(topics[0].Members, []string{"李四", "张三"}) {
+		t.Errorf("members: %v", topics[0].Members)
+	}
+	if topics[0].Activities[0].Content != "SQL 生成" || topics[1].Activities[0].DailyDate != "2026-03-04" {
+		t.Errorf("activity order or date trim: %+v", topics)
+	}
+}
+
+func TestMissingDates(t *testing.T) {
+	due := []string{"2026-03-02", "2026-03-03", "2026-03-04"}
+	if got := missingDates(due, map[string]bool{"2026-03-03": true}); !slices.Equal(got, []string{"2026-03-02", "2026-03-04"}) {
+		t.Errorf("missing: %v", got)
+	}
+	if got := missingDates(due, map[string]bool{"2026-03-02": true, "2026-03-03": true, "2026-03-04": true}); got != nil {
+		t.Errorf("all submitted: %v", got)
+	}
+}
+
+func TestDigestPromptData(t *testing.T) {
+	d := &TeamDigest{
+		TeamID: 1, TeamName: "研发部", Subtree: true, Start: "2026-03-02", End: "2026-03-08", Members: 3,
+		Topics: []DigestTopic{{Topic: "MOI", Members: []string{"张三"}, Activities: []model.TopicActivity{
+			{Topic: "MOI", MemberName: "张三", DailyDate: "2026-03-04", Content: "导入接口"},
+		}}},
+		Risks:   []model.Risk{{Severity: "high", Description: "联调阻塞", MemberName: "李四", DailyDate: "2026-03-05", Topic: "MOI"}},
+		Missing: []MissingReports{{MemberName: "王五", Dates: []string{"2026-03-02", "2026-03-03"}}},
+	}
+	data := d.PromptData()
+	for _, want := range []string{
+		"团队：研发部（含下属团队）", "时间范围：2026-03-02 ~ 2026-03-08",
+		"## MOI（参与：张三）", "[2026-03-04] 张三：导入接口",
+		"- [high] 联调阻塞（报告人：李四，2026-03-05，主题：MOI）",
+		"- 王五：2026-03-02、2026-03-03",
+	} {
+		if !strings.Contains(data, want) {
+			t.Errorf("prompt data lacks %q:\n%s", want, data)
+		}
+	}
+	if all := (&TeamDigest{}).Title(); all != "全员" {
+		t.Errorf("org-wide title: %s", all)
+	}
+}
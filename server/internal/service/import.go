@@ -7,6 +7,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"smart-daily/internal/docxparse"
+	"smart-daily/internal/events"
 	"smart-daily/internal/logger"
 	"smart-daily/internal/model"
 	"smart-daily/internal/repository"
@@ -20,17 +22,25 @@ type ImportService struct {
 	memberRepo  *repository.MemberRepo
 	dailyRepo   *repository.DailyRepo
 	topicRepo   *repository.TopicRepo
+	riskRepo    *repository.RiskRepo
 	catalogSync *CatalogSync
+	jobs        *repository.ImportJobRepo
+	batches     *repository.ImportBatchRepo
+	events      events.Publisher
 }
 
-func NewImportService(ai *AIService, mr *repository.MemberRepo, dr *repository.DailyRepo, tr *repository.TopicRepo, cs *CatalogSync) *ImportService {
-	return &ImportService{ai: ai, memberRepo: mr, dailyRepo: dr, topicRepo: tr, catalogSync: cs}
+func NewImportService(ai *AIService, mr *repository.MemberRepo, dr *repository.DailyRepo, tr *repository.TopicRepo, rr *repository.RiskRepo, cs *CatalogSync, jobs *repository.ImportJobRepo, batches *repository.ImportBatchRepo) *ImportService {
+	return &ImportService{ai: ai, memberRepo: mr, dailyRepo: dr, topicRepo: tr, riskRepo: rr, catalogSync: cs, jobs: jobs, batches: batches}
 }
 
+// SetPublisher enables report.imported events.
+func (s *ImportService) SetPublisher(p events.Publisher) { s.events = p }
+
 type ExtractedEntry struct {
 	Date    string `json:"date"`
 	Name    string `json:"name"`
 	Content string `json:"content"`
+	Risk    string `json:"risk,omitempty"`
 }
 
 type MemberDecision struct {
@@ -47,54 +57,46 @@ type PreviewResult struct {
 	Unmatched []string
 }
 
+// ConfirmResult counts what confirm did: Imported new days, Merged days
+// whose data was replaced, Combined days merged with existing data, Skipped
+// rows (ignored members, skip policy) and Unchanged rows identical to what
+// is stored. BatchID is the import batch that can revert it.
 type ConfirmResult struct {
-	Imported int `json:"imported"`
-	Merged   int `json:"merged"`
-	Skipped  int `json:"skipped"`
-	Total    int `json:"total"`
-}
-
-type DocxSection struct {
-	Date string `json:"date"`
-	Text string `json:"text"`
+	BatchID   int `json:"batch_id"`
+	Imported  int `json:"imported"`
+	Merged    int `json:"merged"`
+	Combined  int `json:"combined"`
+	Skipped   int `json:"skipped"`
+	Unchanged int `json:"unchanged"`
+	Total     int `json:"total"`
 }
 
-// Extract parses sections and returns preview data.
-func (s *ImportService) Extract(ctx context.Context, sections []DocxSection) (*PreviewResult, error) {
-	if len(sections) == 0 {
-		return &PreviewResult{Entries: []ExtractedEntry{}, Unmatched: []string{}}, nil
-	}
+// DocxSection is one date's part of an imported document (see docxparse).
+type DocxSection = docxparse.Section
 
+// preview matches extracted entries against the active members.
+func (s *ImportService) preview(ctx context.Context, entries []ExtractedEntry) *PreviewResult {
 	members, _ := s.memberRepo.ListActive(ctx)
-	var knownNames []string
-	for _, m := range members {
-		knownNames = append(knownNames, m.Name)
-	}
-
-	var entries []ExtractedEntry
-	if entries = extractProgrammatic(sections); len(entries) > 0 {
-		logger.Info("import: programmatic extraction", "entries", len(entries))
-	} else {
-		logger.Info("import: falling back to LLM extraction")
-		entries = s.extractAll(ctx, sections, knownNames)
-	}
-
 	unmatchedSet := map[string]bool{}
+	unmatched := []string{}
 	for _, e := range entries {
-		if strings.TrimSpace(e.Content) != "" && repository.MatchByName(e.Name, members) == 0 {
+		if strings.TrimSpace(e.Content) != "" && repository.MatchByName(e.Name, members) == 0 && !unmatchedSet[e.Name] {
 			unmatchedSet[e.Name] = true
+			unmatched = append(unmatched, e.Name)
 		}
 	}
-	unmatched := make([]string, 0, len(unmatchedSet))
-	for name := range unmatchedSet {
-		unmatched = append(unmatched, name)
-	}
-
-	return &PreviewResult{Entries: entries, Members: members, Unmatched: unmatched}, nil
+	return &PreviewResult{Entries: entries, Members: members, Unmatched: unmatched}
 }
 
-// Confirm processes member decisions and saves entries to DB.
-func (s *ImportService) Confirm(ctx context.Context, entries []ExtractedEntry, members []model.Member, decisions map[string]MemberDecision) (*ConfirmResult, error) {
+// Confirm processes member decisions and saves entries to DB; policies maps
+// EntryKey to what to do with a day that already has data (see
+// defaultPolicy for rows without one). Everything it writes is recorded as
+// batch (JobID, CreatedBy and FileName filled in by the caller), which
+// RevertBatch undoes.
+func (s *ImportService) Confirm(ctx context.Context, batch *model.ImportBatch, entries []ExtractedEntry, members []model.Member, decisions map[string]MemberDecision, policies map[string]string) (*ConfirmResult, error) {
+	if err := s.batches.Create(ctx, batch); err != nil {
+		return nil, fmt.Errorf("create batch: %w", err)
+	}
 	ignoredNames := map[string]bool{}
 	nameToMemberID := map[string]int{}
 
@@ -119,9 +121,10 @@ func (s *ImportService) Confirm(ctx context.Context, entries []ExtractedEntry, m
 			}
 			newMember := model.Member{
 				Username: "user_" + randHex(8),
-				Password: "$2a$10$sH3qZ9F0SIrCWpcOi9oWDO6EjbWMRs4X/8d35hphzkYRRM.ESRsa.",
+				Password: lockedPassword,
 				Name:     createName,
 				Role:     d.Role, TeamID: d.TeamID, Status: "active",
+				MustChangePassword: true, BatchID: batch.ID,
 			}
 			if newMember.Role == "" {
 				newMember.Role = "开发工程师"
@@ -148,8 +151,9 @@ func (s *ImportService) Confirm(ctx context.Context, entries []ExtractedEntry, m
 		}
 		newMember := model.Member{
 			Username: "user_" + randHex(8),
-			Password: "$2a$10$sH3qZ9F0SIrCWpcOi9oWDO6EjbWMRs4X/8d35hphzkYRRM.ESRsa.",
+			Password: lockedPassword,
 			Name:     name, Role: "开发工程师", Status: "active",
+			MustChangePassword: true, BatchID: batch.ID,
 		}
 		if err := s.memberRepo.Create(ctx, &newMember); err != nil {
 			continue
@@ -157,12 +161,9 @@ func (s *ImportService) Confirm(ctx context.Context, entries []ExtractedEntry, m
 		members = append(members, newMember)
 	}
 
-	// Build valid entries
-	type validEntry struct {
-		memberID int
-		date, content string
-	}
-	var valid []validEntry
+	// Build valid entries, one per member and day
+	var valid []importRow
+	index := map[dayKey]int{}
 	skipped := 0
 	for _, e := range entries {
 		if strings.TrimSpace(e.Content) == "" {
@@ -182,67 +183,130 @@ func (s *ImportService) Confirm(ctx context.Context, entries []ExtractedEntry, m
 			skipped++
 			continue
 		}
-		valid = append(valid, validEntry{memberID, e.Date, e.Content})
+		// The same member and day twice in a file: keep both texts
+		k := dayKey{memberID, e.Date}
+		if i, ok := index[k]; ok {
+			valid[i].content += "; " + e.Content
+			valid[i].risk = joinNonEmpty(valid[i].risk, e.Risk)
+			continue
+		}
+		index[k] = len(valid)
+		valid = append(valid, importRow{memberID: memberID, key: EntryKey(e), date: e.Date, content: e.Content, risk: e.Risk})
 	}
 
-	// Find existing imports for merged/imported stats
-	type key struct{ mid int; date string }
-	existingKeys := map[key]bool{}
+	// Apply each row's policy against the data already stored for its day
+	var existing map[dayKey][]model.DailyEntry
 	if len(valid) > 0 {
-		existing, _ := s.dailyRepo.FindExistingImportKeys(ctx)
-		for k := range existing {
-			if mid, ok := k[0].(int); ok {
-				if date, ok := k[1].(string); ok {
-					existingKeys[key{mid, date}] = true
-				}
+		keys := make([][]interface{}, 0, len(valid))
+		for _, v := range valid {
+			keys = append(keys, []interface{}{v.memberID, v.date})
+		}
+		var err error
+		if existing, err = s.dayEntries(ctx, keys); err != nil {
+			return nil, fmt.Errorf("query existing entries: %w", err)
+		}
+	}
+	result := &ConfirmResult{BatchID: batch.ID, Skipped: skipped, Total: len(entries)}
+	var replace, merge []importRow
+	for _, v := range valid {
+		status, _ := classify(v.content, existing[dayKey{v.memberID, v.date}])
+		policy := policies[v.key]
+		if policy == "" {
+			policy = defaultPolicy(status)
+		}
+		switch {
+		case policy == PolicySkip && status == DiffIdentical:
+			result.Unchanged++
+		case policy == PolicySkip:
+			result.Skipped++
+		case policy == PolicyMerge && status != DiffNew:
+			v.stored = hasImport(existing[dayKey{v.memberID, v.date}], v.content)
+			merge = append(merge, v)
+			result.Combined++
+		default:
+			replace = append(replace, v)
+			if status == DiffNew {
+				result.Imported++
+			} else {
+				result.Merged++
 			}
 		}
 	}
 
-	// Bulk save
+	// Bulk save: replaced days lose their earlier import entries and summary,
+	// merged days keep everything and get a summary of all their entries
 	var savedEntries []model.DailyEntry
-	merged, imported := 0, 0
-	if len(valid) > 0 {
+	var summaries []model.DailySummary
+	var risks []model.Risk
+	if written := append(replace[:len(replace):len(replace)], merge...); len(written) > 0 {
+		if err := s.snapshotDays(ctx, batch.ID, written, replace, existing); err != nil {
+			return nil, fmt.Errorf("snapshot days: %w", err)
+		}
 		var delKeys [][]interface{}
-		now := time.Now()
-		for _, v := range valid {
+		for _, v := range replace {
 			delKeys = append(delKeys, []interface{}{v.memberID, v.date})
+		}
+		names := make(map[int]string, len(members))
+		for _, m := range members {
+			names[m.ID] = m.Name
+		}
+		now := time.Now()
+		var entryRisks [][]model.Risk
+		for _, v := range written {
+			if v.stored {
+				continue
+			}
 			entry := model.DailyEntry{
 				MemberID: v.memberID, DailyDate: v.date,
-				Content: v.content, Summary: v.content, Source: "import",
+				Content: v.content, Summary: v.content, Source: "import", BatchID: batch.ID,
 			}
 			entry.CreatedAt = now
 			savedEntries = append(savedEntries, entry)
-			if existingKeys[key{v.memberID, v.date}] {
-				merged++
-			} else {
-				imported++
-			}
+			entryRisks = append(entryRisks, splitRisks(v.memberID, names[v.memberID], v.date, v.risk))
+		}
+		var err error
+		if risks, err = s.dailyRepo.BulkReplaceImportEntries(ctx, delKeys, savedEntries, entryRisks); err != nil {
+			return nil, fmt.Errorf("save entries: %w", err)
 		}
-		s.dailyRepo.BulkReplaceImportEntries(ctx, delKeys, savedEntries)
 
-		// Bulk replace summaries (was 2645 individual UpsertSummary calls)
-		var summaries []model.DailySummary
-		for _, v := range valid {
+		// Bulk replace summaries (was 2645 individual UpsertSummary calls);
+		// status and blocker come from keywords, an LLM call per day is too slow here
+		for _, v := range replace {
+			status, blockers := GuessWorkStatus(v.content)
 			summaries = append(summaries, model.DailySummary{
 				MemberID: v.memberID, DailyDate: v.date, Summary: v.content,
+				Status: status, Risk: v.risk, Blocker: model.BlockerText(blockers), BatchID: batch.ID,
 			})
 		}
-		s.dailyRepo.BulkReplaceSummaries(ctx, delKeys, summaries)
+		if err := s.dailyRepo.BulkReplaceSummaries(ctx, delKeys, summaries); err != nil {
+			return nil, fmt.Errorf("save summaries: %w", err)
+		}
+		summaries = append(summaries, s.mergeSummaries(ctx, batch.ID, merge)...)
 	}
 
 	// Catalog sync — use background context so frontend disconnect won't cancel it
 	bgCtx := context.Background()
 	if len(savedEntries) > 0 && s.catalogSync != nil && s.catalogSync.Ready() {
-		s.catalogSync.SyncDailyEntries(bgCtx, savedEntries)
+		s.catalogSync.SyncDailyEntries(bgCtx, savedEntries, summaries)
+	}
+	if len(risks) > 0 && s.catalogSync != nil {
+		s.catalogSync.ResyncTables("risks")
+	}
+	for _, r := range risks {
+		events.Publish(ctx, s.events, events.RiskDetected, r)
 	}
 
 	// Extract topics async
 	if len(savedEntries) > 0 {
-		go s.batchExtractTopics(savedEntries, members)
+		go s.batchExtractTopics(batch.ID, savedEntries, members)
 	}
 
-	return &ConfirmResult{Imported: imported, Merged: merged, Skipped: skipped, Total: len(entries)}, nil
+	data, _ := json.Marshal(result)
+	if err := s.batches.SetResult(ctx, batch.ID, string(data)); err != nil {
+		logger.Warn("import: save batch result failed", "batch", batch.ID, "err", err)
+	}
+	events.Publish(ctx, s.events, events.ReportImported, result)
+	return result, nil
 }
 
 // --- extraction helpers ---
@@ -298,31 +362,6 @@ func extractProgrammatic(sections []DocxSection) []ExtractedEntry {
 	return all
 }
 
-func (s *ImportService) extractAll(ctx context.Context, sections []DocxSection, knownNames []string) []ExtractedEntry {
-	sem := make(chan struct{}, 50)
-	results := make([][]ExtractedEntry, len(sections))
-	var wg sync.WaitGroup
-	for i, sec := range sections {
-		wg.Add(1)
-		sem <- struct{}{}
-		go func(idx int, sec DocxSection) {
-			defer wg.Done()
-			defer func() { <-sem }()
-			entries, err := s.extractBatch(ctx, sec, knownNames)
-			if err == nil {
-				results[idx] = entries
-			}
-		}(i, sec)
-	}
-	wg.Wait()
-
-	var all []ExtractedEntry
-	for _, r := range results {
-		all = append(all, r...)
-	}
-	return all
-}
-
 func (s *ImportService) extractBatch(ctx context.Context, sec DocxSection, knownNames []string) ([]ExtractedEntry, error) {
 	var extra strings.Builder
 	if len(knownNames) > 0 {
@@ -382,7 +421,13 @@ func randHex(n int) string {
 	return hex.EncodeToString(b)[:n]
 }
 
-func (s *ImportService) batchExtractTopics(entries []model.DailyEntry, members []model.Member) {
+// lockedPassword marks accounts without a password of their own (imported or
+// provisioned on SSO login). It is no bcrypt hash, so password login fails
+// until an admin resets it, and only such accounts may be linked to an SSO
+// identity by email.
+const lockedPassword = "!"
+
+func (s *ImportService) batchExtractTopics(batchID int, entries []model.DailyEntry, members []model.Member) {
 	ctx := context.Background()
 
 	// Delete old topic_activities for these entries (idempotent re-import)
@@ -449,7 +494,7 @@ func (s *ImportService) batchExtractTopics(entries []model.DailyEntry, members [
 				for _, t := range topics {
 					allItems = append(allItems, model.TopicActivity{
 						Topic: t, MemberID: e.MemberID, MemberName: nameMap[e.MemberID],
-						DailyDate: date, Content: e.Content, EntryID: e.ID,
+						DailyDate: date, Content: e.Content, EntryID: e.ID, BatchID: batchID,
 					})
 				}
 			}
@@ -458,6 +503,10 @@ func (s *ImportService) batchExtractTopics(entries []model.DailyEntry, members [
 	}
 	wg.Wait()
 
+	// A batch reverted while its topics were being extracted keeps none
+	if b, err := s.batches.Get(ctx, batchID); err == nil && b.Status == BatchReverted {
+		return
+	}
 	if len(allItems) > 0 {
 		topicSet := map[string]bool{}
 		for _, item := range allItems {
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"smart-daily/internal/logger"
+	"smart-daily/internal/model"
+	"smart-daily/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// Import batch statuses.
+const (
+	BatchApplied  = "applied"
+	BatchReverted = "reverted"
+)
+
+var (
+	ErrImportBatchNotFound = errors.New("import batch not found")
+	ErrImportBatchReverted = errors.New("import batch already reverted")
+	// ErrImportBatchSuperseded: later batches rewrote some of its days and
+	// have to be reverted first.
+	ErrImportBatchSuperseded = errors.New("import batch superseded by later batches")
+)
+
+// revertCatalogTables are the Catalog tables holding rows a revert changes.
+var revertCatalogTables = []string{"members", "daily_entries", "daily_summaries", "topic_activities", "risks"}
+
+// RevertResult is what RevertBatch removed and restored.
+type RevertResult struct {
+	BatchID int `json:"batch_id"`
+	repository.RevertCounts
+}
+
+func (s *ImportService) GetBatch(ctx context.Context, id int) (*model.ImportBatch, error) {
+	b, err := s.batches.Get(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrImportBatchNotFound
+	}
+	return b, err
+}
+
+// ListBatches returns the recent batches of a member, or everyone's for
+// createdBy 0.
+func (s *ImportService) ListBatches(ctx context.Context, createdBy int) ([]model.ImportBatch, error) {
+	return s.batches.List(ctx, createdBy, 50)
+}
+
+// BatchResult returns the confirm result a batch recorded.
+func BatchResult(b *model.ImportBatch) *ConfirmResult {
+	if b.Result == "" {
+		return nil
+	}
+	var result ConfirmResult
+	if json.Unmarshal([]byte(b.Result), &result) != nil {
+		return nil
+	}
+	return &result
+}
+
+// RevertBatch undoes an import batch (see ImportBatchRepo.Revert), reloads
+// the affected Catalog tables and re-extracts the topics of restored entries.
+func (s *ImportService) RevertBatch(ctx context.Context, id, by int) (*RevertResult, error) {
+	if _, err := s.GetBatch(ctx, id); err != nil {
+		return nil, err
+	}
+	counts, applied, err := s.batches.Revert(ctx, id, by)
+	var superseded *repository.SupersededError
+	if errors.As(err, &superseded) {
+		return nil, fmt.Errorf("%w: revert batches %v first", ErrImportBatchSuperseded, superseded.Later)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !applied {
+		return nil, ErrImportBatchReverted
+	}
+	logger.Info("import: batch reverted", "batch", id, "entries", counts.DailyEntries,
+		"restored_summaries", counts.RestoredSummaries, "members", counts.Members)
+	if s.catalogSync != nil {
+		s.catalogSync.ResyncTables(revertCatalogTables...)
+	}
+	if len(counts.Restored) > 0 {
+		go s.restoreTopics(counts.Restored)
+	}
+	return &RevertResult{BatchID: id, RevertCounts: counts}, nil
+}
+
+// restoreTopics extracts the topic activities of entries a revert put back,
+// under the batch each entry came from.
+func (s *ImportService) restoreTopics(entries []model.DailyEntry) {
+	members, _ := s.memberRepo.ListActive(context.Background())
+	byBatch := map[int][]model.DailyEntry{}
+	for _, e := range entries {
+		byBatch[e.BatchID] = append(byBatch[e.BatchID], e)
+	}
+	for batchID, group := range byBatch {
+		s.batchExtractTopics(batchID, group, members)
+	}
+}
+
+// snapshotDays records the days batch is about to write as they are: their
+// summaries and, for replaced days, the import entries about to be deleted
+// with their risks.
+func (s *ImportService) snapshotDays(ctx context.Context, batchID int, written, replace []importRow, existing map[dayKey][]model.DailyEntry) error {
+	keys := make([][]interface{}, 0, len(written))
+	for _, v := range written {
+		keys = append(keys, []interface{}{v.memberID, v.date})
+	}
+	stored, err := s.dailyRepo.SummariesByKeys(ctx, keys)
+	if err != nil {
+		return err
+	}
+	summaryOf := make(map[dayKey]string, len(stored))
+	for _, sum := range stored {
+		date := sum.DailyDate
+		if len(date) > 10 {
+			date = date[:10]
+		}
+		sum.DailyDate = date
+		data, _ := json.Marshal(sum)
+		summaryOf[dayKey{sum.MemberID, date}] = string(data)
+	}
+	replaced := make(map[dayKey]bool, len(replace))
+	var oldIDs []int
+	for _, v := range replace {
+		k := dayKey{v.memberID, v.date}
+		replaced[k] = true
+		for _, e := range existing[k] {
+			if e.Source == "import" {
+				oldIDs = append(oldIDs, e.ID)
+			}
+		}
+	}
+	oldRisks, err := s.riskRepo.ListByEntries(ctx, oldIDs)
+	if err != nil {
+		return err
+	}
+	risksOf := map[int][]model.Risk{}
+	for _, r := range oldRisks {
+		risksOf[r.EntryID] = append(risksOf[r.EntryID], r)
+	}
+	snapshots := make([]model.ImportBatchSnapshot, 0, len(written))
+	for _, v := range written {
+		k := dayKey{v.memberID, v.date}
+		snap := model.ImportBatchSnapshot{BatchID: batchID, MemberID: v.memberID, DailyDate: v.date, Summary: summaryOf[k]}
+		if replaced[k] {
+			var old []model.DailyEntry
+			var risks []model.Risk
+			for _, e := range existing[k] {
+				if e.Source == "import" {
+					e.DailyDate = v.date
+					old = append(old, e)
+					risks = append(risks, risksOf[e.ID]...)
+				}
+			}
+			if len(old) > 0 {
+				data, _ := json.Marshal(old)
+				snap.Entries = string(data)
+			}
+			if len(risks) > 0 {
+				data, _ := json.Marshal(risks)
+				snap.Risks = string(data)
+			}
+		}
+		snapshots = append(snapshots, snap)
+	}
+	return s.batches.SaveSnapshots(ctx, snapshots)
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"smart-daily/internal/logger"
+	"smart-daily/internal/model"
+	"smart-daily/internal/repository"
+	"smart-daily/internal/textdiff"
+	"strings"
+	"sync"
+)
+
+// Per-row confirm policies for days that already have data.
+const (
+	PolicySkip    = "skip"
+	PolicyReplace = "replace"
+	PolicyMerge   = "merge"
+)
+
+// ValidPolicy reports whether p is a confirm policy.
+func ValidPolicy(p string) bool {
+	return p == PolicySkip || p == PolicyReplace || p == PolicyMerge
+}
+
+// Diff statuses of a preview row against the stored day.
+const (
+	DiffNew       = "new"       // no data that day
+	DiffIdentical = "identical" // same text as the earlier import
+	DiffChanged   = "changed"   // earlier import with different text
+	DiffConflict  = "conflict"  // the member reported that day in chat
+)
+
+// EntryDiff is a preview row with how it compares to the stored day:
+// Existing is that day's stored text, Diff its item-level changes and
+// Policy what confirm does by default.
+type EntryDiff struct {
+	ExtractedEntry
+	Key      string        `json:"key"`
+	Status   string        `json:"status"`
+	Existing string        `json:"existing,omitempty"`
+	Diff     []textdiff.Op `json:"diff,omitempty"`
+	Policy   string        `json:"policy"`
+}
+
+// EntryKey identifies a preview row in confirm policies.
+func EntryKey(e ExtractedEntry) string { return e.Date + "|" + strings.TrimSpace(e.Name) }
+
+type dayKey struct {
+	memberID int
+	date     string
+}
+
+// importRow is one member's day as confirm writes it.
+type importRow struct {
+	memberID int
+	key      string
+	date     string
+	content  string
+	risk     string
+	stored   bool // merged text already stored, e.g. by a confirm being retried
+}
+
+// classify compares imported content with the entries stored for its day.
+func classify(content string, existing []model.DailyEntry) (string, []textdiff.Op) {
+	if len(existing) == 0 {
+		return DiffNew, nil
+	}
+	old := existingText(existing)
+	conflict := false
+	for _, e := range existing {
+		if e.Source != "import" {
+			conflict = true
+		}
+	}
+	switch {
+	case conflict:
+		return DiffConflict, textdiff.Diff(old, content)
+	case strings.TrimSpace(old) == strings.TrimSpace(content):
+		return DiffIdentical, nil
+	}
+	return DiffChanged, textdiff.Diff(old, content)
+}
+
+// defaultPolicy keeps what is stored unless the row only updates an earlier
+// import: chat-submitted days are never overwritten without asking.
+func defaultPolicy(status string) string {
+	if status == DiffIdentical || status == DiffConflict {
+		return PolicySkip
+	}
+	return PolicyReplace
+}
+
+func existingText(entries []model.DailyEntry) string {
+	parts := make([]string, 0, len(entries))
+	for _, e := range entries {
+		parts = append(parts, e.Content)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// hasImport reports whether content is already one of the day's import entries.
+func hasImport(entries []model.DailyEntry, content string) bool {
+	for _, e := range entries {
+		if e.Source == "import" && strings.TrimSpace(e.Content) == strings.TrimSpace(content) {
+			return true
+		}
+	}
+	return false
+}
+
+func joinNonEmpty(a, b string) string {
+	switch {
+	case a == "" || a == b:
+		return b
+	case b == "":
+		return a
+	}
+	return a + "; " + b
+}
+
+// dayEntries loads the stored entries of member_id+daily_date keys.
+func (s *ImportService) dayEntries(ctx context.Context, keys [][]interface{}) (map[dayKey][]model.DailyEntry, error) {
+	entries, err := s.dailyRepo.EntriesByKeys(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[dayKey][]model.DailyEntry)
+	for _, e := range entries {
+		date := e.DailyDate
+		if len(date) > 10 {
+			date = date[:10]
+		}
+		k := dayKey{e.MemberID, date}
+		m[k] = append(m[k], e)
+	}
+	return m, nil
+}
+
+// DiffEntries classifies preview entries against stored data. Rows of the
+// same member and day are compared as one, the way Confirm writes them;
+// names without a member yet are new.
+func (s *ImportService) DiffEntries(ctx context.Context, entries []ExtractedEntry, members []model.Member) ([]EntryDiff, error) {
+	memberOf := make([]int, len(entries))
+	content := map[dayKey]string{}
+	var keys [][]interface{}
+	for i, e := range entries {
+		id := repository.MatchByName(strings.TrimSpace(e.Name), members)
+		if id == 0 || strings.TrimSpace(e.Content) == "" {
+			continue
+		}
+		memberOf[i] = id
+		k := dayKey{id, e.Date}
+		if c, ok := content[k]; ok {
+			content[k] = c + "; " + e.Content
+			continue
+		}
+		content[k] = e.Content
+		keys = append(keys, []interface{}{id, e.Date})
+	}
+	var existing map[dayKey][]model.DailyEntry
+	if len(keys) > 0 {
+		var err error
+		if existing, err = s.dayEntries(ctx, keys); err != nil {
+			return nil, err
+		}
+	}
+	out := make([]EntryDiff, len(entries))
+	for i, e := range entries {
+		d := EntryDiff{ExtractedEntry: e, Key: EntryKey(e), Status: DiffNew}
+		if memberOf[i] != 0 {
+			k := dayKey{memberOf[i], e.Date}
+			d.Status, d.Diff = classify(content[k], existing[k])
+			if d.Status != DiffNew {
+				d.Existing = existingText(existing[k])
+			}
+		}
+		d.Policy = defaultPolicy(d.Status)
+		out[i] = d
+	}
+	return out, nil
+}
+
+// mergeSummaries rewrites the summary of each merged day from all of its
+// entries, keeping the stored risk alongside the imported one.
+func (s *ImportService) mergeSummaries(ctx context.Context, batchID int, rows []importRow) []model.DailySummary {
+	var (
+		out []model.DailySummary
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+	)
+	sem := make(chan struct{}, 10)
+	for _, v := range rows {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(v importRow) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			entries, err := s.dailyRepo.GetDayEntries(ctx, v.memberID, v.date)
+			if err != nil {
+				logger.Warn("import: load day entries failed", "member", v.memberID, "date", v.date, "err", err)
+				return
+			}
+			text, err := s.ai.MergeDailySummary(ctx, entries)
+			if err != nil || strings.TrimSpace(text) == "" {
+				text = existingText(entries)
+			}
+			risk := v.risk
+			if old, err := s.dailyRepo.GetSummary(ctx, v.memberID, v.date); err == nil {
+				risk = joinNonEmpty(old.Risk, v.risk)
+			}
+			status, blockers := GuessWorkStatus(text)
+			sum := model.DailySummary{
+				MemberID: v.memberID, DailyDate: v.date, Summary: text,
+				Status: status, Risk: risk, Blocker: model.BlockerText(blockers), BatchID: batchID,
+			}
+			if err := s.dailyRepo.UpsertSummary(ctx, &sum); err != nil {
+				logger.Warn("import: merge summary failed", "member", v.memberID, "date", v.date, "err", err)
+				return
+			}
+			mu.Lock()
+			out = append(out, sum)
+			mu.Unlock()
+		}(v)
+	}
+	wg.Wait()
+	return out
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"reflect"
+	"smart-daily/internal/model"
+	"smart-daily/internal/textdiff"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	imported := []model.DailyEntry{{Content: "导入接口; 分页修复", Source: "import"}}
+	chat := append(imported, model.DailyEntry{Content: "- 评审方案", Source: "chat"})
+	tests := []struct {
+		name     string
+		content  string
+		existing []model.DailyEntry
+		status   string
+		diff     []textdiff.Op
+		policy   string
+	}{
+		{"new", "导入接口", nil, DiffNew, nil, PolicyReplace},
+		{"identical", " 导入接口; 分页修复\n", imported, DiffIdentical, nil, PolicySkip},
+		{"changed", "导入接口; 导出接口", imported, DiffChanged, []textdiff.Op{
+			{Type: textdiff.Equal, Text: "导入接口"}, {Type: textdiff.Delete, Text: "分页修复"}, {Type: textdiff.Insert, Text: "导出接口"},
+		}, PolicyReplace},
+		{"conflict", "导入接口; 分页修复", chat, DiffConflict, []textdiff.Op{
+			{Type: textdiff.Equal, Text: "导入接口"}, {Type: textdiff.Equal, Text: "分页修复"}, {Type: textdiff.Delete, Text: "评审方案"},
+		}, PolicySkip},
+	}
+	for _, tt := range tests {
+		status, diff := classify(tt.content, tt.existing)
+		if status != tt.status || !reflect.DeepEqual(diff, tt.diff) {
+			t.Errorf("%s: got %s %+v", tt.name, status, diff)
+		}
+		if p := defaultPolicy(status); p != tt.policy {
+			t.Errorf("%s: default policy %s, want %s", tt.name, p, tt.policy)
+		}
+	}
+}
+
+func TestJoinNonEmpty(t *testing.T) {
+	for _, tt := range [][3]string{
+		{"", "排期紧", "排期紧"},
+		{"排期紧", "", "排期紧"},
+		{"排期紧", "排期紧", "排期紧"},
+		{"排期紧", "依赖未就绪", "排期紧; 依赖未就绪"},
+	} {
+		if got := joinNonEmpty(tt[0], tt[1]); got != tt[2] {
+			t.Errorf("joinNonEmpty(%q, %q) = %q", tt[0], tt[1], got)
+		}
+	}
+}
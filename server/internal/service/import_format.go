@@ -0,0 +1,256 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"smart-daily/internal/docxparse"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+var (
+	ErrUnsupportedImport = errors.New("unsupported import file")
+	ErrNoImportDates     = errors.New("no dated sections in import file")
+)
+
+// ImportFile is an uploaded file split for extraction. Documents (docx,
+// Markdown, plain text) give Sections, which go through programmatic or LLM
+// extraction; spreadsheets laid out like the daily export give Entries
+// directly, so an export re-imports unchanged.
+type ImportFile struct {
+	Format   string
+	Sections []DocxSection
+	Entries  []ExtractedEntry
+}
+
+// ParseImportFile detects the format of an uploaded file from its name,
+// falling back to its content for unknown extensions, and parses it.
+func ParseImportFile(name string, r io.ReaderAt, size int64) (*ImportFile, error) {
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(name)), ".")
+	switch format {
+	case "docx", "xlsx", "csv", "txt":
+	case "md", "markdown":
+		format = "md"
+	default:
+		format = sniffImportFormat(r, size)
+	}
+
+	switch format {
+	case "docx":
+		sections, err := docxparse.Parse(r, size)
+		if errors.Is(err, docxparse.ErrNotDocx) {
+			return nil, ErrUnsupportedImport
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &ImportFile{Format: format, Sections: sections}, nil
+	case "xlsx":
+		f, err := excelize.OpenReader(io.NewSectionReader(r, 0, size))
+		if err != nil {
+			return nil, ErrUnsupportedImport
+		}
+		defer f.Close()
+		rows, err := f.GetRows(f.GetSheetName(0))
+		if err != nil {
+			return nil, fmt.Errorf("read sheet: %w", err)
+		}
+		return importFromRows(format, rows)
+	}
+
+	data, err := io.ReadAll(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return nil, err
+	}
+	text, ok := decodeText(data)
+	if !ok {
+		return nil, ErrUnsupportedImport
+	}
+	if format == "csv" {
+		cr := csv.NewReader(strings.NewReader(text))
+		cr.FieldsPerRecord = -1
+		cr.LazyQuotes = true
+		rows, err := cr.ReadAll()
+		if err != nil {
+			return nil, ErrUnsupportedImport
+		}
+		return importFromRows(format, rows)
+	}
+	sections := sectionsFromText(text)
+	if len(sections) == 0 {
+		return nil, ErrNoImportDates
+	}
+	return &ImportFile{Format: format, Sections: sections}, nil
+}
+
+// sniffImportFormat tells zip containers (docx, xlsx) from text; anything
+// else is read as plain text.
+func sniffImportFormat(r io.ReaderAt, size int64) string {
+	head := make([]byte, 4)
+	if n, _ := r.ReadAt(head, 0); n < 4 || !bytes.Equal(head, []byte("PK\x03\x04")) {
+		return "txt"
+	}
+	if _, err := docxparse.Parse(r, size); err == nil {
+		return "docx"
+	}
+	return "xlsx"
+}
+
+// decodeText returns data as UTF-8 without a byte order mark. Files that are
+// not valid UTF-8 are taken as GBK, which is what Excel and Notepad on Chinese
+// Windows save; binary content is rejected.
+func decodeText(data []byte) (string, bool) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(data) {
+		decoded, err := simplifiedchinese.GBK.NewDecoder().Bytes(data)
+		if err != nil || !utf8.Valid(decoded) {
+			return "", false
+		}
+		data = decoded
+	}
+	if bytes.IndexByte(data, 0) >= 0 {
+		return "", false
+	}
+	return strings.ReplaceAll(string(data), "\r\n", "\n"), true
+}
+
+// importFromRows reads spreadsheet rows as entries; sheets in another layout
+// are handed to extraction as text sections instead.
+func importFromRows(format string, rows [][]string) (*ImportFile, error) {
+	if entries := entriesFromRows(rows); len(entries) > 0 {
+		return &ImportFile{Format: format, Entries: entries}, nil
+	}
+	lines := make([]string, 0, len(rows))
+	for _, row := range rows {
+		lines = append(lines, strings.Join(row, "\t"))
+	}
+	sections := sectionsFromText(strings.Join(lines, "\n"))
+	if len(sections) == 0 {
+		return nil, ErrNoImportDates
+	}
+	return &ImportFile{Format: format, Sections: sections}, nil
+}
+
+var importHeaders = map[string]bool{"日期": true, "成员": true, "姓名": true, "date": true, "name": true}
+
+// entriesFromRows reads the daily export layout: a row holding only a date
+// starts that day, followed by name / summary / risk rows. Flat rows of
+// date / name / summary / risk (e.g. CSV) are read as well; header rows and
+// rows without a name or summary are skipped.
+func entriesFromRows(rows [][]string) []ExtractedEntry {
+	var out []ExtractedEntry
+	add := func(date string, cells []string) {
+		if len(cells) < 2 {
+			return
+		}
+		e := ExtractedEntry{Date: date, Name: strings.ReplaceAll(cells[0], " ", ""), Content: cells[1]}
+		if len(cells) > 2 {
+			e.Risk = cells[2]
+		}
+		if e.Name != "" && e.Content != "" && !importHeaders[strings.ToLower(e.Name)] {
+			out = append(out, e)
+		}
+	}
+	date := ""
+	for _, row := range rows {
+		cells := make([]string, 0, len(row))
+		for _, c := range row {
+			cells = append(cells, strings.TrimSpace(c))
+		}
+		for len(cells) > 0 && cells[len(cells)-1] == "" {
+			cells = cells[:len(cells)-1]
+		}
+		if len(cells) == 0 {
+			continue
+		}
+		if d, ok := normalizeImportDate(cells[0]); ok {
+			if len(cells) == 1 {
+				date = d
+			} else {
+				add(d, cells[1:])
+			}
+			continue
+		}
+		if date != "" {
+			add(date, cells)
+		}
+	}
+	return out
+}
+
+var lineDateRe = regexp.MustCompile(`^(\d{4})\s*[年/.-]\s*(\d{1,2})\s*[月/.-]\s*(\d{1,2})\s*日?`)
+
+// normalizeImportDate returns s as YYYY-MM-DD if it is a date such as
+// 2026-03-02, 2026/3/2 or 2026年3月2日.
+func normalizeImportDate(s string) (string, bool) {
+	m := lineDateRe.FindStringSubmatch(s)
+	if m == nil || len(m[0]) != len(s) {
+		return "", false
+	}
+	return validImportDate(m)
+}
+
+func validImportDate(m []string) (string, bool) {
+	d := fmt.Sprintf("%s-%02d-%02d", m[1], mustAtoi(m[2]), mustAtoi(m[3]))
+	if _, err := time.Parse("2006-01-02", d); err != nil {
+		return "", false
+	}
+	return d, true
+}
+
+var mdTableSepRe = regexp.MustCompile(`^[\s|:-]+$`)
+
+// sectionsFromText splits Markdown or plain text into one section per date:
+// a line starting with a date, optionally as a heading or in bold, starts a
+// section. Markdown table rows become tab-separated lines like docx tables,
+// so that tables in the usual member / content layout extract without the
+// LLM. Text before the first date and dates without content are dropped.
+func sectionsFromText(text string) []DocxSection {
+	var out []DocxSection
+	date := ""
+	var lines []string
+	flush := func() {
+		if date != "" && len(lines) > 0 {
+			out = append(out, DocxSection{Date: date, Text: strings.Join(lines, "\n")})
+		}
+	}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		head := strings.TrimLeft(line, "#*> \t")
+		if m := lineDateRe.FindStringSubmatch(head); m != nil {
+			if d, ok := validImportDate(m); ok {
+				flush()
+				// Sections carry the date in the docx heading form extraction expects.
+				rest := strings.Trim(head[len(m[0]):], "*# \t")
+				date = strings.TrimSpace(fmt.Sprintf("%s年%d月%d日 %s", d[:4], mustAtoi(d[5:7]), mustAtoi(d[8:]), rest))
+				lines = nil
+				continue
+			}
+		}
+		if date == "" || strings.TrimSpace(line) == "" {
+			continue
+		}
+		if t := strings.TrimSpace(line); strings.HasPrefix(t, "|") && strings.HasSuffix(t, "|") {
+			if mdTableSepRe.MatchString(t) {
+				continue
+			}
+			cells := strings.Split(strings.Trim(t, "|"), "|")
+			for i := range cells {
+				cells[i] = strings.TrimSpace(cells[i])
+			}
+			line = strings.Join(cells, "\t")
+		}
+		lines = append(lines, line)
+	}
+	flush()
+	return out
+}
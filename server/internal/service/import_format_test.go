@@ -0,0 +1,106 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func parseImport(t *testing.T, name string, data []byte) *ImportFile {
+	t.Helper()
+	f, err := ParseImportFile(name, bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return f
+}
+
+func TestImportMarkdown(t *testing.T) {
+	f := parseImport(t, "week.md", []byte(`# 二月第二周
+
+## 2026-02-13 周五
+
+| 成员 | 今日进展 |
+|------|----------|
+| 张三 | 导入接口 |
+
+**2026年2月16日**
+- 张三：分页修复
+`))
+	want := []DocxSection{
+		{Date: "2026年2月13日 周五", Text: "成员\t今日进展\n张三\t导入接口"},
+		{Date: "2026年2月16日", Text: "- 张三：分页修复"},
+	}
+	if f.Format != "md" || !reflect.DeepEqual(f.Sections, want) {
+		t.Errorf("got %s %q", f.Format, f.Sections)
+	}
+	if got := extractProgrammatic(f.Sections[:1]); len(got) != 1 || got[0] != (ExtractedEntry{Date: "2026-02-13", Name: "张三", Content: "导入接口"}) {
+		t.Errorf("markdown table not extracted programmatically: %+v", got)
+	}
+}
+
+func TestImportPlainText(t *testing.T) {
+	gbk, _ := simplifiedchinese.GBK.NewEncoder().Bytes([]byte("2026/3/2\r\n张三 导入接口\r\n2026-02-30\r\n"))
+	f := parseImport(t, "notes.txt", gbk)
+	// 2026-02-30 is no date, so it stays a content line
+	want := []DocxSection{{Date: "2026年3月2日", Text: "张三 导入接口\n2026-02-30"}}
+	if !reflect.DeepEqual(f.Sections, want) {
+		t.Errorf("got %q", f.Sections)
+	}
+
+	if _, err := ParseImportFile("notes.txt", strings.NewReader("张三 导入接口"), 16); !errors.Is(err, ErrNoImportDates) {
+		t.Errorf("undated text: %v", err)
+	}
+	bin := []byte{0x00, 0x01, 0xff, 0xfe}
+	if _, err := ParseImportFile("blob.bin", bytes.NewReader(bin), 4); !errors.Is(err, ErrUnsupportedImport) {
+		t.Errorf("binary: %v", err)
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+	f := parseImport(t, "daily.csv", []byte("\xef\xbb\xbf日期,姓名,内容,风险\n2026-03-02,张 三,\"导入接口\n分页修复\",依赖排期\n2026-03-02,李四,联调,\n"))
+	want := []ExtractedEntry{
+		{Date: "2026-03-02", Name: "张三", Content: "导入接口\n分页修复", Risk: "依赖排期"},
+		{Date: "2026-03-02", Name: "李四", Content: "联调"},
+	}
+	if !reflect.DeepEqual(f.Entries, want) {
+		t.Errorf("got %+v", f.Entries)
+	}
+}
+
+// An xlsx in ExportDaily's layout re-imports as the entries it was written from.
+func TestImportExportedXLSX(t *testing.T) {
+	want := []ExtractedEntry{
+		{Date: "2026-03-03", Name: "张三", Content: "上线导入", Risk: "回滚方案未定"},
+		{Date: "2026-03-03", Name: "李四", Content: "联调"},
+		{Date: "2026-03-02", Name: "张三", Content: "导入接口\n分页修复"},
+	}
+	x := excelize.NewFile()
+	r := 1
+	for i, e := range want {
+		if i == 0 || want[i-1].Date != e.Date {
+			cell, _ := excelize.CoordinatesToCellName(1, r)
+			end, _ := excelize.CoordinatesToCellName(3, r)
+			x.MergeCell("Sheet1", cell, end)
+			x.SetCellValue("Sheet1", cell, e.Date)
+			r++
+		}
+		x.SetSheetRow("Sheet1", "A"+strconv.Itoa(r), &[]string{e.Name, e.Content, e.Risk})
+		r++
+	}
+	var buf bytes.Buffer
+	x.Write(&buf)
+
+	for _, name := range []string{"日报导出.xlsx", "upload"} {
+		f := parseImport(t, name, buf.Bytes())
+		if f.Format != "xlsx" || !reflect.DeepEqual(f.Entries, want) {
+			t.Errorf("%s: got %s %+v", name, f.Format, f.Entries)
+		}
+	}
+}
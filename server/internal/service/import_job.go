@@ -0,0 +1,364 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"smart-daily/internal/logger"
+	"smart-daily/internal/model"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Import job statuses. A job runs parsing → extracting → preview, waits for
+// the member decisions, then runs confirming → done; a failed phase can be
+// resumed.
+const (
+	ImportParsing    = "parsing"
+	ImportExtracting = "extracting"
+	ImportPreview    = "preview"
+	ImportConfirming = "confirming"
+	ImportDone       = "done"
+	ImportFailed     = "failed"
+)
+
+const (
+	sectionPending = "pending"
+	sectionDone    = "done"
+	sectionFailed  = "failed"
+)
+
+var (
+	ErrImportJobNotFound = errors.New("import job not found")
+	ErrImportJobState    = errors.New("import job is not in a state for this action")
+)
+
+// jobPollInterval is how often WatchJob reads a job's progress.
+var jobPollInterval = 500 * time.Millisecond
+
+// ImportProgress is a job's status with its section counts.
+type ImportProgress struct {
+	JobID  int    `json:"job_id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	Total  int    `json:"total"`
+	Done   int    `json:"done"`
+	Failed int    `json:"failed"`
+}
+
+// Settled reports whether the job waits for the caller: at the preview, done or failed.
+func (p *ImportProgress) Settled() bool {
+	return p.Status == ImportPreview || p.Status == ImportDone || p.Status == ImportFailed
+}
+
+// StartJob stores a parsed import file as a job owned by createdBy and starts
+// extracting it in the background.
+func (s *ImportService) StartJob(ctx context.Context, createdBy int, fileName string, file *ImportFile) (*model.ImportJob, error) {
+	sections, err := jobSections(file)
+	if err != nil {
+		return nil, err
+	}
+	job := &model.ImportJob{CreatedBy: createdBy, FileName: fileName, Format: file.Format, Status: ImportParsing}
+	if err := s.jobs.Create(ctx, job, sections); err != nil {
+		return nil, fmt.Errorf("create import job: %w", err)
+	}
+	if _, err := s.jobs.Transition(ctx, job.ID, []string{ImportParsing}, ImportExtracting, nil); err != nil {
+		return nil, err
+	}
+	job.Status = ImportExtracting
+	logger.Info("import job: created", "job", job.ID, "format", file.Format, "sections", len(sections))
+	go s.runExtract(job.ID)
+	return job, nil
+}
+
+// jobSections turns a parsed file into job sections: one pending section per
+// document section, or a single done section holding entries read directly.
+func jobSections(file *ImportFile) ([]model.ImportJobSection, error) {
+	if len(file.Entries) > 0 {
+		data, err := json.Marshal(file.Entries)
+		if err != nil {
+			return nil, err
+		}
+		return []model.ImportJobSection{{Seq: 0, Status: sectionDone, Entries: string(data)}}, nil
+	}
+	sections := make([]model.ImportJobSection, 0, len(file.Sections))
+	for i, sec := range file.Sections {
+		sections = append(sections, model.ImportJobSection{Seq: i, Date: sec.Date, Text: sec.Text, Status: sectionPending})
+	}
+	return sections, nil
+}
+
+// GetJob returns a job.
+func (s *ImportService) GetJob(ctx context.Context, id int) (*model.ImportJob, error) {
+	job, err := s.jobs.Get(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrImportJobNotFound
+	}
+	return job, err
+}
+
+// ListJobs returns the member's recent jobs.
+func (s *ImportService) ListJobs(ctx context.Context, createdBy int) ([]model.ImportJob, error) {
+	return s.jobs.ListFor(ctx, createdBy, 20)
+}
+
+// Progress returns a job's status and section counts.
+func (s *ImportService) Progress(ctx context.Context, id int) (*ImportProgress, error) {
+	job, err := s.GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.jobs.SectionCounts(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	p := &ImportProgress{JobID: id, Status: job.Status, Error: job.Error, Done: counts[sectionDone], Failed: counts[sectionFailed]}
+	for _, n := range counts {
+		p.Total += n
+	}
+	return p, nil
+}
+
+// WatchJob calls fn with a job's progress whenever it changes until the job
+// is settled or ctx ends, and returns the last progress.
+func (s *ImportService) WatchJob(ctx context.Context, id int, fn func(*ImportProgress)) (*ImportProgress, error) {
+	var last *ImportProgress
+	tick := time.NewTicker(jobPollInterval)
+	defer tick.Stop()
+	for {
+		p, err := s.Progress(ctx, id)
+		if err != nil {
+			return last, err
+		}
+		if last == nil || *p != *last {
+			fn(p)
+			last = p
+		}
+		if p.Settled() {
+			return p, nil
+		}
+		select {
+		case <-ctx.Done():
+			return last, ctx.Err()
+		case <-tick.C:
+		}
+	}
+}
+
+// JobPreview returns the entries extracted from a job's sections, matched
+// against the active members.
+func (s *ImportService) JobPreview(ctx context.Context, id int) (*PreviewResult, error) {
+	sections, err := s.jobs.Sections(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	entries := []ExtractedEntry{}
+	for _, sec := range sections {
+		if sec.Status != sectionDone || sec.Entries == "" {
+			continue
+		}
+		var part []ExtractedEntry
+		if err := json.Unmarshal([]byte(sec.Entries), &part); err != nil {
+			return nil, fmt.Errorf("section %d entries: %w", sec.Seq, err)
+		}
+		entries = append(entries, part...)
+	}
+	return s.preview(ctx, entries), nil
+}
+
+// ResumeExtract restarts extraction of a job that failed before its preview;
+// sections already extracted are kept.
+func (s *ImportService) ResumeExtract(ctx context.Context, job *model.ImportJob) error {
+	ok, err := s.jobs.Transition(ctx, job.ID, []string{ImportFailed}, ImportExtracting, map[string]interface{}{"error": ""})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrImportJobState
+	}
+	logger.Info("import job: resume extraction", "job", job.ID)
+	go s.runExtract(job.ID)
+	return nil
+}
+
+// runExtract extracts the job's pending and failed sections, programmatically
+// if they are all tables, otherwise with the LLM, saving each section's
+// result as it completes.
+func (s *ImportService) runExtract(id int) {
+	ctx := context.Background()
+	start := time.Now()
+	sections, err := s.jobs.Sections(ctx, id)
+	if err != nil {
+		s.failJob(ctx, id, err)
+		return
+	}
+	var pending []model.ImportJobSection
+	for _, sec := range sections {
+		if sec.Status != sectionDone {
+			pending = append(pending, sec)
+		}
+	}
+
+	docs := make([]DocxSection, len(pending))
+	for i, sec := range pending {
+		docs[i] = DocxSection{Date: sec.Date, Text: sec.Text}
+	}
+	failed := 0
+	if len(pending) > 0 && len(extractProgrammatic(docs)) > 0 {
+		logger.Info("import job: programmatic extraction", "job", id, "sections", len(pending))
+		for i, sec := range pending {
+			if err := s.saveSection(ctx, sec.ID, extractProgrammatic(docs[i:i+1]), nil); err != nil {
+				failed++
+			}
+		}
+	} else if len(pending) > 0 {
+		logger.Info("import job: LLM extraction", "job", id, "sections", len(pending))
+		failed = s.extractSections(ctx, pending, docs)
+	}
+
+	if failed > 0 {
+		s.failJob(ctx, id, fmt.Errorf("%d 个分段提取失败，可继续重试", failed))
+		return
+	}
+	if _, err := s.jobs.Transition(ctx, id, []string{ImportExtracting}, ImportPreview, nil); err != nil {
+		logger.Error("import job: update status failed", "job", id, "err", err)
+	}
+	logger.Info("import job: preview ready", "job", id, "elapsed", time.Since(start).Round(time.Millisecond))
+}
+
+// extractSections runs LLM extraction on sections concurrently (up to 50 at
+// a time) and returns how many failed.
+func (s *ImportService) extractSections(ctx context.Context, sections []model.ImportJobSection, docs []DocxSection) int {
+	members, _ := s.memberRepo.ListActive(ctx)
+	knownNames := make([]string, 0, len(members))
+	for _, m := range members {
+		knownNames = append(knownNames, m.Name)
+	}
+
+	sem := make(chan struct{}, 50)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	failed := 0
+	for i, sec := range sections {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(id int, doc DocxSection) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			entries, err := s.extractBatch(ctx, doc, knownNames)
+			if err := s.saveSection(ctx, id, entries, err); err != nil {
+				mu.Lock()
+				failed++
+				mu.Unlock()
+			}
+		}(sec.ID, docs[i])
+	}
+	wg.Wait()
+	return failed
+}
+
+// saveSection records a section's extraction result, or extractErr as its
+// failure; it returns an error if the section did not end up done.
+func (s *ImportService) saveSection(ctx context.Context, id int, entries []ExtractedEntry, extractErr error) error {
+	if extractErr != nil {
+		logger.Warn("import job: section extraction failed", "section", id, "err", extractErr)
+		s.jobs.UpdateSection(ctx, id, map[string]interface{}{"status": sectionFailed, "error": extractErr.Error()})
+		return extractErr
+	}
+	if entries == nil {
+		entries = []ExtractedEntry{}
+	}
+	data, _ := json.Marshal(entries)
+	if err := s.jobs.UpdateSection(ctx, id, map[string]interface{}{"status": sectionDone, "entries": string(data), "error": ""}); err != nil {
+		logger.Error("import job: save section failed", "section", id, "err", err)
+		return err
+	}
+	return nil
+}
+
+// StartConfirm saves entries for a job at its preview, or resumes a confirm
+// that failed, in the background. entries are the preview entries the caller
+// may import, policies the per-row choices for days with data (see Confirm).
+func (s *ImportService) StartConfirm(ctx context.Context, job *model.ImportJob, entries []ExtractedEntry, members []model.Member, decisions map[string]MemberDecision, policies map[string]string) error {
+	from := []string{ImportPreview}
+	if job.Decisions != "" {
+		from = append(from, ImportFailed)
+	}
+	data, _ := json.Marshal(decisions)
+	policyData, _ := json.Marshal(policies)
+	ok, err := s.jobs.Transition(ctx, job.ID, from, ImportConfirming, map[string]interface{}{
+		"decisions": string(data), "policies": string(policyData), "error": "",
+	})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrImportJobState
+	}
+	logger.Info("import job: confirm", "job", job.ID, "entries", len(entries), "decisions", len(decisions), "policies", len(policies))
+	go func() {
+		ctx := context.Background()
+		result, err := s.Confirm(ctx, &model.ImportBatch{JobID: job.ID, CreatedBy: job.CreatedBy, FileName: job.FileName}, entries, members, decisions, policies)
+		if err != nil {
+			s.failJob(ctx, job.ID, err)
+			return
+		}
+		data, _ := json.Marshal(result)
+		if _, err := s.jobs.Transition(ctx, job.ID, []string{ImportConfirming}, ImportDone, map[string]interface{}{"result": string(data)}); err != nil {
+			logger.Error("import job: update status failed", "job", job.ID, "err", err)
+		}
+		logger.Info("import job: done", "job", job.ID, "batch", result.BatchID, "imported", result.Imported, "merged", result.Merged, "combined", result.Combined, "skipped", result.Skipped)
+	}()
+	return nil
+}
+
+// JobDecisions returns the member decisions a job was last confirmed with.
+func JobDecisions(job *model.ImportJob) map[string]MemberDecision {
+	var decisions map[string]MemberDecision
+	if job.Decisions != "" {
+		json.Unmarshal([]byte(job.Decisions), &decisions)
+	}
+	return decisions
+}
+
+// JobPolicies returns the per-row policies a job was last confirmed with.
+func JobPolicies(job *model.ImportJob) map[string]string {
+	var policies map[string]string
+	if job.Policies != "" {
+		json.Unmarshal([]byte(job.Policies), &policies)
+	}
+	return policies
+}
+
+// JobResult returns a done job's confirm result.
+func JobResult(job *model.ImportJob) *ConfirmResult {
+	if job.Result == "" {
+		return nil
+	}
+	var result ConfirmResult
+	if json.Unmarshal([]byte(job.Result), &result) != nil {
+		return nil
+	}
+	return &result
+}
+
+// RecoverJobs fails the jobs a previous run left running so they can be
+// resumed; call it once at startup.
+func (s *ImportService) RecoverJobs(ctx context.Context) {
+	n, err := s.jobs.FailRunning(ctx, []string{ImportParsing, ImportExtracting, ImportConfirming}, "服务重启，任务中断，可继续")
+	if err != nil {
+		logger.Error("import job: recover failed", "err", err)
+	} else if n > 0 {
+		logger.Info("import job: interrupted jobs marked failed", "count", n)
+	}
+}
+
+func (s *ImportService) failJob(ctx context.Context, id int, cause error) {
+	logger.Error("import job: failed", "job", id, "err", cause)
+	if _, err := s.jobs.Transition(ctx, id, []string{ImportParsing, ImportExtracting, ImportConfirming}, ImportFailed, map[string]interface{}{"error": cause.Error()}); err != nil {
+		logger.Error("import job: update status failed", "job", id, "err", err)
+	}
+}
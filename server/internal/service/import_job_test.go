@@ -0,0 +1,50 @@
+package service
+
+import (
+	"encoding/json"
+	"reflect"
+	"smart-daily/internal/model"
+	"testing"
+)
+
+func TestJobSections(t *testing.T) {
+	got, err := jobSections(&ImportFile{Format: "md", Sections: []DocxSection{
+		{Date: "2026年3月2日", Text: "张三\t导入接口"},
+		{Date: "2026年3月3日", Text: "张三\t分页修复"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []model.ImportJobSection{
+		{Seq: 0, Date: "2026年3月2日", Text: "张三\t导入接口", Status: sectionPending},
+		{Seq: 1, Date: "2026年3月3日", Text: "张三\t分页修复", Status: sectionPending},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("document sections: %+v", got)
+	}
+
+	// Entries read directly need no extraction: one section, already done
+	entries := []ExtractedEntry{{Date: "2026-03-02", Name: "张三", Content: "导入接口", Risk: "依赖排期"}}
+	got, err = jobSections(&ImportFile{Format: "csv", Entries: entries})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Status != sectionDone {
+		t.Fatalf("entry sections: %+v", got)
+	}
+	var back []ExtractedEntry
+	if err := json.Unmarshal([]byte(got[0].Entries), &back); err != nil || !reflect.DeepEqual(back, entries) {
+		t.Errorf("entries round trip: %+v %v", back, err)
+	}
+}
+
+func TestImportProgressSettled(t *testing.T) {
+	for status, want := range map[string]bool{
+		ImportParsing: false, ImportExtracting: false, ImportPreview: true,
+		ImportConfirming: false, ImportDone: true, ImportFailed: true,
+	} {
+		if got := (&ImportProgress{Status: status}).Settled(); got != want {
+			t.Errorf("%s: settled=%v", status, got)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"smart-daily/internal/config"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+const ldapTimeout = 5 * time.Second
+
+// LDAPAuthenticator checks passwords with a search-then-bind: the service
+// account (or an anonymous bind) looks up the user's DN by user_filter, then
+// the user's own DN and password are bound on the same connection.
+type LDAPAuthenticator struct{ cfg config.LDAPConfig }
+
+func NewLDAPAuthenticator(cfg config.LDAPConfig) *LDAPAuthenticator {
+	return &LDAPAuthenticator{cfg: cfg}
+}
+
+func (a *LDAPAuthenticator) Name() string { return "ldap" }
+
+func (a *LDAPAuthenticator) Authenticate(ctx context.Context, username, password string) (*Identity, error) {
+	conn, err := a.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if a.cfg.BindDN != "" {
+		if err := conn.Bind(a.cfg.BindDN, a.cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("ldap service bind: %w", err)
+		}
+	}
+	attrs := []string{"dn", a.cfg.UsernameAttr, a.cfg.EmailAttr, a.cfg.NameAttr}
+	res, err := conn.Search(ldap.NewSearchRequest(a.cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, int(ldapTimeout/time.Second), false, a.filter(username), attrs, nil))
+	if err != nil {
+		return nil, fmt.Errorf("ldap search: %w", err)
+	}
+	switch len(res.Entries) {
+	case 0:
+		return nil, ErrUnknownUser
+	case 1:
+	default:
+		return nil, fmt.Errorf("ldap search: %q matches %d entries", username, len(res.Entries))
+	}
+	entry := res.Entries[0]
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrWrongPassword
+		}
+		return nil, fmt.Errorf("ldap user bind: %w", err)
+	}
+	id := &Identity{
+		Provider: "ldap",
+		Subject:  entry.DN,
+		Username: entry.GetAttributeValue(a.cfg.UsernameAttr),
+		Email:    entry.GetAttributeValue(a.cfg.EmailAttr),
+		Name:     entry.GetAttributeValue(a.cfg.NameAttr),
+	}
+	if id.Username == "" {
+		id.Username = username
+	}
+	return id, nil
+}
+
+// filter substitutes the escaped username into user_filter.
+func (a *LDAPAuthenticator) filter(username string) string {
+	f := a.cfg.UserFilter
+	if f == "" {
+		f = "(uid=%s)"
+	}
+	return strings.ReplaceAll(f, "%s", ldap.EscapeFilter(username))
+}
+
+func (a *LDAPAuthenticator) dial(ctx context.Context) (*ldap.Conn, error) {
+	tlsCfg := &tls.Config{InsecureSkipVerify: a.cfg.InsecureSkipVerify}
+	if u, err := url.Parse(a.cfg.URL); err == nil {
+		tlsCfg.ServerName = u.Hostname()
+	}
+	dialer := &net.Dialer{Timeout: ldapTimeout}
+	if d, ok := ctx.Deadline(); ok {
+		dialer.Deadline = d
+	}
+	conn, err := ldap.DialURL(a.cfg.URL, ldap.DialWithDialer(dialer), ldap.DialWithTLSConfig(tlsCfg))
+	if err != nil {
+		return nil, fmt.Errorf("ldap dial: %w", err)
+	}
+	conn.SetTimeout(ldapTimeout)
+	if a.cfg.StartTLS {
+		if err := conn.StartTLS(tlsCfg); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("ldap starttls: %w", err)
+		}
+	}
+	return conn, nil
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"smart-daily/internal/config"
+	"strings"
+)
+
+// LLMProvider is a chat-completions backend used by AIService.
+// Messages use the OpenAI shape: [{"role":"system|user|assistant","content":"..."}].
+type LLMProvider interface {
+	Name() string
+	// Model is used for summaries, reports and free chat.
+	Model() string
+	// FastModel is used for classification, validation and extraction.
+	FastModel() string
+	Chat(ctx context.Context, model string, msgs []map[string]string) (string, error)
+	// Stream calls flush for every token and returns the full text.
+	Stream(ctx context.Context, model string, msgs []map[string]string, flush func(string)) (string, error)
+}
+
+// NewLLMProvider builds the provider selected by moi.llm_provider (default "moi").
+func NewLLMProvider(cfg config.MOIConfig) (LLMProvider, error) {
+	switch cfg.LLMProvider {
+	case "", "moi":
+		return NewMOIProvider(cfg.BaseURL, cfg.APIKey, cfg.Model, cfg.FastModel), nil
+	case "openai":
+		if cfg.LLMBaseURL == "" {
+			return nil, fmt.Errorf("llm_provider openai requires llm_base_url")
+		}
+		return NewOpenAIProvider(cfg.LLMBaseURL, cfg.LLMAPIKey, cfg.Model, cfg.FastModel), nil
+	case "fake":
+		return NewFakeProvider(nil), nil
+	default:
+		return nil, fmt.Errorf("unknown llm_provider %q", cfg.LLMProvider)
+	}
+}
+
+// httpProvider talks to an OpenAI-compatible /chat/completions endpoint.
+type httpProvider struct {
+	name      string
+	endpoint  string
+	model     string
+	fastModel string
+	auth      func(*http.Request)
+	client    *http.Client
+}
+
+// NewMOIProvider calls the MOI LLM proxy, authenticated with the moi-key header.
+func NewMOIProvider(baseURL, apiKey, model, fastModel string) LLMProvider {
+	return &httpProvider{
+		name: "moi", endpoint: baseURL + "/llm-proxy/v1/chat/completions",
+		model: model, fastModel: fastModel, client: &http.Client{},
+		auth: func(r *http.Request) { r.Header.Set("moi-key", apiKey) },
+	}
+}
+
+// NewOpenAIProvider calls any OpenAI-compatible server (vLLM, Ollama, DashScope...).
+// baseURL is the API root, e.g. http://localhost:11434/v1.
+func NewOpenAIProvider(baseURL, apiKey, model, fastModel string) LLMProvider {
+	return &httpProvider{
+		name: "openai", endpoint: strings.TrimRight(baseURL, "/") + "/chat/completions",
+		model: model, fastModel: fastModel, client: &http.Client{},
+		auth: func(r *http.Request) {
+			if apiKey != "" {
+				r.Header.Set("Authorization", "Bearer "+apiKey)
+			}
+		},
+	}
+}
+
+func (p *httpProvider) Name() string      { return p.name }
+func (p *httpProvider) Model() string     { return p.model }
+func (p *httpProvider) FastModel() string { return p.fastModel }
+
+func (p *httpProvider) Chat(ctx context.Context, model string, msgs []map[string]string) (string, error) {
+	resp, err := p.post(ctx, model, msgs, false)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(resp.Body)
+	var result struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return "", fmt.Errorf("decode response: %w", err)
+	}
+	if len(result.Choices) == 0 {
+		return "", fmt.Errorf("empty choices")
+	}
+	return result.Choices[0].Message.Content, nil
+}
+
+func (p *httpProvider) Stream(ctx context.Context, model string, msgs []map[string]string, flush func(string)) (string, error) {
+	resp, err := p.post(ctx, model, msgs, true)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	var full strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		data := line[6:]
+		if data == "[DONE]" {
+			break
+		}
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+		}
+		if json.Unmarshal([]byte(data), &chunk) == nil && len(chunk.Choices) > 0 {
+			token := chunk.Choices[0].Delta.Content
+			if token != "" {
+				full.WriteString(token)
+				if flush != nil {
+					flush(token)
+				}
+			}
+		}
+	}
+	return full.String(), nil
+}
+
+func (p *httpProvider) post(ctx context.Context, model string, msgs []map[string]string, stream bool) (*http.Response, error) {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"model":    model,
+		"stream":   stream,
+		"messages": msgs,
+	})
+	req, err := http.NewRequestWithContext(ctx, "POST", p.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	p.auth(req)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("llm call: %w", err)
+	}
+	if resp.StatusCode != 200 {
+		data, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("llm status %d: %s", resp.StatusCode, data)
+	}
+	return resp, nil
+}
+
+// FakeReply produces a deterministic reply for a chat request.
+type FakeReply func(model string, msgs []map[string]string) string
+
+// FakeProvider is an in-process provider for tests and offline runs.
+// With a nil reply func it echoes the last user message, which keeps the report
+// pipeline usable: JSON-expecting callers fall back to their lenient defaults.
+type FakeProvider struct{ reply FakeReply }
+
+func NewFakeProvider(reply FakeReply) *FakeProvider {
+	if reply == nil {
+		reply = func(_ string, msgs []map[string]string) string {
+			if len(msgs) == 0 {
+				return ""
+			}
+			return msgs[len(msgs)-1]["content"]
+		}
+	}
+	return &FakeProvider{reply: reply}
+}
+
+func (p *FakeProvider) Name() string      { return "fake" }
+func (p *FakeProvider) Model() string     { return "fake" }
+func (p *FakeProvider) FastModel() string { return "fake-fast" }
+
+func (p *FakeProvider) Chat(_ context.Context, model string, msgs []map[string]string) (string, error) {
+	return p.reply(model, msgs), nil
+}
+
+func (p *FakeProvider) Stream(ctx context.Context, model string, msgs []map[string]string, flush func(string)) (string, error) {
+	out := p.reply(model, msgs)
+	if flush != nil {
+		for _, line := range strings.SplitAfter(out, "\n") {
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+			if line != "" {
+				flush(line)
+			}
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"smart-daily/internal/config"
+	"strings"
+	"testing"
+)
+
+func TestNewLLMProvider(t *testing.T) {
+	for _, tc := range []struct {
+		cfg  config.MOIConfig
+		want string // provider name, "" for an error
+	}{
+		{config.MOIConfig{}, "moi"},
+		{config.MOIConfig{LLMProvider: "moi"}, "moi"},
+		{config.MOIConfig{LLMProvider: "openai", LLMBaseURL: "http://localhost:11434/v1"}, "openai"},
+		{config.MOIConfig{LLMProvider: "openai"}, ""}, // needs llm_base_url
+		{config.MOIConfig{LLMProvider: "fake"}, "fake"},
+		{config.MOIConfig{LLMProvider: "claude"}, ""},
+	} {
+		tc.cfg.Model, tc.cfg.FastModel = "qwen-plus", "qwen-turbo"
+		p, err := NewLLMProvider(tc.cfg)
+		if tc.want == "" {
+			if err == nil {
+				t.Errorf("llm_provider %q: got %s, want an error", tc.cfg.LLMProvider, p.Name())
+			}
+			continue
+		}
+		if err != nil || p.Name() != tc.want {
+			t.Errorf("llm_provider %q: got %v, %v, want %s", tc.cfg.LLMProvider, p, err, tc.want)
+			continue
+		}
+		if tc.want != "fake" && (p.Model() != "qwen-plus" || p.FastModel() != "qwen-turbo") {
+			t.Errorf("llm_provider %q: models %s/%s", tc.cfg.LLMProvider, p.Model(), p.FastModel())
+		}
+	}
+}
+
+// llmServer answers chat completions with reply, or with a stream of its
+// characters when asked to, and records the last request.
+type llmServer struct {
+	*httptest.Server
+	path   string
+	header http.Header
+	body   map[string]interface{}
+}
+
+func newLLMServer(t *testing.T, reply string) *llmServer {
+	s := &llmServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.path, s.header = r.URL.Path, r.Header.Clone()
+		json.NewDecoder(r.Body).Decode(&s.body)
+		if s.body["stream"] == true {
+			for _, ch := range reply {
+				data, _ := json.Marshal(map[string]interface{}{"choices": []interface{}{map[string]interface{}{"delta": map[string]string{"content": string(ch)}}}})
+				fmt.Fprintf(w, "data: %s\n\n", data)
+			}
+			fmt.Fprint(w, "data: [DONE]\n\n")
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"choices": []interface{}{map[string]interface{}{"message": map[string]string{"content": reply}}}})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestHTTPProviderAuth(t *testing.T) {
+	srv := newLLMServer(t, "好的")
+	msgs := []map[string]string{{"role": "user", "content": "你好"}}
+	for _, tc := range []struct {
+		p          LLMProvider
+		path       string
+		moiKey     string
+		authHeader string
+	}{
+		{NewMOIProvider(srv.URL, "mk", "m", "f"), "/llm-proxy/v1/chat/completions", "mk", ""},
+		{NewOpenAIProvider(srv.URL+"/v1/", "sk", "m", "f"), "/v1/chat/completions", "", "Bearer sk"},
+		{NewOpenAIProvider(srv.URL+"/v1", "", "m", "f"), "/v1/chat/completions", "", ""}, // local servers need no key
+	} {
+		got, err := tc.p.Chat(context.Background(), "m", msgs)
+		if err != nil || got != "好的" {
+			t.Errorf("%s: Chat = %q, %v", tc.p.Name(), got, err)
+			continue
+		}
+		if srv.path != tc.path {
+			t.Errorf("%s: path %s, want %s", tc.p.Name(), srv.path, tc.path)
+		}
+		if k := srv.header.Get("moi-key"); k != tc.moiKey {
+			t.Errorf("%s: moi-key %q, want %q", tc.p.Name(), k, tc.moiKey)
+		}
+		if a := srv.header.Get("Authorization"); a != tc.authHeader {
+			t.Errorf("%s: Authorization %q, want %q", tc.p.Name(), a, tc.authHeader)
+		}
+		if srv.body["model"] != "m" || srv.body["stream"] != false {
+			t.Errorf("%s: request body %v", tc.p.Name(), srv.body)
+		}
+	}
+}
+
+func TestHTTPProviderStream(t *testing.T) {
+	srv := newLLMServer(t, "日报已生成")
+	var tokens []string
+	got, err := NewOpenAIProvider(srv.URL, "", "m", "f").Stream(context.Background(), "f",
+		[]map[string]string{{"role": "user", "content": "总结"}}, func(s string) { tokens = append(tokens, s) })
+	if err != nil || got != "日报已生成" || strings.Join(tokens, "") != got {
+		t.Errorf("Stream = %q, %v, tokens %q", got, err, tokens)
+	}
+	if srv.body["model"] != "f" || srv.body["stream"] != true {
+		t.Errorf("request body %v", srv.body)
+	}
+}
+
+func TestHTTPProviderErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid moi-key", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+	p := NewMOIProvider(srv.URL, "bad", "m", "f")
+	msgs := []map[string]string{{"role": "user", "content": "你好"}}
+	if _, err := p.Chat(context.Background(), "m", msgs); err == nil || !strings.Contains(err.Error(), "llm status 401: invalid moi-key") {
+		t.Errorf("Chat error = %v, want llm status 401", err)
+	}
+	if _, err := p.Stream(context.Background(), "m", msgs, nil); err == nil || !strings.Contains(err.Error(), "llm status 401") {
+		t.Errorf("Stream error = %v, want llm status 401", err)
+	}
+}
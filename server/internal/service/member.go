@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"smart-daily/internal/authz"
+	"smart-daily/internal/model"
+	"smart-daily/internal/repository"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUsernameTaken  = errors.New("username already exists")
+	ErrMemberNotFound = errors.New("member not found")
+	ErrNotDeleted     = errors.New("member is not deleted")
+	ErrTeamNotFound   = errors.New("team not found")
+)
+
+var usernameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{1,63}$`)
+
+// MemberService manages the member lifecycle beyond plain field updates:
+// explicit creation, restoring soft-deleted members and purging them for good.
+type MemberService struct {
+	repo    *repository.MemberRepo
+	catalog *CatalogSync
+}
+
+func NewMemberService(repo *repository.MemberRepo, catalog *CatalogSync) *MemberService {
+	return &MemberService{repo: repo, catalog: catalog}
+}
+
+// NewMember is the input for Create.
+type NewMember struct {
+	Username   string `json:"username"`
+	Name       string `json:"name"`
+	Role       string `json:"role"`
+	TeamID     int    `json:"team_id"`
+	Email      string `json:"email"`
+	AccessRole string `json:"access_role"`
+}
+
+// Validate trims the fields and checks them; it returns a user-facing error.
+func (n *NewMember) Validate() error {
+	n.Username = strings.TrimSpace(n.Username)
+	n.Name = strings.TrimSpace(n.Name)
+	n.Email = strings.TrimSpace(n.Email)
+	if !usernameRe.MatchString(n.Username) {
+		return errors.New("username must be 2-64 letters, digits, '.', '_' or '-'")
+	}
+	if n.Name == "" {
+		return errors.New("name required")
+	}
+	if n.AccessRole == "" {
+		n.AccessRole = string(authz.RoleMember)
+	}
+	if !authz.Role(n.AccessRole).Valid() {
+		return errors.New("access_role must be member, team_lead or admin")
+	}
+	if authz.Role(n.AccessRole) == authz.RoleTeamLead {
+		return errors.New("team leads are designated in the team settings; create the member first")
+	}
+	if n.Role == "" {
+		n.Role = "开发工程师"
+	}
+	return nil
+}
+
+// Create adds an active member with a one-time temporary password, returned
+// once; the member has to change it at first login.
+func (s *MemberService) Create(ctx context.Context, in NewMember) (*model.Member, string, error) {
+	if err := in.Validate(); err != nil {
+		return nil, "", err
+	}
+	if taken, err := s.repo.UsernameExists(ctx, in.Username); err != nil {
+		return nil, "", err
+	} else if taken {
+		return nil, "", ErrUsernameTaken
+	}
+	if in.TeamID > 0 {
+		teams, err := s.repo.TeamMap(ctx)
+		if err != nil {
+			return nil, "", err
+		}
+		if teams[in.TeamID] == "" {
+			return nil, "", ErrTeamNotFound
+		}
+	}
+	temp := NewTempPassword()
+	hash, err := HashPassword(temp)
+	if err != nil {
+		return nil, "", err
+	}
+	role := authz.Role(in.AccessRole)
+	m := &model.Member{
+		Username: in.Username, Password: hash, Name: in.Name, Role: in.Role,
+		TeamID: in.TeamID, Email: in.Email, Status: "active",
+		AccessRole: string(role), IsAdmin: role == authz.RoleAdmin, MustChangePassword: true,
+	}
+	if err := s.repo.Create(ctx, m); err != nil {
+		return nil, "", err
+	}
+	s.resync("members")
+	return m, temp, nil
+}
+
+// Restore reactivates a soft-deleted member.
+func (s *MemberService) Restore(ctx context.Context, id int) (*model.Member, error) {
+	m, err := s.deleted(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, id, map[string]interface{}{"status": "active"}); err != nil {
+		return nil, err
+	}
+	m.Status = "active"
+	s.resync("members")
+	return m, nil
+}
+
+// PurgeReport describes a purge, or with DryRun what a purge would remove.
+type PurgeReport struct {
+	Member  *model.Member          `json:"member"`
+	DryRun  bool                   `json:"dry_run"`
+	Removed repository.PurgeCounts `json:"removed"`
+	Catalog []string               `json:"catalog_tables"` // Catalog tables reloaded without the member's rows
+}
+
+// purgeCatalogTables are the Catalog tables holding rows that Purge removes.
+var purgeCatalogTables = []string{"members", "daily_entries", "daily_summaries", "topic_activities", "risks"}
+
+// Purge permanently removes a soft-deleted member and their data, then
+// reloads the affected Catalog tables so Data Asking forgets them too. With
+// dryRun nothing is changed. Only deleted members can be purged, so a purge
+// always follows an explicit delete.
+func (s *MemberService) Purge(ctx context.Context, id int, dryRun bool) (*PurgeReport, error) {
+	m, err := s.deleted(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	r := &PurgeReport{Member: m, DryRun: dryRun, Catalog: purgeCatalogTables}
+	if dryRun {
+		r.Removed, err = s.repo.CountPurge(ctx, id)
+		return r, err
+	}
+	if r.Removed, err = s.repo.Purge(ctx, id); err != nil {
+		return nil, err
+	}
+	s.resync(purgeCatalogTables...)
+	return r, nil
+}
+
+func (s *MemberService) deleted(ctx context.Context, id int) (*model.Member, error) {
+	m, err := s.repo.FindByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMemberNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if m.Status != "deleted" {
+		return nil, ErrNotDeleted
+	}
+	return m, nil
+}
+
+func (s *MemberService) resync(tables ...string) {
+	if s.catalog != nil {
+		s.catalog.ResyncTables(tables...)
+	}
+}
@@ -0,0 +1,25 @@
+package service
+
+import "testing"
+
+func TestNewMemberValidate(t *testing.T) {
+	n := NewMember{Username: " alice.w ", Name: " 王爱丽 "}
+	if err := n.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if n.Username != "alice.w" || n.Name != "王爱丽" || n.AccessRole != "member" || n.Role == "" {
+		t.Errorf("defaults not applied: %+v", n)
+	}
+	for _, bad := range []NewMember{
+		{Username: "", Name: "x"},
+		{Username: "a", Name: "x"},
+		{Username: "-alice", Name: "x"},
+		{Username: "张三", Name: "x"},
+		{Username: "alice", Name: "  "},
+		{Username: "alice", Name: "x", AccessRole: "root"},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("%+v: want error", bad)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/smtp"
+	"smart-daily/internal/config"
+	"smart-daily/internal/logger"
+	"smart-daily/internal/model"
+	"smart-daily/internal/repository"
+	"strings"
+	"time"
+)
+
+// Notification is a message to deliver to a set of members.
+type Notification struct {
+	Kind    string // reminder / ...
+	Title   string
+	Content string
+}
+
+// Notifier delivers a notification to members over one channel.
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, members []model.Member, n Notification) error
+}
+
+// NewNotifiers builds the named notifiers (inapp / webhook / smtp) from config.
+func NewNotifiers(names []string, cfg config.NotifyConfig, repo *repository.NotificationRepo) ([]Notifier, error) {
+	var out []Notifier
+	for _, name := range names {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "", "inapp":
+			out = append(out, &InAppNotifier{repo: repo})
+		case "webhook":
+			if cfg.WebhookURL == "" {
+				return nil, fmt.Errorf("notifier webhook: notify.webhook_url is required")
+			}
+			out = append(out, &WebhookNotifier{url: cfg.WebhookURL, client: &http.Client{Timeout: 10 * time.Second}})
+		case "smtp":
+			if cfg.SMTP.Host == "" || cfg.SMTP.From == "" {
+				return nil, fmt.Errorf("notifier smtp: notify.smtp.host and from are required")
+			}
+			out = append(out, &SMTPNotifier{cfg: cfg.SMTP})
+		default:
+			return nil, fmt.Errorf("unknown notifier %q (want inapp, webhook or smtp)", name)
+		}
+	}
+	return out, nil
+}
+
+// InAppNotifier stores notifications for /api/notifications.
+type InAppNotifier struct{ repo *repository.NotificationRepo }
+
+func (n *InAppNotifier) Name() string { return "inapp" }
+
+func (n *InAppNotifier) Notify(ctx context.Context, members []model.Member, msg Notification) error {
+	items := make([]model.Notification, 0, len(members))
+	for _, m := range members {
+		items = append(items, model.Notification{MemberID: m.ID, Kind: msg.Kind, Title: msg.Title, Content: msg.Content})
+	}
+	return n.repo.BatchCreate(ctx, items)
+}
+
+// WebhookNotifier posts one JSON message per batch, e.g. to a chat-group bot relay.
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func (n *WebhookNotifier) Name() string { return "webhook" }
+
+func (n *WebhookNotifier) Notify(ctx context.Context, members []model.Member, msg Notification) error {
+	type recipient struct {
+		ID       int    `json:"id"`
+		Username string `json:"username"`
+		Name     string `json:"name"`
+		Email    string `json:"email,omitempty"`
+	}
+	payload := struct {
+		Kind       string      `json:"kind"`
+		Title      string      `json:"title"`
+		Content    string      `json:"content"`
+		Text       string      `json:"text"` // ready-to-post message with names
+		Recipients []recipient `json:"recipients"`
+	}{Kind: msg.Kind, Title: msg.Title, Content: msg.Content}
+	names := make([]string, 0, len(members))
+	for _, m := range members {
+		payload.Recipients = append(payload.Recipients, recipient{m.ID, m.Username, m.Name, m.Email})
+		names = append(names, m.Name)
+	}
+	payload.Text = fmt.Sprintf("%s\n%s\n%s", msg.Title, msg.Content, strings.Join(names, "、"))
+	body, _ := json.Marshal(payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook: status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SMTPNotifier mails each member that has an email address.
+type SMTPNotifier struct{ cfg config.SMTPConfig }
+
+func (n *SMTPNotifier) Name() string { return "smtp" }
+
+func (n *SMTPNotifier) Notify(ctx context.Context, members []model.Member, msg Notification) error {
+	addr := fmt.Sprintf("%s:%d", n.cfg.Host, n.cfg.Port)
+	var auth smtp.Auth
+	if n.cfg.Username != "" {
+		auth = smtp.PlainAuth("", n.cfg.Username, n.cfg.Password, n.cfg.Host)
+	}
+	var errs []error
+	for _, m := range members {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if m.Email == "" {
+			logger.Warn("smtp notify: member has no email", "member_id", m.ID)
+			continue
+		}
+		mail := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n",
+			n.cfg.From, m.Email, mime.BEncoding.Encode("UTF-8", msg.Title), msg.Content)
+		if err := smtp.SendMail(addr, auth, n.cfg.From, []string{m.Email}, []byte(mail)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", m.Email, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"smart-daily/internal/config"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// OIDCProvider runs the OpenID Connect authorization-code flow. Discovery is
+// done on first use and retried until it succeeds, so an IdP that is down at
+// startup does not keep the server from starting.
+type OIDCProvider struct {
+	cfg config.OIDCConfig
+
+	mu       sync.Mutex
+	oauth    *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+func NewOIDCProvider(cfg config.OIDCConfig) (*OIDCProvider, error) {
+	if cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, fmt.Errorf("oidc: auth.oidc.client_id and redirect_url are required")
+	}
+	return &OIDCProvider{cfg: cfg}, nil
+}
+
+// DisplayName is the login button label.
+func (p *OIDCProvider) DisplayName() string { return p.cfg.DisplayName }
+
+// FrontendURL is where the callback sends the browser after login.
+func (p *OIDCProvider) FrontendURL() string { return p.cfg.FrontendURL }
+
+func (p *OIDCProvider) discover(ctx context.Context) (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.oauth != nil {
+		return p.oauth, p.verifier, nil
+	}
+	provider, err := oidc.NewProvider(ctx, p.cfg.Issuer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	p.oauth = &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		RedirectURL:  p.cfg.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       p.cfg.Scopes,
+	}
+	p.verifier = provider.Verifier(&oidc.Config{ClientID: p.cfg.ClientID})
+	return p.oauth, p.verifier, nil
+}
+
+// AuthCodeURL returns the IdP login URL carrying state and nonce.
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, state, nonce string) (string, error) {
+	oc, _, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return oc.AuthCodeURL(state, oidc.Nonce(nonce)), nil
+}
+
+// Exchange trades the authorization code for tokens and returns the identity
+// from the verified ID token. The nonce must match the one sent in AuthCodeURL.
+func (p *OIDCProvider) Exchange(ctx context.Context, code, nonce string) (*Identity, error) {
+	oc, verifier, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tok, err := oc.Exchange(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("oidc token exchange: %w", err)
+	}
+	raw, ok := tok.Extra("id_token").(string)
+	if !ok || raw == "" {
+		return nil, errors.New("oidc: token response has no id_token")
+	}
+	idToken, err := verifier.Verify(ctx, raw)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: verify id_token: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, errors.New("oidc: nonce mismatch")
+	}
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("oidc: parse claims: %w", err)
+	}
+	id := identityFromClaims(claims, idToken.Subject, p.cfg.UsernameClaim)
+	id.Issuer = idToken.Issuer
+	return id, nil
+}
+
+// identityFromClaims maps ID token claims. An email the IdP marks unverified
+// is dropped, and only one it marks verified may link an existing member.
+func identityFromClaims(claims map[string]interface{}, subject, usernameClaim string) *Identity {
+	str := func(k string) string { v, _ := claims[k].(string); return v }
+	id := &Identity{Provider: "oidc", Subject: subject, Username: str(usernameClaim), Email: str("email"), Name: str("name")}
+	verified, ok := claims["email_verified"].(bool)
+	if ok && !verified {
+		id.Email = ""
+	}
+	id.EmailVerified = verified
+	return id
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// QueryScope limits a Data Asking answer to the rows of some members. The
+// agent can only be asked to stay in scope, so the result tables are filtered
+// here instead: a row is kept only if it has a member column naming a member
+// in scope and mentions no member outside it. Free text the agent writes
+// about the data cannot be checked and is dropped.
+type QueryScope struct {
+	Names   map[string]bool // members in scope
+	IDs     map[int]bool
+	Outside []string // names of the other members
+}
+
+// memberNameHeaders and memberIDHeaders are result headers that identify a member, by name or ID.
+var (
+	memberNameHeaders = map[string]bool{"name": true, "member_name": true, "member": true, "姓名": true, "成员": true, "成员姓名": true, "上报人": true, "上报人姓名": true}
+	memberIDHeaders   = map[string]bool{"member_id": true, "成员id": true, "owner_id": true}
+)
+
+// FilterRows returns the rows of a result table the scope allows.
+func (q *QueryScope) FilterRows(headers, rows []interface{}) []interface{} {
+	var names, ids []int
+	for i, h := range headers {
+		key := strings.ToLower(strings.TrimSpace(fmt.Sprint(h)))
+		if j := strings.LastIndex(key, "."); j >= 0 {
+			key = key[j+1:]
+		}
+		switch {
+		case memberNameHeaders[key]:
+			names = append(names, i)
+		case memberIDHeaders[key]:
+			ids = append(ids, i)
+		}
+	}
+	if len(names)+len(ids) == 0 {
+		return nil
+	}
+	var out []interface{}
+	for _, r := range rows {
+		if row, ok := r.([]interface{}); ok && q.allowRow(row, names, ids) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func (q *QueryScope) allowRow(row []interface{}, names, ids []int) bool {
+	for _, i := range names {
+		if i >= len(row) || !q.Names[strings.TrimSpace(fmt.Sprint(row[i]))] {
+			return false
+		}
+	}
+	for _, i := range ids {
+		if i >= len(row) {
+			return false
+		}
+		if id, err := strconv.Atoi(strings.TrimSpace(fmt.Sprint(row[i]))); err != nil || !q.IDs[id] {
+			return false
+		}
+	}
+	for _, cell := range row {
+		text := fmt.Sprint(cell)
+		for _, name := range q.Outside {
+			if strings.Contains(text, name) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQueryScopeFilterRows(t *testing.T) {
+	q := &QueryScope{Names: map[string]bool{"张三": true}, IDs: map[int]bool{7: true}, Outside: []string{"李四"}}
+	rows := []interface{}{
+		[]interface{}{"张三", "导入接口"},
+		[]interface{}{"李四", "分页修复"},
+		[]interface{}{"张三", "和李四对接口"},
+		[]interface{}{"王五", "离职成员"},
+	}
+	got := q.FilterRows([]interface{}{"m.member_name", "summary"}, rows)
+	if want := rows[:1]; !reflect.DeepEqual(got, want) {
+		t.Errorf("by name: %v", got)
+	}
+
+	got = q.FilterRows([]interface{}{"member_id", "cnt"}, []interface{}{
+		[]interface{}{float64(7), 3}, []interface{}{float64(8), 5}, []interface{}{"x", 1},
+	})
+	if len(got) != 1 {
+		t.Errorf("by id: %v", got)
+	}
+
+	// Rows that cannot be attributed to a member (aggregates, topics) are dropped
+	if got := q.FilterRows([]interface{}{"daily_date", "count"}, []interface{}{[]interface{}{"2026-03-02", 12}}); got != nil {
+		t.Errorf("without member column: %v", got)
+	}
+}
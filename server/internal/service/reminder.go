@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"smart-daily/internal/logger"
+	"smart-daily/internal/model"
+	"smart-daily/internal/repository"
+	"strings"
+	"time"
+)
+
+// ReminderService reminds active members who have not submitted today's report.
+type ReminderService struct {
+	memberRepo *repository.MemberRepo
+	dailyRepo  *repository.DailyRepo
+	holiday    *HolidayService
+	notifiers  []Notifier
+	message    string
+}
+
+func NewReminderService(memberRepo *repository.MemberRepo, dailyRepo *repository.DailyRepo, holiday *HolidayService, notifiers []Notifier, message string) *ReminderService {
+	return &ReminderService{memberRepo: memberRepo, dailyRepo: dailyRepo, holiday: holiday, notifiers: notifiers, message: message}
+}
+
+// Run sends reminders for today; it does nothing on non-workdays.
+// Test accounts (name starting with 测试) are skipped.
+func (s *ReminderService) Run(ctx context.Context) {
+	today := time.Now().Format("2006-01-02")
+	if !s.holiday.IsWorkday(today) {
+		logger.Info("reminder: not a workday, skipped", "date", today)
+		return
+	}
+	members, err := s.memberRepo.ListActive(ctx)
+	if err != nil {
+		logger.Error("reminder: list members failed", "err", err)
+		return
+	}
+	submitted, err := s.dailyRepo.MembersWithEntries(ctx, today)
+	if err != nil {
+		logger.Error("reminder: query entries failed", "err", err)
+		return
+	}
+	var missing []model.Member
+	for _, m := range members {
+		if !submitted[m.ID] && !strings.HasPrefix(m.Name, "测试") {
+			missing = append(missing, m)
+		}
+	}
+	if len(missing) == 0 {
+		logger.Info("reminder: everyone submitted", "date", today)
+		return
+	}
+	n := Notification{Kind: "reminder", Title: "日报提醒 " + today, Content: s.message}
+	for _, nt := range s.notifiers {
+		if err := nt.Notify(ctx, missing, n); err != nil {
+			logger.Error("reminder: notify failed", "notifier", nt.Name(), "err", err)
+			continue
+		}
+		logger.Info("reminder: sent", "notifier", nt.Name(), "date", today, "members", len(missing))
+	}
+}
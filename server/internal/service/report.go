@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"smart-daily/internal/logger"
+	"smart-daily/internal/model"
+	"smart-daily/internal/repository"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// weeklyPromptVersion is mixed into the source hash of weekly reports so that
+// changing the weekly prompt regenerates saved reports.
+const weeklyPromptVersion = "weekly-v1"
+
+var (
+	ErrReportNotFound = errors.New("report not found")
+	ErrNoReportData   = errors.New("no reports in this date range")
+)
+
+// sourceHash identifies the input a summary is generated from.
+func sourceHash(version, source string) string {
+	sum := sha256.Sum256([]byte(version + "\n" + source))
+	return hex.EncodeToString(sum[:])
+}
+
+// ReportService generates and keeps members' weekly reports. A report is
+// saved per member and date range and regenerated only when the member's
+// entries in that range changed; otherwise the saved report is returned. An
+// edited report is never regenerated over: it is returned marked stale until
+// its edits are discarded.
+type ReportService struct {
+	repo  *repository.ReportRepo
+	daily *DailyService
+	ai    *AIService
+}
+
+func NewReportService(repo *repository.ReportRepo, daily *DailyService, ai *AIService) *ReportService {
+	return &ReportService{repo: repo, daily: daily, ai: ai}
+}
+
+// WeeklyRange resolves an empty date range to the default of the weekly
+// report: the ISO week (Monday to Sunday) of now, so reports asked for on
+// different days of a week are the same saved report.
+func WeeklyRange(start, end string, now time.Time) (string, string) {
+	if start == "" || end == "" {
+		monday := now.AddDate(0, 0, -(int(now.Weekday())+6)%7)
+		return monday.Format("2006-01-02"), monday.AddDate(0, 0, 6).Format("2006-01-02")
+	}
+	return start, end
+}
+
+// Weekly returns the weekly report of the member between start and end
+// (empty for the default range), streaming it through flush. It is generated
+// for createdBy unless the saved report is still current, which is replayed.
+func (s *ReportService) Weekly(ctx context.Context, memberID int, memberName string, createdBy int, start, end string, flush func(string)) (*model.Report, error) {
+	start, end = WeeklyRange(start, end, time.Now())
+	data, err := s.daily.GetMemberDateRangeData(ctx, memberID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(data) == "" {
+		return nil, ErrNoReportData
+	}
+
+	hash := sourceHash(weeklyPromptVersion, memberName+"\n"+data)
+	saved, err := s.repo.Find(ctx, memberID, start, end)
+	if err == nil && (saved.SourceHash == hash || saved.Edited) {
+		saved.Stale = saved.SourceHash != hash
+		logger.Info("weekly report reused", "report_id", saved.ID, "member_id", memberID, "start", start, "end", end, "stale", saved.Stale)
+		flush(saved.Content)
+		return saved, nil
+	} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		logger.Warn("load saved report failed", "err", err, "member_id", memberID)
+	}
+
+	md, err := s.ai.StreamWeeklySummary(ctx, memberName, data, flush)
+	if err != nil {
+		return nil, err
+	}
+	rep := &model.Report{
+		MemberID: memberID, MemberName: memberName, CreatedBy: createdBy,
+		PeriodStart: start, PeriodEnd: end, Title: fmt.Sprintf("周报_%s_%s~%s", memberName, start, end),
+		Content: md, SourceHash: hash,
+	}
+	if err := s.repo.Upsert(ctx, rep); err != nil {
+		return nil, fmt.Errorf("save report: %w", err)
+	}
+	return rep, nil
+}
+
+// Get returns a saved report with its content.
+func (s *ReportService) Get(ctx context.Context, id int) (*model.Report, error) {
+	rep, err := s.repo.Get(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrReportNotFound
+	}
+	return rep, err
+}
+
+// ListFor returns the reports about member uid or generated by uid.
+func (s *ReportService) ListFor(ctx context.Context, uid int) ([]model.Report, error) {
+	return s.repo.ListFor(ctx, uid)
+}
+
+// Edit replaces a report's title and/or content and marks it edited. Edited
+// content is kept even when the underlying entries change, until
+// DiscardEdits.
+func (s *ReportService) Edit(ctx context.Context, rep *model.Report, title, content *string) error {
+	updates := map[string]interface{}{}
+	if title != nil {
+		rep.Title = *title
+		updates["title"] = *title
+	}
+	if content != nil {
+		rep.Content, rep.Edited = *content, true
+		updates["content"], updates["edited"] = *content, true
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	return s.repo.Update(ctx, rep.ID, updates)
+}
+
+// DiscardEdits clears a report's edited mark so that the next Weekly for its
+// period regenerates it.
+func (s *ReportService) DiscardEdits(ctx context.Context, rep *model.Report) error {
+	rep.Edited = false
+	return s.repo.Update(ctx, rep.ID, map[string]interface{}{"edited": false, "source_hash": ""})
+}
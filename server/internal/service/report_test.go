@@ -0,0 +1,32 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWeeklyRange(t *testing.T) {
+	for _, day := range []int{9, 11, 15} { // Monday, Wednesday and Sunday of one ISO week
+		now := time.Date(2026, 3, day, 15, 0, 0, 0, time.Local)
+		if s, e := WeeklyRange("", "", now); s != "2026-03-09" || e != "2026-03-15" {
+			t.Errorf("default range on the %dth: %s ~ %s", day, s, e)
+		}
+	}
+	now := time.Date(2026, 3, 11, 15, 0, 0, 0, time.Local)
+	if s, e := WeeklyRange("2026-03-02", "2026-03-08", now); s != "2026-03-02" || e != "2026-03-08" {
+		t.Errorf("explicit range: %s ~ %s", s, e)
+	}
+}
+
+func TestSourceHash(t *testing.T) {
+	a := sourceHash(weeklyPromptVersion, "张三\n[2026-03-02] 导入接口\n")
+	if len(a) != 64 || a != sourceHash(weeklyPromptVersion, "张三\n[2026-03-02] 导入接口\n") {
+		t.Fatalf("hash not stable: %s", a)
+	}
+	if a == sourceHash(weeklyPromptVersion, "张三\n[2026-03-02] 导入接口完成\n") {
+		t.Error("changed entries keep the hash")
+	}
+	if a == sourceHash("weekly-v0", "张三\n[2026-03-02] 导入接口\n") {
+		t.Error("changed prompt version keeps the hash")
+	}
+}
@@ -0,0 +1,310 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"smart-daily/internal/logger"
+	"smart-daily/internal/model"
+	"smart-daily/internal/repository"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// reviewPromptVersion is mixed into every source hash so that changing the
+// rollup or review prompts regenerates cached summaries.
+const reviewPromptVersion = "review-v1"
+
+// maxRollupRunes bounds the input of one weekly rollup call; a larger week
+// (a big team) is split into chunks summarized separately.
+const maxRollupRunes = 12000
+
+var (
+	ErrInvalidPeriod = errors.New("invalid period, use YYYY-MM or YYYY-Q1..Q4")
+	ErrNoReviewData  = errors.New("no reports in this period")
+)
+
+// Period is a month or quarter to review.
+type Period struct {
+	Type  string `json:"type"`  // month / quarter
+	Key   string `json:"key"`   // 2026-03 / 2026-Q1
+	Label string `json:"label"` // 2026年3月 / 2026年第1季度
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// monthPeriod normalizes month like time.Date, so month 0 is last December.
+func monthPeriod(year int, month time.Month) Period {
+	start := time.Date(year, month, 1, 0, 0, 0, 0, time.Local)
+	return Period{
+		Type: "month", Key: start.Format("2006-01"), Label: fmt.Sprintf("%d年%d月", start.Year(), start.Month()),
+		Start: start.Format("2006-01-02"), End: start.AddDate(0, 1, -1).Format("2006-01-02"),
+	}
+}
+
+func quarterPeriod(year, q int) Period {
+	start := time.Date(year, time.Month(3*q-2), 1, 0, 0, 0, 0, time.Local)
+	return Period{
+		Type: "quarter", Key: fmt.Sprintf("%d-Q%d", year, q), Label: fmt.Sprintf("%d年第%d季度", year, q),
+		Start: start.Format("2006-01-02"), End: start.AddDate(0, 3, -1).Format("2006-01-02"),
+	}
+}
+
+var (
+	periodKeyRe    = regexp.MustCompile(`^(\d{4})-(?:(0[1-9]|1[0-2])|Q([1-4]))$`)
+	yearQuarterRe  = regexp.MustCompile(`(\d{4})\s*(?:年|-)?\s*(?:[Qq]([1-4])|第?([一二三四1-4])季度)`)
+	quarterRe      = regexp.MustCompile(`[Qq]([1-4])|第?([一二三四1-4])季度`)
+	yearMonthRe    = regexp.MustCompile(`(\d{4})\s*(?:年|-|/)\s*(\d{1,2})\s*月?`)
+	monthRe        = regexp.MustCompile(`(\d{1,2})\s*月`)
+	chineseQuarter = map[string]int{"一": 1, "二": 2, "三": 3, "四": 4, "1": 1, "2": 2, "3": 3, "4": 4}
+)
+
+// ParsePeriod parses a period key: YYYY-MM for a month, YYYY-Q1..Q4 for a quarter.
+func ParsePeriod(key string) (Period, error) {
+	m := periodKeyRe.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(key)))
+	if m == nil {
+		return Period{}, ErrInvalidPeriod
+	}
+	year, _ := strconv.Atoi(m[1])
+	if m[2] != "" {
+		month, _ := strconv.Atoi(m[2])
+		return monthPeriod(year, time.Month(month)), nil
+	}
+	return quarterPeriod(year, chineseQuarter[m[3]]), nil
+}
+
+// PeriodFromText reads the period a review request names ("上个月", "3月",
+// "2026年第一季度", "本季度", ...). Without one it is the last full month, or
+// the last full quarter when the text asks for a quarterly review.
+func PeriodFromText(text string, now time.Time) Period {
+	year, month := now.Year(), now.Month()
+	q := (int(month)-1)/3 + 1
+	lastQuarter := func() Period {
+		if q == 1 {
+			return quarterPeriod(year-1, 4)
+		}
+		return quarterPeriod(year, q-1)
+	}
+	switch {
+	case strings.Contains(text, "上季度") || strings.Contains(text, "上个季度") || strings.Contains(text, "上一季度"):
+		return lastQuarter()
+	case strings.Contains(text, "本季度") || strings.Contains(text, "这个季度") || strings.Contains(text, "这季度"):
+		return quarterPeriod(year, q)
+	}
+	if m := yearQuarterRe.FindStringSubmatch(text); m != nil {
+		y, _ := strconv.Atoi(m[1])
+		return quarterPeriod(y, chineseQuarter[m[2]+m[3]])
+	}
+	if m := quarterRe.FindStringSubmatch(text); m != nil {
+		return quarterPeriod(year, chineseQuarter[m[1]+m[2]])
+	}
+	switch {
+	case strings.Contains(text, "上月") || strings.Contains(text, "上个月") || strings.Contains(text, "上一个月"):
+		return monthPeriod(year, month-1) // month 0 normalizes to December of the year before
+	case strings.Contains(text, "本月") || strings.Contains(text, "这个月") || strings.Contains(text, "这月"):
+		return monthPeriod(year, month)
+	}
+	if m := yearMonthRe.FindStringSubmatch(text); m != nil {
+		y, _ := strconv.Atoi(m[1])
+		if mo, _ := strconv.Atoi(m[2]); mo >= 1 && mo <= 12 {
+			return monthPeriod(y, time.Month(mo))
+		}
+	}
+	if m := monthRe.FindStringSubmatch(text); m != nil {
+		if mo, _ := strconv.Atoi(m[1]); mo >= 1 && mo <= 12 {
+			// a month later than this one means last year's
+			if time.Month(mo) > month {
+				return monthPeriod(year-1, time.Month(mo))
+			}
+			return monthPeriod(year, time.Month(mo))
+		}
+	}
+	if strings.Contains(text, "季") {
+		return lastQuarter()
+	}
+	return monthPeriod(year, month-1)
+}
+
+// weeksIn splits [start, end] into Monday-to-Sunday weeks clipped to the range.
+func weeksIn(start, end string) [][2]string {
+	from, _ := time.Parse("2006-01-02", start)
+	to, _ := time.Parse("2006-01-02", end)
+	var weeks [][2]string
+	for ws := from; !ws.After(to); {
+		we := ws.AddDate(0, 0, (7-int(ws.Weekday()))%7) // the Sunday ending ws's week
+		if we.After(to) {
+			we = to
+		}
+		weeks = append(weeks, [2]string{ws.Format("2006-01-02"), we.Format("2006-01-02")})
+		ws = we.AddDate(0, 0, 1)
+	}
+	return weeks
+}
+
+// ReviewSubject is whose work a review covers: a member (MemberID > 0) or a
+// team with its sub-teams (TeamID, 0 for everyone).
+type ReviewSubject struct {
+	MemberID int    `json:"member_id"`
+	TeamID   int    `json:"team_id"`
+	Name     string `json:"name"`
+}
+
+// ReviewService writes monthly and quarterly reviews hierarchically: daily
+// reports are rolled up per week, the weekly rollups (cached in
+// period_summaries and reused while their source data is unchanged) are then
+// summarized into the review, so a quarter never has to fit one prompt.
+type ReviewService struct {
+	repo       *repository.PeriodSummaryRepo
+	daily      *DailyService
+	topicRepo  *repository.TopicRepo
+	memberRepo *repository.MemberRepo
+	ai         *AIService
+}
+
+func NewReviewService(repo *repository.PeriodSummaryRepo, daily *DailyService, topicRepo *repository.TopicRepo, memberRepo *repository.MemberRepo, ai *AIService) *ReviewService {
+	return &ReviewService{repo: repo, daily: daily, topicRepo: topicRepo, memberRepo: memberRepo, ai: ai}
+}
+
+// Generate writes the review of subj for p, streaming it through flush and
+// reporting each weekly rollup through progress. An unchanged review is
+// replayed from the cache.
+func (s *ReviewService) Generate(ctx context.Context, subj ReviewSubject, p Period, progress, flush func(string)) (string, error) {
+	var teamIDs []int
+	if subj.MemberID == 0 && subj.TeamID != 0 {
+		var err error
+		if teamIDs, err = s.memberRepo.SubtreeTeamIDs(ctx, subj.TeamID); err != nil {
+			return "", fmt.Errorf("list teams: %w", err)
+		}
+	}
+
+	var weeks []string
+	for i, w := range weeksIn(p.Start, p.End) {
+		data, err := s.weekData(ctx, subj, teamIDs, w[0], w[1])
+		if err != nil {
+			return "", err
+		}
+		if strings.TrimSpace(data) == "" {
+			continue
+		}
+		progress(fmt.Sprintf("汇总第 %d 周（%s ~ %s）", i+1, w[0], w[1]))
+		rollup, err := s.cached(ctx, subj, "week", w[0], w[1], data, func() (string, error) {
+			return s.rollup(ctx, data)
+		})
+		if err != nil {
+			return "", fmt.Errorf("week %s rollup: %w", w[0], err)
+		}
+		weeks = append(weeks, fmt.Sprintf("### %s ~ %s\n%s", w[0], w[1], strings.TrimSpace(rollup)))
+	}
+	if len(weeks) == 0 {
+		return "", ErrNoReviewData
+	}
+
+	input := strings.Join(weeks, "\n\n")
+	period := fmt.Sprintf("%s（%s ~ %s）", p.Label, p.Start, p.End)
+	streamed := false
+	review, err := s.cached(ctx, subj, p.Type, p.Start, p.End, subj.Name+"\n"+period+"\n"+input, func() (string, error) {
+		streamed = true
+		return s.ai.StreamPeriodReview(ctx, subj.Name, period, input, flush)
+	})
+	if err != nil {
+		return "", err
+	}
+	if !streamed {
+		flush(review)
+	}
+	return review, nil
+}
+
+// cached returns the stored summary for the period while source is unchanged,
+// otherwise generates, stores and returns a new one.
+func (s *ReviewService) cached(ctx context.Context, subj ReviewSubject, periodType, start, end, source string, generate func() (string, error)) (string, error) {
+	hash := sourceHash(reviewPromptVersion, source)
+	teamID := subj.TeamID
+	if subj.MemberID > 0 {
+		teamID = 0
+	}
+	if ps, err := s.repo.Find(ctx, subj.MemberID, teamID, periodType, start, end); err == nil && ps.SourceHash == hash {
+		return ps.Content, nil
+	} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		logger.Warn("load period summary failed", "err", err, "period_type", periodType, "start", start)
+	}
+	content, err := generate()
+	if err != nil {
+		return "", err
+	}
+	ps := &model.PeriodSummary{
+		MemberID: subj.MemberID, TeamID: teamID, PeriodType: periodType,
+		PeriodStart: start, PeriodEnd: end, Content: content, SourceHash: hash,
+	}
+	if err := s.repo.Upsert(ctx, ps); err != nil {
+		logger.Warn("save period summary failed", "err", err, "period_type", periodType, "start", start)
+	}
+	return content, nil
+}
+
+// weekData is the week's daily reports of the subject, one "[date] ..." line
+// per report (with the member's name for a team).
+func (s *ReviewService) weekData(ctx context.Context, subj ReviewSubject, teamIDs []int, start, end string) (string, error) {
+	if subj.MemberID > 0 {
+		return s.daily.GetMemberDateRangeData(ctx, subj.MemberID, start, end)
+	}
+	rows, err := s.topicRepo.ListSummariesByDateRange(ctx, start, end, repository.SummaryFilter{TeamIDs: teamIDs})
+	if err != nil {
+		return "", fmt.Errorf("query summaries: %w", err)
+	}
+	for i := range rows {
+		if len(rows[i].DailyDate) > 10 {
+			rows[i].DailyDate = rows[i].DailyDate[:10]
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].DailyDate < rows[j].DailyDate })
+	var sb strings.Builder
+	for _, r := range rows {
+		if strings.TrimSpace(r.Summary) != "" {
+			fmt.Fprintf(&sb, "[%s] %s：%s\n", r.DailyDate, r.MemberName, strings.ReplaceAll(strings.TrimSpace(r.Summary), "\n", " "))
+		}
+	}
+	return sb.String(), nil
+}
+
+// rollup summarizes one week, in chunks of whole lines when it is too long
+// for a single call.
+func (s *ReviewService) rollup(ctx context.Context, data string) (string, error) {
+	var parts []string
+	for _, chunk := range chunkLines(data, maxRollupRunes) {
+		part, err := s.ai.SummarizeWeek(ctx, chunk)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, strings.TrimSpace(part))
+	}
+	return strings.Join(parts, "\n"), nil
+}
+
+// chunkLines splits text into chunks of whole lines of at most max runes (a
+// single longer line forms its own chunk).
+func chunkLines(text string, max int) []string {
+	var chunks []string
+	var cur strings.Builder
+	n := 0
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		l := utf8.RuneCountInString(line) + 1
+		if n > 0 && n+l > max {
+			chunks = append(chunks, cur.String())
+			cur.Reset()
+			n = 0
+		}
+		cur.WriteString(line + "\n")
+		n += l
+	}
+	if n > 0 {
+		chunks = append(chunks, cur.String())
+	}
+	return chunks
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePeriod(t *testing.T) {
+	for key, want := range map[string]Period{
+		"2026-03": {Type: "month", Key: "2026-03", Label: "2026年3月", Start: "2026-03-01", End: "2026-03-31"},
+		"2024-02": {Type: "month", Key: "2024-02", Label: "2024年2月", Start: "2024-02-01", End: "2024-02-29"},
+		"2026-q4": {Type: "quarter", Key: "2026-Q4", Label: "2026年第4季度", Start: "2026-10-01", End: "2026-12-31"},
+	} {
+		if got, err := ParsePeriod(key); err != nil || got != want {
+			t.Errorf("ParsePeriod(%q) = %+v, %v", key, got, err)
+		}
+	}
+	for _, bad := range []string{"", "2026-13", "2026-Q5", "2026-3", "2026年3月"} {
+		if _, err := ParsePeriod(bad); !errors.Is(err, ErrInvalidPeriod) {
+			t.Errorf("ParsePeriod(%q) = %v, want ErrInvalidPeriod", bad, err)
+		}
+	}
+}
+
+func TestPeriodFromText(t *testing.T) {
+	now := time.Date(2026, 1, 15, 10, 0, 0, 0, time.Local)
+	for text, want := range map[string]string{
+		"":             "2025-12",
+		"生成上个月的总结":     "2025-12",
+		"本月总结":         "2026-01",
+		"帮我写季度总结":      "2025-Q4",
+		"本季度":          "2026-Q1",
+		"2025年第三季度":    "2025-Q3",
+		"2025 Q2 张三":   "2025-Q2",
+		"Q3":           "2026-Q3",
+		"2025年11月 研发部": "2025-11",
+		"11月的总结":       "2025-11", // a later month than now is last year's
+		"1月":           "2026-01",
+		"第二季度的张三的季度总结": "2026-Q2",
+	} {
+		if got := PeriodFromText(text, now); got.Key != want {
+			t.Errorf("PeriodFromText(%q) = %s, want %s", text, got.Key, want)
+		}
+	}
+}
+
+func TestWeeksIn(t *testing.T) {
+	// March 2026 starts on a Sunday and ends on a Tuesday
+	weeks := weeksIn("2026-03-01", "2026-03-31")
+	if len(weeks) != 6 {
+		t.Fatalf("want 6 weeks, got %v", weeks)
+	}
+	if weeks[0] != [2]string{"2026-03-01", "2026-03-01"} || weeks[1] != [2]string{"2026-03-02", "2026-03-08"} || weeks[5] != [2]string{"2026-03-30", "2026-03-31"} {
+		t.Errorf("weeks: %v", weeks)
+	}
+}
+
+func TestChunkLines(t *testing.T) {
+	text := "[2026-03-02] aaaa\n[2026-03-03] bbbb\n[2026-03-04] cccc\n"
+	chunks := chunkLines(text, 40)
+	if len(chunks) != 2 || !strings.HasPrefix(chunks[1], "[2026-03-04]") {
+		t.Fatalf("chunks: %q", chunks)
+	}
+	if strings.Join(chunks, "") != text {
+		t.Errorf("chunks lose lines: %q", chunks)
+	}
+	if got := chunkLines(text, 1000); len(got) != 1 {
+		t.Errorf("short text split: %q", got)
+	}
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"smart-daily/internal/model"
+	"smart-daily/internal/repository"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTeamNameEmpty = errors.New("name required")
+	ErrTeamNameTaken = errors.New("team name already exists")
+	ErrTeamNotEmpty  = errors.New("team still has members or sub-teams")
+	ErrNoSuchParent  = errors.New("parent team not found")
+	ErrTeamCycle     = errors.New("a team cannot be placed under itself or its sub-teams")
+	ErrLeadNotMember = errors.New("lead must be an active member of the team")
+)
+
+// TeamService manages the team tree: renaming, moving, designating leads,
+// deleting and merging teams.
+type TeamService struct {
+	repo    *repository.MemberRepo
+	catalog *CatalogSync
+}
+
+func NewTeamService(repo *repository.MemberRepo, catalog *CatalogSync) *TeamService {
+	return &TeamService{repo: repo, catalog: catalog}
+}
+
+// TeamUpdate is the input for Update; nil fields are left unchanged.
+type TeamUpdate struct {
+	Name     *string `json:"name"`
+	ParentID *int    `json:"parent_id"`
+	LeadID   *int    `json:"lead_id"`
+}
+
+// Update applies u to a team and returns it before and after. A designated
+// lead holding only member rights is promoted to team_lead, and the lead it
+// replaces is demoted to member unless they still lead another team.
+func (s *TeamService) Update(ctx context.Context, id int, u TeamUpdate) (before, after *model.Team, err error) {
+	teams, err := s.repo.ListTeams(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	i := slices.IndexFunc(teams, func(t model.Team) bool { return t.ID == id })
+	if i < 0 {
+		return nil, nil, ErrTeamNotFound
+	}
+	old := teams[i]
+	t := old
+	updates := map[string]interface{}{}
+	if u.Name != nil {
+		name := strings.TrimSpace(*u.Name)
+		if name == "" {
+			return nil, nil, ErrTeamNameEmpty
+		}
+		if slices.ContainsFunc(teams, func(o model.Team) bool { return o.ID != id && o.Name == name }) {
+			return nil, nil, ErrTeamNameTaken
+		}
+		t.Name, updates["name"] = name, name
+	}
+	if u.ParentID != nil {
+		if err := checkParent(teams, id, *u.ParentID); err != nil {
+			return nil, nil, err
+		}
+		t.ParentID, updates["parent_id"] = *u.ParentID, *u.ParentID
+	}
+	var lead *model.Member
+	if u.LeadID != nil {
+		if *u.LeadID != 0 {
+			lead, err = s.repo.FindByID(ctx, *u.LeadID)
+			if errors.Is(err, gorm.ErrRecordNotFound) || err == nil && (lead.TeamID != id || lead.Status == "deleted") {
+				return nil, nil, ErrLeadNotMember
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		t.LeadID, updates["lead_id"] = *u.LeadID, *u.LeadID
+	}
+	if len(updates) == 0 {
+		return &old, &t, nil
+	}
+	if err := s.repo.UpdateTeam(ctx, id, updates); err != nil {
+		return nil, nil, err
+	}
+	tables := []string{"teams"}
+	if t.LeadID != old.LeadID {
+		tables = append(tables, "members")
+	}
+	s.resync(tables...)
+	return &old, &t, nil
+}
+
+// Delete removes a team without active members or sub-teams; other teams are
+// merged away instead.
+func (s *TeamService) Delete(ctx context.Context, id int) (*model.Team, error) {
+	teams, err := s.repo.ListTeams(ctx)
+	if err != nil {
+		return nil, err
+	}
+	i := slices.IndexFunc(teams, func(t model.Team) bool { return t.ID == id })
+	if i < 0 {
+		return nil, ErrTeamNotFound
+	}
+	if len(repository.SubTeamIDs(teams, id)) > 0 {
+		return nil, ErrTeamNotEmpty
+	}
+	if n, err := s.repo.CountTeamMembers(ctx, id); err != nil {
+		return nil, err
+	} else if n > 0 {
+		return nil, ErrTeamNotEmpty
+	}
+	if err := s.repo.DeleteTeam(ctx, id); err != nil {
+		return nil, err
+	}
+	s.resync("teams", "members")
+	return &teams[i], nil
+}
+
+// Merge moves source's members and sub-teams into target and deletes source.
+// It returns both teams as they were before the merge.
+func (s *TeamService) Merge(ctx context.Context, sourceID, targetID int) (source, target *model.Team, err error) {
+	teams, err := s.repo.ListTeams(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	si := slices.IndexFunc(teams, func(t model.Team) bool { return t.ID == sourceID })
+	ti := slices.IndexFunc(teams, func(t model.Team) bool { return t.ID == targetID })
+	if si < 0 || ti < 0 {
+		return nil, nil, ErrTeamNotFound
+	}
+	// source's sub-teams move under target, so target must not be one of them
+	if sourceID == targetID || slices.Contains(repository.SubTeamIDs(teams, sourceID), targetID) {
+		return nil, nil, ErrTeamCycle
+	}
+	if err := s.repo.MergeTeam(ctx, sourceID, targetID); err != nil {
+		return nil, nil, err
+	}
+	s.resync("teams", "members")
+	return &teams[si], &teams[ti], nil
+}
+
+// checkParent validates moving team id under parent (0 for top level).
+func checkParent(teams []model.Team, id, parent int) error {
+	if parent == 0 {
+		return nil
+	}
+	if !slices.ContainsFunc(teams, func(t model.Team) bool { return t.ID == parent }) {
+		return ErrNoSuchParent
+	}
+	if parent == id || slices.Contains(repository.SubTeamIDs(teams, id), parent) {
+		return ErrTeamCycle
+	}
+	return nil
+}
+
+func (s *TeamService) resync(tables ...string) {
+	if s.catalog != nil {
+		s.catalog.ResyncTables(tables...)
+	}
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"smart-daily/internal/authz"
+	"smart-daily/internal/model"
+	"smart-daily/internal/repository"
+	"testing"
+)
+
+// teamTree: 研发部 (1) holds 后端组 (2) and 前端组 (3), 存储小组 (4) sits under
+// 后端组, and 运营部 (5) stands alone.
+var teamTree = []model.Team{
+	{ID: 1, Name: "研发部"},
+	{ID: 2, Name: "后端组", ParentID: 1},
+	{ID: 3, Name: "前端组", ParentID: 1},
+	{ID: 4, Name: "存储小组", ParentID: 2},
+	{ID: 5, Name: "运营部"},
+}
+
+func TestSubTeamIDs(t *testing.T) {
+	for root, want := range map[int][]int{1: {2, 3, 4}, 2: {4}, 4: nil, 5: nil} {
+		if got := repository.SubTeamIDs(teamTree, root); !slices.Equal(got, want) {
+			t.Errorf("SubTeamIDs(%d) = %v, want %v", root, got, want)
+		}
+	}
+	loop := []model.Team{{ID: 1, ParentID: 2}, {ID: 2, ParentID: 1}}
+	if got := repository.SubTeamIDs(loop, 1); !slices.Equal(got, []int{2}) {
+		t.Errorf("SubTeamIDs on a loop = %v, want [2]", got)
+	}
+}
+
+func TestAncestorTeamIDs(t *testing.T) {
+	for id, want := range map[int][]int{4: {4, 2, 1}, 3: {3, 1}, 5: {5}, 0: nil} {
+		if got := repository.AncestorTeamIDs(teamTree, id); !slices.Equal(got, want) {
+			t.Errorf("AncestorTeamIDs(%d) = %v, want %v", id, got, want)
+		}
+	}
+	loop := []model.Team{{ID: 1, ParentID: 2}, {ID: 2, ParentID: 1}}
+	if got := repository.AncestorTeamIDs(loop, 1); !slices.Equal(got, []int{1, 2}) {
+		t.Errorf("AncestorTeamIDs on a loop = %v, want [1 2]", got)
+	}
+}
+
+func TestCheckParent(t *testing.T) {
+	for _, tc := range []struct {
+		id, parent int
+		want       error
+	}{
+		{2, 0, nil},
+		{3, 2, nil},
+		{5, 4, nil},
+		{1, 1, ErrTeamCycle},
+		{1, 4, ErrTeamCycle},
+		{2, 4, ErrTeamCycle},
+		{2, 99, ErrNoSuchParent},
+	} {
+		if err := checkParent(teamTree, tc.id, tc.parent); !errors.Is(err, tc.want) {
+			t.Errorf("checkParent(%d, %d) = %v, want %v", tc.id, tc.parent, err, tc.want)
+		}
+	}
+}
+
+func TestSubjectOfFollowsTeamLead(t *testing.T) {
+	teams := slices.Clone(teamTree)
+	teams[1].LeadID = 7 // 后端组
+	lead := model.Member{ID: 7, TeamID: 2, AccessRole: string(authz.RoleTeamLead)}
+	if s := repository.SubjectOf(lead, teams); s.Role != authz.RoleTeamLead || !slices.Equal(s.Teams(), []int{2, 4}) {
+		t.Fatalf("designated lead: %+v", s)
+	}
+
+	// moved to 运营部 while still holding the role
+	moved := lead
+	moved.TeamID = 5
+	if s := repository.SubjectOf(moved, teams); s.Role != authz.RoleMember || s.CanAccessTeam(authz.ReportRead, 2) || s.CanAccessTeam(authz.ReportRead, 5) {
+		t.Errorf("moved lead keeps lead rights: %+v", s)
+	}
+
+	// 后端组 gets another lead
+	teams[1].LeadID = 8
+	if s := repository.SubjectOf(lead, teams); s.Role != authz.RoleMember || s.CanAccessTeam(authz.ReportRead, 2) {
+		t.Errorf("replaced lead keeps lead rights: %+v", s)
+	}
+	next := model.Member{ID: 8, TeamID: 2, AccessRole: string(authz.RoleTeamLead)}
+	if s := repository.SubjectOf(next, teams); !s.CanAccessTeam(authz.ReportRead, 4) {
+		t.Errorf("new lead lacks lead rights: %+v", s)
+	}
+
+	admin := model.Member{ID: 9, AccessRole: string(authz.RoleAdmin), IsAdmin: true}
+	if s := repository.SubjectOf(admin, nil); !s.IsAdmin() {
+		t.Errorf("admin: %+v", s)
+	}
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"smart-daily/internal/events"
+	"smart-daily/internal/logger"
+	"smart-daily/internal/model"
+	"smart-daily/internal/repository"
+	"strconv"
+	"time"
+)
+
+// webhookBackoff is the wait before each retry; a delivery fails for good after
+// len(webhookBackoff)+1 attempts.
+var webhookBackoff = []time.Duration{30 * time.Second, 2 * time.Minute, 10 * time.Minute, time.Hour, 6 * time.Hour}
+
+// webhookLease is how long a claimed delivery is kept from other workers; well
+// above the client timeout.
+const webhookLease = 2 * time.Minute
+
+// WebhookService queues events for subscribed webhooks and delivers them in
+// the background. Deliveries live in webhook_deliveries, so pending retries
+// survive a restart.
+type WebhookService struct {
+	repo   *repository.WebhookRepo
+	client *http.Client
+	wake   chan struct{}
+}
+
+func NewWebhookService(repo *repository.WebhookRepo) *WebhookService {
+	return &WebhookService{repo: repo, client: &http.Client{Timeout: 10 * time.Second}, wake: make(chan struct{}, 1)}
+}
+
+// Publish implements events.Publisher: it records a pending delivery for each
+// subscribed webhook and wakes the worker.
+func (s *WebhookService) Publish(ctx context.Context, event string, data any) {
+	ctx = context.WithoutCancel(ctx)
+	hooks, err := s.repo.ListActiveFor(ctx, event)
+	if err != nil {
+		logger.Error("webhook: list subscriptions failed", "event", event, "err", err)
+		return
+	}
+	if len(hooks) == 0 {
+		return
+	}
+	s.enqueue(ctx, hooks, event, data)
+}
+
+// Ping queues a test delivery to one webhook regardless of its subscriptions.
+func (s *WebhookService) Ping(ctx context.Context, h *model.Webhook) (*model.WebhookDelivery, error) {
+	items := s.enqueue(ctx, []model.Webhook{*h}, events.Ping, map[string]any{"webhook_id": h.ID, "name": h.Name})
+	if len(items) == 0 {
+		return nil, fmt.Errorf("queue ping failed")
+	}
+	return &items[0], nil
+}
+
+func (s *WebhookService) enqueue(ctx context.Context, hooks []model.Webhook, event string, data any) []model.WebhookDelivery {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		logger.Error("webhook: marshal payload failed", "event", event, "err", err)
+		return nil
+	}
+	now := time.Now()
+	items := make([]model.WebhookDelivery, 0, len(hooks))
+	for _, h := range hooks {
+		items = append(items, model.WebhookDelivery{
+			WebhookID: h.ID, Event: event, Payload: string(payload), Status: "pending", NextAttemptAt: &now,
+		})
+	}
+	if err := s.repo.CreateDeliveries(ctx, items); err != nil {
+		logger.Error("webhook: queue deliveries failed", "event", event, "err", err)
+		return nil
+	}
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return items
+}
+
+// Start runs the delivery worker until ctx is done.
+func (s *WebhookService) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(15 * time.Second)
+		defer ticker.Stop()
+		for {
+			s.deliverDue(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-s.wake:
+			}
+		}
+	}()
+}
+
+func (s *WebhookService) deliverDue(ctx context.Context) {
+	for {
+		claimed, due, err := s.repo.ClaimDueDeliveries(ctx, time.Now(), webhookLease, 50)
+		if err != nil {
+			logger.Error("webhook: claim due deliveries failed", "err", err)
+			return
+		}
+		hooks := map[int]*model.Webhook{}
+		for _, d := range claimed {
+			h, ok := hooks[d.WebhookID]
+			if !ok {
+				h, _ = s.repo.Get(ctx, d.WebhookID)
+				hooks[d.WebhookID] = h
+			}
+			s.attempt(ctx, h, d)
+		}
+		if due < 50 {
+			return
+		}
+	}
+}
+
+// attempt sends one delivery and records the outcome, scheduling a retry on failure.
+func (s *WebhookService) attempt(ctx context.Context, h *model.Webhook, d model.WebhookDelivery) {
+	if h == nil || (!h.Active && d.Event != events.Ping) {
+		s.repo.UpdateDelivery(ctx, d.ID, map[string]interface{}{"status": "failed", "error": "webhook deleted or disabled", "next_attempt_at": nil})
+		return
+	}
+	code, err := s.send(ctx, h, d)
+	attempts := d.Attempts + 1
+	updates := map[string]interface{}{"attempts": attempts, "response_code": code}
+	switch {
+	case err == nil:
+		updates["status"], updates["error"], updates["next_attempt_at"], updates["delivered_at"] = "success", "", nil, time.Now()
+	case attempts > len(webhookBackoff):
+		updates["status"], updates["error"], updates["next_attempt_at"] = "failed", err.Error(), nil
+		logger.Warn("webhook: delivery failed", "webhook_id", h.ID, "delivery_id", d.ID, "event", d.Event, "attempts", attempts, "err", err)
+	default:
+		updates["error"], updates["next_attempt_at"] = err.Error(), time.Now().Add(webhookBackoff[attempts-1])
+	}
+	if err := s.repo.UpdateDelivery(ctx, d.ID, updates); err != nil {
+		logger.Error("webhook: save delivery failed", "delivery_id", d.ID, "err", err)
+	}
+}
+
+// send POSTs the delivery envelope. The signature header is
+// "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body)).
+func (s *WebhookService) send(ctx context.Context, h *model.Webhook, d model.WebhookDelivery) (int, error) {
+	body, _ := json.Marshal(map[string]any{
+		"id": d.ID, "event": d.Event, "created_at": d.CreatedAt, "data": json.RawMessage(d.Payload),
+	})
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "smart-daily-webhook")
+	req.Header.Set("X-Webhook-Event", d.Event)
+	req.Header.Set("X-Webhook-Delivery", strconv.Itoa(d.ID))
+	req.Header.Set("X-Webhook-Timestamp", ts)
+	req.Header.Set("X-Webhook-Signature", "sha256="+SignWebhook(h.Secret, ts, body))
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// SignWebhook returns the hex HMAC-SHA256 of timestamp + "." + body.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewWebhookSecret returns a random signing secret.
+func NewWebhookSecret() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,84 @@
+// Package textdiff diffs two daily-report texts item by item. A report lists
+// its work items one per line or separated by semicolons (imports join table
+// cells with "; "), so texts are compared as sequences of such segments
+// rather than characters.
+package textdiff
+
+import "strings"
+
+// Op types.
+const (
+	Equal  = "equal"
+	Delete = "delete"
+	Insert = "insert"
+)
+
+// Op is one segment of a diff: kept, only in the old text, or only in the new.
+type Op struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// maxCells bounds the LCS table; larger texts are diffed as a whole.
+const maxCells = 1 << 20
+
+// Segments splits text into its items: lines, split further at semicolons,
+// without list markers and surrounding space.
+func Segments(text string) []string {
+	var out []string
+	for _, part := range strings.FieldsFunc(text, func(r rune) bool { return r == '\n' || r == ';' || r == '；' }) {
+		if part = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(part), "-*•")); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// Diff returns the segments of a and b as a shortest edit sequence (by
+// longest common subsequence); within a change, deletions come first.
+func Diff(a, b string) []Op {
+	sa, sb := Segments(a), Segments(b)
+	if len(sa)*len(sb) > maxCells {
+		return append(ops(Delete, sa), ops(Insert, sb)...)
+	}
+	// lcs[i][j] is the LCS length of sa[i:] and sb[j:]
+	lcs := make([][]int, len(sa)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(sb)+1)
+	}
+	for i := len(sa) - 1; i >= 0; i-- {
+		for j := len(sb) - 1; j >= 0; j-- {
+			if sa[i] == sb[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var out []Op
+	i, j := 0, 0
+	for i < len(sa) && j < len(sb) {
+		switch {
+		case sa[i] == sb[j]:
+			out = append(out, Op{Equal, sa[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, Op{Delete, sa[i]})
+			i++
+		default:
+			out = append(out, Op{Insert, sb[j]})
+			j++
+		}
+	}
+	out = append(out, ops(Delete, sa[i:])...)
+	return append(out, ops(Insert, sb[j:])...)
+}
+
+func ops(typ string, segments []string) []Op {
+	out := make([]Op, 0, len(segments))
+	for _, s := range segments {
+		out = append(out, Op{typ, s})
+	}
+	return out
+}
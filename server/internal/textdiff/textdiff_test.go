@@ -0,0 +1,28 @@
+package textdiff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSegments(t *testing.T) {
+	got := Segments("- 导入接口; 分页修复\n\n* 联调；上线 ")
+	want := []string{"导入接口", "分页修复", "联调", "上线"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	got := Diff("导入接口; 分页修复; 联调", "- 导入接口\n- 分页修复完成\n- 联调")
+	want := []Op{{Equal, "导入接口"}, {Delete, "分页修复"}, {Insert, "分页修复完成"}, {Equal, "联调"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v", got)
+	}
+	if got := Diff("", "导入接口"); !reflect.DeepEqual(got, []Op{{Insert, "导入接口"}}) {
+		t.Errorf("from empty: %v", got)
+	}
+	if got := Diff("导入接口", "导入接口"); !reflect.DeepEqual(got, []Op{{Equal, "导入接口"}}) {
+		t.Errorf("identical: %v", got)
+	}
+}
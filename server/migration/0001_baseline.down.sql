@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS risks;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS report_drafts;
+DROP TABLE IF EXISTS feedback;
+DROP TABLE IF EXISTS topics;
+DROP TABLE IF EXISTS topic_activities;
+DROP TABLE IF EXISTS daily_summaries;
+DROP TABLE IF EXISTS daily_entries;
+DROP TABLE IF EXISTS members;
+DROP TABLE IF EXISTS teams;
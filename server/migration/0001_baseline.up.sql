@@ -0,0 +1,156 @@
+-- 基线：原 init.sql + 启动时临时 DDL 的完整表结构
+-- 建表全部 IF NOT EXISTS；旧版本建好的库缺少的列由文件末尾的 ADD COLUMN 补齐
+-- （迁移工具会跳过已存在的列），之后直接 migrate up 即可纳入版本管理
+
+CREATE TABLE IF NOT EXISTS teams (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(50) NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS members (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    username VARCHAR(50) NOT NULL UNIQUE,
+    password VARCHAR(255) NOT NULL,
+    name VARCHAR(50) NOT NULL,
+    avatar VARCHAR(255) DEFAULT '',
+    role VARCHAR(50) DEFAULT '开发工程师',
+    team_id INT DEFAULT 0,
+    team VARCHAR(50) DEFAULT '',
+    status VARCHAR(20) DEFAULT 'active',
+    is_admin BOOL DEFAULT FALSE,
+    email VARCHAR(100) DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS daily_entries (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    member_id INT NOT NULL,
+    daily_date DATE NOT NULL,
+    content TEXT NOT NULL,
+    summary TEXT,
+    source VARCHAR(20) DEFAULT 'chat',
+    created_at DATETIME DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS daily_summaries (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    member_id INT NOT NULL,
+    daily_date DATE NOT NULL,
+    summary TEXT,
+    status TEXT,
+    risk TEXT,
+    blocker TEXT,
+    UNIQUE KEY uk_member_date (member_id, daily_date)
+);
+
+CREATE TABLE IF NOT EXISTS topic_activities (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    topic VARCHAR(100) NOT NULL,
+    member_id INT NOT NULL,
+    member_name VARCHAR(50) NOT NULL,
+    daily_date DATE NOT NULL,
+    content TEXT,
+    entry_id INT DEFAULT 0,
+    INDEX idx_topic (topic),
+    INDEX idx_daily_date (daily_date)
+);
+
+CREATE TABLE IF NOT EXISTS topics (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    description TEXT DEFAULT '',
+    status VARCHAR(20) DEFAULT 'active',
+    created_at DATETIME DEFAULT NOW(),
+    resolved_at DATETIME DEFAULT NULL
+);
+
+CREATE TABLE IF NOT EXISTS feedback (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    member_id INT NOT NULL,
+    member_name VARCHAR(50) NOT NULL,
+    content TEXT NOT NULL,
+    status VARCHAR(20) DEFAULT 'open',
+    created_at DATETIME DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS report_drafts (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    member_id INT NOT NULL,
+    daily_date DATE NOT NULL,
+    mode VARCHAR(20) DEFAULT 'report',
+    content TEXT,
+    summary TEXT,
+    status VARCHAR(20) DEFAULT '',
+    blockers TEXT,
+    risks TEXT,
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME DEFAULT NOW(),
+    updated_at DATETIME DEFAULT NOW(),
+    INDEX idx_member (member_id)
+);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    member_id INT NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    title VARCHAR(200) NOT NULL,
+    content TEXT,
+    read_at DATETIME DEFAULT NULL,
+    created_at DATETIME DEFAULT NOW(),
+    INDEX idx_member (member_id)
+);
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) DEFAULT '',
+    url VARCHAR(500) NOT NULL,
+    secret VARCHAR(100) NOT NULL,
+    events TEXT,
+    active BOOLEAN DEFAULT TRUE,
+    created_by INT DEFAULT 0,
+    created_at DATETIME DEFAULT NOW(),
+    updated_at DATETIME DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    webhook_id INT NOT NULL,
+    event VARCHAR(50) NOT NULL,
+    payload TEXT,
+    status VARCHAR(20) DEFAULT 'pending',
+    attempts INT DEFAULT 0,
+    response_code INT DEFAULT 0,
+    error TEXT,
+    next_attempt_at DATETIME DEFAULT NULL,
+    created_at DATETIME DEFAULT NOW(),
+    delivered_at DATETIME DEFAULT NULL,
+    INDEX idx_webhook (webhook_id),
+    INDEX idx_due (status, next_attempt_at)
+);
+
+CREATE TABLE IF NOT EXISTS risks (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    member_id INT NOT NULL,
+    member_name VARCHAR(50) NOT NULL,
+    daily_date DATE NOT NULL,
+    entry_id INT DEFAULT 0,
+    topic_id INT DEFAULT 0,
+    description TEXT NOT NULL,
+    severity VARCHAR(10) DEFAULT 'medium',
+    category VARCHAR(20) DEFAULT 'blocked',
+    owner_id INT DEFAULT 0,
+    status VARCHAR(20) DEFAULT 'open',
+    resolution TEXT,
+    created_at DATETIME DEFAULT NOW(),
+    updated_at DATETIME DEFAULT NOW(),
+    closed_at DATETIME DEFAULT NULL,
+    INDEX idx_member_date (member_id, daily_date),
+    INDEX idx_entry (entry_id),
+    INDEX idx_status (status)
+);
+
+-- 旧版 init.sql 建的表缺少原先启动时才加的列
+ALTER TABLE members ADD COLUMN team_id INT DEFAULT 0;
+ALTER TABLE members ADD COLUMN email VARCHAR(100) DEFAULT '';
+ALTER TABLE members ADD COLUMN is_admin BOOL DEFAULT FALSE;
+ALTER TABLE report_drafts ADD COLUMN status VARCHAR(20) DEFAULT '';
+ALTER TABLE report_drafts ADD COLUMN blockers TEXT;
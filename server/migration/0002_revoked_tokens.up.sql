@@ -0,0 +1,13 @@
+-- 登出 / 强制下线：jti 非空封禁单个 token；jti 为空封禁该成员 issued_before 之前签发的全部 token
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    jti VARCHAR(64) NOT NULL DEFAULT '',
+    member_id INT NOT NULL,
+    issued_before DATETIME DEFAULT NULL,
+    expires_at DATETIME NOT NULL,
+    revoked_by INT DEFAULT 0,
+    created_at DATETIME DEFAULT NOW(),
+    INDEX idx_jti (jti),
+    INDEX idx_member (member_id),
+    INDEX idx_expires (expires_at)
+);
@@ -0,0 +1,4 @@
+-- 首次登录/管理员重置后必须修改密码
+ALTER TABLE members ADD COLUMN must_change_password BOOL DEFAULT FALSE;
+-- 仍在用公共默认密码（123456）的账号下次登录强制改密
+UPDATE members SET must_change_password = TRUE WHERE password = '$2a$10$sH3qZ9F0SIrCWpcOi9oWDO6EjbWMRs4X/8d35hphzkYRRM.ESRsa.';
@@ -0,0 +1,5 @@
+-- 访问角色：member（成员）/ team_lead（组长，权限限于本团队）/ admin（管理员）
+-- is_admin 继续与 access_role = 'admin' 同步维护，供 Catalog 查询和旧客户端使用
+ALTER TABLE members ADD COLUMN access_role VARCHAR(20) DEFAULT 'member';
+UPDATE members SET access_role = 'team_lead' WHERE role = 'Leader' AND team_id > 0;
+UPDATE members SET access_role = 'admin' WHERE is_admin = TRUE;
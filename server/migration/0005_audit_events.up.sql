@@ -0,0 +1,17 @@
+-- 审计日志：谁在什么时候对哪个对象做了什么，before / after 为变更前后的 JSON 快照
+CREATE TABLE IF NOT EXISTS audit_events (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    actor_id INT NOT NULL DEFAULT 0,
+    actor_name VARCHAR(100) NOT NULL DEFAULT '',
+    action VARCHAR(50) NOT NULL,
+    target_type VARCHAR(30) NOT NULL DEFAULT '',
+    target_id VARCHAR(64) NOT NULL DEFAULT '',
+    before_data TEXT,
+    after_data TEXT,
+    request_id VARCHAR(64) NOT NULL DEFAULT '',
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    created_at DATETIME DEFAULT NOW(),
+    INDEX idx_actor (actor_id),
+    INDEX idx_action (action),
+    INDEX idx_created (created_at)
+);
@@ -0,0 +1,4 @@
+ALTER TABLE members ADD COLUMN team VARCHAR(50) DEFAULT '';
+UPDATE members m JOIN teams t ON t.id = m.team_id SET m.team = t.name;
+ALTER TABLE teams DROP COLUMN lead_id;
+ALTER TABLE teams DROP COLUMN parent_id;
@@ -0,0 +1,12 @@
+-- 团队层级与组长：parent_id 为上级团队（0 表示顶级部门），lead_id 为指定的组长（members.id，0 表示未指定）
+ALTER TABLE teams ADD COLUMN parent_id INT DEFAULT 0;
+ALTER TABLE teams ADD COLUMN lead_id INT DEFAULT 0;
+-- 以各团队职位为 Leader 的成员（多个时取 id 最小者）作为初始组长
+UPDATE teams t JOIN (
+    SELECT team_id, MIN(id) AS lead_id FROM members
+    WHERE role = 'Leader' AND team_id > 0 AND status != 'deleted'
+    GROUP BY team_id
+) l ON l.team_id = t.id
+SET t.lead_id = l.lead_id;
+-- members.team 只是 team_id 的字符串副本，团队名称统一从 teams 读取
+ALTER TABLE members DROP COLUMN team;
@@ -0,0 +1,16 @@
+-- 分层总结缓存：日报 → 周汇总 → 月度/季度总结。
+-- member_id > 0 为个人总结；否则为团队总结（team_id 为团队，含下属团队；0 表示全员）。
+-- source_hash 为生成时输入数据的 SHA-256，输入未变化时直接复用 content
+CREATE TABLE IF NOT EXISTS period_summaries (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    member_id INT NOT NULL DEFAULT 0,
+    team_id INT NOT NULL DEFAULT 0,
+    period_type VARCHAR(10) NOT NULL,
+    period_start DATE NOT NULL,
+    period_end DATE NOT NULL,
+    content TEXT,
+    source_hash VARCHAR(64) NOT NULL DEFAULT '',
+    created_at DATETIME DEFAULT NOW(),
+    updated_at DATETIME DEFAULT NOW(),
+    UNIQUE KEY uk_period (member_id, team_id, period_type, period_start, period_end)
+);
@@ -0,0 +1,18 @@
+-- 已生成的个人周报：按成员 + 时间范围保存，source_hash 为生成时日报数据的 SHA-256，
+-- 日报未变化时再次生成直接复用（包括手动编辑过的内容）。
+-- created_by 为生成人（组长可为本团队成员生成周报）
+CREATE TABLE IF NOT EXISTS reports (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    member_id INT NOT NULL,
+    created_by INT NOT NULL DEFAULT 0,
+    period_start DATE NOT NULL,
+    period_end DATE NOT NULL,
+    title VARCHAR(200) NOT NULL DEFAULT '',
+    content TEXT,
+    source_hash VARCHAR(64) NOT NULL DEFAULT '',
+    edited TINYINT(1) NOT NULL DEFAULT 0,
+    created_at DATETIME DEFAULT NOW(),
+    updated_at DATETIME DEFAULT NOW(),
+    UNIQUE KEY uk_report (member_id, period_start, period_end),
+    INDEX idx_created_by (created_by)
+);
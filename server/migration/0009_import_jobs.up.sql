@@ -0,0 +1,31 @@
+-- 异步导入任务：上传后后台解析/提取，预览确认后后台写入，进度通过 SSE 推送。
+-- status：parsing → extracting → preview → confirming → done，任一阶段出错为 failed，可继续（resume）。
+-- decisions 为确认时的成员处理决定（JSON），result 为写入结果（JSON）
+CREATE TABLE IF NOT EXISTS import_jobs (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    created_by INT NOT NULL,
+    file_name VARCHAR(255) NOT NULL DEFAULT '',
+    format VARCHAR(16) NOT NULL DEFAULT '',
+    status VARCHAR(16) NOT NULL DEFAULT 'parsing',
+    error TEXT,
+    decisions TEXT,
+    result TEXT,
+    created_at DATETIME DEFAULT NOW(),
+    updated_at DATETIME DEFAULT NOW(),
+    INDEX idx_created_by (created_by)
+);
+
+-- 导入任务的分段（一个日期一段）及其提取结果：status 为 pending / done / failed，
+-- entries 为提取出的条目（JSON）。继续失败的任务时只重新提取未完成的分段。
+-- xlsx/csv 直接读出条目，存为一个已完成的分段
+CREATE TABLE IF NOT EXISTS import_job_sections (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    job_id INT NOT NULL,
+    seq INT NOT NULL,
+    date VARCHAR(255) NOT NULL DEFAULT '',
+    text LONGTEXT,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    entries LONGTEXT,
+    error TEXT,
+    UNIQUE KEY uk_job_seq (job_id, seq)
+);
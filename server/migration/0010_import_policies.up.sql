@@ -0,0 +1,2 @@
+-- 确认导入时每行的处理方式（JSON，行 key → skip / replace / merge），继续失败的写入时沿用
+ALTER TABLE import_jobs ADD COLUMN policies TEXT;
@@ -0,0 +1,9 @@
+DROP INDEX idx_batch ON topic_activities;
+DROP INDEX idx_batch ON daily_summaries;
+DROP INDEX idx_batch ON daily_entries;
+ALTER TABLE members DROP COLUMN batch_id;
+ALTER TABLE topic_activities DROP COLUMN batch_id;
+ALTER TABLE daily_summaries DROP COLUMN batch_id;
+ALTER TABLE daily_entries DROP COLUMN batch_id;
+DROP TABLE IF EXISTS import_batch_snapshots;
+DROP TABLE IF EXISTS import_batches;
@@ -0,0 +1,35 @@
+-- 导入批次：每次确认导入（ImportService.Confirm）记录一批，写入的条目、总结、Topic 动态和自动创建的成员都带 batch_id，
+-- 可通过 DELETE /api/import/batches/:id 整批撤销。status：applied / reverted
+CREATE TABLE IF NOT EXISTS import_batches (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    job_id INT NOT NULL DEFAULT 0,
+    created_by INT NOT NULL,
+    file_name VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(16) NOT NULL DEFAULT 'applied',
+    result TEXT,
+    created_at DATETIME DEFAULT NOW(),
+    reverted_by INT NOT NULL DEFAULT 0,
+    reverted_at DATETIME NULL,
+    INDEX idx_created_by (created_by)
+);
+
+-- 批次写入前各天的原始数据，撤销时恢复：summary 为被覆盖/合并前的当日总结（JSON，当天原来没有总结则为空），
+-- entries 为被覆盖删除的旧导入条目（JSON）
+CREATE TABLE IF NOT EXISTS import_batch_snapshots (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    batch_id INT NOT NULL,
+    member_id INT NOT NULL,
+    daily_date DATE NOT NULL,
+    summary TEXT,
+    entries LONGTEXT,
+    INDEX idx_batch (batch_id)
+);
+
+-- 写入来源批次，0 表示不是导入写入的
+ALTER TABLE daily_entries ADD COLUMN batch_id INT DEFAULT 0;
+ALTER TABLE daily_summaries ADD COLUMN batch_id INT DEFAULT 0;
+ALTER TABLE topic_activities ADD COLUMN batch_id INT DEFAULT 0;
+ALTER TABLE members ADD COLUMN batch_id INT DEFAULT 0;
+CREATE INDEX idx_batch ON daily_entries (batch_id);
+CREATE INDEX idx_batch ON daily_summaries (batch_id);
+CREATE INDEX idx_batch ON topic_activities (batch_id);
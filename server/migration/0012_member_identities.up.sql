@@ -0,0 +1,11 @@
+-- 外部登录身份绑定：OIDC 按 (issuer, sub) 找到成员，不再按可修改的用户名匹配
+CREATE TABLE IF NOT EXISTS member_identities (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    member_id INT NOT NULL,
+    provider VARCHAR(20) NOT NULL,
+    issuer VARCHAR(255) NOT NULL DEFAULT '',
+    subject VARCHAR(255) NOT NULL,
+    created_at DATETIME DEFAULT NOW(),
+    UNIQUE KEY uk_identity (provider, issuer, subject),
+    INDEX idx_member (member_id)
+);
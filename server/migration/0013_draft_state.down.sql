@@ -0,0 +1 @@
+ALTER TABLE report_drafts DROP COLUMN state;
@@ -0,0 +1,2 @@
+-- 草稿确认状态：确认时先把 pending 改为 confirming 占住草稿，日报保存成功后在同一事务中删除；保存失败改回 pending
+ALTER TABLE report_drafts ADD COLUMN state VARCHAR(20) NOT NULL DEFAULT 'pending';
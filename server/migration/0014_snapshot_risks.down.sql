@@ -0,0 +1 @@
+ALTER TABLE import_batch_snapshots DROP COLUMN risks;
@@ -0,0 +1,2 @@
+-- 导入快照同时记录被替换的导入日报的风险（JSON），撤销批次时一并恢复
+ALTER TABLE import_batch_snapshots ADD COLUMN risks LONGTEXT;
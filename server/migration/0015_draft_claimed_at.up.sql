@@ -0,0 +1,2 @@
+-- 草稿确认租约：记录草稿被占住的时间，确认过程中进程崩溃时，超过租约的 confirming 草稿可以被重新确认
+ALTER TABLE report_drafts ADD COLUMN claimed_at DATETIME DEFAULT NULL;
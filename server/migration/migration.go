@@ -0,0 +1,8 @@
+// Package migration holds the numbered schema migrations
+// (NNNN_name.up.sql / NNNN_name.down.sql), embedded into the server binary.
+package migration
+
+import "embed"
+
+//go:embed [0-9]*.sql
+var FS embed.FS
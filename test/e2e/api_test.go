@@ -2,10 +2,15 @@ package e2e
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
@@ -282,6 +287,443 @@ func TestAPILoginFail(t *testing.T) {
 	t.Log("OK: invalid login rejected")
 }
 
+func TestAPILogout(t *testing.T) {
+	c := newAPIClient(t)
+	if code, _ := c.do("GET", "/api/teams", nil); code != 200 {
+		t.Fatalf("before logout: status %d", code)
+	}
+	if code, result := c.do("POST", "/api/logout", nil); code != 200 {
+		t.Fatalf("logout: status %d, %v", code, result)
+	}
+	if code, _ := c.do("GET", "/api/teams", nil); code != 401 {
+		t.Fatalf("revoked token: expected 401, got %d", code)
+	}
+	// a fresh login still works
+	c.login("kuaiweikang", "123456")
+	if code, _ := c.do("GET", "/api/teams", nil); code != 200 {
+		t.Fatalf("after re-login: status %d", code)
+	}
+	t.Log("OK: logout revokes the token")
+}
+
+func TestAPIAuthProviders(t *testing.T) {
+	resp, err := http.Get(baseURL + "/api/auth/providers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var providers struct {
+		Password []string               `json:"password"`
+		OIDC     map[string]interface{} `json:"oidc"`
+	}
+	json.NewDecoder(resp.Body).Decode(&providers)
+	if resp.StatusCode != 200 || len(providers.Password) == 0 {
+		t.Fatalf("providers: status %d, %+v", resp.StatusCode, providers)
+	}
+
+	body, _ := json.Marshal(map[string]string{"username": "kuaiweikang", "password": "wrong-password"})
+	bad, err := http.Post(baseURL+"/api/login", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad.Body.Close()
+	if bad.StatusCode != 401 {
+		t.Errorf("wrong password: expected 401, got %d", bad.StatusCode)
+	}
+
+	c := newAPIClient(t)
+	code, me := c.do("GET", "/api/me", nil)
+	if code != 200 || me["name"] == "" || me["is_admin"] != true {
+		t.Fatalf("me: status %d, %v", code, me)
+	}
+	t.Logf("OK: login chain %v, oidc=%v, me=%v", providers.Password, providers.OIDC != nil, me["name"])
+}
+
+func TestAPIForceLogout(t *testing.T) {
+	admin := newAPIClient(t)
+	user := &apiClient{t: t}
+	user.login("test10", "123456")
+	code, me := user.do("GET", "/api/teams", nil)
+	if code != 200 {
+		t.Fatalf("test10 before: status %d, %v", code, me)
+	}
+	var id int
+	_, list := admin.doList("GET", "/api/members")
+	for _, m := range list {
+		if mm := m.(map[string]interface{}); mm["username"] == "test10" {
+			id = int(mm["id"].(float64))
+		}
+	}
+	if id == 0 {
+		t.Skip("test10 not found")
+	}
+	if code, _ := user.do("POST", fmt.Sprintf("/api/members/%d/logout", id), nil); code != 403 {
+		t.Errorf("non-admin force logout: expected 403, got %d", code)
+	}
+	if code, result := admin.do("POST", fmt.Sprintf("/api/members/%d/logout", id), nil); code != 200 {
+		t.Fatalf("force logout: status %d, %v", code, result)
+	}
+	if code, _ := user.do("GET", "/api/teams", nil); code != 401 {
+		t.Fatalf("after force logout: expected 401, got %d", code)
+	}
+	t.Log("OK: force logout revokes the member's tokens")
+}
+
+func TestAPIPasswordResetAndChange(t *testing.T) {
+	admin := newAPIClient(t)
+	var id int
+	_, list := admin.doList("GET", "/api/members")
+	for _, m := range list {
+		if mm := m.(map[string]interface{}); mm["username"] == "test09" {
+			id = int(mm["id"].(float64))
+		}
+	}
+	if id == 0 {
+		t.Skip("test09 not found")
+	}
+
+	// Reset works regardless of the current password, so the test is repeatable
+	code, result := admin.do("POST", fmt.Sprintf("/api/members/%d/password/reset", id), nil)
+	temp, _ := result["temporary_password"].(string)
+	if code != 200 || temp == "" {
+		t.Fatalf("reset: status %d, %v", code, result)
+	}
+
+	body, _ := json.Marshal(map[string]string{"username": "test09", "password": temp})
+	resp, err := http.Post(baseURL+"/api/login", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var login map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&login)
+	resp.Body.Close()
+	if user, _ := login["user"].(map[string]interface{}); user["must_change_password"] != true {
+		t.Fatalf("login with temporary password: %v", login)
+	}
+	user := &apiClient{t: t, token: login["token"].(string)}
+	other := &apiClient{t: t}
+	other.login("test09", temp) // a second session, logged out by the change
+	if code, _ := user.do("GET", "/api/teams", nil); code != 403 {
+		t.Errorf("before change: expected 403, got %d", code)
+	}
+	if code, _ := user.do("PUT", "/api/me/password", map[string]string{"old_password": "wrong", "new_password": "e2e-new-pass"}); code != 400 {
+		t.Errorf("wrong old password: expected 400, got %d", code)
+	}
+	if code, _ := user.do("PUT", "/api/me/password", map[string]string{"old_password": temp, "new_password": "short"}); code != 400 {
+		t.Errorf("short password: expected 400, got %d", code)
+	}
+	code, result = user.do("PUT", "/api/me/password", map[string]string{"old_password": temp, "new_password": "e2e-new-pass"})
+	if code != 200 {
+		t.Fatalf("change: status %d, %v", code, result)
+	}
+	if code, _ := user.do("GET", "/api/teams", nil); code != 401 {
+		t.Errorf("old token after change: expected 401, got %d", code)
+	}
+	if code, _ := other.do("GET", "/api/me", nil); code != 401 {
+		t.Errorf("other session after change: expected 401, got %d", code)
+	}
+	user.token = result["token"].(string)
+	if code, _ := user.do("GET", "/api/teams", nil); code != 200 {
+		t.Errorf("new token: status %d", code)
+	}
+	t.Log("OK: reset → forced change → new token")
+}
+
+func TestAPIPermissions(t *testing.T) {
+	admin := newAPIClient(t)
+	code, me := admin.do("GET", "/api/me", nil)
+	if perms, _ := me["permissions"].([]interface{}); code != 200 || me["access_role"] != "admin" || len(perms) == 0 {
+		t.Fatalf("admin me: status %d, %v", code, me)
+	}
+
+	user := &apiClient{t: t}
+	user.login("test08", "123456")
+	code, me = user.do("GET", "/api/me", nil)
+	if code != 200 || me["access_role"] != "member" {
+		t.Fatalf("test08 me: status %d, %v", code, me)
+	}
+	selfID := me["id"]
+
+	_, topics := admin.doList("GET", "/api/topics/all")
+	if len(topics) > 0 {
+		id := int(topics[0].(map[string]interface{})["id"].(float64))
+		if code, _ := user.do("PUT", fmt.Sprintf("/api/topics/%d/resolve", id), nil); code != 403 {
+			t.Errorf("member resolve topic: expected 403, got %d", code)
+		}
+	}
+	if code, result := user.do("PUT", fmt.Sprintf("/api/members/%v", selfID), map[string]string{"access_role": "admin"}); code != 403 || result["permission"] != "member.manage" {
+		t.Errorf("member self-promotion: expected 403 member.manage, got %d %v", code, result)
+	}
+	if code, _ := user.do("GET", "/api/webhooks", nil); code != 403 {
+		t.Errorf("member webhooks: expected 403, got %d", code)
+	}
+	if code, _ := admin.do("PUT", fmt.Sprintf("/api/members/%v", selfID), map[string]string{"access_role": "owner"}); code != 400 {
+		t.Errorf("unknown access_role: expected 400, got %d", code)
+	}
+	t.Log("OK: member role is denied management endpoints")
+}
+
+func TestAPIMemberLifecycle(t *testing.T) {
+	admin := newAPIClient(t)
+	username := fmt.Sprintf("e2e_life_%d", time.Now().UnixNano()%1000000)
+	code, result := admin.do("POST", "/api/members", map[string]string{"username": username, "name": "生命周期" + username[9:]})
+	temp, _ := result["temporary_password"].(string)
+	member, _ := result["member"].(map[string]interface{})
+	if code != 200 || temp == "" || member == nil {
+		t.Fatalf("create: status %d, %v", code, result)
+	}
+	id := int(member["id"].(float64))
+	if code, _ := admin.do("POST", "/api/members", map[string]string{"username": username, "name": "重复"}); code != 409 {
+		t.Errorf("duplicate username: expected 409, got %d", code)
+	}
+
+	user := &apiClient{t: t}
+	user.login(username, temp)
+	if code, me := user.do("GET", "/api/me", nil); code != 200 || me["must_change_password"] != true {
+		t.Errorf("new member me: status %d, %v", code, me)
+	}
+
+	purgePath := fmt.Sprintf("/api/members/%d/purge", id)
+	if code, _ := admin.do("DELETE", purgePath+"?dry_run=true", nil); code != 409 {
+		t.Errorf("purge active member: expected 409, got %d", code)
+	}
+	if code, _ := admin.do("DELETE", fmt.Sprintf("/api/members/%d", id), nil); code != 200 {
+		t.Fatalf("delete: status %d", code)
+	}
+	if code, _ := user.do("GET", "/api/me", nil); code != 401 {
+		t.Errorf("deleted member's token: expected 401, got %d", code)
+	}
+	if code, m := admin.do("POST", fmt.Sprintf("/api/members/%d/restore", id), nil); code != 200 || m["status"] != "active" {
+		t.Fatalf("restore: status %d, %v", code, m)
+	}
+	admin.do("DELETE", fmt.Sprintf("/api/members/%d", id), nil)
+
+	code, report := admin.do("DELETE", purgePath+"?dry_run=true", nil)
+	if code != 200 || report["dry_run"] != true || report["removed"] == nil {
+		t.Fatalf("dry run: status %d, %v", code, report)
+	}
+	if removed, _ := report["removed"].(map[string]interface{}); removed["import_batch_snapshots"] == nil || removed["team_period_summaries"] == nil {
+		t.Errorf("dry run: missing snapshot/team summary counts: %v", removed)
+	}
+	if _, deleted := admin.doList("GET", "/api/members?status=deleted"); !containsMember(deleted, id) {
+		t.Error("dry run removed the member")
+	}
+	if code, report = admin.do("DELETE", purgePath, nil); code != 200 || report["dry_run"] != false {
+		t.Fatalf("purge: status %d, %v", code, report)
+	}
+	if _, deleted := admin.doList("GET", "/api/members?status=deleted"); containsMember(deleted, id) {
+		t.Error("purged member still listed")
+	}
+	if code, _ := admin.do("POST", fmt.Sprintf("/api/members/%d/restore", id), nil); code != 404 {
+		t.Errorf("restore purged member: expected 404, got %d", code)
+	}
+	t.Logf("OK: member %d created, restored and purged", id)
+}
+
+func TestAPITeamHierarchy(t *testing.T) {
+	admin := newAPIClient(t)
+	suffix := fmt.Sprintf("%d", time.Now().UnixNano()%1000000)
+	create := func(name string, parent int) int {
+		t.Helper()
+		code, team := admin.do("POST", "/api/teams", map[string]interface{}{"name": name + suffix, "parent_id": parent})
+		if code != 200 {
+			t.Fatalf("create team %s: status %d, %v", name, code, team)
+		}
+		return int(team["id"].(float64))
+	}
+	dept := create("e2e部门_", 0)
+	group := create("e2e小组_", dept)
+	sub := create("e2e子组_", group)
+	if code, _ := admin.do("PUT", fmt.Sprintf("/api/teams/%d", dept), map[string]int{"parent_id": sub}); code != 400 {
+		t.Errorf("move team under its sub-team: expected 400, got %d", code)
+	}
+
+	code, result := admin.do("POST", "/api/members", map[string]interface{}{"username": "e2e_lead_" + suffix, "name": "组长" + suffix, "team_id": group})
+	if code != 200 {
+		t.Fatalf("create member: status %d, %v", code, result)
+	}
+	lead := int(result["member"].(map[string]interface{})["id"].(float64))
+	if code, _ := admin.do("PUT", fmt.Sprintf("/api/teams/%d", dept), map[string]int{"lead_id": lead}); code != 400 {
+		t.Errorf("lead from another team: expected 400, got %d", code)
+	}
+	if code, team := admin.do("PUT", fmt.Sprintf("/api/teams/%d", group), map[string]int{"lead_id": lead}); code != 200 || int(team["lead_id"].(float64)) != lead {
+		t.Fatalf("set lead: status %d, %v", code, team)
+	}
+	_, members := admin.doList("GET", "/api/members")
+	for _, m := range members {
+		if m := m.(map[string]interface{}); int(m["id"].(float64)) == lead && m["access_role"] != "team_lead" {
+			t.Errorf("designated lead access_role = %v, want team_lead", m["access_role"])
+		}
+	}
+
+	if code, _ := admin.do("GET", fmt.Sprintf("/api/feed/by-member?team_id=%d&subtree=true", dept), nil); code != 200 {
+		t.Errorf("feed by team subtree: status %d", code)
+	}
+	if code, _ := admin.do("GET", "/api/insights?team_id=abc", nil); code != 400 {
+		t.Errorf("insights with bad team_id: expected 400, got %d", code)
+	}
+	if code, _ := admin.do("DELETE", fmt.Sprintf("/api/teams/%d", group), nil); code != 409 {
+		t.Errorf("delete team with members: expected 409, got %d", code)
+	}
+	if code, _ := admin.do("POST", "/api/teams/merge", map[string]int{"source_id": group, "target_id": dept}); code != 200 {
+		t.Fatalf("merge: status %d", code)
+	}
+	_, teams := admin.doList("GET", "/api/teams")
+	for _, tm := range teams {
+		tm := tm.(map[string]interface{})
+		switch int(tm["id"].(float64)) {
+		case group:
+			t.Error("merged team still listed")
+		case sub:
+			if int(tm["parent_id"].(float64)) != dept {
+				t.Errorf("sub-team parent after merge = %v, want %d", tm["parent_id"], dept)
+			}
+		case dept:
+			if int(tm["lead_id"].(float64)) != lead {
+				t.Errorf("merged lead = %v, want %d", tm["lead_id"], lead)
+			}
+		}
+	}
+
+	admin.do("DELETE", fmt.Sprintf("/api/members/%d", lead), nil)
+	admin.do("DELETE", fmt.Sprintf("/api/members/%d/purge", lead), nil)
+	for _, id := range []int{sub, dept} {
+		if code, _ := admin.do("DELETE", fmt.Sprintf("/api/teams/%d", id), nil); code != 200 {
+			t.Errorf("delete team %d: status %d", id, code)
+		}
+	}
+	t.Log("OK: nested teams, lead, merge and delete")
+}
+
+func TestAPITeamLeadChanges(t *testing.T) {
+	admin := newAPIClient(t)
+	suffix := fmt.Sprintf("%d", time.Now().UnixNano()%1000000)
+	team := func(name string) int {
+		t.Helper()
+		code, tm := admin.do("POST", "/api/teams", map[string]interface{}{"name": name + suffix})
+		if code != 200 {
+			t.Fatalf("create team %s: status %d, %v", name, code, tm)
+		}
+		return int(tm["id"].(float64))
+	}
+	member := func(username string, teamID int) int {
+		t.Helper()
+		code, result := admin.do("POST", "/api/members", map[string]interface{}{"username": username + suffix, "name": username + suffix, "team_id": teamID})
+		if code != 200 {
+			t.Fatalf("create member %s: status %d, %v", username, code, result)
+		}
+		return int(result["member"].(map[string]interface{})["id"].(float64))
+	}
+	accessRole := func(id int) interface{} {
+		_, members := admin.doList("GET", "/api/members")
+		for _, m := range members {
+			if m := m.(map[string]interface{}); int(m["id"].(float64)) == id {
+				return m["access_role"]
+			}
+		}
+		return nil
+	}
+	teamA, teamB := team("e2e甲组_"), team("e2e乙组_")
+	first, second := member("e2e_lead_a_", teamA), member("e2e_lead_b_", teamA)
+
+	if code, _ := admin.do("PUT", fmt.Sprintf("/api/members/%d", first), map[string]string{"access_role": "team_lead"}); code != 400 {
+		t.Errorf("team_lead without leading a team: expected 400, got %d", code)
+	}
+	admin.do("PUT", fmt.Sprintf("/api/teams/%d", teamA), map[string]int{"lead_id": first})
+	if role := accessRole(first); role != "team_lead" {
+		t.Fatalf("designated lead access_role = %v, want team_lead", role)
+	}
+
+	// Replacing the lead demotes the previous one
+	if code, _ := admin.do("PUT", fmt.Sprintf("/api/teams/%d", teamA), map[string]int{"lead_id": second}); code != 200 {
+		t.Fatalf("replace lead: status %d", code)
+	}
+	if role := accessRole(first); role != "member" {
+		t.Errorf("replaced lead access_role = %v, want member", role)
+	}
+	if role := accessRole(second); role != "team_lead" {
+		t.Errorf("new lead access_role = %v, want team_lead", role)
+	}
+
+	// Moving the lead to another team (the member form resends the role) demotes them too
+	if code, _ := admin.do("PUT", fmt.Sprintf("/api/members/%d", second), map[string]interface{}{"team_id": teamB, "access_role": "team_lead"}); code != 200 {
+		t.Fatalf("move lead: status %d", code)
+	}
+	if role := accessRole(second); role != "member" {
+		t.Errorf("moved lead access_role = %v, want member", role)
+	}
+	_, teams := admin.doList("GET", "/api/teams")
+	for _, tm := range teams {
+		if tm := tm.(map[string]interface{}); int(tm["id"].(float64)) == teamA && int(tm["lead_id"].(float64)) != 0 {
+			t.Errorf("team lead after the lead moved = %v, want 0", tm["lead_id"])
+		}
+	}
+
+	for _, id := range []int{first, second} {
+		admin.do("DELETE", fmt.Sprintf("/api/members/%d", id), nil)
+		admin.do("DELETE", fmt.Sprintf("/api/members/%d/purge", id), nil)
+	}
+	for _, id := range []int{teamA, teamB} {
+		admin.do("DELETE", fmt.Sprintf("/api/teams/%d", id), nil)
+	}
+	t.Log("OK: replaced and moved leads lose team_lead")
+}
+
+func containsMember(list []interface{}, id int) bool {
+	for _, m := range list {
+		if int(m.(map[string]interface{})["id"].(float64)) == id {
+			return true
+		}
+	}
+	return false
+}
+
+func TestAPIAudit(t *testing.T) {
+	admin := newAPIClient(t)
+	teamName := fmt.Sprintf("e2e审计组_%d", time.Now().UnixNano()%100000)
+	resp := admin.doRaw("POST", "/api/teams", map[string]string{"name": teamName})
+	resp.Body.Close()
+	reqID := resp.Header.Get("X-Request-ID")
+	if resp.StatusCode != 200 || reqID == "" {
+		t.Fatalf("create team: status %d, request id %q", resp.StatusCode, reqID)
+	}
+
+	today := time.Now().Format("2006-01-02")
+	code, result := admin.do("GET", "/api/admin/audit?action=team.create&start="+today+"&end="+today, nil)
+	items, _ := result["items"].([]interface{})
+	if code != 200 || len(items) == 0 {
+		t.Fatalf("audit list: status %d, %v", code, result)
+	}
+	var found map[string]interface{}
+	for _, it := range items {
+		e := it.(map[string]interface{})
+		if after, _ := e["after"].(map[string]interface{}); after["name"] == teamName {
+			found = e
+			break
+		}
+	}
+	if found == nil || found["request_id"] != reqID || found["actor_name"] == "" || found["target_type"] != "team" {
+		t.Fatalf("audit event for %s: %v", teamName, found)
+	}
+
+	if code, _ := admin.do("GET", "/api/admin/audit?start=not-a-date", nil); code != 400 {
+		t.Errorf("bad start: expected 400, got %d", code)
+	}
+	resp = admin.doRaw("GET", "/api/admin/audit/export?action=team.create&start="+today)
+	csvBody, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != 200 || !strings.Contains(string(csvBody), teamName) {
+		t.Errorf("audit export: status %d, %d bytes", resp.StatusCode, len(csvBody))
+	}
+
+	user := &apiClient{t: t}
+	user.login("test08", "123456")
+	if code, _ := user.do("GET", "/api/admin/audit", nil); code != 403 {
+		t.Errorf("member audit list: expected 403, got %d", code)
+	}
+	t.Logf("OK: team.create audited with request id %s", reqID)
+}
+
 func TestAPIUnauthorized(t *testing.T) {
 	// No token
 	resp, err := http.Get(baseURL + "/api/members")
@@ -319,6 +761,118 @@ func TestAPIFeedByMember(t *testing.T) {
 	t.Logf("OK: %d members in feed", len(members))
 }
 
+func TestAPIFeedScope(t *testing.T) {
+	admin := newAPIClient(t)
+	member := &apiClient{t: t}
+	member.login("test08", "123456")
+	_, me := member.do("GET", "/api/me", nil)
+	selfID := int(me["id"].(float64))
+
+	// members see their own team, nothing of other teams
+	teamOf := map[int]int{}
+	_, all := admin.doList("GET", "/api/members")
+	for _, m := range all {
+		m := m.(map[string]interface{})
+		teamOf[int(m["id"].(float64))] = int(m["team_id"].(float64))
+	}
+	visible := func(id int) bool {
+		return id == selfID || (teamOf[selfID] != 0 && teamOf[id] == teamOf[selfID])
+	}
+
+	code, result := member.do("GET", "/api/feed/by-member?start=2024-01-01&end=2026-12-31", nil)
+	if code != 200 {
+		t.Fatalf("member feed: status %d, %v", code, result)
+	}
+	members, _ := result["members"].([]interface{})
+	for _, m := range members {
+		if id := int(m.(map[string]interface{})["member_id"].(float64)); !visible(id) {
+			t.Errorf("member feed leaks member %d of another team", id)
+		}
+	}
+	if code, _ := member.do("GET", "/api/feed/by-topic?start=2024-01-01&end=2026-12-31", nil); code != 200 {
+		t.Fatalf("member topic feed: status %d", code)
+	}
+
+	if code, _ := member.do("GET", "/api/insights", nil); code != 200 {
+		t.Errorf("member insights: status %d", code)
+	}
+	_, teams := admin.doList("GET", "/api/teams")
+	for _, tm := range teams {
+		id := int(tm.(map[string]interface{})["id"].(float64))
+		if code, _ := admin.do("GET", fmt.Sprintf("/api/insights?team_id=%d", id), nil); code != 200 {
+			t.Errorf("admin insights for team %d: status %d", id, code)
+		}
+		want := 403
+		if id == teamOf[selfID] {
+			want = 200
+		}
+		if code, _ := member.do("GET", fmt.Sprintf("/api/insights?team_id=%d", id), nil); code != want {
+			t.Errorf("member insights for team %d: expected %d, got %d", id, want, code)
+		}
+	}
+
+	_, risks := admin.doList("GET", "/api/risks")
+	checked := map[bool]bool{}
+	for _, r := range risks {
+		r := r.(map[string]interface{})
+		memberID := int(r["member_id"].(float64))
+		if memberID == selfID || int(r["owner_id"].(float64)) == selfID || checked[visible(memberID)] {
+			continue
+		}
+		checked[visible(memberID)] = true
+		want := 403
+		if visible(memberID) {
+			want = 200
+		}
+		if code, _ := member.do("GET", fmt.Sprintf("/api/risks/%d", int(r["id"].(float64))), nil); code != want {
+			t.Errorf("member reading risk of member %d: expected %d, got %d", memberID, want, code)
+		}
+	}
+	_, own := member.doList("GET", "/api/risks")
+	for _, r := range own {
+		r := r.(map[string]interface{})
+		if !visible(int(r["member_id"].(float64))) && int(r["owner_id"].(float64)) != selfID {
+			t.Errorf("member risk list leaks risk %v", r["id"])
+		}
+	}
+	t.Log("OK: feeds and risks scoped to the caller's team")
+}
+
+func TestAPIFeedByMemberStatusFilter(t *testing.T) {
+	c := newAPIClient(t)
+	code, result := c.do("GET", "/api/feed/by-member?start=2024-01-01&end=2026-12-31&status=blocked,at-risk", nil)
+	if code != 200 {
+		t.Fatalf("status %d, %v", code, result)
+	}
+	n := 0
+	for _, m := range result["members"].([]interface{}) {
+		for _, it := range m.(map[string]interface{})["items"].([]interface{}) {
+			item := it.(map[string]interface{})
+			if s := item["status"]; s != "blocked" && s != "at-risk" {
+				t.Errorf("status filter leaked %v on %v", s, item["daily_date"])
+			}
+			n++
+		}
+	}
+
+	code, result = c.do("GET", "/api/feed/by-member?start=2024-01-01&end=2026-12-31&blocked=true", nil)
+	if code != 200 {
+		t.Fatalf("blocked: status %d, %v", code, result)
+	}
+	for _, m := range result["members"].([]interface{}) {
+		for _, it := range m.(map[string]interface{})["items"].([]interface{}) {
+			if item := it.(map[string]interface{}); item["blocker"] == "" {
+				t.Errorf("blocked filter returned a day without blocker: %v", item["daily_date"])
+			}
+		}
+	}
+
+	if code, _ := c.do("GET", "/api/feed/by-member?status=stuck", nil); code != 400 {
+		t.Errorf("invalid status: expected 400, got %d", code)
+	}
+	t.Logf("OK: %d blocked/at-risk days", n)
+}
+
 func TestAPIFeedByTopic(t *testing.T) {
 	c := newAPIClient(t)
 	code, result := c.do("GET", "/api/feed/by-topic?start=2024-01-01&end=2026-12-31", nil)
@@ -489,6 +1043,37 @@ func TestAPICalendar(t *testing.T) {
 	}
 	t.Logf("OK: 2026-03 has %d days, workdays=%.0f, filled=%.0f", len(days), result["workdays"], result["filled_workdays"])
 
+	// Day detail carries the work status of submitted days
+	for _, d := range days {
+		day := d.(map[string]interface{})
+		if day["submitted"] != true {
+			continue
+		}
+		code, detail := c.do("GET", "/api/calendar/day?date="+day["date"].(string), nil)
+		if code != 200 {
+			t.Fatalf("calendar/day: status %d", code)
+		}
+		for _, f := range []string{"summary", "status", "risk", "blocker"} {
+			if _, ok := detail[f]; !ok {
+				t.Errorf("calendar/day missing field: %s", f)
+			}
+		}
+		// the status filter keeps the day only when its status is listed
+		status, _ := detail["status"].(string)
+		other := "off"
+		if status == "off" {
+			other = "on-track"
+		}
+		_, filtered := c.do("GET", "/api/calendar/day?date="+day["date"].(string)+"&status="+other, nil)
+		if filtered["matched"] != false || filtered["summary"] != nil {
+			t.Errorf("calendar/day filtered by status %s: %v", other, filtered)
+		}
+		if code, _ := c.do("GET", "/api/calendar/day?date="+day["date"].(string)+"&status=bogus", nil); code != 400 {
+			t.Errorf("calendar/day invalid status: expected 400, got %d", code)
+		}
+		break
+	}
+
 	// Navigate to previous month (翻页)
 	code, result = c.do("GET", "/api/calendar?month=2026-02", nil)
 	if code != 200 {
@@ -532,6 +1117,655 @@ func TestAPICalendar(t *testing.T) {
 	t.Logf("OK: 2026-01 holidays correct, Jan1=%v Jan4_workday=%v", jan1["holiday"], jan4["is_workday"])
 }
 
+func TestAPICompliance(t *testing.T) {
+	c := newAPIClient(t)
+
+	code, result := c.do("GET", "/api/compliance?month=2026-03", nil)
+	if code != 200 {
+		t.Fatalf("status %d, %v", code, result)
+	}
+	members, ok := result["members"].([]interface{})
+	if !ok || len(members) == 0 {
+		t.Fatal("missing or empty members")
+	}
+	workdays := result["workdays"].(float64)
+	for _, m := range members {
+		mc := m.(map[string]interface{})
+		filled, missing := mc["filled_days"].(float64), mc["missing_dates"].([]interface{})
+		if mc["workdays"].(float64) != workdays || filled+float64(len(missing)) != workdays {
+			t.Errorf("%v: workdays=%v filled=%v missing=%d", mc["member_name"], mc["workdays"], filled, len(missing))
+		}
+	}
+	t.Logf("OK: 2026-03 compliance for %d members, %.0f workdays", len(members), workdays)
+
+	if code, _ := c.do("GET", "/api/compliance?month=2026-13", nil); code != 400 {
+		t.Errorf("invalid month: expected 400, got %d", code)
+	}
+
+	resp := c.doRaw("GET", "/api/compliance/export?month=2026-03")
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 200 || len(body) < 2 || body[0] != 0x50 || body[1] != 0x4B {
+		t.Fatalf("export: status %d, %d bytes", resp.StatusCode, len(body))
+	}
+	t.Logf("OK: exported %d bytes xlsx", len(body))
+}
+
+func TestAPITeamDigest(t *testing.T) {
+	c := newAPIClient(t)
+	const month = "start=2026-03-01&end=2026-03-31"
+
+	for path, want := range map[string]int{
+		"/api/digest?format=pdf":                        400,
+		"/api/digest?start=2026-03-31&end=2026-03-01":   400,
+		"/api/digest?start=2026-01-01&end=2026-12-31":   400,
+		"/api/digest?team_id=999999&format=md&" + month: 404,
+	} {
+		if code, result := c.do("GET", path, nil); code != want {
+			t.Errorf("GET %s: expected %d, got %d %v", path, want, code, result)
+		}
+	}
+
+	// SSE: tokens, then a meta event offering .md and .docx downloads
+	resp := c.doRaw("GET", "/api/digest?"+month)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != 200 || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		t.Fatalf("stream: status %d, content-type %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	stream := string(body)
+	if !strings.Contains(stream, "event: token") || !strings.HasSuffix(strings.TrimSpace(stream), "data: {}") {
+		t.Fatalf("stream lacks tokens or done: %.300s", stream)
+	}
+	if i := strings.Index(stream, "event: meta\ndata: "); i >= 0 {
+		line := stream[i+len("event: meta\ndata: "):]
+		var meta map[string]string
+		json.Unmarshal([]byte(line[:strings.Index(line, "\n")]), &meta)
+		if meta["downloadUrl"] == "" || meta["docxUrl"] == "" {
+			t.Fatalf("meta without downloads: %v", meta)
+		}
+		dl := c.doRaw("GET", meta["docxUrl"])
+		doc, _ := io.ReadAll(dl.Body)
+		dl.Body.Close()
+		if dl.StatusCode != 200 || len(doc) < 2 || doc[0] != 0x50 || doc[1] != 0x4B {
+			t.Errorf("docx download: status %d, %d bytes", dl.StatusCode, len(doc))
+		}
+		if code, _ := c.do("GET", meta["docxUrl"], nil); code != 404 {
+			t.Errorf("second download: expected 404, got %d", code)
+		}
+		t.Logf("OK: streamed digest, downloaded %s (%d bytes)", meta["docxTitle"], len(doc))
+	} else {
+		t.Logf("OK: no reports in 2026-03: %.200s", stream)
+	}
+
+	resp = c.doRaw("GET", "/api/digest?format=docx&"+month)
+	doc, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode == 404:
+		t.Log("OK: docx export reports no data")
+	case resp.StatusCode != 200 || resp.Header.Get("Content-Type") != "application/vnd.openxmlformats-officedocument.wordprocessingml.document" || len(doc) < 2 || doc[0] != 0x50:
+		t.Fatalf("docx export: status %d, content-type %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	member := &apiClient{t: t}
+	member.login("test08", "123456")
+	if code, _ := member.do("GET", "/api/digest?format=md", nil); code != 403 {
+		t.Errorf("plain member: expected 403, got %d", code)
+	}
+}
+
+func TestAPIReview(t *testing.T) {
+	c := newAPIClient(t)
+
+	for path, want := range map[string]int{
+		"/api/review?format=pdf":                         400,
+		"/api/review?period=2026-13":                     400,
+		"/api/review?period=2026-Q5":                     400,
+		"/api/review?member_id=999999&period=2026-03":    404,
+		"/api/review?team_id=999999&period=2026-03":      404,
+		"/api/review?period=1999-01&format=md":           404,
+		"/api/review?team_id=0&period=1999-Q1&format=md": 404,
+	} {
+		if code, result := c.do("GET", path, nil); code != want {
+			t.Errorf("GET %s: expected %d, got %d %v", path, want, code, result)
+		}
+	}
+
+	// Everyone's quarter: one thinking step per weekly rollup, then the review
+	resp := c.doRaw("GET", "/api/review?team_id=0&period=2026-Q1")
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != 200 || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		t.Fatalf("stream: status %d, content-type %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	stream := string(body)
+	if !strings.Contains(stream, "event: meta") {
+		t.Logf("OK: no reports in 2026-Q1: %.200s", stream)
+		return
+	}
+	if !strings.Contains(stream, "event: thinking") {
+		t.Errorf("stream lacks weekly rollup steps: %.300s", stream)
+	}
+
+	// Unchanged reports: the second run replays the cached review
+	first := c.doRaw("GET", "/api/review?team_id=0&period=2026-Q1&format=md")
+	a, _ := io.ReadAll(first.Body)
+	first.Body.Close()
+	second := c.doRaw("GET", "/api/review?team_id=0&period=2026-Q1&format=md")
+	b, _ := io.ReadAll(second.Body)
+	second.Body.Close()
+	if first.StatusCode != 200 || string(a) != string(b) {
+		t.Errorf("cached review: status %d, identical=%v", first.StatusCode, string(a) == string(b))
+	}
+	if !strings.Contains(second.Header.Get("Content-Disposition"), "2026-Q1") {
+		t.Errorf("download name: %s", second.Header.Get("Content-Disposition"))
+	}
+
+	member := &apiClient{t: t}
+	member.login("test08", "123456")
+	if code, _ := member.do("GET", "/api/review?team_id=0&period=2026-03", nil); code != 403 {
+		t.Errorf("plain member reviewing everyone: expected 403, got %d", code)
+	}
+	t.Logf("OK: quarterly review streamed and cached (%d bytes)", len(a))
+}
+
+func TestAPIWeeklyReports(t *testing.T) {
+	c := newAPIClient(t)
+	summary := func() map[string]interface{} {
+		resp := c.doRaw("POST", "/api/chat/stream", map[string]string{"text": "", "mode": "summary"})
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		stream := string(body)
+		i := strings.Index(stream, "event: meta\ndata: ")
+		if i < 0 {
+			return nil
+		}
+		line := stream[i+len("event: meta\ndata: "):]
+		var meta map[string]interface{}
+		json.Unmarshal([]byte(line[:strings.Index(line, "\n")]), &meta)
+		return meta
+	}
+
+	meta := summary()
+	if meta == nil {
+		t.Skip("no entries this week")
+	}
+	id := int(meta["reportId"].(float64))
+	if again := summary(); again == nil || int(again["reportId"].(float64)) != id {
+		t.Fatalf("unchanged entries must reuse report %d: %v", id, again)
+	}
+
+	code, list := c.doList("GET", "/api/reports")
+	if code != 200 || len(list) == 0 {
+		t.Fatalf("GET /api/reports: status %d, %d reports", code, len(list))
+	}
+
+	// An edit survives regeneration while the entries are unchanged
+	code, edited := c.do("PUT", fmt.Sprintf("/api/reports/%d", id), map[string]string{"content": "# 周报\n\n- 手动修改"})
+	if code != 200 || edited["edited"] != true {
+		t.Fatalf("PUT /api/reports/%d: %d %v", id, code, edited)
+	}
+	summary()
+	resp := c.doRaw("GET", meta["downloadUrl"].(string))
+	md, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != 200 || !strings.Contains(string(md), "手动修改") {
+		t.Errorf("download after regeneration: status %d, %.100s", resp.StatusCode, md)
+	}
+	// Downloads are repeatable, unlike generated files
+	resp = c.doRaw("GET", meta["docxUrl"].(string))
+	doc, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != 200 || len(doc) < 2 || doc[0] != 0x50 || doc[1] != 0x4B {
+		t.Errorf("docx download: status %d, %d bytes", resp.StatusCode, len(doc))
+	}
+	if code, _ := c.do("GET", fmt.Sprintf("/api/reports/%d/download?format=pdf", id), nil); code != 400 {
+		t.Errorf("bad format: expected 400, got %d", code)
+	}
+
+	// Discarding the edits lets the next summary regenerate the same report
+	if code, rep := c.do("PUT", fmt.Sprintf("/api/reports/%d", id), map[string]interface{}{"edited": false}); code != 200 || rep["edited"] != false {
+		t.Fatalf("discard edits: %d %v", code, rep)
+	}
+	if again := summary(); again == nil || int(again["reportId"].(float64)) != id || again["stale"] == true {
+		t.Errorf("regenerate after discarding edits: %v", again)
+	}
+	if code, rep := c.do("GET", fmt.Sprintf("/api/reports/%d", id), nil); code != 200 || strings.Contains(rep["content"].(string), "手动修改") {
+		t.Errorf("report still holds the discarded edit: %d", code)
+	}
+
+	member := &apiClient{t: t}
+	member.login("test08", "123456")
+	if code, _ := member.do("GET", fmt.Sprintf("/api/reports/%d", id), nil); code != 404 {
+		t.Errorf("foreign report: expected 404, got %d", code)
+	}
+	if code, _ := member.do("PUT", fmt.Sprintf("/api/reports/%d", id), map[string]string{"content": "x"}); code != 404 {
+		t.Errorf("foreign edit: expected 404, got %d", code)
+	}
+	t.Logf("OK: weekly report %d reused, edited and re-downloaded", id)
+}
+
+// upload posts data as the multipart file field of path.
+func (c *apiClient) upload(path, name string, data []byte) (int, map[string]interface{}) {
+	c.t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fw, _ := mw.CreateFormFile("file", name)
+	fw.Write(data)
+	mw.Close()
+	req, _ := http.NewRequest("POST", baseURL+path, &buf)
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.t.Fatalf("upload %s: %v", name, err)
+	}
+	defer resp.Body.Close()
+	var result map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, result
+}
+
+func TestAPIImportPreview(t *testing.T) {
+	c := newAPIClient(t)
+	upload := func(name string, data []byte) (int, map[string]interface{}) {
+		return c.upload("/api/import/preview", name, data)
+	}
+
+	if code, result := upload("blob.bin", []byte{0x00, 0x01, 0xff, 0xfe}); code != 400 {
+		t.Errorf("binary upload: expected 400, got %d %v", code, result)
+	}
+	if code, result := upload("notes.txt", []byte("曹凯 完成导入接口")); code != 400 {
+		t.Errorf("undated text: expected 400, got %d %v", code, result)
+	}
+
+	doc, err := os.ReadFile("../../server/internal/docxparse/testdata/daily_reports.docx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, result := upload("daily_reports.docx", doc)
+	if code != 200 {
+		t.Fatalf("preview: status %d, %v", code, result)
+	}
+	entries, _ := result["entries"].([]interface{})
+	found := false
+	for _, e := range entries {
+		m := e.(map[string]interface{})
+		if m["date"] == "2026-02-13" && m["name"] == "曹凯" {
+			found = m["content"] == "完成导入接口; 修复分页问题; 联调"
+		}
+	}
+	if !found {
+		t.Errorf("parsed entries lack 曹凯 on 2026-02-13 with the in-cell lines joined: %v", entries)
+	}
+	t.Logf("OK: docx parsed natively, %d entries", len(entries))
+
+	// A CSV in the export's date / name / summary / risk layout is read as is
+	code, result = upload("daily.csv", []byte("日期,姓名,内容,风险\n2026-02-13,曹凯,\"完成导入接口\n联调\",依赖排期\n"))
+	if code != 200 {
+		t.Fatalf("csv preview: status %d, %v", code, result)
+	}
+	entries, _ = result["entries"].([]interface{})
+	if len(entries) != 1 {
+		t.Fatalf("csv preview: expected 1 entry, got %v", entries)
+	}
+	if m := entries[0].(map[string]interface{}); m["content"] != "完成导入接口\n联调" || m["risk"] != "依赖排期" {
+		t.Errorf("csv entry not read losslessly: %v", m)
+	}
+
+	code, result = upload("notes.md", []byte("## 2026-02-13\n\n| 成员 | 进展 |\n|---|---|\n| 曹凯 | 完成导入接口 |\n"))
+	if code != 200 {
+		t.Fatalf("markdown preview: status %d, %v", code, result)
+	}
+	entries, _ = result["entries"].([]interface{})
+	if len(entries) != 1 || entries[0].(map[string]interface{})["content"] != "完成导入接口" {
+		t.Errorf("markdown table not extracted: %v", entries)
+	}
+}
+
+func TestAPIImportJobs(t *testing.T) {
+	c := newAPIClient(t)
+	// events reads a job's SSE stream to the end and returns the last event
+	// and its data
+	events := func(id int) (string, map[string]interface{}) {
+		resp := c.doRaw("GET", fmt.Sprintf("/api/import/jobs/%d/events", id))
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != 200 || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+			t.Fatalf("events: status %d, %s", resp.StatusCode, body)
+		}
+		var last string
+		var data map[string]interface{}
+		for _, block := range strings.Split(string(body), "\n\n") {
+			lines := strings.SplitN(block, "\n", 2)
+			if len(lines) == 2 && strings.HasPrefix(lines[0], "event: ") && lines[0] != "event: done" {
+				last = strings.TrimPrefix(lines[0], "event: ")
+				data = nil
+				json.Unmarshal([]byte(strings.TrimPrefix(lines[1], "data: ")), &data)
+			}
+		}
+		if !strings.Contains(string(body), "event: progress") {
+			t.Errorf("stream without progress events: %.300s", body)
+		}
+		return last, data
+	}
+
+	code, job := c.upload("/api/import/jobs", "daily.csv", []byte("日期,姓名,内容,风险\n2019-12-30,曹凯,整理导入任务,\n"))
+	if code != 202 {
+		t.Fatalf("create job: status %d, %v", code, job)
+	}
+	id := int(job["id"].(float64))
+
+	event, view := events(id)
+	if event != "preview" {
+		t.Fatalf("expected preview, got %s %v", event, view)
+	}
+	if entries, _ := view["entries"].([]interface{}); len(entries) != 1 {
+		t.Fatalf("preview entries: %v", view["entries"])
+	}
+	if code, result := c.do("POST", fmt.Sprintf("/api/import/jobs/%d/resume", id), nil); code != 409 {
+		t.Errorf("resume at preview: expected 409, got %d %v", code, result)
+	}
+
+	if code, result := c.do("POST", fmt.Sprintf("/api/import/jobs/%d/confirm", id), map[string]interface{}{}); code != 202 {
+		t.Fatalf("confirm: status %d, %v", code, result)
+	}
+	if code, result := c.do("POST", fmt.Sprintf("/api/import/jobs/%d/confirm", id), map[string]interface{}{}); code != 409 {
+		t.Errorf("second confirm: expected 409, got %d %v", code, result)
+	}
+	if event, view = events(id); event != "result" {
+		t.Fatalf("expected result, got %s %v", event, view)
+	}
+	result, _ := view["result"].(map[string]interface{})
+	if result == nil || result["imported"].(float64)+result["merged"].(float64) != 1 {
+		t.Errorf("unexpected result: %v", view["result"])
+	}
+
+	code, jobs := c.doList("GET", "/api/import/jobs")
+	if code != 200 || len(jobs) == 0 || int(jobs[0].(map[string]interface{})["id"].(float64)) != id {
+		t.Errorf("list jobs: status %d, %v", code, jobs)
+	}
+	member := &apiClient{t: t}
+	member.login("test08", "123456")
+	if code, _ := member.do("GET", fmt.Sprintf("/api/import/jobs/%d", id), nil); code != 404 {
+		t.Errorf("another member's job: expected 404, got %d", code)
+	}
+
+	// A member may not map names onto other members or create members
+	code, own := member.upload("/api/import/jobs", "daily.csv", []byte("日期,姓名,内容\n2019-12-30,曹凯,冒充导入\n"))
+	if code != 202 {
+		t.Fatalf("member create job: status %d, %v", code, own)
+	}
+	ownID := int(own["id"].(float64))
+	resp := member.doRaw("GET", fmt.Sprintf("/api/import/jobs/%d/events", ownID))
+	io.ReadAll(resp.Body)
+	resp.Body.Close()
+	_, me := c.do("GET", "/api/me", nil)
+	for _, d := range []map[string]interface{}{
+		{"action": "map", "member_id": me["id"]},
+		{"action": "create", "name": "冒充成员", "team_id": 1},
+	} {
+		body := map[string]interface{}{"member_decisions": map[string]interface{}{"曹凯": d}}
+		if code, result := member.do("POST", fmt.Sprintf("/api/import/jobs/%d/confirm", ownID), body); code != 403 {
+			t.Errorf("member decision %v: expected 403, got %d %v", d["action"], code, result)
+		}
+	}
+	t.Logf("OK: import job %d previewed and confirmed asynchronously", id)
+}
+
+func TestAPIImportDiff(t *testing.T) {
+	c := newAPIClient(t)
+	// preview uploads csv and returns its single row and the preview token
+	preview := func(csv string) (map[string]interface{}, string) {
+		code, result := c.upload("/api/import/preview", "daily.csv", []byte("日期,姓名,内容\n"+csv+"\n"))
+		if code != 200 {
+			t.Fatalf("preview: status %d, %v", code, result)
+		}
+		entries, _ := result["entries"].([]interface{})
+		if len(entries) != 1 {
+			t.Fatalf("preview entries: %v", result["entries"])
+		}
+		return entries[0].(map[string]interface{}), result["token"].(string)
+	}
+	confirm := func(token string, policies map[string]string) map[string]interface{} {
+		code, result := c.do("POST", "/api/import/confirm", map[string]interface{}{"token": token, "policies": policies})
+		if code != 200 {
+			t.Fatalf("confirm: status %d, %v", code, result)
+		}
+		return result
+	}
+
+	_, token := preview("2019-12-31,曹凯,核对导入差异")
+	confirm(token, nil)
+
+	// The same file again: identical, skipped by default
+	row, token := preview("2019-12-31,曹凯,核对导入差异")
+	if row["status"] != "identical" || row["policy"] != "skip" {
+		t.Fatalf("re-import: %v", row)
+	}
+	if result := confirm(token, nil); result["unchanged"].(float64) != 1 {
+		t.Errorf("re-import result: %v", result)
+	}
+
+	// Changed text: diffed item by item, merged on request
+	row, token = preview("2019-12-31,曹凯,核对导入差异; 补充合并策略")
+	diff, _ := row["diff"].([]interface{})
+	if row["status"] != "changed" || row["policy"] != "replace" || len(diff) != 2 {
+		t.Fatalf("changed import: %v", row)
+	}
+	key := row["key"].(string)
+	if code, _ := c.do("POST", "/api/import/confirm", map[string]interface{}{"token": token, "policies": map[string]string{key: "overwrite"}}); code != 400 {
+		t.Errorf("unknown policy: expected 400, got %d", code)
+	}
+	if result := confirm(token, map[string]string{key: "merge"}); result["combined"].(float64) != 1 {
+		t.Errorf("merge result: %v", result)
+	}
+	t.Logf("OK: import diff %s", key)
+}
+
+func TestAPIImportRevert(t *testing.T) {
+	c := newAPIClient(t)
+	const newcomer = "回滚测试员"
+	hasMember := func() bool {
+		_, list := c.doList("GET", "/api/members")
+		for _, m := range list {
+			if m.(map[string]interface{})["name"] == newcomer {
+				return true
+			}
+		}
+		return false
+	}
+
+	code, preview := c.upload("/api/import/preview", "revert.csv", []byte("日期,姓名,内容\n2019-12-28,曹凯,回滚前的导入,联调环境延期\n2019-12-28,"+newcomer+",自动创建的成员\n"))
+	if code != 200 {
+		t.Fatalf("preview: status %d, %v", code, preview)
+	}
+	code, result := c.do("POST", "/api/import/confirm", map[string]interface{}{"token": preview["token"]})
+	if code != 200 || result["batch_id"] == nil {
+		t.Fatalf("confirm: status %d, %v", code, result)
+	}
+	id := int(result["batch_id"].(float64))
+	if !hasMember() {
+		t.Fatalf("%s not created by the import", newcomer)
+	}
+	code, batches := c.doList("GET", "/api/import/batches")
+	if code != 200 || len(batches) == 0 || int(batches[0].(map[string]interface{})["id"].(float64)) != id {
+		t.Errorf("list batches: status %d, %v", code, batches)
+	}
+
+	member := &apiClient{t: t}
+	member.login("test08", "123456")
+	if code, _ := member.do("DELETE", fmt.Sprintf("/api/import/batches/%d", id), nil); code != 404 {
+		t.Errorf("another member's batch: expected 404, got %d", code)
+	}
+	code, reverted := c.do("DELETE", fmt.Sprintf("/api/import/batches/%d", id), nil)
+	if code != 200 {
+		t.Fatalf("revert: status %d, %v", code, reverted)
+	}
+	if reverted["daily_entries"].(float64) != 2 || reverted["risks"].(float64) != 1 || reverted["members"].(float64) != 1 {
+		t.Errorf("revert counts: %v", reverted)
+	}
+	if hasMember() {
+		t.Errorf("%s still listed after revert", newcomer)
+	}
+	if code, _ := c.do("DELETE", fmt.Sprintf("/api/import/batches/%d", id), nil); code != 409 {
+		t.Errorf("second revert: expected 409, got %d", code)
+	}
+	t.Logf("OK: import batch %d reverted: %v", id, reverted)
+}
+
+func TestAPIImportRevertOrder(t *testing.T) {
+	c := newAPIClient(t)
+	suffix := fmt.Sprintf("%d", time.Now().UnixNano()%1000000)
+	importDay := func(content, risk string) int {
+		t.Helper()
+		code, preview := c.upload("/api/import/preview", "order.csv", []byte("日期,姓名,内容,风险\n2019-12-27,曹凯,"+content+suffix+","+risk+"\n"))
+		if code != 200 {
+			t.Fatalf("preview: status %d, %v", code, preview)
+		}
+		code, result := c.do("POST", "/api/import/confirm", map[string]interface{}{"token": preview["token"]})
+		if code != 200 || result["batch_id"] == nil {
+			t.Fatalf("confirm: status %d, %v", code, result)
+		}
+		return int(result["batch_id"].(float64))
+	}
+	a := importDay("批次A", "批次A依赖排期"+suffix)
+	b := importDay("批次B", "")
+
+	// A's data is in B's snapshot: reverting A first would bring it back with B
+	if code, result := c.do("DELETE", fmt.Sprintf("/api/import/batches/%d", a), nil); code != 409 {
+		t.Fatalf("revert A before B: expected 409, got %d %v", code, result)
+	}
+	code, reverted := c.do("DELETE", fmt.Sprintf("/api/import/batches/%d", b), nil)
+	// B replaced A's entry and its risk; reverting B puts both back
+	if code != 200 || reverted["restored_entries"].(float64) != 1 || reverted["restored_summaries"].(float64) != 1 ||
+		reverted["restored_risks"].(float64) != 1 {
+		t.Fatalf("revert B: status %d, %v", code, reverted)
+	}
+	code, reverted = c.do("DELETE", fmt.Sprintf("/api/import/batches/%d", a), nil)
+	if code != 200 || reverted["daily_entries"].(float64) != 1 || reverted["risks"].(float64) != 1 {
+		t.Fatalf("revert A after B: status %d, %v", code, reverted)
+	}
+	t.Logf("OK: batches %d and %d reverted newest first", b, a)
+}
+
+func TestAPINotifications(t *testing.T) {
+	c := newAPIClient(t)
+
+	// Run the reminder now; on a workday members without entries get an in-app notification.
+	if code, result := c.do("POST", "/api/reminders/run", nil); code != 200 {
+		t.Fatalf("run reminders: status %d, %v", code, result)
+	}
+	code, result := c.do("GET", "/api/notifications", nil)
+	if code != 200 {
+		t.Fatalf("status %d, %v", code, result)
+	}
+	items, ok := result["items"].([]interface{})
+	if !ok {
+		t.Fatal("missing 'items' field")
+	}
+	if _, ok := result["unread"]; !ok {
+		t.Fatal("missing 'unread' field")
+	}
+	for _, it := range items {
+		if n := it.(map[string]interface{}); n["read_at"] == nil {
+			id := int(n["id"].(float64))
+			if code, _ := c.do("PUT", fmt.Sprintf("/api/notifications/%d/read", id), nil); code != 200 {
+				t.Errorf("mark read: status %d", code)
+			}
+			if code, _ := c.do("PUT", fmt.Sprintf("/api/notifications/%d/read", id), nil); code != 404 {
+				t.Errorf("mark read twice: expected 404, got %d", code)
+			}
+			break
+		}
+	}
+	if code, _ := c.do("PUT", "/api/notifications/read-all", nil); code != 200 {
+		t.Errorf("read-all: status %d", code)
+	}
+	if _, result = c.do("GET", "/api/notifications?unread=true", nil); result["unread"].(float64) != 0 {
+		t.Errorf("unread after read-all: %v", result["unread"])
+	}
+	t.Logf("OK: %d notifications", len(items))
+}
+
+func TestAPIWebhooks(t *testing.T) {
+	c := newAPIClient(t)
+
+	// Receiver on this host; the server under test must be able to reach it.
+	const secret = "e2e-secret"
+	got := make(chan *http.Request, 4)
+	bodies := make(chan []byte, 4)
+	recv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got <- r
+		bodies <- b
+	}))
+	defer recv.Close()
+
+	if code, _ := c.do("POST", "/api/webhooks", map[string]interface{}{"url": recv.URL, "events": []string{"no.such.event"}}); code != 400 {
+		t.Errorf("unknown event: expected 400, got %d", code)
+	}
+	code, result := c.do("POST", "/api/webhooks", map[string]interface{}{
+		"name": "e2e", "url": recv.URL, "events": []string{"topic.resolved", "risk.detected"}, "secret": secret,
+	})
+	if code != 200 || result["secret"] != secret {
+		t.Fatalf("create: status %d, %v", code, result)
+	}
+	id := int(result["webhook"].(map[string]interface{})["id"].(float64))
+	defer c.do("DELETE", fmt.Sprintf("/api/webhooks/%d", id), nil)
+
+	_, result = c.do("GET", "/api/webhooks", nil)
+	hooks, _ := result["webhooks"].([]interface{})
+	found := false
+	for _, h := range hooks {
+		hm := h.(map[string]interface{})
+		if int(hm["id"].(float64)) == id {
+			found = true
+			if _, leaked := hm["secret"]; leaked {
+				t.Error("secret must not be listed")
+			}
+		}
+	}
+	if !found {
+		t.Fatal("created webhook not listed")
+	}
+
+	if code, _ := c.do("POST", fmt.Sprintf("/api/webhooks/%d/ping", id), nil); code != 200 {
+		t.Fatalf("ping: status %d", code)
+	}
+	select {
+	case r := <-got:
+		body := <-bodies
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(r.Header.Get("X-Webhook-Timestamp") + "."))
+		mac.Write(body)
+		if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); r.Header.Get("X-Webhook-Signature") != want {
+			t.Errorf("bad signature %q", r.Header.Get("X-Webhook-Signature"))
+		}
+		if r.Header.Get("X-Webhook-Event") != "ping" {
+			t.Errorf("event header = %q", r.Header.Get("X-Webhook-Event"))
+		}
+	case <-time.After(10 * time.Second):
+		t.Skip("ping not received; server may not reach this host")
+	}
+
+	// The delivery log records the attempt
+	time.Sleep(500 * time.Millisecond)
+	code, list := c.doList("GET", fmt.Sprintf("/api/webhooks/%d/deliveries", id))
+	if code != 200 || len(list) == 0 {
+		t.Fatalf("deliveries: status %d, %d items", code, len(list))
+	}
+	if d := list[0].(map[string]interface{}); d["status"] != "success" {
+		t.Errorf("ping delivery status = %v", d["status"])
+	}
+
+	if code, _ := c.do("PUT", fmt.Sprintf("/api/webhooks/%d", id), map[string]interface{}{"active": false}); code != 200 {
+		t.Errorf("disable: status %d", code)
+	}
+	t.Log("OK: webhook create / ping / deliveries")
+}
+
 func TestAPILogs(t *testing.T) {
 	c := newAPIClient(t)
 
@@ -760,3 +1994,149 @@ func TestFeedback(t *testing.T) {
 	}
 	t.Log("OK: feedback deleted")
 }
+
+func TestAPIDrafts(t *testing.T) {
+	c := newAPIClient(t)
+	date := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+
+	// Stream a report → a draft is stored server-side
+	resp := c.doRaw("POST", "/api/chat/stream", map[string]string{"text": "完成了草稿接口的联调，修复两个分页问题", "mode": "supplement", "date": date})
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "draftId") {
+		t.Fatalf("result event missing draftId: %s", string(body)[:min(len(body), 200)])
+	}
+
+	code, list := c.doList("GET", "/api/drafts")
+	if code != 200 || len(list) == 0 {
+		t.Fatalf("GET /api/drafts: status %d, %d drafts", code, len(list))
+	}
+	draft := list[0].(map[string]interface{})
+	id := int(draft["id"].(float64))
+	if draft["daily_date"] != date {
+		t.Errorf("draft date = %v, want %s", draft["daily_date"], date)
+	}
+
+	// Edit
+	code, updated := c.do("PUT", fmt.Sprintf("/api/drafts/%d", id), map[string]interface{}{"summary": "- 草稿接口联调", "risks": []string{}})
+	if code != 200 || updated["summary"] != "- 草稿接口联调" {
+		t.Fatalf("PUT /api/drafts/%d: %d %v", id, code, updated)
+	}
+	code, _ = c.do("PUT", fmt.Sprintf("/api/drafts/%d", id), map[string]string{"daily_date": "2999-01-01"})
+	if code != 400 {
+		t.Errorf("future date: expected 400, got %d", code)
+	}
+
+	// Discard; confirming it afterwards must not submit anything
+	resp = c.doRaw("DELETE", fmt.Sprintf("/api/drafts/%d", id))
+	resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Fatalf("DELETE /api/drafts/%d: %d", id, resp.StatusCode)
+	}
+	_, result := c.do("POST", "/api/chat", map[string]interface{}{"action": "confirm", "draft_id": id})
+	if result["content"] == "日报已提交成功！" {
+		t.Error("discarded draft was confirmed")
+	}
+	resp = c.doRaw("DELETE", fmt.Sprintf("/api/drafts/%d", id))
+	resp.Body.Close()
+	if resp.StatusCode != 404 {
+		t.Errorf("second DELETE: expected 404, got %d", resp.StatusCode)
+	}
+	t.Log("OK: drafts list/edit/discard")
+}
+
+func TestAPIDailyEntryEditDelete(t *testing.T) {
+	c := newAPIClient(t)
+	// An old weekday nobody reports on, so the day holds only this entry
+	date := "2020-06-03"
+
+	resp := c.doRaw("POST", "/api/chat/stream", map[string]string{"text": "完成了日报编辑接口，补充了权限校验", "mode": "supplement", "date": date})
+	io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if code, result := c.do("POST", "/api/chat", map[string]string{"action": "confirm"}); code != 200 || result["content"] != "日报已提交成功！" {
+		t.Fatalf("confirm failed: %d %v", code, result)
+	}
+
+	code, list := c.doList("GET", "/api/daily/entries?date="+date)
+	if code != 200 || len(list) == 0 {
+		t.Fatalf("GET /api/daily/entries: status %d, %d entries", code, len(list))
+	}
+	id := int(list[len(list)-1].(map[string]interface{})["id"].(float64))
+
+	// Edit → day summary follows
+	code, updated := c.do("PUT", fmt.Sprintf("/api/daily/entries/%d", id), map[string]string{"content": "完成了日报编辑接口", "summary": "- 日报编辑接口"})
+	if code != 200 || updated["summary"] != "- 日报编辑接口" {
+		t.Fatalf("PUT entry: %d %v", code, updated)
+	}
+	_, day := c.do("GET", "/api/calendar/day?date="+date, nil)
+	if len(list) == 1 && day["summary"] != "- 日报编辑接口" {
+		t.Errorf("day summary not rebuilt: %v", day["summary"])
+	}
+
+	// Another member cannot touch it
+	other := &apiClient{t: t}
+	other.login("test01", "123456")
+	if code, _ := other.do("DELETE", fmt.Sprintf("/api/daily/entries/%d", id), nil); code != 403 {
+		t.Errorf("foreign delete: expected 403, got %d", code)
+	}
+
+	// Delete every entry of the day → summary removed
+	for _, e := range list {
+		eid := int(e.(map[string]interface{})["id"].(float64))
+		if code, _ := c.do("DELETE", fmt.Sprintf("/api/daily/entries/%d", eid), nil); code != 200 {
+			t.Fatalf("DELETE entry %d: %d", eid, code)
+		}
+	}
+	_, day = c.do("GET", "/api/calendar/day?date="+date, nil)
+	if day["submitted"] != false {
+		t.Errorf("summary should be removed after deleting all entries: %v", day)
+	}
+	t.Log("OK: daily entry edit/delete")
+}
+
+func TestAPIRisks(t *testing.T) {
+	c := newAPIClient(t)
+	date := time.Now().AddDate(0, 0, -2).Format("2006-01-02")
+
+	// A report that mentions a blocker files an open risk on confirm
+	resp := c.doRaw("POST", "/api/chat/stream", map[string]string{"text": "联调被阻塞，等待后端接口，导入功能可能延期", "mode": "supplement", "date": date})
+	io.ReadAll(resp.Body)
+	resp.Body.Close()
+	c.do("POST", "/api/chat", map[string]string{"action": "confirm"})
+
+	code, list := c.doList("GET", "/api/risks?status=open&start="+date+"&end="+date)
+	if code != 200 {
+		t.Fatalf("GET /api/risks: status %d", code)
+	}
+	if len(list) == 0 {
+		t.Skip("LLM detected no risk")
+	}
+	risk := list[0].(map[string]interface{})
+	for _, f := range []string{"id", "description", "severity", "category", "status", "owner_id", "entry_id"} {
+		if _, ok := risk[f]; !ok {
+			t.Errorf("risk missing field: %s", f)
+		}
+	}
+	id := int(risk["id"].(float64))
+
+	// Triage
+	code, updated := c.do("PUT", fmt.Sprintf("/api/risks/%d", id), map[string]string{"severity": "high", "status": "mitigated"})
+	if code != 200 || updated["severity"] != "high" || updated["status"] != "mitigated" {
+		t.Fatalf("PUT risk: %d %v", code, updated)
+	}
+	if code, _ := c.do("PUT", fmt.Sprintf("/api/risks/%d", id), map[string]string{"category": "weather"}); code != 400 {
+		t.Errorf("invalid category: expected 400, got %d", code)
+	}
+	for field, bad := range map[string]int{"owner_id": 99999999, "topic_id": 99999999} {
+		if code, _ := c.do("PUT", fmt.Sprintf("/api/risks/%d", id), map[string]int{field: bad}); code != 400 {
+			t.Errorf("unknown %s: expected 400, got %d", field, code)
+		}
+	}
+
+	// Close
+	code, closed := c.do("PUT", fmt.Sprintf("/api/risks/%d/close", id), map[string]string{"resolution": "接口已联调通过"})
+	if code != 200 || closed["status"] != "closed" || closed["closed_at"] == nil {
+		t.Fatalf("close risk: %d %v", code, closed)
+	}
+	t.Log("OK: risk triage/close")
+}
@@ -4,8 +4,9 @@ import { ChatInterface } from './components/ChatInterface';
 import { DailyFeed } from './components/DailyFeed';
 import { Stats } from './components/Stats';
 import { MyCalendar } from './components/MyCalendar';
+import { Reports } from './components/Reports';
 import { ViewMode, User } from './types';
-import { getCurrentUser, isLoggedIn, login, listSessions, deleteSession, SessionInfo } from './services/apiService';
+import { getCurrentUser, isLoggedIn, login, logout, changePassword, getAuthProviders, completeSSOLogin, AuthProviders, listSessions, deleteSession, SessionInfo } from './services/apiService';
 
 function LoginPage({ onLogin }: { onLogin: (user: User) => void }): React.ReactElement {
   const [username, setUsername] = useState('');
@@ -13,6 +14,12 @@ function LoginPage({ onLogin }: { onLogin: (user: User) => void }): React.ReactE
   const [error, setError] = useState('');
   const [loading, setLoading] = useState(false);
   const [showPwd, setShowPwd] = useState(false);
+  const [providers, setProviders] = useState<AuthProviders>({ password: ['local'] });
+
+  useEffect(() => {
+    getAuthProviders().then(setProviders).catch(() => {});
+    completeSSOLogin().then(u => { if (u) onLogin(u); }).catch(err => setError((err as Error).message));
+  }, []);
 
   async function handleSubmit(e: React.FormEvent): Promise<void> {
     e.preventDefault();
@@ -133,6 +140,13 @@ function LoginPage({ onLogin }: { onLogin: (user: User) => void }): React.ReactE
             >
               {loading ? '登录中...' : '登 录'}
             </button>
+            {providers.oidc && (
+              <a href={providers.oidc.login_url}
+                className="block w-full py-3 text-sm text-center transition-all hover:opacity-80"
+                style={{ border: '1px solid var(--border)', color: 'var(--text-primary)', borderRadius: '8px' }}>
+                使用 {providers.oidc.name} 登录
+              </a>
+            )}
           </form>
 
           <p className="text-center text-xs mt-8" style={{ color: 'var(--text-faint)', lineHeight: 2 }}>
@@ -147,6 +161,56 @@ function LoginPage({ onLogin }: { onLogin: (user: User) => void }): React.ReactE
   );
 }
 
+function ChangePasswordPage({ onDone }: { onDone: (user: User) => void }): React.ReactElement {
+  const [oldPwd, setOldPwd] = useState('');
+  const [newPwd, setNewPwd] = useState('');
+  const [confirmPwd, setConfirmPwd] = useState('');
+  const [error, setError] = useState('');
+  const [loading, setLoading] = useState(false);
+
+  async function handleSubmit(e: React.FormEvent): Promise<void> {
+    e.preventDefault();
+    if (newPwd !== confirmPwd) { setError('两次输入的新密码不一致'); return; }
+    setLoading(true);
+    setError('');
+    try {
+      onDone(await changePassword(oldPwd, newPwd));
+    } catch (err) {
+      setError((err as Error).message);
+    } finally {
+      setLoading(false);
+    }
+  }
+
+  const inputStyle = { border: 'none', borderBottom: '2px solid var(--border)', color: 'var(--text-primary)' };
+  return (
+    <div className="min-h-screen flex items-center justify-center p-8" style={{ background: 'var(--bg-page)' }}>
+      <form onSubmit={handleSubmit} className="w-full max-w-sm space-y-6">
+        <div>
+          <h1 className="text-2xl font-medium mb-2" style={{ color: 'var(--text-primary)' }}>请修改密码</h1>
+          <p className="text-sm" style={{ color: 'var(--text-muted)' }}>当前密码为初始或临时密码，修改后才能继续使用</p>
+        </div>
+        <input type="password" value={oldPwd} onChange={e => setOldPwd(e.target.value)} placeholder="当前密码" autoFocus
+          className="w-full py-3 text-sm focus:outline-none bg-transparent" style={inputStyle} />
+        <input type="password" value={newPwd} onChange={e => setNewPwd(e.target.value)} placeholder="新密码（至少 8 位）"
+          className="w-full py-3 text-sm focus:outline-none bg-transparent" style={inputStyle} />
+        <input type="password" value={confirmPwd} onChange={e => setConfirmPwd(e.target.value)} placeholder="确认新密码"
+          className="w-full py-3 text-sm focus:outline-none bg-transparent" style={inputStyle} />
+        {error && (
+          <p className="text-red-500 text-xs bg-red-50 px-3 py-2 rounded-lg border border-red-100">⚠ {error}</p>
+        )}
+        <button type="submit" disabled={loading || !oldPwd || !newPwd}
+          className="w-full py-4 text-sm font-medium transition-all disabled:cursor-not-allowed hover:opacity-90"
+          style={{ background: loading ? 'var(--text-muted)' : 'var(--btn-primary)', color: 'var(--btn-primary-text)', borderRadius: '8px' }}>
+          {loading ? '提交中...' : '修改密码'}
+        </button>
+        <button type="button" onClick={() => { logout(); window.location.reload(); }}
+          className="w-full text-xs" style={{ color: 'var(--text-muted)' }}>退出登录</button>
+      </form>
+    </div>
+  );
+}
+
 export default function App(): React.ReactElement {
   const [currentView, setCurrentView] = useState<ViewMode>('chat');
   const [loggedIn, setLoggedIn] = useState(isLoggedIn());
@@ -195,6 +259,10 @@ export default function App(): React.ReactElement {
     }} />;
   }
 
+  if (user.must_change_password) {
+    return <ChangePasswordPage onDone={setUser} />;
+  }
+
   function handleSupplement(date: string) {
     const today = new Date().toLocaleDateString('sv-SE');
     setSupplementDate(date === today ? '__today__' : date);
@@ -211,6 +279,7 @@ export default function App(): React.ReactElement {
         {currentView === 'feed' && <DailyFeed />}
         {currentView === 'stats' && <Stats />}
         {currentView === 'calendar' && <MyCalendar onSupplement={handleSupplement} />}
+        {currentView === 'reports' && <Reports />}
       </>
     );
   }
@@ -1,11 +1,18 @@
 import React, { useState, useRef, useEffect, useMemo } from 'react';
-import { Send, Loader2, Sparkles, FileText, Search, Calendar, FileDown, X, ChevronDown, ChevronRight, Brain, Clock } from 'lucide-react';
+import { Send, Loader2, Sparkles, FileText, Search, Calendar, FileDown, X, ChevronDown, ChevronRight, Brain, Clock, Users, CalendarRange } from 'lucide-react';
 import ReactMarkdown from 'react-markdown';
 import remarkGfm from 'remark-gfm';
 import { Message, User } from '../types';
-import { processUserMessage, MO_LOGO, createSession, loadSessionMessages } from '../services/apiService';
+import { processUserMessage, MO_LOGO, createSession, loadSessionMessages, downloadGeneratedFile, can } from '../services/apiService';
 
-type ChatMode = 'report' | 'query' | 'summary' | 'supplement' | null;
+type ChatMode = 'report' | 'query' | 'summary' | 'digest' | 'review' | 'supplement' | null;
+
+/** Modes that run without input, using DEFAULT_PROMPTS. */
+const DEFAULT_PROMPTS: Partial<Record<string, string>> = {
+  summary: '生成最近一周的周报',
+  digest: '生成最近一周的团队周报',
+  review: '生成上个月的月度总结',
+};
 
 interface ChatInterfaceProps {
   user: User;
@@ -55,6 +62,8 @@ const MODE_LABELS: Record<string, { icon: string; text: string }> = {
   supplement: { icon: '📝', text: '补报' },
   query:      { icon: '🔍', text: '查询' },
   summary:    { icon: '📊', text: '周报' },
+  digest:     { icon: '📋', text: '团队周报' },
+  review:     { icon: '🗓️', text: '阶段总结' },
 };
 
 function formatElapsed(ms: number): string {
@@ -194,8 +203,7 @@ export function ChatInterface({ user, onReportSubmitted, sessionId, onSessionCre
   }
 
   async function handleSend(text: string = input): Promise<void> {
-    if (!text.trim() && activeMode !== 'summary') return;
-    const sendText = text.trim() || (activeMode === 'summary' ? '生成最近一周的周报' : '');
+    const sendText = text.trim() || (activeMode && DEFAULT_PROMPTS[activeMode]) || '';
     if (!sendText) return;
     const isConfirm = ['确认', '是', 'ok', '确认提交', 'confirm'].includes(sendText.toLowerCase());
 
@@ -335,6 +343,8 @@ export function ChatInterface({ user, onReportSubmitted, sessionId, onSessionCre
       case 'report': return '输入今日完成的工作内容...';
       case 'query': return '输入想查询的同事姓名、项目或关键词...';
       case 'summary': return '输入周报的时间范围或重点关注内容...';
+      case 'digest': return '输入团队名称或时间范围，如“研发部上周”...';
+      case 'review': return '输入月份或季度，如“3月”“上季度”“研发部第一季度”...';
       case 'supplement': return selectedDate ? '输入该日的工作内容...' : '请先选择补填日期...';
       default: return '选择上方功能，或随便聊聊...';
     }
@@ -347,6 +357,7 @@ export function ChatInterface({ user, onReportSubmitted, sessionId, onSessionCre
   }
 
   const isEmpty = messages.length <= 1 && messages[0]?.id === 'welcome';
+  const canSendEmpty = !!(activeMode && DEFAULT_PROMPTS[activeMode]);
 
   // Shared input area (used in both layouts)
   const inputArea = (
@@ -355,6 +366,10 @@ export function ChatInterface({ user, onReportSubmitted, sessionId, onSessionCre
         <ModeButton mode="report" label="汇报今日工作" icon={FileText} active={activeMode === 'report'} disabled={isLoading} onToggle={() => toggleMode('report')} />
         <ModeButton mode="supplement" label="补填往期日报" icon={Calendar} active={activeMode === 'supplement'} disabled={isLoading} onToggle={() => toggleMode('supplement')} />
         <ModeButton mode="summary" label="生成周报总结" icon={Sparkles} active={activeMode === 'summary'} disabled={isLoading} onToggle={() => toggleMode('summary')} />
+        <ModeButton mode="review" label="月度/季度总结" icon={CalendarRange} active={activeMode === 'review'} disabled={isLoading} onToggle={() => toggleMode('review')} />
+        {can('report.read') && (
+          <ModeButton mode="digest" label="生成团队周报" icon={Users} active={activeMode === 'digest'} disabled={isLoading} onToggle={() => toggleMode('digest')} />
+        )}
         <ModeButton mode="query" label="查询团队动态" icon={Search} active={activeMode === 'query'} disabled={isLoading} onToggle={() => toggleMode('query')} />
       </div>
 
@@ -398,12 +413,12 @@ export function ChatInterface({ user, onReportSubmitted, sessionId, onSessionCre
 
           <button
             onClick={() => handleSend()}
-            disabled={(activeMode !== 'summary' && !input.trim()) || isLoading || (activeMode === 'supplement' && !selectedDate)}
+            disabled={(!canSendEmpty && !input.trim()) || isLoading || (activeMode === 'supplement' && !selectedDate)}
             className="p-2 m-1.5 rounded-xl transition-all flex-shrink-0"
             style={{
-              background: (input.trim() || activeMode === 'summary') && !isLoading && !(activeMode === 'supplement' && !selectedDate) ? 'var(--btn-primary)' : 'var(--bg-active)',
-              color: (input.trim() || activeMode === 'summary') && !isLoading && !(activeMode === 'supplement' && !selectedDate) ? 'var(--btn-primary-text)' : 'var(--text-muted)',
-              cursor: (input.trim() || activeMode === 'summary') && !isLoading && !(activeMode === 'supplement' && !selectedDate) ? 'pointer' : 'not-allowed',
+              background: (input.trim() || canSendEmpty) && !isLoading && !(activeMode === 'supplement' && !selectedDate) ? 'var(--btn-primary)' : 'var(--bg-active)',
+              color: (input.trim() || canSendEmpty) && !isLoading && !(activeMode === 'supplement' && !selectedDate) ? 'var(--btn-primary-text)' : 'var(--text-muted)',
+              cursor: (input.trim() || canSendEmpty) && !isLoading && !(activeMode === 'supplement' && !selectedDate) ? 'pointer' : 'not-allowed',
             }}
           >
             <Send size={18} />
@@ -574,18 +589,9 @@ export function ChatInterface({ user, onReportSubmitted, sessionId, onSessionCre
                     </div>
                   )}
 
-                  {/* Download card */}
-                  {msg.metadata?.downloadUrl && (
-                    <div className="rounded-xl p-3 shadow-sm mt-2 max-w-sm ml-0 mr-auto flex items-center cursor-pointer transition-colors group" style={{ background: 'var(--bg-input)', border: '1px solid var(--border)' }}>
-                      <div className="w-10 h-10 rounded-lg flex items-center justify-center flex-shrink-0 transition-colors" style={{ background: 'var(--bg-accent)', color: 'var(--text-secondary)' }}>
-                        <FileDown size={20} />
-                      </div>
-                      <div className="ml-3 flex-1 min-w-0">
-                        <p className="text-sm font-medium truncate" style={{ color: 'var(--text-primary)' }}>{msg.metadata.downloadTitle || 'Document.pdf'}</p>
-                        <p className="text-xs" style={{ color: 'var(--text-secondary)' }}>点击下载生成的文档</p>
-                      </div>
-                    </div>
-                  )}
+                  {/* Download cards */}
+                  {msg.metadata?.downloadUrl && <DownloadCard url={msg.metadata.downloadUrl} title={msg.metadata.downloadTitle || 'Document.md'} />}
+                  {msg.metadata?.docxUrl && <DownloadCard url={msg.metadata.docxUrl} title={msg.metadata.docxTitle || 'Document.docx'} />}
 
                   <span className="text-xs block pt-1" style={{ color: 'var(--accent-dot)' }}>
                     {msg.timestamp.toLocaleTimeString([], { hour: '2-digit', minute: '2-digit' })}
@@ -622,6 +628,22 @@ export function ChatInterface({ user, onReportSubmitted, sessionId, onSessionCre
 
 // ============ Sub-components ============
 
+function DownloadCard({ url, title }: { url: string; title: string }): React.ReactElement {
+  const [error, setError] = useState('');
+  return (
+    <div onClick={() => downloadGeneratedFile(url, title).catch(e => setError((e as Error).message))}
+      className="rounded-xl p-3 shadow-sm mt-2 max-w-sm ml-0 mr-auto flex items-center cursor-pointer transition-colors group" style={{ background: 'var(--bg-input)', border: '1px solid var(--border)' }}>
+      <div className="w-10 h-10 rounded-lg flex items-center justify-center flex-shrink-0 transition-colors" style={{ background: 'var(--bg-accent)', color: 'var(--text-secondary)' }}>
+        <FileDown size={20} />
+      </div>
+      <div className="ml-3 flex-1 min-w-0">
+        <p className="text-sm font-medium truncate" style={{ color: 'var(--text-primary)' }}>{title}</p>
+        <p className="text-xs" style={{ color: error ? '#dc2626' : 'var(--text-secondary)' }}>{error || '点击下载生成的文档'}</p>
+      </div>
+    </div>
+  );
+}
+
 function ModeButton({ label, icon: Icon, active, disabled, onToggle }: {
   mode: ChatMode; label: string; icon: React.ElementType;
   active: boolean; disabled: boolean; onToggle: () => void;
@@ -1,11 +1,13 @@
 import React, { useEffect, useState } from 'react';
-import { Member } from '../types';
+import { AccessRole, Member } from '../types';
 import {
   getMembers, updateMember, deleteMember, getTeams, createTeam, Team,
-  getFeedByMember, getFeedByTopic, MemberFeed, TopicFeed,
+  getFeedByMember, getFeedByTopic, MemberFeed, TopicFeed, can,
+  getDeletedMembers, createMember, restoreMember, purgeMember, PurgeReport, TeamFilter, teamTree,
 } from '../services/apiService';
-import { Users, BarChart2, Hash, Pencil, Check, X, Download, Trash2 } from 'lucide-react';
+import { Users, BarChart2, Hash, Pencil, Check, X, Download, Trash2, UserPlus, RotateCcw } from 'lucide-react';
 import { ConfirmModal } from './ConfirmModal';
+import { TeamFilterSelect, TeamManager } from './Teams';
 
 type Tab = 'by-member' | 'by-topic' | 'members';
 
@@ -15,8 +17,10 @@ const STATUS_LABELS: Record<string, { label: string; color: string }> = {
   transferred: { label: '转岗',  color: 'bg-yellow-100 text-yellow-700' },
 };
 const ROLE_OPTIONS = ['Leader', '后端开发', '前端开发', '测试', '开发工程师'];
+const ACCESS_ROLE_LABELS: Record<AccessRole, string> = { member: '成员', team_lead: '团队负责人', admin: '管理员' };
 
-interface EditState { id: number; status: string; teamId: number; role: string }
+interface EditState { id: number; status: string; teamId: number; role: string; accessRole: AccessRole }
+interface CreateState { username: string; name: string; teamId: number; role: string }
 
 export function DailyFeed(): React.ReactElement {
   const [tab, setTab] = useState<Tab>('by-member');
@@ -27,6 +31,12 @@ export function DailyFeed(): React.ReactElement {
   const [saving, setSaving] = useState(false);
   const [deleteTarget, setDeleteTarget] = useState<Member | null>(null);
   const [teamModal, setTeamModal] = useState(false);
+  const [showDeleted, setShowDeleted] = useState(false);
+  const [deletedMembers, setDeletedMembers] = useState<Member[]>([]);
+  const [creating, setCreating] = useState<CreateState | null>(null);
+  const [createError, setCreateError] = useState('');
+  const [createdPassword, setCreatedPassword] = useState<{ name: string; password: string } | null>(null);
+  const [purgeTarget, setPurgeTarget] = useState<PurgeReport | null>(null);
 
   // Feed state
   const [memberFeeds, setMemberFeeds] = useState<MemberFeed[]>([]);
@@ -34,6 +44,7 @@ export function DailyFeed(): React.ReactElement {
   const [dateRange, setDateRange] = useState({ start: '', end: '' });
   const [startInput, setStartInput] = useState('');
   const [endInput, setEndInput] = useState('');
+  const [teamFilter, setTeamFilter] = useState<TeamFilter>({ teamId: 0 });
 
   // Date range presets
   type Preset = '本周' | '上周' | '近7天' | '近30天';
@@ -78,7 +89,7 @@ export function DailyFeed(): React.ReactElement {
       const e = endInput || getPresetRange(activePreset).end;
       if (!startInput) { setStartInput(s); setEndInput(e); }
       setLoading(true);
-      getFeedByMember(s, e).then(r => {
+      getFeedByMember(s, e, undefined, undefined, teamFilter).then(r => {
         setMemberFeeds(r.members); setDateRange({ start: r.start, end: r.end }); setLoading(false);
       });
     } else if (tab === 'by-topic') {
@@ -86,7 +97,7 @@ export function DailyFeed(): React.ReactElement {
       const e = endInput || getPresetRange(activePreset).end;
       if (!startInput) { setStartInput(s); setEndInput(e); }
       setLoading(true);
-      getFeedByTopic(s, e).then(r => {
+      getFeedByTopic(s, e, teamFilter).then(r => {
         setTopicFeeds(r.topics); setDateRange({ start: r.start, end: r.end }); setLoading(false);
       });
     }
@@ -95,30 +106,64 @@ export function DailyFeed(): React.ReactElement {
   function refreshFeed() {
     if (tab === 'by-member') {
       setLoading(true);
-      getFeedByMember(startInput, endInput).then(r => {
+      getFeedByMember(startInput, endInput, undefined, undefined, teamFilter).then(r => {
         setMemberFeeds(r.members); setDateRange({ start: r.start, end: r.end }); setLoading(false);
       });
     } else if (tab === 'by-topic') {
       setLoading(true);
-      getFeedByTopic(startInput, endInput).then(r => {
+      getFeedByTopic(startInput, endInput, teamFilter).then(r => {
         setTopicFeeds(r.topics); setDateRange({ start: r.start, end: r.end }); setLoading(false);
       });
     }
   }
 
-  function startEdit(m: Member) { setEditing({ id: m.id, status: m.status, teamId: m.team_id || 0, role: m.role || '' }); }
+  useEffect(() => { getTeams().then(setTeams); }, []);
+  useEffect(() => { if (startInput) refreshFeed(); }, [teamFilter]);
+
+  function reloadMembers() {
+    Promise.all([getMembers(), getTeams()]).then(([m, t]) => { setMembers(m); setTeams(t); });
+  }
+
+  function startEdit(m: Member) { setEditing({ id: m.id, status: m.status, teamId: m.team_id || 0, role: m.role || '', accessRole: m.access_role || 'member' }); }
   function cancelEdit() { setEditing(null); }
   async function saveEdit(m: Member) {
     if (!editing) return;
     setSaving(true);
     try {
-      await updateMember(m.id, { status: editing.status, team_id: editing.teamId, role: editing.role || undefined });
+      await updateMember(m.id, { status: editing.status, team_id: editing.teamId, role: editing.role || undefined, access_role: editing.accessRole });
       const teamName = teams.find(t => t.id === editing.teamId)?.name || '';
-      setMembers(prev => prev.map(x => x.id === m.id ? { ...x, status: editing.status as Member['status'], team_id: editing.teamId, team_name: teamName, role: editing.role } : x));
+      // a lead moved to another team is no longer its lead and is demoted by the server
+      const accessRole = editing.accessRole === 'team_lead' && editing.teamId !== (m.team_id || 0) ? 'member' : editing.accessRole;
+      setMembers(prev => prev.map(x => x.id === m.id ? { ...x, status: editing.status as Member['status'], team_id: editing.teamId, team_name: teamName, role: editing.role, access_role: accessRole } : x));
       setEditing(null);
     } finally { setSaving(false); }
   }
 
+  function toggleDeleted(show: boolean) {
+    setShowDeleted(show);
+    if (show) getDeletedMembers().then(setDeletedMembers);
+  }
+  async function submitCreate() {
+    if (!creating) return;
+    setSaving(true); setCreateError('');
+    try {
+      const { member, temporary_password } = await createMember({
+        username: creating.username, name: creating.name, team_id: creating.teamId || undefined, role: creating.role || undefined,
+      });
+      const teamName = teams.find(t => t.id === member.team_id)?.name || '';
+      setMembers(prev => [...prev, { ...member, team_name: teamName }]);
+      setCreatedPassword({ name: member.name, password: temporary_password });
+      setCreating(null);
+    } catch (e) {
+      setCreateError((e as Error).message);
+    } finally { setSaving(false); }
+  }
+  async function handleRestore(m: Member) {
+    await restoreMember(m.id);
+    setDeletedMembers(prev => prev.filter(x => x.id !== m.id));
+    setMembers(await getMembers());
+  }
+
   const presets: Preset[] = ['本周', '上周', '近7天', '近30天'];
 
   function selectPreset(p: Preset) {
@@ -127,12 +172,12 @@ export function DailyFeed(): React.ReactElement {
     const { start, end } = getPresetRange(p);
     if (tab === 'by-member') {
       setLoading(true);
-      getFeedByMember(start, end).then(r => {
+      getFeedByMember(start, end, undefined, undefined, teamFilter).then(r => {
         setMemberFeeds(r.members); setDateRange({ start: r.start, end: r.end }); setLoading(false);
       });
     } else if (tab === 'by-topic') {
       setLoading(true);
-      getFeedByTopic(start, end).then(r => {
+      getFeedByTopic(start, end, teamFilter).then(r => {
         setTopicFeeds(r.topics); setDateRange({ start: r.start, end: r.end }); setLoading(false);
       });
     }
@@ -150,6 +195,8 @@ export function DailyFeed(): React.ReactElement {
         </button>
       ))}
       <span className="text-xs" style={{ color: 'var(--text-muted)' }}>{startInput} ~ {endInput}</span>
+      <span className="flex-1" />
+      <TeamFilterSelect teams={teams} value={teamFilter} onChange={setTeamFilter} />
     </div>
   );
 
@@ -255,7 +302,64 @@ export function DailyFeed(): React.ReactElement {
       {/* 成员管理 */}
       {tab === 'members' && (
         <div>
-          {loading ? <LoadingSkeleton /> : (
+          {can('team.manage') && !showDeleted && !loading && <TeamManager members={members} onChanged={reloadMembers} />}
+          {can('member.manage') && (
+            <div className="flex items-center justify-between mb-3">
+              <label className="flex items-center space-x-2 text-sm cursor-pointer" style={{ color: 'var(--text-secondary)' }}>
+                <input type="checkbox" checked={showDeleted} onChange={e => toggleDeleted(e.target.checked)} className="rounded" />
+                <span>查看已删除成员</span>
+              </label>
+              {!showDeleted && (
+                <button onClick={() => { setCreating({ username: '', name: '', teamId: 0, role: '' }); setCreateError(''); }}
+                  className="flex items-center space-x-1 px-3 py-1.5 rounded-lg text-sm" style={{ background: 'var(--btn-primary)', color: '#fff' }}>
+                  <UserPlus size={14} /><span>新建成员</span>
+                </button>
+              )}
+            </div>
+          )}
+          {creating && !showDeleted && (
+            <div className="flex flex-wrap items-center gap-2 mb-3 p-3 rounded-xl text-sm" style={{ background: 'var(--bg-input)', border: '1px solid var(--border)' }}>
+              <input placeholder="登录用户名" value={creating.username} onChange={e => setCreating(prev => prev ? { ...prev, username: e.target.value } : prev)}
+                className="rounded px-2 py-1 focus:outline-none" style={{ border: '1px solid var(--border)', width: 140 }} autoFocus />
+              <input placeholder="姓名" value={creating.name} onChange={e => setCreating(prev => prev ? { ...prev, name: e.target.value } : prev)}
+                className="rounded px-2 py-1 focus:outline-none" style={{ border: '1px solid var(--border)', width: 120 }} />
+              <select className="rounded px-2 py-1 focus:outline-none" style={{ border: '1px solid var(--border)' }}
+                value={creating.role} onChange={e => setCreating(prev => prev ? { ...prev, role: e.target.value } : prev)}>
+                <option value="">职位</option>
+                {ROLE_OPTIONS.map(r => <option key={r} value={r}>{r}</option>)}
+              </select>
+              <select className="rounded px-2 py-1 focus:outline-none" style={{ border: '1px solid var(--border)' }}
+                value={creating.teamId} onChange={e => setCreating(prev => prev ? { ...prev, teamId: Number(e.target.value) } : prev)}>
+                <option value={0}>未分配团队</option>
+                {teamTree(teams).map(({ team: t, depth }) => <option key={t.id} value={t.id}>{'　'.repeat(depth)}{t.name}</option>)}
+              </select>
+              <button onClick={submitCreate} disabled={saving || !creating.username || !creating.name} className="p-1 text-green-600 hover:text-green-700 disabled:opacity-50" title="创建"><Check size={16} /></button>
+              <button onClick={() => setCreating(null)} className="p-1" style={{ color: 'var(--text-muted)' }} title="取消"><X size={16} /></button>
+              {createError && <span className="text-red-600">{createError}</span>}
+            </div>
+          )}
+          {showDeleted ? (
+            <div className="rounded-xl overflow-hidden" style={{ background: 'var(--bg-input)', border: '1px solid var(--border)' }}>
+              <table className="w-full text-sm">
+                <tbody className="divide-y" style={{ borderColor: 'var(--border-light)' }}>
+                  {deletedMembers.map(m => (
+                    <tr key={m.id}>
+                      <td className="px-4 py-3 font-medium" style={{ color: 'var(--text-primary)' }}>{m.name}</td>
+                      <td className="px-4 py-3" style={{ color: 'var(--text-secondary)' }}>{m.username}</td>
+                      <td className="px-4 py-3" style={{ color: 'var(--text-secondary)' }}>{m.team_name || '—'}</td>
+                      <td className="px-4 py-3 text-right">
+                        <div className="flex items-center justify-end space-x-1">
+                          <button onClick={() => handleRestore(m)} className="p-1 transition-colors" style={{ color: 'var(--text-muted)' }} title="恢复"><RotateCcw size={15} /></button>
+                          <button onClick={async () => setPurgeTarget(await purgeMember(m.id, true))} className="p-1 transition-colors hover:text-red-500" style={{ color: 'var(--text-muted)' }} title="彻底删除"><Trash2 size={15} /></button>
+                        </div>
+                      </td>
+                    </tr>
+                  ))}
+                </tbody>
+              </table>
+              {deletedMembers.length === 0 && <Empty text="没有已删除的成员" />}
+            </div>
+          ) : loading ? <LoadingSkeleton /> : (
             <div className="rounded-xl overflow-hidden" style={{ background: 'var(--bg-input)', border: '1px solid var(--border)' }}>
               <table className="w-full text-sm">
                 <thead>
@@ -263,6 +367,7 @@ export function DailyFeed(): React.ReactElement {
                     <th className="text-left px-4 py-3 font-medium" style={{ color: 'var(--text-dim)' }}>姓名</th>
                     <th className="text-left px-4 py-3 font-medium" style={{ color: 'var(--text-dim)' }}>职位</th>
                     <th className="text-left px-4 py-3 font-medium" style={{ color: 'var(--text-dim)' }}>团队</th>
+                    <th className="text-left px-4 py-3 font-medium" style={{ color: 'var(--text-dim)' }}>权限</th>
                     <th className="text-left px-4 py-3 font-medium" style={{ color: 'var(--text-dim)' }}>状态</th>
                     <th className="px-4 py-3" />
                   </tr>
@@ -293,11 +398,19 @@ export function DailyFeed(): React.ReactElement {
                                 else setEditing(prev => prev ? { ...prev, teamId: Number(e.target.value) } : prev);
                               }}>
                               <option value={0}>未分配</option>
-                              {teams.map(t => <option key={t.id} value={t.id}>{t.name}</option>)}
+                              {teamTree(teams).map(({ team: t, depth }) => <option key={t.id} value={t.id}>{'　'.repeat(depth)}{t.name}</option>)}
                               <option value="__new__">＋ 新建团队...</option>
                             </select>
                           ) : <span style={{ color: 'var(--text-secondary)' }}>{m.team_name || '—'}</span>}
                         </td>
+                        <td className="px-4 py-3">
+                          {isEditing ? (
+                            <select className="rounded px-2 py-1 text-sm focus:outline-none" style={{ border: '1px solid var(--border)' }}
+                              value={editing.accessRole} onChange={e => setEditing(prev => prev ? { ...prev, accessRole: e.target.value as AccessRole } : prev)}>
+                              {(Object.keys(ACCESS_ROLE_LABELS) as AccessRole[]).map(r => <option key={r} value={r}>{ACCESS_ROLE_LABELS[r]}</option>)}
+                            </select>
+                          ) : <span style={{ color: 'var(--text-secondary)' }}>{ACCESS_ROLE_LABELS[m.access_role || 'member']}</span>}
+                        </td>
                         <td className="px-4 py-3">
                           {isEditing ? (
                             <select className="rounded px-2 py-1 text-sm focus:outline-none" style={{ border: '1px solid var(--border)' }}
@@ -312,7 +425,7 @@ export function DailyFeed(): React.ReactElement {
                               <button onClick={() => saveEdit(m)} disabled={saving} className="p-1 text-green-600 hover:text-green-700 disabled:opacity-50" title="保存"><Check size={16} /></button>
                               <button onClick={cancelEdit} className="p-1 transition-colors" style={{ color: 'var(--text-muted)' }} title="取消"><X size={16} /></button>
                             </div>
-                          ) : (
+                          ) : can('member.manage') && (
                             <div className="flex items-center justify-end space-x-1">
                               <button onClick={() => startEdit(m)} className="p-1 transition-colors" style={{ color: 'var(--text-muted)' }} title="编辑"><Pencil size={15} /></button>
                               <button onClick={() => setDeleteTarget(m)} className="p-1 transition-colors hover:text-red-500" style={{ color: 'var(--text-muted)' }} title="删除"><Trash2 size={15} /></button>
@@ -330,13 +443,24 @@ export function DailyFeed(): React.ReactElement {
         </div>
       )}
 
-      <ConfirmModal open={!!deleteTarget} title="删除成员" message={`确定要删除「${deleteTarget?.name}」吗？该操作不可撤销。`}
+      <ConfirmModal open={!!deleteTarget} title="删除成员" message={`确定要删除「${deleteTarget?.name}」吗？删除后可在已删除成员中恢复。`}
         confirmText="删除" danger onConfirm={async () => {
           if (!deleteTarget) return;
           await deleteMember(deleteTarget.id);
           setMembers(prev => prev.filter(x => x.id !== deleteTarget.id));
           setDeleteTarget(null);
         }} onCancel={() => setDeleteTarget(null)} />
+      <ConfirmModal open={!!createdPassword} title="成员已创建"
+        message={`「${createdPassword?.name}」的临时密码：${createdPassword?.password}（只显示这一次，首次登录须修改）`}
+        confirmText="我已记下" onConfirm={() => setCreatedPassword(null)} onCancel={() => setCreatedPassword(null)} />
+      <ConfirmModal open={!!purgeTarget} title="彻底删除成员" danger confirmText="彻底删除"
+        message={purgeTarget ? `将永久删除「${purgeTarget.member.name}」及其 ${purgeTarget.removed.daily_entries} 条日报、${purgeTarget.removed.daily_summaries} 条每日总结、${purgeTarget.removed.topic_activities} 条 Topic 动态、${purgeTarget.removed.risks} 条风险，并同步清理问数数据。该操作不可撤销。` : ''}
+        onConfirm={async () => {
+          if (!purgeTarget) return;
+          await purgeMember(purgeTarget.member.id, false);
+          setDeletedMembers(prev => prev.filter(x => x.id !== purgeTarget.member.id));
+          setPurgeTarget(null);
+        }} onCancel={() => setPurgeTarget(null)} />
       <ConfirmModal open={teamModal} title="新建团队" inputMode inputPlaceholder="输入团队名称" confirmText="创建"
         onConfirm={async (name) => {
           if (!name) return;
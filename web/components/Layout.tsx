@@ -1,7 +1,8 @@
 import React, { useState, useEffect } from 'react';
-import { LayoutDashboard, MessageSquare, PieChart, CalendarDays, Menu, X, UploadCloud, FileUp, CheckCircle2, LogOut, Trash2, Eye, PlusCircle, MessageCircle, Send, XCircle } from 'lucide-react';
+import { LayoutDashboard, MessageSquare, PieChart, CalendarDays, FileText, Menu, X, UploadCloud, FileUp, CheckCircle2, LogOut, Trash2, Eye, PlusCircle, MessageCircle, Send, XCircle } from 'lucide-react';
 import { ViewMode, User } from '../types';
-import { MO_LOGO, logout, SessionInfo, previewImport, confirmImport, PreviewEntry, PreviewResult, ConfirmResult, MemberDecision, getTeams, Team, FeedbackItem, submitFeedback, listFeedback, closeFeedback, deleteFeedback } from '../services/apiService';
+import { MO_LOGO, logout, SessionInfo, createImportJob, listImportJobs, confirmImportJob, resumeImportJob, revertImportBatch, watchImportJob, ImportJob, ImportJobView, ImportProgress, ImportPolicy, ImportDiffStatus, PreviewEntry, PreviewResult, ConfirmResult, MemberDecision, getTeams, Team, FeedbackItem, submitFeedback, listFeedback, closeFeedback, deleteFeedback } from '../services/apiService';
+import { ConfirmModal } from './ConfirmModal';
 
 const THEMES = [
   { id: 'warm', label: '暖沙', color: '#C8B898' },
@@ -21,6 +22,18 @@ interface LayoutProps {
   onDeleteSession: (id: number) => void;
 }
 
+const IMPORT_STATUS_LABELS: Record<ImportJob['status'], string> = {
+  parsing: '解析中', extracting: '提取中', preview: '待确认', confirming: '写入中', done: '已完成', failed: '失败',
+};
+
+const DIFF_STATUS_LABELS: Record<ImportDiffStatus, string> = {
+  new: '新增', identical: '相同', changed: '有变化', conflict: '与对话提交冲突',
+};
+
+const DIFF_STATUS_STYLES: Record<ImportDiffStatus, string> = {
+  new: 'bg-green-50 text-green-600', identical: 'bg-gray-100 text-gray-500', changed: 'bg-amber-50 text-amber-600', conflict: 'bg-red-50 text-red-500',
+};
+
 export function Layout({ children, currentView, onChangeView, user, sessions, activeSessionId, onNewChat, onSelectSession, onDeleteSession }: LayoutProps): React.ReactElement {
   const [isMobileMenuOpen, setIsMobileMenuOpen] = useState(false);
   const [isImportModalOpen, setIsImportModalOpen] = useState(false);
@@ -31,7 +44,13 @@ export function Layout({ children, currentView, onChangeView, user, sessions, ac
   const [importResult, setImportResult] = useState<ConfirmResult | null>(null);
   const [importError, setImportError] = useState('');
   const [memberDecisions, setMemberDecisions] = useState<Record<string, MemberDecision>>({});
+  const [importPolicies, setImportPolicies] = useState<Record<string, ImportPolicy>>({});
+  const [expandedDiff, setExpandedDiff] = useState<string | null>(null);
+  const [revertStatus, setRevertStatus] = useState<'idle' | 'asking' | 'reverting' | 'reverted'>('idle');
   const [importTeams, setImportTeams] = useState<Team[]>([]);
+  const [importJobId, setImportJobId] = useState<number | null>(null);
+  const [importProgress, setImportProgress] = useState<ImportProgress | null>(null);
+  const [unfinishedJob, setUnfinishedJob] = useState<ImportJob | null>(null);
   const [elapsedTime, setElapsedTime] = useState(0);
   const timerRef = React.useRef<ReturnType<typeof setInterval> | null>(null);
   const [avatarOk, setAvatarOk] = useState(true);
@@ -73,47 +92,115 @@ export function Layout({ children, currentView, onChangeView, user, sessions, ac
     setIsThemeOpen(false);
   }
 
+  useEffect(() => {
+    if (!isImportModalOpen) return;
+    // Offer to pick up the latest job if it has not finished, e.g. after the page was closed
+    listImportJobs().then(jobs => setUnfinishedJob(jobs[0] && jobs[0].status !== 'done' ? jobs[0] : null)).catch(() => {});
+  }, [isImportModalOpen]);
+
   function handleCloseModal(): void {
     setIsImportModalOpen(false);
-    setTimeout(() => { setUploadStatus('idle'); setPreviewData(null); setImportResult(null); setImportError(''); setMemberDecisions({}); setElapsedTime(0); if (timerRef.current) clearInterval(timerRef.current); }, 300);
+    setTimeout(() => { setUploadStatus('idle'); setPreviewData(null); setImportResult(null); setImportError(''); setMemberDecisions({}); setImportPolicies({}); setExpandedDiff(null); setRevertStatus('idle'); setImportJobId(null); setImportProgress(null); setElapsedTime(0); if (timerRef.current) clearInterval(timerRef.current); }, 300);
   }
 
-  async function handleFileUpload(file: File) {
-    setUploadStatus('uploading');
+  function startTimer() {
     setElapsedTime(0);
+    if (timerRef.current) clearInterval(timerRef.current);
     timerRef.current = setInterval(() => setElapsedTime(t => t + 1), 1000);
+  }
+
+  /** Follows a job until it settles and shows its preview, result or failure. */
+  async function followJob(id: number) {
+    setImportJobId(id);
+    setUnfinishedJob(null);
+    startTimer();
     try {
-      const result = await previewImport(file);
-      setPreviewData(result);
+      const { event, view } = await watchImportJob(id, p => {
+        setImportProgress(p);
+        if (p.status === 'confirming') setUploadStatus('confirming');
+        else if (p.status === 'parsing' || p.status === 'extracting') setUploadStatus('uploading');
+      });
+      showSettledJob(event, view);
+    } catch (e: any) {
+      setImportError(e.message || '导入失败');
+      setUploadStatus('error');
+    }
+    if (timerRef.current) clearInterval(timerRef.current);
+  }
+
+  function showSettledJob(event: 'preview' | 'result' | 'failed', view: ImportJobView) {
+    if (event === 'preview') {
+      const preview: PreviewResult = { entries: view.entries || [], diff_counts: view.diff_counts, unmatched_members: view.unmatched_members || [], members: view.members || [] };
+      setPreviewData(preview);
       // Init decisions: default to "create" for each unmatched member
       const decisions: Record<string, MemberDecision> = {};
-      for (const name of result.unmatched_members) {
+      for (const name of preview.unmatched_members) {
         decisions[name] = { action: 'create', name };
       }
       setMemberDecisions(decisions);
+      const policies: Record<string, ImportPolicy> = {};
+      for (const e of preview.entries) policies[e.key] = e.policy;
+      setImportPolicies(policies);
       getTeams().then(setImportTeams);
-      if (timerRef.current) clearInterval(timerRef.current);
       setUploadStatus('preview');
+    } else if (event === 'result' && view.result) {
+      setImportResult(view.result);
+      setUploadStatus('success');
+    } else {
+      setImportError(view.job?.error || view.error || '导入失败');
+      setUploadStatus('error');
+    }
+  }
+
+  async function handleFileUpload(file: File) {
+    setUploadStatus('uploading');
+    setImportProgress(null);
+    try {
+      const job = await createImportJob(file);
+      await followJob(job.id);
     } catch (e: any) {
-      if (timerRef.current) clearInterval(timerRef.current);
       setImportError(e.message || '解析失败');
       setUploadStatus('error');
     }
   }
 
   async function handleConfirmImport() {
-    if (!previewData?.token) return;
+    if (importJobId === null) return;
     setUploadStatus('confirming');
     try {
-      const result = await confirmImport(previewData.token, Object.keys(memberDecisions).length > 0 ? memberDecisions : undefined);
-      setImportResult(result);
-      setUploadStatus('success');
+      await confirmImportJob(importJobId, Object.keys(memberDecisions).length > 0 ? memberDecisions : undefined, importPolicies);
+      await followJob(importJobId);
     } catch (e: any) {
       setImportError(e.message || '导入失败');
       setUploadStatus('error');
     }
   }
 
+  async function handleRevertImport() {
+    if (!importResult?.batch_id) return;
+    setRevertStatus('reverting');
+    try {
+      await revertImportBatch(importResult.batch_id);
+      setRevertStatus('reverted');
+    } catch (e: any) {
+      setImportError(e.message || '撤销失败');
+      setUploadStatus('error');
+    }
+  }
+
+  async function handleResumeImport() {
+    if (importJobId === null) return;
+    setImportError('');
+    setUploadStatus('uploading');
+    try {
+      await resumeImportJob(importJobId);
+      await followJob(importJobId);
+    } catch (e: any) {
+      setImportError(e.message || '继续失败');
+      setUploadStatus('error');
+    }
+  }
+
   return (
     <div className="flex h-screen overflow-hidden" style={{ background: 'var(--bg-page)', fontFamily: "'Inter', sans-serif" }}>
       {/* Mobile Header */}
@@ -146,6 +233,7 @@ export function Layout({ children, currentView, onChangeView, user, sessions, ac
           <div className="text-xs font-semibold uppercase tracking-wider px-3 mb-3" style={{ color: 'var(--text-muted)' }}>菜单</div>
           <NavItem icon={MessageSquare} label="AI 助手" active={currentView === 'chat' && !activeSessionId} onClick={() => { onNewChat(); setIsMobileMenuOpen(false); }} />
           <NavItem icon={CalendarDays} label="我的日历" active={currentView === 'calendar'} onClick={() => { onChangeView('calendar'); setIsMobileMenuOpen(false); }} />
+          <NavItem icon={FileText} label="我的周报" active={currentView === 'reports'} onClick={() => { onChangeView('reports'); setIsMobileMenuOpen(false); }} />
           <NavItem icon={LayoutDashboard} label="团队动态" active={currentView === 'feed'} onClick={() => { onChangeView('feed'); setIsMobileMenuOpen(false); }} />
           <NavItem icon={PieChart} label="数据洞察" active={currentView === 'stats'} onClick={() => { onChangeView('stats'); setIsMobileMenuOpen(false); }} />
 
@@ -253,7 +341,7 @@ export function Layout({ children, currentView, onChangeView, user, sessions, ac
             </div>
 
             <div className="p-6 overflow-y-auto" style={{ maxHeight: 'calc(80vh - 120px)' }}>
-              <input ref={fileInputRef} type="file" accept=".docx" className="hidden"
+              <input ref={fileInputRef} type="file" accept=".docx,.md,.markdown,.txt,.xlsx,.csv" className="hidden"
                 onChange={e => { const f = e.target.files?.[0]; if (f) handleFileUpload(f); e.target.value = ''; }} />
               {uploadStatus === 'idle' && (
                 <div onClick={() => fileInputRef.current?.click()} className="border-2 border-dashed rounded-xl p-8 flex flex-col items-center justify-center text-center transition-all cursor-pointer group" style={{ borderColor: 'var(--border)' }}>
@@ -261,7 +349,13 @@ export function Layout({ children, currentView, onChangeView, user, sessions, ac
                     <FileUp size={24} style={{ color: 'var(--text-secondary)' }} />
                   </div>
                   <p className="text-sm font-medium mb-1" style={{ color: 'var(--text-primary)' }}>点击上传日报文档</p>
-                  <p className="text-xs" style={{ color: 'var(--text-muted)' }}>支持 .docx 格式</p>
+                  <p className="text-xs" style={{ color: 'var(--text-muted)' }}>支持 .docx / .md / .txt / .xlsx / .csv，日报导出的 xlsx 可直接导回</p>
+                </div>
+              )}
+              {uploadStatus === 'idle' && unfinishedJob && (
+                <div className="mt-4 rounded-lg px-4 py-3 flex items-center justify-between text-sm" style={{ background: 'var(--bg-accent)', color: 'var(--text-dim)' }}>
+                  <span>上次导入未完成：{unfinishedJob.file_name}（{IMPORT_STATUS_LABELS[unfinishedJob.status]}）</span>
+                  <button onClick={() => followJob(unfinishedJob.id)} className="font-medium" style={{ color: 'var(--text-primary)' }}>查看</button>
                 </div>
               )}
               {uploadStatus === 'uploading' && (
@@ -273,6 +367,9 @@ export function Layout({ children, currentView, onChangeView, user, sessions, ac
                     </svg>
                   </div>
                   <p className="text-sm font-medium" style={{ color: 'var(--text-dim)' }}>正在解析文档，AI 提取中... {elapsedTime}s</p>
+                  {importProgress && importProgress.total > 0 && (
+                    <p className="text-xs" style={{ color: 'var(--text-muted)' }}>已提取 {importProgress.done}/{importProgress.total} 段，关闭页面不影响后台处理</p>
+                  )}
                 </div>
               )}
               {uploadStatus === 'preview' && previewData && (
@@ -282,6 +379,9 @@ export function Layout({ children, currentView, onChangeView, user, sessions, ac
                     <span className="px-2 py-1 rounded-md" style={{ background: 'var(--bg-accent)', color: 'var(--text-dim)' }}>{new Set(previewData.entries.map(e => e.date)).size} 天</span>
                     <span className="px-2 py-1 rounded-md" style={{ background: 'var(--bg-accent)', color: 'var(--text-dim)' }}>{new Set(previewData.entries.map(e => e.name)).size} 人</span>
                     <span className="px-2 py-1 rounded-md" style={{ background: 'var(--bg-accent)', color: 'var(--text-dim)' }}>{elapsedTime}s</span>
+                    {(Object.keys(DIFF_STATUS_LABELS) as ImportDiffStatus[]).filter(st => previewData.diff_counts?.[st]).map(st => (
+                      <span key={st} className={`px-2 py-1 rounded-md ${DIFF_STATUS_STYLES[st]}`}>{DIFF_STATUS_LABELS[st]} {previewData.diff_counts?.[st]}</span>
+                    ))}
                   </div>
                   {previewData.unmatched_members.length > 0 && (
                     <div className="space-y-2">
@@ -364,15 +464,48 @@ export function Layout({ children, currentView, onChangeView, user, sessions, ac
                           <th className="px-3 py-2 text-left font-medium" style={{ color: 'var(--text-dim)' }}>日期</th>
                           <th className="px-3 py-2 text-left font-medium" style={{ color: 'var(--text-dim)' }}>成员</th>
                           <th className="px-3 py-2 text-left font-medium" style={{ color: 'var(--text-dim)' }}>工作内容</th>
+                          <th className="px-3 py-2 text-left font-medium" style={{ color: 'var(--text-dim)' }}>对比</th>
+                          <th className="px-3 py-2 text-left font-medium" style={{ color: 'var(--text-dim)' }}>处理</th>
                         </tr>
                       </thead>
                       <tbody className="divide-y" style={{ borderColor: 'var(--border-light)' }}>
                         {previewData.entries.map((e, i) => (
-                          <tr key={i}>
-                            <td className="px-3 py-1.5 whitespace-nowrap" style={{ color: 'var(--text-dim)' }}>{e.date}</td>
-                            <td className="px-3 py-1.5 whitespace-nowrap" style={{ color: 'var(--text-primary)' }}>{e.name}</td>
-                            <td className="px-3 py-1.5 break-all" style={{ color: 'var(--text-dim)' }}>{e.content}</td>
-                          </tr>
+                          <React.Fragment key={i}>
+                            <tr>
+                              <td className="px-3 py-1.5 whitespace-nowrap" style={{ color: 'var(--text-dim)' }}>{e.date}</td>
+                              <td className="px-3 py-1.5 whitespace-nowrap" style={{ color: 'var(--text-primary)' }}>{e.name}</td>
+                              <td className="px-3 py-1.5 break-all" style={{ color: 'var(--text-dim)' }}>{e.content}</td>
+                              <td className="px-3 py-1.5 whitespace-nowrap">
+                                <button disabled={!e.diff?.length} onClick={() => setExpandedDiff(expandedDiff === e.key ? null : e.key)}
+                                  className={`px-1.5 py-0.5 rounded ${DIFF_STATUS_STYLES[e.status]} ${e.diff?.length ? 'cursor-pointer' : 'cursor-default'}`}>
+                                  {DIFF_STATUS_LABELS[e.status]}{e.diff?.length ? (expandedDiff === e.key ? ' ▴' : ' ▾') : ''}
+                                </button>
+                              </td>
+                              <td className="px-3 py-1.5 whitespace-nowrap">
+                                {e.status === 'new' ? <span style={{ color: 'var(--text-dim)' }}>导入</span> : (
+                                  <select className="text-xs rounded px-2 py-1" style={{ border: '1px solid var(--border)', background: 'var(--bg-card)' }}
+                                    value={importPolicies[e.key] || e.policy}
+                                    onChange={ev => setImportPolicies(prev => ({ ...prev, [e.key]: ev.target.value as ImportPolicy }))}>
+                                    <option value="skip">跳过</option>
+                                    <option value="replace">覆盖</option>
+                                    <option value="merge">合并</option>
+                                  </select>
+                                )}
+                              </td>
+                            </tr>
+                            {expandedDiff === e.key && e.diff && (
+                              <tr>
+                                <td colSpan={5} className="px-3 py-2" style={{ background: 'var(--bg-accent)' }}>
+                                  {e.diff.map((op, j) => (
+                                    <div key={j} className={op.type === 'delete' ? 'text-red-500 line-through' : op.type === 'insert' ? 'text-green-600' : ''}
+                                      style={op.type === 'equal' ? { color: 'var(--text-dim)' } : undefined}>
+                                      {op.type === 'delete' ? '− ' : op.type === 'insert' ? '+ ' : '  '}{op.text}
+                                    </div>
+                                  ))}
+                                </td>
+                              </tr>
+                            )}
+                          </React.Fragment>
                         ))}
                       </tbody>
                     </table>
@@ -399,11 +532,22 @@ export function Layout({ children, currentView, onChangeView, user, sessions, ac
                   </div>
                   <h4 className="text-lg font-semibold" style={{ color: 'var(--text-primary)' }}>导入完成</h4>
                   <div className="text-sm space-y-1" style={{ color: 'var(--text-secondary)' }}>
-                    <p>新增 {importResult.imported} 条，覆盖 {importResult.merged} 条，跳过 {importResult.skipped} 条</p>
+                    <p>新增 {importResult.imported} 条，覆盖 {importResult.merged} 条，合并 {importResult.combined} 条，跳过 {importResult.skipped} 条，未变化 {importResult.unchanged} 条</p>
                     <p className="text-xs">数据已同步至 MOI</p>
                     {importResult.skipped_members?.length > 0 && (
                       <p className="text-xs">未匹配成员：{importResult.skipped_members.join('、')}</p>
                     )}
+                    {importResult.batch_id > 0 && (revertStatus === 'reverted' ? (
+                      <p className="text-xs">已撤销本次导入（批次 #{importResult.batch_id}）</p>
+                    ) : (
+                      <button onClick={() => setRevertStatus('asking')} disabled={revertStatus === 'reverting'}
+                        className="text-xs underline disabled:opacity-50" style={{ color: 'var(--text-dim)' }}>
+                        {revertStatus === 'reverting' ? '撤销中...' : `撤销本次导入（批次 #${importResult.batch_id}）`}
+                      </button>
+                    ))}
+                    <ConfirmModal open={revertStatus === 'asking'} title="撤销导入"
+                      message="确定要撤销本次导入吗？导入的日报、Topic 动态和自动创建的成员会被删除，被覆盖的总结会恢复。"
+                      confirmText="撤销" danger onConfirm={handleRevertImport} onCancel={() => setRevertStatus('idle')} />
                   </div>
                 </div>
               )}
@@ -422,6 +566,9 @@ export function Layout({ children, currentView, onChangeView, user, sessions, ac
               {uploadStatus === 'preview' ? (<>
                 <button onClick={handleCloseModal} className="px-4 py-2 text-sm font-medium rounded-lg transition-colors" style={{ color: 'var(--text-dim)' }}>取消</button>
                 <button onClick={handleConfirmImport} className="px-4 py-2 text-sm font-medium text-white rounded-lg transition-colors" style={{ background: 'var(--btn-primary)' }}>确认导入</button>
+              </>) : uploadStatus === 'error' && importProgress?.status === 'failed' ? (<>
+                <button onClick={handleCloseModal} className="px-4 py-2 text-sm font-medium rounded-lg transition-colors" style={{ color: 'var(--text-dim)' }}>关闭</button>
+                <button onClick={handleResumeImport} className="px-4 py-2 text-sm font-medium text-white rounded-lg transition-colors" style={{ background: 'var(--btn-primary)' }}>继续导入</button>
               </>) : (uploadStatus === 'success' || uploadStatus === 'error') ? (
                 <button onClick={handleCloseModal} className="px-4 py-2 text-sm font-medium text-white rounded-lg transition-colors" style={{ background: 'var(--btn-primary)' }}>完成</button>
               ) : uploadStatus === 'idle' ? (
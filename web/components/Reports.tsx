@@ -0,0 +1,125 @@
+import React, { useEffect, useState } from 'react';
+import ReactMarkdown from 'react-markdown';
+import remarkGfm from 'remark-gfm';
+import { SavedReport, listReports, getReport, updateReport, downloadReport, getCurrentUser } from '../services/apiService';
+import { FileDown, Pencil, Check, X, RotateCcw } from 'lucide-react';
+
+/** Saved weekly reports: the caller's own and those generated for team members; view, edit and re-download. */
+export function Reports(): React.ReactElement {
+  const [reports, setReports] = useState<SavedReport[]>([]);
+  const [loading, setLoading] = useState(true);
+  const [selected, setSelected] = useState<SavedReport | null>(null);
+  const [draft, setDraft] = useState<string | null>(null);
+  const [error, setError] = useState('');
+  const uid = String(getCurrentUser().id);
+
+  useEffect(() => {
+    listReports().then(r => { setReports(r); setLoading(false); });
+  }, []);
+
+  async function open(r: SavedReport) {
+    setError(''); setDraft(null);
+    if (selected?.id === r.id) { setSelected(null); return; }
+    try {
+      setSelected(await getReport(r.id));
+    } catch (e) {
+      setError((e as Error).message);
+    }
+  }
+
+  async function save() {
+    if (!selected || draft === null) return;
+    try {
+      const updated = await updateReport(selected.id, { content: draft });
+      setSelected(updated);
+      setReports(prev => prev.map(r => r.id === updated.id ? { ...r, edited: true, updated_at: updated.updated_at } : r));
+      setDraft(null);
+    } catch (e) {
+      setError((e as Error).message);
+    }
+  }
+
+  /** Drops the manual edits; the next generation for this period follows the latest reports again. */
+  async function discardEdits() {
+    if (!selected) return;
+    try {
+      const updated = await updateReport(selected.id, { edited: false });
+      setSelected(updated);
+      setReports(prev => prev.map(r => r.id === updated.id ? { ...r, edited: false } : r));
+    } catch (e) {
+      setError((e as Error).message);
+    }
+  }
+
+  async function download(r: SavedReport, format: 'md' | 'docx') {
+    try {
+      await downloadReport(r, format);
+    } catch (e) {
+      setError((e as Error).message);
+    }
+  }
+
+  const canEdit = (r: SavedReport) => String(r.member_id) === uid || String(r.created_by) === uid;
+  const btn = 'flex items-center space-x-1 px-2 py-1 rounded text-xs hover:bg-[var(--bg-active)]';
+
+  return (
+    <div className="h-full overflow-y-auto" style={{ background: 'var(--bg-page)' }}>
+      <div className="max-w-3xl mx-auto px-4 py-8">
+        <h1 className="text-xl font-semibold mb-1" style={{ color: 'var(--text-primary)' }}>我的周报</h1>
+        <p className="text-sm mb-6" style={{ color: 'var(--text-secondary)' }}>在 AI 助手中生成的周报会保存在这里；日报没有变化时再次生成会直接复用；手动编辑过的周报不会被覆盖，放弃修改后再次生成才会按最新日报更新。</p>
+        {error && <div className="text-sm text-red-600 mb-3">{error}</div>}
+        {loading ? (
+          <div className="text-sm" style={{ color: 'var(--text-muted)' }}>加载中...</div>
+        ) : reports.length === 0 ? (
+          <div className="text-sm" style={{ color: 'var(--text-muted)' }}>还没有周报，在 AI 助手中选择「生成周报总结」即可生成。</div>
+        ) : (
+          <div className="space-y-3">
+            {reports.map(r => (
+              <div key={r.id} className="rounded-xl overflow-hidden" style={{ background: 'var(--bg-card)', border: '1px solid var(--border)' }}>
+                <div className="px-4 py-3 flex items-center justify-between cursor-pointer" onClick={() => open(r)}>
+                  <div>
+                    <div className="text-sm font-medium" style={{ color: 'var(--text-primary)' }}>
+                      {r.member_name} · {r.period_start} ~ {r.period_end}
+                      {r.edited && <span className="ml-2 text-xs" style={{ color: 'var(--text-muted)' }}>已编辑</span>}
+                    </div>
+                    <div className="text-xs mt-0.5" style={{ color: 'var(--text-muted)' }}>{r.title}</div>
+                  </div>
+                  <div className="flex items-center space-x-1" onClick={e => e.stopPropagation()}>
+                    <button className={btn} style={{ color: 'var(--text-dim)' }} onClick={() => download(r, 'md')}><FileDown size={14} /><span>.md</span></button>
+                    <button className={btn} style={{ color: 'var(--text-dim)' }} onClick={() => download(r, 'docx')}><FileDown size={14} /><span>.docx</span></button>
+                  </div>
+                </div>
+                {selected?.id === r.id && (
+                  <div className="px-4 pb-4 border-t" style={{ borderColor: 'var(--border-light)' }}>
+                    <div className="flex justify-end space-x-1 py-2">
+                      {draft === null ? (
+                        canEdit(selected) && <>
+                          {selected.edited && <button className={btn} style={{ color: 'var(--text-dim)' }} onClick={discardEdits}><RotateCcw size={14} /><span>放弃修改</span></button>}
+                          <button className={btn} style={{ color: 'var(--text-dim)' }} onClick={() => setDraft(selected.content || '')}><Pencil size={14} /><span>编辑</span></button>
+                        </>
+                      ) : (
+                        <>
+                          <button className={`${btn} text-green-600`} onClick={save}><Check size={14} /><span>保存</span></button>
+                          <button className={btn} style={{ color: 'var(--text-muted)' }} onClick={() => setDraft(null)}><X size={14} /><span>取消</span></button>
+                        </>
+                      )}
+                    </div>
+                    {draft === null ? (
+                      <div className="prose prose-sm max-w-none" style={{ color: 'var(--text-primary)' }}>
+                        <ReactMarkdown remarkPlugins={[remarkGfm]}>{selected.content || ''}</ReactMarkdown>
+                      </div>
+                    ) : (
+                      <textarea value={draft} onChange={e => setDraft(e.target.value)} rows={18}
+                        className="w-full rounded-lg p-3 text-sm font-mono focus:outline-none"
+                        style={{ background: 'var(--bg-input)', border: '1px solid var(--border)', color: 'var(--text-primary)' }} />
+                    )}
+                  </div>
+                )}
+              </div>
+            ))}
+          </div>
+        )}
+      </div>
+    </div>
+  );
+}
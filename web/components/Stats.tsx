@@ -1,5 +1,6 @@
 import React, { useEffect, useState } from 'react';
-import { getInsights, InsightItem, resolveTopic, updateTopic } from '../services/apiService';
+import { getInsights, InsightItem, resolveTopic, updateTopic, can, getTeams, Team, TeamFilter } from '../services/apiService';
+import { TeamFilterSelect } from './Teams';
 import { AlertTriangle, CheckCircle, Clock, Users, ChevronDown, ChevronUp, Pencil, Check, X } from 'lucide-react';
 
 type RiskFilter = 'all' | 'high' | 'medium' | 'low';
@@ -13,6 +14,7 @@ const RISK_COLORS: Record<string, { bg: string; text: string; label: string }> =
 const PAGE_SIZE = 20;
 
 export function Stats(): React.ReactElement {
+  const canManage = can('topic.manage');
   const [allInsights, setAllInsights] = useState<InsightItem[]>([]);
   const [loading, setLoading] = useState(true);
   const [expanded, setExpanded] = useState<Set<string>>(new Set());
@@ -22,10 +24,14 @@ export function Stats(): React.ReactElement {
   const [selected, setSelected] = useState<Set<number>>(new Set());
   const [renaming, setRenaming] = useState<number | null>(null);
   const [renameValue, setRenameValue] = useState('');
+  const [teams, setTeams] = useState<Team[]>([]);
+  const [teamFilter, setTeamFilter] = useState<TeamFilter>({ teamId: 0 });
 
+  useEffect(() => { getTeams().then(setTeams); }, []);
   useEffect(() => {
-    getInsights().then(r => { setAllInsights(r.insights || []); setLoading(false); });
-  }, []);
+    setLoading(true);
+    getInsights(teamFilter).then(r => { setAllInsights(r.insights || []); setLoading(false); });
+  }, [teamFilter]);
 
   // Derived
   const filtered = allInsights
@@ -109,8 +115,9 @@ export function Stats(): React.ReactElement {
               {label}
             </button>
           ))}
+          <TeamFilterSelect teams={teams} value={teamFilter} onChange={setTeamFilter} />
         </div>
-        {selected.size > 0 && (
+        {canManage && selected.size > 0 && (
           <button onClick={handleBatchResolve}
             className="px-4 py-1.5 rounded-lg text-sm font-medium transition-colors"
             style={{ background: 'var(--btn-primary)', color: '#fff' }}>
@@ -168,12 +175,15 @@ export function Stats(): React.ReactElement {
                     <span className="flex items-center space-x-1"><Clock size={12} /><span>活跃 {item.days} 天</span></span>
                     <span className="flex items-center space-x-1"><Users size={12} /><span>{item.member_count} 人</span></span>
                     <span>{item.entry_count} 条</span>
-                    <button onClick={e => { e.stopPropagation(); setRenaming(item.topic_id); setRenameValue(item.topic); }}
-                      className="p-1 rounded transition-colors hover:bg-gray-100" title="重命名"><Pencil size={13} /></button>
-                    <button onClick={e => { e.stopPropagation(); handleResolve(item.topic_id); }}
-                      className="px-2 py-0.5 rounded text-xs transition-colors hover:bg-green-100 text-green-700" title="标记已解决">
-                      已解决
-                    </button>
+                    {item.open_risks > 0 && <span className="text-red-600">{item.open_risks} 个未关闭风险</span>}
+                    {canManage && (<>
+                      <button onClick={e => { e.stopPropagation(); setRenaming(item.topic_id); setRenameValue(item.topic); }}
+                        className="p-1 rounded transition-colors hover:bg-gray-100" title="重命名"><Pencil size={13} /></button>
+                      <button onClick={e => { e.stopPropagation(); handleResolve(item.topic_id); }}
+                        className="px-2 py-0.5 rounded text-xs transition-colors hover:bg-green-100 text-green-700" title="标记已解决">
+                        已解决
+                      </button>
+                    </>)}
                     <button onClick={() => toggleExpand(item.topic)} className="p-0.5">
                       {isExpanded ? <ChevronUp size={14} /> : <ChevronDown size={14} />}
                     </button>
@@ -186,7 +196,7 @@ export function Stats(): React.ReactElement {
                     </div>
                     {item.risks && item.risks.length > 0 && (
                       <div className="space-y-1">
-                        <div className="text-xs font-medium" style={{ color: 'var(--text-dim)' }}>关联风险项：</div>
+                        <div className="text-xs font-medium" style={{ color: 'var(--text-dim)' }}>未关闭风险项：</div>
                         {item.risks.map((r, i) => (
                           <div key={i} className="text-xs px-3 py-2 rounded-lg bg-red-50">
                             <span className="font-medium text-red-700">{r.member_name}</span>
@@ -0,0 +1,163 @@
+import React, { useEffect, useState } from 'react';
+import { Member } from '../types';
+import { Team, TeamFilter, getTeams, createTeam, updateTeam, deleteTeam, mergeTeam, teamTree } from '../services/apiService';
+import { Check, X, Pencil, Trash2, GitMerge, Plus } from 'lucide-react';
+import { ConfirmModal } from './ConfirmModal';
+
+const INDENT = '　';
+
+/** Team picker for feeds and insights: all teams, one team, or a team with its sub-teams. */
+export function TeamFilterSelect({ teams, value, onChange }: { teams: Team[]; value: TeamFilter; onChange: (v: TeamFilter) => void }): React.ReactElement {
+  return (
+    <span className="inline-flex items-center space-x-2 text-sm">
+      <select className="rounded px-2 py-1 focus:outline-none" style={{ border: '1px solid var(--border)', color: 'var(--text-dim)' }}
+        value={value.teamId} onChange={e => onChange({ ...value, teamId: Number(e.target.value) })}>
+        <option value={0}>全部团队</option>
+        {teamTree(teams).map(({ team, depth }) => <option key={team.id} value={team.id}>{INDENT.repeat(depth)}{team.name}</option>)}
+      </select>
+      {value.teamId > 0 && (
+        <label className="flex items-center space-x-1 cursor-pointer" style={{ color: 'var(--text-secondary)' }}>
+          <input type="checkbox" checked={!!value.subtree} onChange={e => onChange({ ...value, subtree: e.target.checked })} className="rounded" />
+          <span>含下属团队</span>
+        </label>
+      )}
+    </span>
+  );
+}
+
+interface TeamEdit { id: number; name: string; parentId: number; leadId: number }
+
+/** Team management: nesting, leads, rename, delete and merge (team.manage). */
+export function TeamManager({ members, onChanged }: { members: Member[]; onChanged: () => void }): React.ReactElement {
+  const [teams, setTeams] = useState<Team[]>([]);
+  const [editing, setEditing] = useState<TeamEdit | null>(null);
+  const [error, setError] = useState('');
+  const [creating, setCreating] = useState(false);
+  const [deleteTarget, setDeleteTarget] = useState<Team | null>(null);
+  const [mergeSource, setMergeSource] = useState<Team | null>(null);
+  const [mergeTarget, setMergeTarget] = useState(0);
+
+  const reload = () => getTeams().then(setTeams);
+  useEffect(() => { reload(); }, []);
+
+  const tree = teamTree(teams);
+  const nameOf = (id: number) => teams.find(t => t.id === id)?.name || '—';
+  const memberName = (id: number) => members.find(m => m.id === id)?.name || '—';
+
+  async function run(action: () => Promise<unknown>) {
+    setError('');
+    try {
+      await action();
+      await reload();
+      onChanged();
+      return true;
+    } catch (e) {
+      setError((e as Error).message);
+      return false;
+    }
+  }
+  async function save() {
+    if (!editing) return;
+    const t = teams.find(x => x.id === editing.id);
+    if (!t) return;
+    const data: { name?: string; parent_id?: number; lead_id?: number } = {};
+    if (editing.name.trim() !== t.name) data.name = editing.name.trim();
+    if (editing.parentId !== t.parent_id) data.parent_id = editing.parentId;
+    if (editing.leadId !== t.lead_id) data.lead_id = editing.leadId;
+    if (await run(() => updateTeam(t.id, data))) setEditing(null);
+  }
+
+  const selectStyle = { border: '1px solid var(--border)' };
+  return (
+    <div className="mb-6">
+      <div className="flex items-center justify-between mb-2">
+        <span className="text-sm font-medium" style={{ color: 'var(--text-primary)' }}>团队</span>
+        <button onClick={() => setCreating(true)} className="flex items-center space-x-1 px-3 py-1.5 rounded-lg text-sm" style={{ background: 'var(--btn-primary)', color: '#fff' }}>
+          <Plus size={14} /><span>新建团队</span>
+        </button>
+      </div>
+      {error && <div className="text-sm text-red-600 mb-2">{error}</div>}
+      <div className="rounded-xl overflow-hidden" style={{ background: 'var(--bg-input)', border: '1px solid var(--border)' }}>
+        <table className="w-full text-sm">
+          <thead>
+            <tr style={{ background: 'var(--bg-sidebar)', borderBottom: '1px solid var(--border)' }}>
+              <th className="text-left px-4 py-3 font-medium" style={{ color: 'var(--text-dim)' }}>团队</th>
+              <th className="text-left px-4 py-3 font-medium" style={{ color: 'var(--text-dim)' }}>上级团队</th>
+              <th className="text-left px-4 py-3 font-medium" style={{ color: 'var(--text-dim)' }}>组长</th>
+              <th className="text-left px-4 py-3 font-medium" style={{ color: 'var(--text-dim)' }}>成员数</th>
+              <th className="px-4 py-3" />
+            </tr>
+          </thead>
+          <tbody className="divide-y" style={{ borderColor: 'var(--border-light)' }}>
+            {tree.map(({ team: t, depth }) => {
+              const isEditing = editing?.id === t.id;
+              const teamMembers = members.filter(m => m.team_id === t.id);
+              return (
+                <tr key={t.id}>
+                  <td className="px-4 py-3 font-medium" style={{ color: 'var(--text-primary)', paddingLeft: 16 + depth * 20 }}>
+                    {isEditing ? (
+                      <input value={editing.name} onChange={e => setEditing(prev => prev ? { ...prev, name: e.target.value } : prev)}
+                        className="rounded px-2 py-1 focus:outline-none" style={{ ...selectStyle, width: 140 }} />
+                    ) : t.name}
+                  </td>
+                  <td className="px-4 py-3" style={{ color: 'var(--text-secondary)' }}>
+                    {isEditing ? (
+                      <select className="rounded px-2 py-1 focus:outline-none" style={selectStyle}
+                        value={editing.parentId} onChange={e => setEditing(prev => prev ? { ...prev, parentId: Number(e.target.value) } : prev)}>
+                        <option value={0}>无（顶级部门）</option>
+                        {tree.filter(o => o.team.id !== t.id).map(o => <option key={o.team.id} value={o.team.id}>{INDENT.repeat(o.depth)}{o.team.name}</option>)}
+                      </select>
+                    ) : t.parent_id ? nameOf(t.parent_id) : '—'}
+                  </td>
+                  <td className="px-4 py-3" style={{ color: 'var(--text-secondary)' }}>
+                    {isEditing ? (
+                      <select className="rounded px-2 py-1 focus:outline-none" style={selectStyle}
+                        value={editing.leadId} onChange={e => setEditing(prev => prev ? { ...prev, leadId: Number(e.target.value) } : prev)}>
+                        <option value={0}>未指定</option>
+                        {teamMembers.map(m => <option key={m.id} value={m.id}>{m.name}</option>)}
+                      </select>
+                    ) : t.lead_id ? memberName(t.lead_id) : '—'}
+                  </td>
+                  <td className="px-4 py-3" style={{ color: 'var(--text-secondary)' }}>{teamMembers.length}</td>
+                  <td className="px-4 py-3 text-right">
+                    {isEditing ? (
+                      <div className="flex items-center justify-end space-x-2">
+                        <button onClick={save} className="p-1 text-green-600 hover:text-green-700" title="保存"><Check size={16} /></button>
+                        <button onClick={() => setEditing(null)} className="p-1" style={{ color: 'var(--text-muted)' }} title="取消"><X size={16} /></button>
+                      </div>
+                    ) : (
+                      <div className="flex items-center justify-end space-x-1">
+                        <button onClick={() => setEditing({ id: t.id, name: t.name, parentId: t.parent_id, leadId: t.lead_id })} className="p-1" style={{ color: 'var(--text-muted)' }} title="编辑"><Pencil size={15} /></button>
+                        <button onClick={() => { setMergeSource(t); setMergeTarget(0); }} className="p-1" style={{ color: 'var(--text-muted)' }} title="合并到其他团队"><GitMerge size={15} /></button>
+                        <button onClick={() => setDeleteTarget(t)} className="p-1 hover:text-red-500" style={{ color: 'var(--text-muted)' }} title="删除"><Trash2 size={15} /></button>
+                      </div>
+                    )}
+                  </td>
+                </tr>
+              );
+            })}
+          </tbody>
+        </table>
+      </div>
+      {mergeSource && (
+        <div className="flex items-center gap-2 mt-2 p-3 rounded-xl text-sm" style={{ background: 'var(--bg-input)', border: '1px solid var(--border)' }}>
+          <span style={{ color: 'var(--text-dim)' }}>将「{mergeSource.name}」的成员和下属团队合并到</span>
+          <select className="rounded px-2 py-1 focus:outline-none" style={selectStyle} value={mergeTarget} onChange={e => setMergeTarget(Number(e.target.value))}>
+            <option value={0}>选择团队</option>
+            {tree.filter(o => o.team.id !== mergeSource.id).map(o => <option key={o.team.id} value={o.team.id}>{INDENT.repeat(o.depth)}{o.team.name}</option>)}
+          </select>
+          <button disabled={!mergeTarget} className="p-1 text-green-600 hover:text-green-700 disabled:opacity-50" title="合并"
+            onClick={async () => { if (await run(() => mergeTeam(mergeSource.id, mergeTarget))) setMergeSource(null); }}><Check size={16} /></button>
+          <button onClick={() => setMergeSource(null)} className="p-1" style={{ color: 'var(--text-muted)' }} title="取消"><X size={16} /></button>
+        </div>
+      )}
+      <ConfirmModal open={creating} title="新建团队" inputMode inputPlaceholder="输入团队名称" confirmText="创建"
+        onConfirm={async (name) => { if (name && await run(() => createTeam(name))) setCreating(false); }}
+        onCancel={() => setCreating(false)} />
+      <ConfirmModal open={!!deleteTarget} title="删除团队" danger confirmText="删除"
+        message={`确定要删除「${deleteTarget?.name}」吗？团队中还有成员或下属团队时不能删除，请先合并到其他团队。`}
+        onConfirm={async () => { if (deleteTarget) await run(() => deleteTeam(deleteTarget.id)); setDeleteTarget(null); }}
+        onCancel={() => setDeleteTarget(null)} />
+    </div>
+  );
+}
@@ -1,4 +1,4 @@
-import { Message, User } from '../types';
+import { Message, User, WorkStatus } from '../types';
 
 export const MO_LOGO = '/mo-logo.png';
 
@@ -45,13 +45,71 @@ export async function login(username: string, password: string): Promise<User> {
   return data.user;
 }
 
+export interface AuthProviders {
+  password: string[];
+  oidc?: { name: string; login_url: string };
+}
+
+export async function getAuthProviders(): Promise<AuthProviders> {
+  const res = await fetch('/api/auth/providers');
+  if (!res.ok) return { password: ['local'] };
+  return res.json();
+}
+
+/** Finishes an SSO redirect: the callback leaves #sso_token=... (or #sso_error=...) in the URL. */
+export async function completeSSOLogin(): Promise<User | null> {
+  const params = new URLSearchParams(window.location.hash.slice(1));
+  const token = params.get('sso_token');
+  const error = params.get('sso_error');
+  if (!token && !error) return null;
+  history.replaceState(null, '', window.location.pathname + window.location.search);
+  if (error === 'account_unavailable') throw new Error('账号未开通或已停用，请联系管理员');
+  if (error === 'account_conflict') throw new Error('已有同名或同邮箱的本地账号，请使用密码登录或联系管理员');
+  if (error) throw new Error('单点登录失败');
+  const res = await fetch('/api/me', { headers: { Authorization: `Bearer ${token}` } });
+  if (!res.ok) throw new Error('单点登录失败');
+  const user = await res.json();
+  _token = token;
+  _user = user;
+  localStorage.setItem('token', token!);
+  localStorage.setItem('user', JSON.stringify(user));
+  return user;
+}
+
 export function logout(): void {
+  // revoke the token server-side; keepalive lets it finish across the reload that follows
+  if (_token) {
+    fetch('/api/logout', { method: 'POST', headers: { Authorization: `Bearer ${_token}` }, keepalive: true }).catch(() => {});
+  }
   _token = null;
   _user = null;
   localStorage.removeItem('token');
   localStorage.removeItem('user');
 }
 
+/** Changes the caller's password; the server returns a fresh token, like a login. */
+export async function changePassword(oldPassword: string, newPassword: string): Promise<User> {
+  const res = await apiFetch('/api/me/password', {
+    method: 'PUT',
+    body: JSON.stringify({ old_password: oldPassword, new_password: newPassword }),
+  });
+  const data = await res.json();
+  if (!res.ok) throw new Error(data.error || '修改失败');
+  _token = data.token;
+  _user = data.user;
+  localStorage.setItem('token', data.token);
+  localStorage.setItem('user', JSON.stringify(data.user));
+  return data.user;
+}
+
+/** Admin: resets a member's password and returns the one-time temporary password. */
+export async function resetMemberPassword(memberId: number): Promise<string> {
+  const res = await apiFetch(`/api/members/${memberId}/password/reset`, { method: 'POST' });
+  const data = await res.json();
+  if (!res.ok) throw new Error(data.error || '重置失败');
+  return data.temporary_password;
+}
+
 export function isLoggedIn(): boolean {
   return !!_token;
 }
@@ -60,6 +118,11 @@ export function getCurrentUser(): User {
   return _user || { id: '', name: '未登录', avatar: '', role: '' };
 }
 
+/** Whether the current user's role grants the permission, e.g. 'topic.manage'. */
+export function can(permission: string): boolean {
+  return !!_user?.permissions?.includes(permission);
+}
+
 // ============ Chat ============
 
 interface StreamCallbacks {
@@ -84,9 +147,11 @@ export async function processUserMessage(
 
   // confirm 走非流式
   if (isConfirmation) {
+    // 确认最近一条待确认摘要对应的草稿；找不到时后端确认最新草稿
+    const draftId = [...history].reverse().find(m => m.type === 'summary_confirm')?.metadata?.draftId;
     const res = await apiFetch('/api/chat', {
       method: 'POST',
-      body: JSON.stringify({ action: 'confirm', session_id: sessionId }),
+      body: JSON.stringify({ action: 'confirm', session_id: sessionId, draft_id: draftId }),
     });
     const data = await res.json();
     return {
@@ -185,6 +250,48 @@ export async function processUserMessage(
   };
 }
 
+/** Downloads a file the assistant generated; team digest and review links work once, saved weekly reports any time. */
+export async function downloadGeneratedFile(url: string, filename: string): Promise<void> {
+  const res = await apiFetch(url);
+  if (!res.ok) throw new Error('文件已过期，请重新生成');
+  const blob = await res.blob();
+  const a = document.createElement('a');
+  a.href = URL.createObjectURL(blob);
+  a.download = filename;
+  a.click(); URL.revokeObjectURL(a.href);
+}
+
+// ============ Weekly reports ============
+
+export interface SavedReport {
+  id: number; member_id: number; member_name: string; created_by: number;
+  period_start: string; period_end: string; title: string; content?: string; edited: boolean;
+  created_at: string; updated_at: string;
+}
+
+/** Lists the caller's weekly reports and those they generated for team members, latest first. */
+export async function listReports(): Promise<SavedReport[]> {
+  const res = await apiFetch('/api/reports');
+  if (!res.ok) return [];
+  return res.json();
+}
+
+export async function getReport(id: number): Promise<SavedReport> {
+  const res = await apiFetch(`/api/reports/${id}`);
+  if (!res.ok) throw new Error((await res.json()).error || '周报不存在');
+  return res.json();
+}
+
+export async function updateReport(id: number, data: { title?: string; content?: string; edited?: false }): Promise<SavedReport> {
+  const res = await apiFetch(`/api/reports/${id}`, { method: 'PUT', body: JSON.stringify(data) });
+  if (!res.ok) throw new Error((await res.json()).error || '保存失败');
+  return res.json();
+}
+
+export function downloadReport(r: SavedReport, format: 'md' | 'docx'): Promise<void> {
+  return downloadGeneratedFile(`/api/reports/${r.id}/download?format=${format}`, `${r.title}.${format}`);
+}
+
 // ============ Sessions ============
 
 export type SessionInfo = { id: number; title: string; created_at: number; updated_at: number };
@@ -233,54 +340,127 @@ function tryParse(s: string): any { try { return JSON.parse(s); } catch { return
 
 // ============ Import ============
 
-export interface PreviewEntry { date: string; name: string; content: string }
+export type ImportPolicy = 'skip' | 'replace' | 'merge';
+export type ImportDiffStatus = 'new' | 'identical' | 'changed' | 'conflict';
+export interface DiffOp { type: 'equal' | 'delete' | 'insert'; text: string }
+/** A preview row compared with the data already stored for that member and day. */
+export interface PreviewEntry { date: string; name: string; content: string; risk?: string; key: string; status: ImportDiffStatus; existing?: string; diff?: DiffOp[]; policy: ImportPolicy }
 export interface PreviewMember { id: number; name: string }
-export interface PreviewResult { token: string; entries: PreviewEntry[]; unmatched_members: string[]; members: PreviewMember[] }
+export interface PreviewResult { entries: PreviewEntry[]; diff_counts?: Partial<Record<ImportDiffStatus, number>>; unmatched_members: string[]; members: PreviewMember[] }
 export interface MemberDecision { action: 'create' | 'map' | 'ignore'; name?: string; member_id?: number; team_id?: number; role?: string }
-export interface ConfirmResult { imported: number; merged: number; skipped: number; skipped_members: string[]; total: number }
-
-export async function previewImport(file: File): Promise<PreviewResult> {
-  const form = new FormData();
-  form.append('file', file);
-  const res = await fetch('/api/import/preview', {
-    method: 'POST',
-    headers: _token ? { 'Authorization': `Bearer ${_token}` } : {},
-    body: form,
+export interface ConfirmResult { batch_id: number; imported: number; merged: number; combined: number; skipped: number; unchanged: number; skipped_members: string[]; total: number }
+
+export type ImportJobStatus = 'parsing' | 'extracting' | 'preview' | 'confirming' | 'done' | 'failed';
+export interface ImportJob { id: number; file_name: string; format: string; status: ImportJobStatus; error: string; created_at: string }
+export interface ImportProgress { job_id: number; status: ImportJobStatus; error?: string; total: number; done: number; failed: number }
+/** A job as the server reports it: the preview fields from the preview on, the result once done. */
+export interface ImportJobView extends Partial<PreviewResult> { job?: ImportJob; progress?: ImportProgress; result?: ConfirmResult; error?: string }
+
+async function importRequest<T>(path: string, init: RequestInit, fallback: string): Promise<T> {
+  const res = await fetch(path, {
+    ...init,
+    headers: { ...(init.headers as Record<string, string> || {}), ...(_token ? { 'Authorization': `Bearer ${_token}` } : {}) },
   });
   if (!res.ok) {
-    const err = await res.json().catch(() => ({ error: '解析失败' }));
-    throw new Error(err.error || '解析失败');
+    const err = await res.json().catch(() => ({ error: fallback }));
+    throw new Error(err.error || fallback);
   }
   return res.json();
 }
 
-export async function confirmImport(token: string, memberDecisions?: Record<string, MemberDecision>): Promise<ConfirmResult> {
-  const body: any = { token };
-  if (memberDecisions) body.member_decisions = memberDecisions;
-  const res = await fetch('/api/import/confirm', {
+/** Uploads a file and starts an import job; extraction runs on the server even if the page is closed. */
+export async function createImportJob(file: File): Promise<ImportJob> {
+  const form = new FormData();
+  form.append('file', file);
+  return importRequest('/api/import/jobs', { method: 'POST', body: form }, '解析失败');
+}
+
+export async function listImportJobs(): Promise<ImportJob[]> {
+  return importRequest('/api/import/jobs', {}, '加载失败');
+}
+
+/** policies maps preview row keys to what to do with days that already have data. */
+export async function confirmImportJob(id: number, memberDecisions?: Record<string, MemberDecision>, policies?: Record<string, ImportPolicy>): Promise<void> {
+  await importRequest(`/api/import/jobs/${id}/confirm`, {
     method: 'POST',
-    headers: { 'Content-Type': 'application/json', ...(_token ? { 'Authorization': `Bearer ${_token}` } : {}) },
-    body: JSON.stringify(body),
-  });
-  if (!res.ok) {
-    const err = await res.json().catch(() => ({ error: '导入失败' }));
-    throw new Error(err.error || '导入失败');
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ member_decisions: memberDecisions, policies }),
+  }, '导入失败');
+}
+
+export interface RevertResult { batch_id: number; daily_entries: number; daily_summaries: number; topic_activities: number; restored_summaries: number; restored_entries: number; restored_risks: number; members: number; kept_members: number }
+
+/** Undoes everything one confirmed import wrote, restoring the summaries it replaced. */
+export async function revertImportBatch(id: number): Promise<RevertResult> {
+  return importRequest(`/api/import/batches/${id}`, { method: 'DELETE' }, '撤销失败');
+}
+
+/** Continues a failed job: sections already extracted are not extracted again. */
+export async function resumeImportJob(id: number): Promise<void> {
+  await importRequest(`/api/import/jobs/${id}/resume`, { method: 'POST' }, '继续失败');
+}
+
+/** Follows a job's progress over SSE until it reaches its preview, its result or a failure; a dropped stream is reopened. */
+export async function watchImportJob(id: number, onProgress: (p: ImportProgress) => void): Promise<{ event: 'preview' | 'result' | 'failed'; view: ImportJobView }> {
+  for (let attempt = 0; attempt < 6; attempt++) {
+    if (attempt > 0) await new Promise(r => setTimeout(r, 2000));
+    try {
+      const res = await apiFetch(`/api/import/jobs/${id}/events`);
+      if (!res.ok) {
+        const err = await res.json().catch(() => ({ error: '导入任务不存在' }));
+        return { event: 'failed', view: { error: err.error } };
+      }
+      const reader = res.body!.getReader();
+      const decoder = new TextDecoder();
+      let buffer = '';
+      let currentEvent = '';
+      while (true) {
+        const { done, value } = await reader.read();
+        if (done) break;
+        buffer += decoder.decode(value, { stream: true });
+        const lines = buffer.split('\n');
+        buffer = lines.pop() || '';
+        for (const line of lines) {
+          if (line.startsWith('event: ')) {
+            currentEvent = line.slice(7);
+          } else if (line.startsWith('data: ')) {
+            let data: any;
+            try { data = JSON.parse(line.slice(6)); } catch { continue; }
+            if (currentEvent === 'progress') onProgress(data);
+            else if (currentEvent === 'preview' || currentEvent === 'result' || currentEvent === 'failed') return { event: currentEvent, view: data };
+          }
+        }
+      }
+    } catch { /* reconnect */ }
   }
-  return res.json();
+  throw new Error('连接中断，任务仍在后台运行，可稍后重新打开导入窗口查看');
 }
 
 // ============ Feed ============
 
 export interface MemberDailySummary {
-  member_id: number; member_name: string; daily_date: string; summary: string; risk: string;
+  member_id: number; member_name: string; daily_date: string; summary: string;
+  status: WorkStatus | ''; risk: string; blocker: string;
 }
 export interface MemberFeed { member_id: number; member_name: string; items: MemberDailySummary[] }
 export interface FeedByMemberResult { start: string; end: string; members: MemberFeed[] }
 
-export async function getFeedByMember(start?: string, end?: string): Promise<FeedByMemberResult> {
+/** Narrows feeds and insights to a team, with subtree also to the teams nested below it. */
+export interface TeamFilter { teamId: number; subtree?: boolean }
+
+function setTeamFilter(params: URLSearchParams, team?: TeamFilter) {
+  if (!team?.teamId) return;
+  params.set('team_id', String(team.teamId));
+  if (team.subtree) params.set('subtree', 'true');
+}
+
+export async function getFeedByMember(start?: string, end?: string, status?: WorkStatus[], blocked?: boolean, team?: TeamFilter): Promise<FeedByMemberResult> {
   const params = new URLSearchParams();
   if (start) params.set('start', start);
   if (end) params.set('end', end);
+  if (status?.length) params.set('status', status.join(','));
+  if (blocked) params.set('blocked', 'true');
+  setTeamFilter(params, team);
   const res = await apiFetch(`/api/feed/by-member?${params}`);
   return res.json();
 }
@@ -289,23 +469,29 @@ export interface TopicActivityItem { member_name: string; daily_date: string; co
 export interface TopicFeed { topic: string; members: string[]; items: TopicActivityItem[] }
 export interface FeedByTopicResult { start: string; end: string; topics: TopicFeed[] }
 
-export async function getFeedByTopic(start?: string, end?: string): Promise<FeedByTopicResult> {
+export async function getFeedByTopic(start?: string, end?: string, team?: TeamFilter): Promise<FeedByTopicResult> {
   const params = new URLSearchParams();
   if (start) params.set('start', start);
   if (end) params.set('end', end);
+  setTeamFilter(params, team);
   const res = await apiFetch(`/api/feed/by-topic?${params}`);
   return res.json();
 }
 
-export interface TopicRiskItem { topic: string; member_name: string; daily_date: string; risk: string }
+export interface TopicRiskItem {
+  id: number; topic: string; member_name: string; daily_date: string; risk: string;
+  severity: string; category: string; status: string;
+}
 export interface InsightItem {
   topic_id: number; topic: string; first_date: string; last_date: string; days: number;
-  member_count: number; entry_count: number; risk_level: string; risks: TopicRiskItem[] | null;
+  member_count: number; entry_count: number; risk_level: string; open_risks: number; risks: TopicRiskItem[] | null;
 }
 export interface InsightsResult { insights: InsightItem[] }
 
-export async function getInsights(): Promise<InsightsResult> {
-  const res = await apiFetch('/api/insights');
+export async function getInsights(team?: TeamFilter): Promise<InsightsResult> {
+  const params = new URLSearchParams();
+  setTeamFilter(params, team);
+  const res = await apiFetch(`/api/insights?${params}`);
   return res.json();
 }
 
@@ -341,7 +527,7 @@ export async function getMembers(): Promise<Member[]> {
   return res.json();
 }
 
-export async function updateMember(id: number, data: { status?: string; team?: string; team_id?: number; role?: string }): Promise<void> {
+export async function updateMember(id: number, data: { status?: string; team_id?: number; role?: string; access_role?: string }): Promise<void> {
   await apiFetch(`/api/members/${id}`, { method: 'PUT', body: JSON.stringify(data) });
 }
 
@@ -349,16 +535,87 @@ export async function deleteMember(id: number): Promise<void> {
   await apiFetch(`/api/members/${id}`, { method: 'DELETE' });
 }
 
-export type Team = { id: number; name: string };
+export async function getDeletedMembers(): Promise<Member[]> {
+  const res = await apiFetch('/api/members?status=deleted');
+  return res.json();
+}
+
+export interface NewMember { username: string; name: string; role?: string; team_id?: number; email?: string; access_role?: string }
+
+/** Creates a member; the temporary password is only returned here. */
+export async function createMember(data: NewMember): Promise<{ member: Member; temporary_password: string }> {
+  const res = await apiFetch('/api/members', { method: 'POST', body: JSON.stringify(data) });
+  const body = await res.json();
+  if (!res.ok) throw new Error(body.error || '创建失败');
+  return body;
+}
+
+export async function restoreMember(id: number): Promise<void> {
+  const res = await apiFetch(`/api/members/${id}/restore`, { method: 'POST' });
+  if (!res.ok) throw new Error((await res.json()).error || '恢复失败');
+}
+
+export interface PurgeReport {
+  member: Member; dry_run: boolean;
+  removed: { daily_entries: number; daily_summaries: number; topic_activities: number; risks: number; report_drafts: number; notifications: number; revoked_tokens: number; period_summaries: number; reports: number; member_identities: number; import_batch_snapshots: number; team_period_summaries: number; owned_risks: number };
+}
+
+/** Permanently removes a deleted member and their data; dryRun only reports what would go. */
+export async function purgeMember(id: number, dryRun: boolean): Promise<PurgeReport> {
+  const res = await apiFetch(`/api/members/${id}/purge${dryRun ? '?dry_run=true' : ''}`, { method: 'DELETE' });
+  const body = await res.json();
+  if (!res.ok) throw new Error(body.error || '删除失败');
+  return body;
+}
+
+/** parent_id 0 is a top-level department; lead_id 0 means no designated lead. */
+export type Team = { id: number; name: string; parent_id: number; lead_id: number };
 
 export async function getTeams(): Promise<Team[]> {
   const res = await apiFetch('/api/teams');
   return res.json();
 }
 
-export async function createTeam(name: string): Promise<Team> {
-  const res = await apiFetch('/api/teams', { method: 'POST', body: JSON.stringify({ name }) });
-  return res.json();
+export async function createTeam(name: string, parentId = 0): Promise<Team> {
+  const res = await apiFetch('/api/teams', { method: 'POST', body: JSON.stringify({ name, parent_id: parentId }) });
+  const body = await res.json();
+  if (!res.ok) throw new Error(body.error || '创建失败');
+  return body;
+}
+
+export async function updateTeam(id: number, data: { name?: string; parent_id?: number; lead_id?: number }): Promise<Team> {
+  const res = await apiFetch(`/api/teams/${id}`, { method: 'PUT', body: JSON.stringify(data) });
+  const body = await res.json();
+  if (!res.ok) throw new Error(body.error || '保存失败');
+  return body;
+}
+
+/** Deletes a team without members or sub-teams; merge others instead. */
+export async function deleteTeam(id: number): Promise<void> {
+  const res = await apiFetch(`/api/teams/${id}`, { method: 'DELETE' });
+  if (!res.ok) throw new Error((await res.json()).error || '删除失败');
+}
+
+/** Moves the source team's members and sub-teams into the target and deletes the source. */
+export async function mergeTeam(sourceId: number, targetId: number): Promise<void> {
+  const res = await apiFetch('/api/teams/merge', { method: 'POST', body: JSON.stringify({ source_id: sourceId, target_id: targetId }) });
+  if (!res.ok) throw new Error((await res.json()).error || '合并失败');
+}
+
+/** Teams in tree order, parents before their sub-teams, with their depth (for indented selects). */
+export function teamTree(teams: Team[]): { team: Team; depth: number }[] {
+  const out: { team: Team; depth: number }[] = [];
+  const seen = new Set<number>();
+  const walk = (parent: number, depth: number) => {
+    for (const t of teams) {
+      if (t.parent_id !== parent || seen.has(t.id)) continue;
+      seen.add(t.id); out.push({ team: t, depth }); walk(t.id, depth + 1);
+    }
+  };
+  walk(0, 0);
+  // a team whose parent no longer exists is shown at the top level
+  for (const t of teams) if (!seen.has(t.id)) { seen.add(t.id); out.push({ team: t, depth: 0 }); walk(t.id, 1); }
+  return out;
 }
 
 // ============ Calendar ============
@@ -369,6 +626,7 @@ export interface CalendarDay {
   is_workday: boolean;
   holiday?: string;
   submitted: boolean;
+  status?: WorkStatus;
 }
 
 export interface CalendarData {
@@ -387,7 +645,9 @@ export interface DaySummary {
   date: string;
   submitted: boolean;
   summary?: string;
+  status?: WorkStatus | '';
   risk?: string;
+  blocker?: string;
 }
 
 export async function getDaySummary(date: string): Promise<DaySummary> {
@@ -395,6 +655,82 @@ export async function getDaySummary(date: string): Promise<DaySummary> {
   return res.json();
 }
 
+// ============ Compliance ============
+
+export interface MemberCompliance {
+  member_id: number; member_name: string; team_id: number;
+  workdays: number; filled_days: number; missing_dates: string[]; streak: number;
+}
+export interface ComplianceReport { month: string; team_id: number; subtree: boolean; workdays: number; members: MemberCompliance[] }
+
+export async function getCompliance(month: string, teamId?: number, subtree?: boolean): Promise<ComplianceReport> {
+  const params = new URLSearchParams({ month });
+  setTeamFilter(params, teamId ? { teamId, subtree } : undefined);
+  const res = await apiFetch(`/api/compliance?${params}`);
+  return res.json();
+}
+
+// ============ Notifications ============
+
+export interface NotificationItem {
+  id: number; member_id: number; kind: string; title: string; content: string; read_at?: string; created_at: string;
+}
+
+export async function listNotifications(unreadOnly = false): Promise<{ items: NotificationItem[]; unread: number }> {
+  const res = await apiFetch(`/api/notifications${unreadOnly ? '?unread=true' : ''}`);
+  return res.json();
+}
+
+export async function markNotificationRead(id: number): Promise<void> {
+  await apiFetch(`/api/notifications/${id}/read`, { method: 'PUT' });
+}
+
+export async function markAllNotificationsRead(): Promise<void> {
+  await apiFetch('/api/notifications/read-all', { method: 'PUT' });
+}
+
+// ============ Webhooks (admin) ============
+
+export interface Webhook {
+  id: number; name: string; url: string; events: string[]; active: boolean; created_by: number; created_at: string; updated_at: string;
+}
+export interface WebhookDelivery {
+  id: number; webhook_id: number; event: string; payload: string; status: 'pending' | 'success' | 'failed';
+  attempts: number; response_code: number; error: string; next_attempt_at?: string; created_at: string; delivered_at?: string;
+}
+export interface WebhookInput { name?: string; url?: string; events?: string[]; secret?: string; active?: boolean }
+
+export async function listWebhooks(): Promise<{ webhooks: Webhook[]; events: string[] }> {
+  const res = await apiFetch('/api/webhooks');
+  return res.json();
+}
+
+export async function createWebhook(input: WebhookInput): Promise<{ webhook: Webhook; secret: string }> {
+  const res = await apiFetch('/api/webhooks', { method: 'POST', body: JSON.stringify(input) });
+  if (!res.ok) throw new Error((await res.json()).error || '创建失败');
+  return res.json();
+}
+
+export async function updateWebhook(id: number, input: WebhookInput): Promise<Webhook> {
+  const res = await apiFetch(`/api/webhooks/${id}`, { method: 'PUT', body: JSON.stringify(input) });
+  if (!res.ok) throw new Error((await res.json()).error || '保存失败');
+  return res.json();
+}
+
+export async function deleteWebhook(id: number): Promise<void> {
+  await apiFetch(`/api/webhooks/${id}`, { method: 'DELETE' });
+}
+
+export async function listWebhookDeliveries(id: number): Promise<WebhookDelivery[]> {
+  const res = await apiFetch(`/api/webhooks/${id}/deliveries`);
+  return res.json();
+}
+
+export async function pingWebhook(id: number): Promise<WebhookDelivery> {
+  const res = await apiFetch(`/api/webhooks/${id}/ping`, { method: 'POST' });
+  return res.json();
+}
+
 // ============ Feedback ============
 
 export interface FeedbackItem {
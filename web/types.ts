@@ -4,17 +4,29 @@ export type User = {
   avatar: string;
   role: string;
   is_admin?: boolean;
+  access_role?: AccessRole;
+  permissions?: string[];
+  must_change_password?: boolean;
 };
 
+export type AccessRole = 'member' | 'team_lead' | 'admin';
+
+export type WorkStatus = 'on-track' | 'at-risk' | 'blocked' | 'off';
+
 export type MessageType = 'text' | 'system' | 'summary_confirm';
 
 export type MessageMetadata = {
   summary?: string;
   risks?: string[];
+  status?: WorkStatus;
+  blockers?: string[];
   isSupplement?: boolean;
   supplementDate?: string;
+  draftId?: number;
   downloadUrl?: string;
   downloadTitle?: string;
+  docxUrl?: string;
+  docxTitle?: string;
   mode?: string;
   confirmed?: boolean;
   dismissed?: boolean;
@@ -47,7 +59,7 @@ export type DailyReport = {
   timestamp: Date;
 };
 
-export type ViewMode = 'chat' | 'feed' | 'stats' | 'calendar';
+export type ViewMode = 'chat' | 'feed' | 'stats' | 'calendar' | 'reports';
 
 export type Member = {
   id: number;
@@ -55,8 +67,9 @@ export type Member = {
   name: string;
   avatar: string;
   role: string;
-  team: string;
   team_id: number;
   team_name: string;
   status: 'active' | 'resigned' | 'transferred';
+  email?: string;
+  access_role?: AccessRole;
 };